@@ -5,15 +5,24 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
 )
 
 // GenerateRequest represents a request to generate AI code
 type GenerateRequest struct {
-	Prompt      string   `json:"prompt" binding:"required"`
-	UserID      string   `json:"user_id,omitempty"`
-	Model       string   `json:"model,omitempty"`
-	Temperature *float64 `json:"temperature,omitempty"`
-	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Prompt           string   `json:"prompt" binding:"required"`
+	UserID           string   `json:"user_id,omitempty"`
+	Model            string   `json:"model,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty"`
+	MaxTokens        *int     `json:"max_tokens,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	ResponseFormat   string   `json:"response_format,omitempty"`
 }
 
 // GenerateResponse represents a response from AI code generation
@@ -25,15 +34,28 @@ type GenerateResponse struct {
 
 // ValidateRequest represents a request to validate code
 type ValidateRequest struct {
-	Code string `json:"code" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+	Language string `json:"language,omitempty"` // Defaults to html
+}
+
+// DiagnosticResponse represents a problem found at a position in the code
+type DiagnosticResponse struct {
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
 }
 
 // ValidateResponse represents a response from code validation
 type ValidateResponse struct {
-	Valid   bool     `json:"valid"`
-	Errors  []string `json:"errors,omitempty"`
-	Message string   `json:"message,omitempty"`
-	Error   string   `json:"error,omitempty"`
+	Valid       bool                            `json:"valid"`
+	Language    string                          `json:"language,omitempty"`
+	Errors      []string                        `json:"errors,omitempty"`
+	Diagnostics []DiagnosticResponse            `json:"diagnostics,omitempty"`
+	Findings    []aiapp.SecurityFindingResponse `json:"findings,omitempty"`
+	Message     string                          `json:"message,omitempty"`
+	Error       string                          `json:"error,omitempty"`
 }
 
 // Handler handles AI-related HTTP requests
@@ -66,21 +88,25 @@ func (h *Handler) Generate(c *gin.Context) {
 		}
 	}
 
-	var code string
-	var err error
-
-	// Check if model parameters are provided
-	if req.Model != "" || req.Temperature != nil || req.MaxTokens != nil {
-		params := GenerationParams{
-			Model:       req.Model,
-			Temperature: req.Temperature,
-			MaxTokens:   req.MaxTokens,
-		}
-		code, err = h.service.GenerateCodeWithParams(req.Prompt, userID, params)
-	} else {
-		code, err = h.service.GenerateCode(req.Prompt, userID)
+	params := GenerationParams{
+		Model:            req.Model,
+		Temperature:      req.Temperature,
+		MaxTokens:        req.MaxTokens,
+		TopP:             req.TopP,
+		Stop:             req.Stop,
+		Seed:             req.Seed,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		ResponseFormat:   req.ResponseFormat,
 	}
 
+	code, err := h.service.GenerateCodeWithParams(req.Prompt, userID, params)
+	if llm.IsInvalidRequest(err) {
+		c.JSON(http.StatusBadRequest, GenerateResponse{
+			Error: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, GenerateResponse{
 			Error: "Failed to generate code: " + err.Error(),
@@ -114,20 +140,16 @@ func (h *Handler) Stream(c *gin.Context) {
 	}
 
 	// Parse optional model parameters
-	model := c.Query("model")
-	var temperature *float64
-	var maxTokens *int
-
-	if tempStr := c.Query("temperature"); tempStr != "" {
-		if temp, err := strconv.ParseFloat(tempStr, 64); err == nil {
-			temperature = &temp
-		}
-	}
-
-	if tokenStr := c.Query("max_tokens"); tokenStr != "" {
-		if tokens, err := strconv.Atoi(tokenStr); err == nil {
-			maxTokens = &tokens
-		}
+	params := GenerationParams{
+		Model:            c.Query("model"),
+		Temperature:      queryFloat(c, "temperature"),
+		MaxTokens:        queryInt(c, "max_tokens"),
+		TopP:             queryFloat(c, "top_p"),
+		Stop:             c.QueryArray("stop"),
+		Seed:             queryInt(c, "seed"),
+		PresencePenalty:  queryFloat(c, "presence_penalty"),
+		FrequencyPenalty: queryFloat(c, "frequency_penalty"),
+		ResponseFormat:   c.Query("response_format"),
 	}
 
 	// Set proper headers for streaming
@@ -137,12 +159,6 @@ func (h *Handler) Stream(c *gin.Context) {
 
 	responseChannel := make(chan string, 10)
 
-	params := GenerationParams{
-		Model:       model,
-		Temperature: temperature,
-		MaxTokens:   maxTokens,
-	}
-
 	// Start streaming in a goroutine
 	go func() {
 		defer close(responseChannel)
@@ -161,6 +177,24 @@ func (h *Handler) Stream(c *gin.Context) {
 	}
 }
 
+// queryFloat parses an optional float query parameter, ignoring malformed values
+func queryFloat(c *gin.Context, name string) *float64 {
+	value, err := strconv.ParseFloat(c.Query(name), 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// queryInt parses an optional integer query parameter, ignoring malformed values
+func queryInt(c *gin.Context, name string) *int {
+	value, err := strconv.Atoi(c.Query(name))
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
 // ValidateCode handles code validation requests
 func (h *Handler) ValidateCode(c *gin.Context) {
 	var req ValidateRequest
@@ -171,7 +205,7 @@ func (h *Handler) ValidateCode(c *gin.Context) {
 		return
 	}
 
-	valid, errors, err := h.service.ValidateGeneratedCode(req.Code)
+	result, findings, err := h.service.ValidateCode(c.Request.Context(), req.Code, req.Language)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ValidateResponse{
 			Error: "Failed to validate code: " + err.Error(),
@@ -180,15 +214,30 @@ func (h *Handler) ValidateCode(c *gin.Context) {
 	}
 
 	response := ValidateResponse{
-		Valid:   valid,
-		Errors:  errors,
-		Message: "Code validation completed",
+		Valid:    result.Valid,
+		Language: result.Language,
+		Errors:   result.Errors,
+		Message:  "Code validation completed",
+	}
+	for _, d := range result.Diagnostics {
+		response.Diagnostics = append(response.Diagnostics, DiagnosticResponse{
+			Line:     d.Line,
+			Column:   d.Column,
+			Severity: string(d.Severity),
+			Rule:     d.Rule,
+			Message:  d.Message,
+		})
 	}
+	response.Findings = aiapp.NewSecurityFindingResponses(findings)
 
 	c.JSON(http.StatusOK, response)
 }
 
-// GetQuota handles quota checking requests
+// GetQuota handles quota checking requests. It is no longer registered by
+// RegisterRoutes.
+//
+// Deprecated: GetQuota returns placeholder numbers. Use GET /api/v1/ai/quota
+// instead.
 func (h *Handler) GetQuota(c *gin.Context) {
 	userID := c.Query("user_id")
 	if userID == "" {
@@ -209,7 +258,11 @@ func (h *Handler) GetQuota(c *gin.Context) {
 	c.JSON(http.StatusOK, quota)
 }
 
-// History handles request history retrieval
+// History handles request history retrieval. It is no longer registered by
+// RegisterRoutes.
+//
+// Deprecated: History trusts the user_id query parameter and only sees this
+// replica's history. Use GET /api/v1/ai/generations instead.
 func (h *Handler) History(c *gin.Context) {
 	userID := c.Query("user_id")
 	if userID == "" {
@@ -232,6 +285,4 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	ai.POST("/generate", h.Generate)
 	ai.GET("/stream/:sessionId", h.Stream)
 	ai.POST("/validate", h.ValidateCode)
-	ai.GET("/quota", h.GetQuota)
-	ai.GET("/history", h.History)
 }
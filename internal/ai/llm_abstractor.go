@@ -1,12 +1,15 @@
 package ai
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
+
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
 )
 
 // LLMClient defines the interface for LLM communication
@@ -15,6 +18,13 @@ type LLMClient interface {
 	StreamGenerate(prompt string, responseChannel chan string) error
 }
 
+// ParamsLLMClient is implemented by LLM clients that accept per-request
+// model and sampling parameters. Clients without it use their configured defaults.
+type ParamsLLMClient interface {
+	GenerateWithParams(prompt string, params GenerationParams) (string, error)
+	StreamGenerateWithParams(prompt string, params GenerationParams, responseChannel chan string) error
+}
+
 // OpenAICompatibleClient implements LLMClient for OpenAI-compatible APIs
 type OpenAICompatibleClient struct {
 	endpoint    string
@@ -22,6 +32,16 @@ type OpenAICompatibleClient struct {
 	modelName   string
 	maxTokens   int
 	temperature float64
+	params      llm.ParamAllowList
+	client      *llm.ResilientClient
+}
+
+// completionRequest represents a request to an OpenAI-compatible completions API
+type completionRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Stream bool   `json:"stream,omitempty"`
+	llm.SamplingParams
 }
 
 // NewOpenAICompatibleClient creates a new OpenAI-compatible client
@@ -32,33 +52,44 @@ func NewOpenAICompatibleClient(endpoint, apiKey, modelName string, maxTokens int
 		modelName:   modelName,
 		maxTokens:   maxTokens,
 		temperature: temperature,
+		params:      llm.DefaultParamAllowList(),
+		client: llm.NewResilientClient(
+			llm.NewDefaultHTTPClient(120*time.Second),
+			llm.DefaultRetryPolicy(),
+			llm.NewCircuitBreaker("LLM", llm.CircuitBreakerConfig{}),
+		),
 	}
 }
 
+// post sends body to the endpoint with retries, rebuilding the request per attempt
+func (c *OpenAICompatibleClient) post(body []byte) (*http.Response, error) {
+	return c.client.Do(context.Background(), func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
+		return req, nil
+	})
+}
+
 // Generate generates text using the LLM
 func (c *OpenAICompatibleClient) Generate(prompt string) (string, error) {
-	payload := map[string]interface{}{
-		"model":       c.modelName,
-		"prompt":      prompt,
-		"max_tokens":  c.maxTokens,
-		"temperature": c.temperature,
-	}
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
+	return c.GenerateWithParams(prompt, GenerationParams{})
+}
 
-	req, err := http.NewRequest("POST", c.endpoint, bytes.NewBuffer(body))
+// GenerateWithParams generates text using the LLM, overriding the configured
+// model and sampling defaults with params
+func (c *OpenAICompatibleClient) GenerateWithParams(prompt string, params GenerationParams) (string, error) {
+	body, err := c.buildPayload(prompt, params, false)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if c.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.post(body)
 	if err != nil {
 		return "", err
 	}
@@ -83,29 +114,18 @@ func (c *OpenAICompatibleClient) Generate(prompt string) (string, error) {
 
 // StreamGenerate generates text with streaming
 func (c *OpenAICompatibleClient) StreamGenerate(prompt string, responseChannel chan string) error {
-	payload := map[string]interface{}{
-		"model":       c.modelName,
-		"prompt":      prompt,
-		"max_tokens":  c.maxTokens,
-		"temperature": c.temperature,
-		"stream":      true,
-	}
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
+	return c.StreamGenerateWithParams(prompt, GenerationParams{}, responseChannel)
+}
 
-	req, err := http.NewRequest("POST", c.endpoint, bytes.NewBuffer(body))
+// StreamGenerateWithParams generates text with streaming, overriding the
+// configured model and sampling defaults with params
+func (c *OpenAICompatibleClient) StreamGenerateWithParams(prompt string, params GenerationParams, responseChannel chan string) error {
+	body, err := c.buildPayload(prompt, params, true)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if c.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.post(body)
 	if err != nil {
 		return err
 	}
@@ -115,31 +135,62 @@ func (c *OpenAICompatibleClient) StreamGenerate(prompt string, responseChannel c
 		return fmt.Errorf("llm error: %s", string(b))
 	}
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" || len(line) < 6 || line[:6] != "data: " {
-			continue
+	reader := llm.NewSSEReader(resp.Body)
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
 		}
-		data := line[6:]
-		if data == "[DONE]" {
-			break
+		if event.IsDone() {
+			return nil
 		}
+		if llmErr := llm.StreamErrorFromEvent(event); llmErr != nil {
+			return llmErr
+		}
+
 		var chunk struct {
 			Choices []struct {
 				Text string `json:"text"`
 			} `json:"choices"`
 		}
-		if err := json.Unmarshal([]byte(data), &chunk); err == nil {
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err == nil {
 			for _, choice := range chunk.Choices {
 				responseChannel <- choice.Text
 			}
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		return err
+}
+
+// buildPayload marshals a completions request, applying the client defaults
+// for any parameter not set in params and validating the result
+func (c *OpenAICompatibleClient) buildPayload(prompt string, params GenerationParams, stream bool) ([]byte, error) {
+	model := params.Model
+	if model == "" {
+		model = c.modelName
 	}
-	return nil
+
+	sampling := params.samplingParams()
+	if sampling.MaxTokens == 0 {
+		sampling.MaxTokens = c.maxTokens
+	}
+	if sampling.Temperature == nil {
+		temperature := c.temperature
+		sampling.Temperature = &temperature
+	}
+
+	if err := c.params.Validate(model, sampling); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&completionRequest{
+		Model:          model,
+		Prompt:         prompt,
+		Stream:         stream,
+		SamplingParams: sampling,
+	})
 }
 
 // MockLLMClient implements LLMClient for testing
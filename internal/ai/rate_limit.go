@@ -10,6 +10,10 @@ import (
 )
 
 // RateLimiter manages rate limiting for AI requests
+//
+// Deprecated: RateLimiter keeps a limiter per client in this replica's memory,
+// never evicts them and trusts the user_id query parameter. Use
+// ratelimit.Limiter with middleware.DistributedRateLimit instead.
 type RateLimiter struct {
 	limiters map[string]*rate.Limiter
 	mu       sync.RWMutex
@@ -64,6 +68,10 @@ func (rl *RateLimiter) RateLimitMiddleware() gin.HandlerFunc {
 }
 
 // QuotaManager manages usage quotas
+//
+// Deprecated: QuotaManager counts requests in this replica's memory, resets
+// on restart and trusts the user_id query parameter. Use the application
+// QuotaService, which counts tokens against plan limits in Redis.
 type QuotaManager struct {
 	quotas map[string]*UserQuota
 	mu     sync.RWMutex
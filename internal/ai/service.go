@@ -1,9 +1,13 @@
 package ai
 
 import (
-	"strings"
+	"context"
+	"fmt"
 
-	"golang.org/x/net/html"
+	domainai "github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/security"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/validation"
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
 )
 
 // ValidationFunc is a function type that defines the signature for validation functions
@@ -17,15 +21,42 @@ type GenerationHistory struct {
 
 // GenerationParams holds parameters for AI generation
 type GenerationParams struct {
-	Model       string   `json:"model,omitempty"`
-	Temperature *float64 `json:"temperature,omitempty"`
-	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Model            string   `json:"model,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty"`
+	MaxTokens        *int     `json:"max_tokens,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	ResponseFormat   string   `json:"response_format,omitempty"`
+}
+
+// samplingParams converts the parameters to the LLM request format
+func (p GenerationParams) samplingParams() llm.SamplingParams {
+	params := llm.SamplingParams{
+		Temperature:      p.Temperature,
+		TopP:             p.TopP,
+		Stop:             p.Stop,
+		Seed:             p.Seed,
+		PresencePenalty:  p.PresencePenalty,
+		FrequencyPenalty: p.FrequencyPenalty,
+	}
+	if p.MaxTokens != nil {
+		params.MaxTokens = *p.MaxTokens
+	}
+	if p.ResponseFormat != "" {
+		params.ResponseFormat = &llm.ResponseFormat{Type: p.ResponseFormat}
+	}
+	return params
 }
 
 // Service provides AI generation business logic
 type Service struct {
 	llmClient    LLMClient
 	validateFunc ValidationFunc // optional, for testability
+	validator    domainai.CodeValidator
+	scanner      domainai.SecurityScanner
 
 	history map[string][]GenerationHistory // userID -> history
 }
@@ -34,6 +65,8 @@ type Service struct {
 func NewService(llmClient LLMClient) *Service {
 	return &Service{
 		llmClient: llmClient,
+		validator: validation.NewService(),
+		scanner:   security.NewScanner(),
 		history:   make(map[string][]GenerationHistory),
 	}
 }
@@ -43,6 +76,8 @@ func NewServiceWithValidation(llmClient LLMClient, validateFunc ValidationFunc)
 	return &Service{
 		llmClient:    llmClient,
 		validateFunc: validateFunc,
+		validator:    validation.NewService(),
+		scanner:      security.NewScanner(),
 	}
 }
 
@@ -64,9 +99,13 @@ func (s *Service) GenerateCodeWithParams(prompt string, userID string, params Ge
 		return "", nil
 	}
 
-	// For now, use the basic generate method. In a real implementation,
-	// you would pass these parameters to the LLM client
-	code, err := s.llmClient.Generate(prompt)
+	var code string
+	var err error
+	if client, ok := s.llmClient.(ParamsLLMClient); ok {
+		code, err = client.GenerateWithParams(prompt, params)
+	} else {
+		code, err = s.llmClient.Generate(prompt)
+	}
 	if err == nil && userID != "" {
 		s.addToHistory(userID, prompt, code)
 	}
@@ -108,22 +147,42 @@ func (s *Service) StreamGenerationWithParams(prompt string, userID string, param
 	if s.llmClient == nil {
 		return nil
 	}
-	// For now, use the basic stream method. In a real implementation,
-	// you would pass these parameters to the LLM client
+	if client, ok := s.llmClient.(ParamsLLMClient); ok {
+		return client.StreamGenerateWithParams(prompt, params, responseChannel)
+	}
 	return s.llmClient.StreamGenerate(prompt, responseChannel)
 }
 
-// ValidateGeneratedCode validates the generated code
+// ValidateGeneratedCode validates the generated code in the language it is
+// detected as
 func (s *Service) ValidateGeneratedCode(code string) (bool, []string, error) {
-	var errors []string
-	// Syntax validation (HTML)
-	if _, err := html.Parse(strings.NewReader(code)); err != nil {
-		errors = append(errors, "HTML syntax error: "+err.Error())
+	result, _, err := s.ValidateCode(context.Background(), code, "")
+	if err != nil {
+		return false, nil, err
+	}
+	return result.Valid, result.Errors, nil
+}
+
+// ValidateCode checks code written in language, which is detected when empty, and
+// scans it for risky patterns. Code with error findings is invalid.
+func (s *Service) ValidateCode(ctx context.Context, code, language string) (domainai.ValidationResult, []domainai.SecurityFinding, error) {
+	if language == "" {
+		language = validation.Detect(code)
+	}
+	result, err := s.validator.Validate(ctx, code, language)
+	if err != nil {
+		return domainai.ValidationResult{}, nil, err
+	}
+
+	findings, err := s.scanner.Scan(ctx, []domainai.ArtifactFile{{Content: code, Language: result.Language}}, domainai.DefaultSecurityPolicy())
+	if err != nil {
+		return domainai.ValidationResult{}, nil, err
 	}
-	// Security check: disallow <script> tags
-	if strings.Contains(strings.ToLower(code), "<script") {
-		errors = append(errors, "Security issue: <script> tags are not allowed")
+	for _, f := range findings {
+		if f.Severity == domainai.SeverityError {
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("Security issue at %d:%d: %s", f.Line, f.Column, f.Message))
+		}
 	}
-	valid := len(errors) == 0
-	return valid, errors, nil
+	return result, findings, nil
 }
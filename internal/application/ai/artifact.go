@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// artifactInstructions asks the model for a multi-file artifact document
+const artifactInstructions = `Respond with a single JSON object and nothing else, in this shape:
+{"files": [{"path": "src/Button.tsx", "language": "tsx", "content": "..."}], "dependencies": {"package-name": "^1.0.0"}}
+Split the component into separate files where it helps, for example the component, its styles, a story, a test and shared types. List every npm package the files import under "dependencies".`
+
+// ArtifactFileResponse represents a single generated file
+type ArtifactFileResponse struct {
+	Path     string `json:"path"`
+	Language string `json:"language,omitempty"`
+	Content  string `json:"content"`
+}
+
+// ArtifactResponse represents the files and npm dependencies of a generation
+type ArtifactResponse struct {
+	Files        []ArtifactFileResponse `json:"files"`
+	Dependencies map[string]string      `json:"dependencies,omitempty"`
+}
+
+// newArtifactResponse converts a domain artifact to its response
+func newArtifactResponse(artifact *ai.Artifact) *ArtifactResponse {
+	if artifact == nil || artifact.IsEmpty() {
+		return nil
+	}
+	resp := &ArtifactResponse{
+		Files:        make([]ArtifactFileResponse, len(artifact.Files)),
+		Dependencies: artifact.Dependencies,
+	}
+	for i, f := range artifact.Files {
+		resp.Files[i] = ArtifactFileResponse{Path: f.Path, Language: f.Language, Content: f.Content}
+	}
+	return resp
+}
+
+// requestArtifact adds the artifact instructions to the system prompt of req,
+// and asks for a JSON response when the model supports it
+func requestArtifact(req *ai.GenerationRequest, llmService ai.LLMService) {
+	if len(req.Messages) == 0 {
+		req.Messages = []ai.ChatMessage{{Role: ai.RoleUser, Content: req.Prompt}}
+	}
+	if req.Messages[0].Role == ai.RoleSystem {
+		req.Messages[0].Content += "\n\n" + artifactInstructions
+	} else {
+		req.Messages = append([]ai.ChatMessage{{Role: ai.RoleSystem, Content: artifactInstructions}}, req.Messages...)
+	}
+	if req.ResponseFormat == "" && acceptsParam(llmService, *req, "response_format") {
+		req.ResponseFormat = "json_object"
+	}
+}
+
+// parseArtifact parses generated code into an artifact, naming a single
+// unstructured file after the framework and language
+func parseArtifact(code, framework, language string) *ai.Artifact {
+	artifact := ai.ParseArtifact(code, artifactFallbackPath(framework, language))
+	return &artifact
+}
+
+// artifactFallbackPath returns the file name of a single-file component
+func artifactFallbackPath(framework, language string) string {
+	typescript := language == "typescript"
+	switch framework {
+	case "react", "next", "nextjs":
+		if typescript {
+			return "Component.tsx"
+		}
+		return "Component.jsx"
+	case "vue":
+		return "Component.vue"
+	case "svelte":
+		return "Component.svelte"
+	case "angular":
+		return "component.ts"
+	case "html":
+		return "index.html"
+	}
+
+	switch language {
+	case "typescript":
+		return "component.ts"
+	case "python":
+		return "main.py"
+	case "go":
+		return "main.go"
+	case "java":
+		return "Main.java"
+	}
+	return "component.js"
+}
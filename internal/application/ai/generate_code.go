@@ -3,6 +3,10 @@ package ai
 
 import (
 	"context"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
 	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
@@ -17,45 +21,101 @@ type GenerateCodeRequest struct {
 	Complexity string            `json:"complexity" validate:"oneof=simple medium complex"`
 	UserID     common.UserID     `json:"user_id" validate:"required"`
 	ProjectID  *common.ProjectID `json:"project_id,omitempty"`
+	GenerationParams
 }
 
 // GenerateCodeResponse represents a code generation response
 type GenerateCodeResponse struct {
-	ID            string  `json:"id"`
-	Code          string  `json:"code"`
-	Language      string  `json:"language"`
-	Framework     string  `json:"framework"`
-	Model         string  `json:"model"`
-	UsedTokens    int     `json:"used_tokens"`
-	EstimatedCost float64 `json:"estimated_cost"`
-	CreatedAt     string  `json:"created_at"`
+	ID               string                    `json:"id"`
+	GenerationID     string                    `json:"generation_id,omitempty"` // Stored history entry, used to refine the result
+	Code             string                    `json:"code"`
+	Artifact         *ArtifactResponse         `json:"artifact,omitempty"`
+	SecurityFindings []SecurityFindingResponse `json:"security_findings,omitempty"`
+	RepairAttempts   []RepairAttemptResponse   `json:"repair_attempts,omitempty"` // Set when the code was validated
+	Language         string                    `json:"language"`
+	Framework        string                    `json:"framework"`
+	Model            string                    `json:"model"`
+	UsedTokens       int                       `json:"used_tokens"`
+	EstimatedCost    float64                   `json:"estimated_cost"`
+	Metadata         map[string]string         `json:"metadata,omitempty"`
+	CreatedAt        string                    `json:"created_at"`
 }
 
 // GenerateCodeUseCase handles code generation
 type GenerateCodeUseCase struct {
-	repo        ai.Repository
-	llmService  ai.LLMService
-	rateLimiter ai.RateLimiter
-	publisher   ai.EventPublisher
+	repo         ai.Repository
+	llmService   ai.LLMService
+	rateLimiter  ai.RateLimiter
+	publisher    ai.EventPublisher
+	prompts      ai.PromptBuilder
+	tracker      *GenerationTracker
+	post         *CodePostProcessor
+	scanner      *CodeScanner
+	validator    ai.CodeValidator
+	repairConfig *RepairConfig
+	quota        *QuotaService
+	logger       Logger
+}
+
+// candidate is generated code after parsing and post-processing
+type candidate struct {
+	result   ai.GenerationResult
+	code     string
+	artifact *ai.Artifact
+	metadata map[string]string
+}
+
+// GenerateCodeDeps holds the dependencies of a GenerateCodeUseCase. Repo,
+// LLMService and RateLimiter are required; the others are optional.
+type GenerateCodeDeps struct {
+	Repo          ai.Repository
+	LLMService    ai.LLMService
+	RateLimiter   ai.RateLimiter
+	Publisher     ai.EventPublisher
+	Prompts       ai.PromptBuilder   // Renders requests through prompt templates
+	Tracker       *GenerationTracker // Records generations so they can be cancelled while running
+	PostProcessor *CodePostProcessor // Cleans up generated code before it is returned and stored
+	Scanner       *CodeScanner       // Scans generated artifacts for risky patterns
+	Validator     ai.CodeValidator   // Validates generated code, asking the model to repair it while invalid
+	RepairConfig  *RepairConfig      // Limits repairs; defaults to DefaultRepairConfig
+	Quota         *QuotaService      // Enforces plan limits instead of the repository's daily token count
+	Logger        Logger             // Records failures that do not fail the request
 }
 
 // NewGenerateCodeUseCase creates a new GenerateCodeUseCase
-func NewGenerateCodeUseCase(
-	repo ai.Repository,
-	llmService ai.LLMService,
-	rateLimiter ai.RateLimiter,
-	publisher ai.EventPublisher,
-) *GenerateCodeUseCase {
+func NewGenerateCodeUseCase(deps GenerateCodeDeps) *GenerateCodeUseCase {
+	config := deps.RepairConfig
+	if config == nil {
+		config = DefaultRepairConfig()
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultRepairMaxAttempts
+	}
+
 	return &GenerateCodeUseCase{
-		repo:        repo,
-		llmService:  llmService,
-		rateLimiter: rateLimiter,
-		publisher:   publisher,
+		repo:         deps.Repo,
+		llmService:   deps.LLMService,
+		rateLimiter:  deps.RateLimiter,
+		publisher:    deps.Publisher,
+		prompts:      deps.Prompts,
+		tracker:      deps.Tracker,
+		post:         deps.PostProcessor,
+		scanner:      deps.Scanner,
+		validator:    deps.Validator,
+		repairConfig: config,
+		quota:        deps.Quota,
+		logger:       deps.Logger,
 	}
 }
 
 // Execute executes the code generation use case
 func (uc *GenerateCodeUseCase) Execute(ctx context.Context, req GenerateCodeRequest) (*GenerateCodeResponse, error) {
+	return uc.execute(ctx, req, nil)
+}
+
+// execute generates code for req. With a parent, req.Prompt is an instruction
+// to revise the parent's code and the result is stored as its child.
+func (uc *GenerateCodeUseCase) execute(ctx context.Context, req GenerateCodeRequest, parent *ai.GenerationHistory) (_ *GenerateCodeResponse, err error) {
 	// Convert to domain request
 	domainReq := ai.GenerationRequest{
 		Prompt:     req.Prompt,
@@ -66,67 +126,145 @@ func (uc *GenerateCodeUseCase) Execute(ctx context.Context, req GenerateCodeRequ
 		UserID:     req.UserID,
 		ProjectID:  req.ProjectID,
 	}
+	req.GenerationParams.applyTo(&domainReq)
 
 	// Validate request
 	if err := domainReq.Validate(); err != nil {
 		return nil, common.NewValidationError("invalid generation request", err)
 	}
+	if err := checkParams(uc.llmService, domainReq); err != nil {
+		return nil, err
+	}
 
 	// Check rate limit
 	if !uc.rateLimiter.Allow(req.UserID) {
 		return nil, common.NewValidationError("rate limit exceeded", nil)
 	}
 
-	// Check quota
-	quota, err := uc.repo.GetQuotaUsage(ctx, req.UserID)
+	// Reserve quota, charging the tokens used however the generation ends
+	reservation, err := reserveQuota(ctx, uc.quota, uc.repo, req.UserID)
 	if err != nil {
 		return nil, err
 	}
+	var model string
+	var tokens int
+	defer func() {
+		_ = reservation.charge(ctx, model, tokens)
+	}()
 
-	if !quota.CanGenerate() {
-		return nil, common.NewValidationError("quota exceeded", nil)
+	// Render the prompt template
+	metadata, err := applyPrompt(ctx, uc.prompts, &domainReq)
+	if err != nil {
+		return nil, err
+	}
+	if parent != nil {
+		domainReq.Messages = refinementMessages(domainReq.Messages, *parent, req.Prompt)
+	}
+	if req.MultiFile {
+		requestArtifact(&domainReq, uc.llmService)
 	}
 
-	// Generate code
-	result, err := uc.llmService.Generate(ctx, domainReq)
+	// Track the generation so it can be cancelled from any replica
+	generationID := uuid.NewString()
+	if uc.tracker != nil {
+		generation, trackedCtx, startErr := uc.tracker.Start(ctx, ai.Generation{
+			UserID:    req.UserID,
+			Prompt:    req.Prompt,
+			Framework: req.Framework,
+		})
+		if startErr != nil {
+			return nil, startErr
+		}
+		generationID = generation.ID
+		ctx = trackedCtx
+		defer func() {
+			_ = uc.tracker.Finish(ctx, generationID, err)
+		}()
+		_ = uc.tracker.MarkInProgress(ctx, generationID)
+	}
+
+	// Generate code, repairing it if it does not validate
+	started := time.Now()
+	generated, err := uc.generate(ctx, domainReq, req, metadata)
 	if err != nil {
 		return nil, err
 	}
+	model, tokens = generated.result.Model, generated.result.UsedTokens
+	cost := generated.result.EstimatedCost
+	var attempts []ai.RepairAttempt
+	if uc.validator != nil {
+		generated, attempts, tokens, cost = uc.repair(ctx, domainReq, req, metadata, generated)
+	}
+
+	result, code, artifact := generated.result, generated.code, generated.artifact
+	metadata = generated.metadata
+	model = result.Model
+	findings := scanArtifact(ctx, uc.scanner, req.UserID, req.ProjectID, artifact)
 
 	// Save to history
 	history := ai.GenerationHistory{
-		UserID: req.UserID,
-		Prompt: req.Prompt,
-		Code:   result.Code,
-		Model:  result.Model,
-		Tokens: result.UsedTokens,
+		ID:               generationID, // Completes the tracked generation row, if any
+		UserID:           req.UserID,
+		ProjectID:        req.ProjectID,
+		Prompt:           req.Prompt,
+		Code:             code,
+		Artifact:         artifact,
+		SecurityFindings: findings,
+		RepairAttempts:   attempts,
+		Framework:        req.Framework,
+		Model:            result.Model,
+		Tokens:           tokens,
+		Status:           ai.GenerationCompleted,
+		ProcessingTime:   time.Since(started),
+		Metadata:         metadata,
 	}
-
-	if err := uc.repo.SaveGeneration(ctx, history); err != nil {
-		// Log error but don't fail the request
+	if parent != nil {
+		history.ParentID = parent.ID
 	}
 
-	// Update quota
-	if err := uc.repo.UpdateQuotaUsage(ctx, req.UserID, result.UsedTokens); err != nil {
+	if err := uc.repo.SaveGeneration(ctx, history); err != nil {
 		// Log error but don't fail the request
+		logError(uc.logger, "Failed to save generation", err, map[string]interface{}{
+			"generation_id": history.ID,
+			"user_id":       string(req.UserID),
+		})
+		generationID = ""
 	}
 
 	// Publish event
 	if uc.publisher != nil {
-		_ = uc.publisher.PublishGenerationEvent(ctx, req.UserID, result.UsedTokens)
+		_ = uc.publisher.PublishGenerationEvent(ctx, req.UserID, tokens)
 	}
 
 	// Convert to response
 	response := &GenerateCodeResponse{
-		ID:            result.ID,
-		Code:          result.Code,
-		Language:      req.Language,
-		Framework:     req.Framework,
-		Model:         result.Model,
-		UsedTokens:    result.UsedTokens,
-		EstimatedCost: result.EstimatedCost,
-		CreatedAt:     result.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:               result.ID,
+		GenerationID:     generationID,
+		Code:             code,
+		Artifact:         newArtifactResponse(artifact),
+		SecurityFindings: NewSecurityFindingResponses(findings),
+		RepairAttempts:   newRepairAttemptResponses(attempts),
+		Language:         req.Language,
+		Framework:        req.Framework,
+		Model:            result.Model,
+		UsedTokens:       tokens,
+		EstimatedCost:    cost,
+		Metadata:         metadata,
+		CreatedAt:        result.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	}
 
 	return response, nil
 }
+
+// generate sends domainReq to the LLM, then parses and post-processes the
+// code it returns
+func (uc *GenerateCodeUseCase) generate(ctx context.Context, domainReq ai.GenerationRequest, req GenerateCodeRequest, metadata map[string]string) (candidate, error) {
+	result, err := uc.llmService.Generate(ctx, domainReq)
+	if err != nil {
+		return candidate{}, err
+	}
+
+	artifact := parseArtifact(result.Code, req.Framework, req.Language)
+	code, metadata := postProcess(ctx, uc.post, result.Code, artifact, ai.CodeTarget{Language: req.Language, Framework: req.Framework}, maps.Clone(metadata))
+	return candidate{result: result, code: code, artifact: artifact, metadata: metadata}, nil
+}
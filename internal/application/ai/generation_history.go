@@ -0,0 +1,242 @@
+package ai
+
+import (
+	"context"
+	"time"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/user"
+)
+
+// ListGenerationsRequest filters and pages through generation history
+type ListGenerationsRequest struct {
+	UserID    common.UserID `form:"user_id"` // Only honoured for admins
+	ProjectID string        `form:"project_id"`
+	Framework string        `form:"framework"`
+	Model     string        `form:"model"`
+	Status    string        `form:"status"`
+	From      string        `form:"from"` // RFC 3339 or YYYY-MM-DD, inclusive
+	To        string        `form:"to"`   // RFC 3339 or YYYY-MM-DD, exclusive
+	Query     string        `form:"q"`
+	Cursor    string        `form:"cursor"`
+	Limit     int           `form:"limit"`
+}
+
+// GenerationHistoryResponse represents a generation history entry
+type GenerationHistoryResponse struct {
+	ID               string                    `json:"id"`
+	ParentID         string                    `json:"parent_id,omitempty"`
+	UserID           string                    `json:"user_id"`
+	ProjectID        string                    `json:"project_id,omitempty"`
+	Prompt           string                    `json:"prompt"`
+	Code             string                    `json:"code,omitempty"`
+	Artifact         *ArtifactResponse         `json:"artifact,omitempty"`
+	SecurityFindings []SecurityFindingResponse `json:"security_findings,omitempty"`
+	RepairAttempts   []RepairAttemptResponse   `json:"repair_attempts,omitempty"`
+	Framework        string                    `json:"framework,omitempty"`
+	Model            string                    `json:"model,omitempty"`
+	Tokens           int                       `json:"tokens"`
+	Status           string                    `json:"status"`
+	Error            string                    `json:"error,omitempty"`
+	ProcessingTimeMS int64                     `json:"processing_time_ms"`
+	Metadata         map[string]string         `json:"metadata,omitempty"`
+	CreatedAt        string                    `json:"created_at"`
+	UpdatedAt        string                    `json:"updated_at"`
+}
+
+// GenerationHistoryListResponse is a page of generation history
+type GenerationHistoryListResponse struct {
+	Items      []GenerationHistoryResponse `json:"items"`
+	NextCursor string                      `json:"next_cursor,omitempty"`
+}
+
+// newGenerationHistoryResponse converts a history entry to its response.
+// Lists leave out the code and artifact, which the detail view returns.
+func newGenerationHistoryResponse(h ai.GenerationHistory, withCode bool) GenerationHistoryResponse {
+	resp := GenerationHistoryResponse{
+		ID:               h.ID,
+		ParentID:         h.ParentID,
+		UserID:           string(h.UserID),
+		Prompt:           h.Prompt,
+		Framework:        h.Framework,
+		Model:            h.Model,
+		Tokens:           h.Tokens,
+		Status:           string(h.Status),
+		Error:            h.ErrorMessage,
+		ProcessingTimeMS: h.ProcessingTime.Milliseconds(),
+		Metadata:         h.Metadata,
+		CreatedAt:        h.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:        h.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	if h.ProjectID != nil {
+		resp.ProjectID = string(*h.ProjectID)
+	}
+	if withCode {
+		resp.Code = h.Code
+		resp.Artifact = newArtifactResponse(h.Artifact)
+		resp.SecurityFindings = NewSecurityFindingResponses(h.SecurityFindings)
+		resp.RepairAttempts = newRepairAttemptResponses(h.RepairAttempts)
+	}
+	return resp
+}
+
+// GenerationHistoryService lists, shows and deletes persisted generations.
+// Users only see their own generations; admins see everyone's.
+type GenerationHistoryService struct {
+	repo  ai.HistoryRepository
+	users user.Repository
+}
+
+// NewGenerationHistoryService creates a new GenerationHistoryService. Without
+// users, every caller is treated as a regular user.
+func NewGenerationHistoryService(repo ai.HistoryRepository, users user.Repository) *GenerationHistoryService {
+	return &GenerationHistoryService{
+		repo:  repo,
+		users: users,
+	}
+}
+
+// List returns a page of the caller's generations matching req
+func (s *GenerationHistoryService) List(ctx context.Context, callerID common.UserID, req ListGenerationsRequest) (*GenerationHistoryListResponse, error) {
+	query, err := s.historyQuery(ctx, callerID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.repo.ListHistory(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &GenerationHistoryListResponse{
+		Items:      make([]GenerationHistoryResponse, len(page.Items)),
+		NextCursor: page.NextCursor,
+	}
+	for i, h := range page.Items {
+		resp.Items[i] = newGenerationHistoryResponse(h, false)
+	}
+	return resp, nil
+}
+
+// Get returns a generation visible to the caller, including its code
+func (s *GenerationHistoryService) Get(ctx context.Context, callerID common.UserID, id string) (*GenerationHistoryResponse, error) {
+	h, err := s.get(ctx, callerID, id)
+	if err != nil {
+		return nil, err
+	}
+	resp := newGenerationHistoryResponse(h, true)
+	return &resp, nil
+}
+
+// Revisions returns the revision tree containing a generation visible to the
+// caller, oldest first. Entries link to the generation they refine; revisions
+// by other users are left out unless the caller is an admin.
+func (s *GenerationHistoryService) Revisions(ctx context.Context, callerID common.UserID, id string) (*GenerationHistoryListResponse, error) {
+	h, err := s.repo.GetHistoryEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	admin := s.isAdmin(ctx, callerID)
+	if h.UserID != callerID && !admin {
+		return nil, common.NewNotFoundError("generation not found")
+	}
+
+	revisions, err := s.repo.ListRevisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &GenerationHistoryListResponse{Items: make([]GenerationHistoryResponse, 0, len(revisions))}
+	for _, h := range revisions {
+		if h.UserID == callerID || admin {
+			resp.Items = append(resp.Items, newGenerationHistoryResponse(h, false))
+		}
+	}
+	return resp, nil
+}
+
+// Delete removes a finished generation visible to the caller from history.
+// Generations that are still running must be cancelled instead.
+func (s *GenerationHistoryService) Delete(ctx context.Context, callerID common.UserID, id string) error {
+	h, err := s.get(ctx, callerID, id)
+	if err != nil {
+		return err
+	}
+	if h.Status != "" && !h.Status.IsTerminal() {
+		return common.NewConflictError("generation is still running")
+	}
+	return s.repo.DeleteHistoryEntry(ctx, id)
+}
+
+// get retrieves a generation, hiding other users' generations from non-admins
+func (s *GenerationHistoryService) get(ctx context.Context, callerID common.UserID, id string) (ai.GenerationHistory, error) {
+	h, err := s.repo.GetHistoryEntry(ctx, id)
+	if err != nil {
+		return ai.GenerationHistory{}, err
+	}
+	if h.UserID != callerID && !s.isAdmin(ctx, callerID) {
+		return ai.GenerationHistory{}, common.NewNotFoundError("generation not found")
+	}
+	return h, nil
+}
+
+// historyQuery validates req and scopes it to the caller
+func (s *GenerationHistoryService) historyQuery(ctx context.Context, callerID common.UserID, req ListGenerationsRequest) (ai.HistoryQuery, error) {
+	query := ai.HistoryQuery{
+		UserID:    callerID,
+		Framework: req.Framework,
+		Model:     req.Model,
+		Status:    ai.GenerationStatus(req.Status),
+		Search:    req.Query,
+		Limit:     req.Limit,
+	}
+	if s.isAdmin(ctx, callerID) {
+		query.UserID = req.UserID
+	}
+
+	if req.ProjectID != "" {
+		projectID := common.ProjectID(req.ProjectID)
+		query.ProjectID = &projectID
+	}
+	if query.Status != "" && !query.Status.IsValid() {
+		return ai.HistoryQuery{}, common.NewValidationError("invalid status filter", nil)
+	}
+
+	var err error
+	if query.CreatedAfter, err = parseHistoryTime(req.From); err != nil {
+		return ai.HistoryQuery{}, common.NewValidationError("invalid from date", err)
+	}
+	if query.CreatedBefore, err = parseHistoryTime(req.To); err != nil {
+		return ai.HistoryQuery{}, common.NewValidationError("invalid to date", err)
+	}
+
+	if req.Cursor != "" {
+		cursor, err := ai.ParseHistoryCursor(req.Cursor)
+		if err != nil {
+			return ai.HistoryQuery{}, err
+		}
+		query.Cursor = &cursor
+	}
+	return query, nil
+}
+
+// isAdmin reports whether userID belongs to an admin
+func (s *GenerationHistoryService) isAdmin(ctx context.Context, userID common.UserID) bool {
+	if s.users == nil {
+		return false
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	return err == nil && u.IsAdmin()
+}
+
+// parseHistoryTime parses an optional RFC 3339 timestamp or date
+func parseHistoryTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
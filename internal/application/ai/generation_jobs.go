@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/user"
+)
+
+// GenerationJobResponse represents the state of an asynchronous generation job
+type GenerationJobResponse struct {
+	ID        string                `json:"id"`
+	Status    string                `json:"status"`
+	Model     string                `json:"model"`
+	Priority  int                   `json:"priority"`
+	Result    *GenerateCodeResponse `json:"result,omitempty"`
+	Error     string                `json:"error,omitempty"`
+	CreatedAt string                `json:"created_at"`
+	UpdatedAt string                `json:"updated_at"`
+}
+
+// newGenerationJobResponse converts a domain job to its response
+func newGenerationJobResponse(job ai.GenerationJob) (*GenerationJobResponse, error) {
+	resp := &GenerationJobResponse{
+		ID:        job.ID,
+		Status:    string(job.Status),
+		Model:     job.Model,
+		Priority:  int(job.Priority),
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt: job.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if len(job.Result) > 0 {
+		if err := json.Unmarshal(job.Result, &resp.Result); err != nil {
+			return nil, fmt.Errorf("failed to decode result of job %s: %w", job.ID, err)
+		}
+	}
+	return resp, nil
+}
+
+// GenerationJobService submits generations to the job queue and reports on them
+type GenerationJobService struct {
+	queue     ai.JobQueue
+	users     user.Repository
+	publisher ai.JobEventPublisher
+}
+
+// NewGenerationJobService creates a new GenerationJobService. Jobs are
+// prioritized by the role of the submitting user when users is set.
+func NewGenerationJobService(queue ai.JobQueue, users user.Repository, publisher ai.JobEventPublisher) *GenerationJobService {
+	return &GenerationJobService{
+		queue:     queue,
+		users:     users,
+		publisher: publisher,
+	}
+}
+
+// Submit validates a generation request and queues it, returning the pending job
+func (s *GenerationJobService) Submit(ctx context.Context, req GenerateCodeRequest) (*GenerationJobResponse, error) {
+	domainReq := ai.GenerationRequest{
+		Prompt:     req.Prompt,
+		Language:   req.Language,
+		Framework:  req.Framework,
+		Style:      req.Style,
+		Complexity: req.Complexity,
+		UserID:     req.UserID,
+		ProjectID:  req.ProjectID,
+	}
+	req.GenerationParams.applyTo(&domainReq)
+
+	// Reject invalid requests now rather than when a worker picks them up
+	if err := domainReq.Validate(); err != nil {
+		return nil, common.NewValidationError("invalid generation request", err)
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode generation request: %w", err)
+	}
+
+	model := req.Model
+	if model == "" {
+		model = ai.DefaultJobModel
+	}
+
+	now := time.Now()
+	job := ai.GenerationJob{
+		ID:       uuid.NewString(),
+		UserID:   req.UserID,
+		Model:    model,
+		Priority: s.priority(ctx, req.UserID),
+		Status:   ai.GenerationPending,
+		Payload:  payload,
+	}
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if err := s.queue.Enqueue(ctx, job); err != nil {
+		return nil, err
+	}
+	if s.publisher != nil {
+		_ = s.publisher.PublishJobEvent(ctx, job)
+	}
+
+	return newGenerationJobResponse(job)
+}
+
+// Get returns a job owned by userID
+func (s *GenerationJobService) Get(ctx context.Context, userID common.UserID, id string) (*GenerationJobResponse, error) {
+	job, err := s.queue.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, common.NewNotFoundError("job not found")
+	}
+	return newGenerationJobResponse(job)
+}
+
+// priority returns the queue priority for jobs submitted by userID. Admins go
+// first; users that cannot be looked up get the normal priority.
+func (s *GenerationJobService) priority(ctx context.Context, userID common.UserID) ai.JobPriority {
+	if s.users == nil {
+		return ai.JobPriorityNormal
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil || !u.IsAdmin() {
+		return ai.JobPriorityNormal
+	}
+	return ai.JobPriorityHigh
+}
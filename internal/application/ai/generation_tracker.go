@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// ErrGenerationCancelled is the cancellation cause of a generation stopped through Cancel
+var ErrGenerationCancelled = errors.New("generation cancelled")
+
+// GenerationTracker gives each generation a server-side ID and lifecycle, and
+// lets generations be cancelled from any replica. Each replica keeps the cancel
+// functions of the generations it runs; Cancel stops a local generation directly
+// and otherwise broadcasts the ID on the cancellation bus, which Run listens to.
+type GenerationTracker struct {
+	repo ai.GenerationRepository
+	bus  ai.CancellationBus
+
+	mu      sync.Mutex
+	running map[string]context.CancelCauseFunc
+}
+
+// NewGenerationTracker creates a new GenerationTracker. A nil bus limits
+// cancellation to generations running on this replica.
+func NewGenerationTracker(repo ai.GenerationRepository, bus ai.CancellationBus) *GenerationTracker {
+	return &GenerationTracker{
+		repo:    repo,
+		bus:     bus,
+		running: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// Start records a pending generation and returns it with its ID set, along
+// with a context that is cancelled when the generation is
+func (t *GenerationTracker) Start(ctx context.Context, generation ai.Generation) (ai.Generation, context.Context, error) {
+	now := time.Now()
+	generation.ID = uuid.NewString()
+	generation.Status = ai.GenerationPending
+	generation.CreatedAt = now
+	generation.UpdatedAt = now
+
+	if err := t.repo.CreateGeneration(ctx, generation); err != nil {
+		return ai.Generation{}, nil, err
+	}
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	t.mu.Lock()
+	t.running[generation.ID] = cancel
+	t.mu.Unlock()
+
+	return generation, runCtx, nil
+}
+
+// MarkInProgress records that the upstream request for a generation has started
+func (t *GenerationTracker) MarkInProgress(ctx context.Context, id string) error {
+	return t.repo.UpdateGenerationStatus(ctx, id, ai.GenerationInProgress, "")
+}
+
+// Finish records the final status of a generation from the error it ended
+// with and releases its context. A generation whose context was cancelled,
+// by Cancel or by the client going away, is recorded as cancelled.
+func (t *GenerationTracker) Finish(ctx context.Context, id string, err error) error {
+	status, message := ai.GenerationCompleted, ""
+	switch {
+	case ctx.Err() != nil:
+		status = ai.GenerationCancelled
+	case err != nil:
+		status, message = ai.GenerationFailed, err.Error()
+	}
+
+	t.mu.Lock()
+	cancel := t.running[id]
+	delete(t.running, id)
+	t.mu.Unlock()
+	if cancel != nil {
+		cancel(nil)
+	}
+
+	return t.repo.UpdateGenerationStatus(context.WithoutCancel(ctx), id, status, message)
+}
+
+// Cancel cancels a generation owned by userID wherever it is running. The
+// status is recorded as cancelled by the replica running the generation.
+func (t *GenerationTracker) Cancel(ctx context.Context, userID common.UserID, id string) error {
+	generation, err := t.repo.GetGeneration(ctx, id)
+	if err != nil {
+		return err
+	}
+	if generation.UserID != userID {
+		return common.NewNotFoundError("generation not found")
+	}
+	if !generation.CanCancel() {
+		return common.NewConflictError("generation already " + string(generation.Status))
+	}
+
+	if t.cancelLocal(id) || t.bus == nil {
+		return nil
+	}
+	return t.bus.PublishCancel(ctx, id)
+}
+
+// Run cancels local generations as their IDs arrive on the cancellation bus.
+// It blocks until ctx is done.
+func (t *GenerationTracker) Run(ctx context.Context) error {
+	if t.bus == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	ids, err := t.bus.SubscribeCancels(ctx)
+	if err != nil {
+		return err
+	}
+	for id := range ids {
+		t.cancelLocal(id)
+	}
+	return nil
+}
+
+// cancelLocal cancels a generation running on this replica, reporting whether there was one
+func (t *GenerationTracker) cancelLocal(id string) bool {
+	t.mu.Lock()
+	cancel, ok := t.running[id]
+	t.mu.Unlock()
+	if ok {
+		cancel(ErrGenerationCancelled)
+	}
+	return ok
+}
@@ -0,0 +1,223 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// WorkerPoolConfig holds configuration for the generation worker pool
+type WorkerPoolConfig struct {
+	Workers                 int            `json:"workers"`
+	ModelConcurrency        map[string]int `json:"model_concurrency"`         // Concurrent jobs per model on this replica
+	DefaultModelConcurrency int            `json:"default_model_concurrency"` // Limit for models missing from ModelConcurrency
+	PollInterval            time.Duration  `json:"poll_interval"`             // How often the queue is checked while no job is waiting
+	LeaseRenewInterval      time.Duration  `json:"lease_renew_interval"`      // How often running jobs renew their lease; keep below the queue's lease
+}
+
+// GenerationWorkerPool runs queued generation jobs. Whenever a worker is free
+// it takes the highest priority job whose model is below its concurrency
+// limit, runs it through the generate use case and stores the result on the
+// job.
+type GenerationWorkerPool struct {
+	config     *WorkerPoolConfig
+	queue      ai.JobQueue
+	generateUC *GenerateCodeUseCase
+	publisher  ai.JobEventPublisher
+
+	mu      sync.Mutex
+	running map[string]int // Jobs in progress per model
+}
+
+// NewGenerationWorkerPool creates a new GenerationWorkerPool
+func NewGenerationWorkerPool(
+	config *WorkerPoolConfig,
+	queue ai.JobQueue,
+	generateUC *GenerateCodeUseCase,
+	publisher ai.JobEventPublisher,
+) *GenerationWorkerPool {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.DefaultModelConcurrency <= 0 {
+		config.DefaultModelConcurrency = 2
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 250 * time.Millisecond
+	}
+	if config.LeaseRenewInterval <= 0 {
+		config.LeaseRenewInterval = 30 * time.Second
+	}
+
+	return &GenerationWorkerPool{
+		config:     config,
+		queue:      queue,
+		generateUC: generateUC,
+		publisher:  publisher,
+		running:    make(map[string]int),
+	}
+}
+
+// Run dispatches jobs to the workers and blocks until ctx is done and every
+// job in progress has stopped. The queue is only polled while a worker is
+// free and no job is waiting.
+func (p *GenerationWorkerPool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+
+	workers := make(chan struct{}, p.config.Workers)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case workers <- struct{}{}:
+		}
+
+		job, ok, err := p.next(ctx)
+		if err != nil || !ok {
+			<-workers
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.process(ctx, job)
+			p.release(job.Model)
+			<-workers
+		}()
+	}
+}
+
+// next dequeues a job for a model with free capacity and reserves a slot for
+// it. Only Run calls next, so slots cannot be over-reserved while the lock is
+// released for the dequeue.
+func (p *GenerationWorkerPool) next(ctx context.Context) (ai.GenerationJob, bool, error) {
+	if ctx.Err() != nil {
+		return ai.GenerationJob{}, false, ctx.Err()
+	}
+
+	p.mu.Lock()
+	var saturated []string
+	for model, n := range p.running {
+		if n >= p.limit(model) {
+			saturated = append(saturated, model)
+		}
+	}
+	p.mu.Unlock()
+
+	job, ok, err := p.queue.Dequeue(ctx, saturated)
+	if err != nil || !ok {
+		return ai.GenerationJob{}, false, err
+	}
+
+	p.mu.Lock()
+	p.running[job.Model]++
+	p.mu.Unlock()
+	return job, true, nil
+}
+
+// release frees the slot held by a finished job
+func (p *GenerationWorkerPool) release(model string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running[model]--; p.running[model] <= 0 {
+		delete(p.running, model)
+	}
+}
+
+// limit returns the concurrency limit of model
+func (p *GenerationWorkerPool) limit(model string) int {
+	if n, ok := p.config.ModelConcurrency[model]; ok && n > 0 {
+		return n
+	}
+	return p.config.DefaultModelConcurrency
+}
+
+// process runs a job and records its outcome, renewing the job's lease while
+// it runs. A job interrupted by shutdown is queued again for another replica.
+func (p *GenerationWorkerPool) process(ctx context.Context, job ai.GenerationJob) {
+	job.Status = ai.GenerationInProgress
+	p.update(ctx, job)
+
+	renewCtx, stopRenewing := context.WithCancel(ctx)
+	go p.renewLease(renewCtx, job.ID)
+	resp, err := p.run(ctx, job)
+	stopRenewing()
+
+	switch {
+	case err != nil && ctx.Err() != nil:
+		job.Status = ai.GenerationPending
+		p.requeue(context.WithoutCancel(ctx), job)
+		return
+	case err != nil:
+		job.Status = ai.GenerationFailed
+		job.Error = err.Error()
+	default:
+		job.Status = ai.GenerationCompleted
+		job.Result = resp
+	}
+	p.update(context.WithoutCancel(ctx), job)
+}
+
+// run executes the generation request of a job and returns the encoded response
+func (p *GenerationWorkerPool) run(ctx context.Context, job ai.GenerationJob) (json.RawMessage, error) {
+	var req GenerateCodeRequest
+	if err := json.Unmarshal(job.Payload, &req); err != nil {
+		return nil, fmt.Errorf("failed to decode job request: %w", err)
+	}
+
+	resp, err := p.generateUC.Execute(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resp)
+}
+
+// renewLease renews the lease of a job until ctx is done
+func (p *GenerationWorkerPool) renewLease(ctx context.Context, id string) {
+	ticker := time.NewTicker(p.config.LeaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = p.queue.RenewLease(ctx, id)
+		}
+	}
+}
+
+// requeue returns a job to the queue and announces it is pending again. A job
+// whose lease has already expired was requeued by the queue and is left alone.
+func (p *GenerationWorkerPool) requeue(ctx context.Context, job ai.GenerationJob) {
+	job.Touch()
+	if err := p.queue.Requeue(ctx, job); err != nil {
+		return
+	}
+	if p.publisher != nil {
+		_ = p.publisher.PublishJobEvent(ctx, job)
+	}
+}
+
+// update stores a job and announces its new status
+func (p *GenerationWorkerPool) update(ctx context.Context, job ai.GenerationJob) {
+	job.Touch()
+	_ = p.queue.UpdateJob(ctx, job)
+	if p.publisher != nil {
+		_ = p.publisher.PublishJobEvent(ctx, job)
+	}
+}
@@ -0,0 +1,14 @@
+package ai
+
+// Logger records failures that do not fail a request, such as a generation
+// that could not be saved to history. observability.Logger satisfies it.
+type Logger interface {
+	Error(msg string, err error, fields ...map[string]interface{})
+}
+
+// logError logs err through logger, if one is configured
+func logError(logger Logger, msg string, err error, fields map[string]interface{}) {
+	if logger != nil {
+		logger.Error(msg, err, fields)
+	}
+}
@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// GenerationParams holds the optional per-request model and sampling parameters
+type GenerationParams struct {
+	Model            string   `json:"model,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty" validate:"omitempty,min=0,max=2"`
+	MaxTokens        *int     `json:"max_tokens,omitempty" validate:"omitempty,min=1"`
+	TopP             *float64 `json:"top_p,omitempty" validate:"omitempty,gt=0,max=1"`
+	Stop             []string `json:"stop,omitempty" validate:"max=4"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty" validate:"omitempty,min=-2,max=2"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty" validate:"omitempty,min=-2,max=2"`
+	ResponseFormat   string   `json:"response_format,omitempty" validate:"omitempty,oneof=text json_object"`
+	MultiFile        bool     `json:"multi_file,omitempty"` // Ask for separate component, style, story, test and type files
+}
+
+// applyTo copies the parameters onto a domain generation request
+func (p GenerationParams) applyTo(req *ai.GenerationRequest) {
+	req.Model = p.Model
+	req.Temperature = p.Temperature
+	req.MaxTokens = p.MaxTokens
+	req.TopP = p.TopP
+	req.Stop = p.Stop
+	req.Seed = p.Seed
+	req.PresencePenalty = p.PresencePenalty
+	req.FrequencyPenalty = p.FrequencyPenalty
+	req.ResponseFormat = p.ResponseFormat
+}
+
+// checkParams rejects req up front when the LLM service knows the provider
+// would refuse its sampling parameters
+func checkParams(llmService ai.LLMService, req ai.GenerationRequest) error {
+	checker, ok := llmService.(ai.ParamChecker)
+	if !ok {
+		return nil
+	}
+	return checker.CheckParams(req)
+}
+
+// acceptsParam reports whether the model of req accepts the named parameter.
+// Services that cannot tell are assumed to accept it.
+func acceptsParam(llmService ai.LLMService, req ai.GenerationRequest, name string) bool {
+	checker, ok := llmService.(ai.ParamChecker)
+	return !ok || checker.AcceptsParam(req, name)
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// CodePostProcessor runs generated code through a pipeline of processors.
+// A processor that fails is skipped so that one formatter cannot fail a
+// generation.
+type CodePostProcessor struct {
+	processors []ai.CodeProcessor
+}
+
+// NewCodePostProcessor creates a new CodePostProcessor running processors in order
+func NewCodePostProcessor(processors ...ai.CodeProcessor) *CodePostProcessor {
+	return &CodePostProcessor{
+		processors: processors,
+	}
+}
+
+// Process runs code through every processor and returns the result along
+// with the names of the processors that changed it
+func (p *CodePostProcessor) Process(ctx context.Context, code string, target ai.CodeTarget) (string, []string) {
+	var applied []string
+	for _, processor := range p.processors {
+		processed, err := processor.Process(ctx, code, target)
+		if err != nil || processed == code {
+			continue
+		}
+		code = processed
+		applied = append(applied, processor.Name())
+	}
+	return code, applied
+}
+
+// postProcess cleans up the raw generated code and the files of its
+// artifact with post, if configured. The processors that changed anything are recorded
+// in metadata, which is allocated when needed.
+func postProcess(ctx context.Context, post *CodePostProcessor, code string, artifact *ai.Artifact, target ai.CodeTarget, metadata map[string]string) (string, map[string]string) {
+	if post == nil {
+		return code, metadata
+	}
+
+	target.RawOutput = true
+	code, applied := post.Process(ctx, code, target)
+	if artifact != nil {
+		for i, f := range artifact.Files {
+			fileTarget := ai.CodeTarget{Language: f.Language, Framework: target.Framework}
+			content, fileApplied := post.Process(ctx, f.Content, fileTarget)
+			artifact.Files[i].Content = content
+			applied = appendUnique(applied, fileApplied...)
+		}
+	}
+
+	if len(applied) > 0 {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[ai.MetadataPostProcessors] = strings.Join(applied, ",")
+	}
+	return code, metadata
+}
+
+// appendUnique appends the values not already in s
+func appendUnique(s []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(s, v) {
+			s = append(s, v)
+		}
+	}
+	return s
+}
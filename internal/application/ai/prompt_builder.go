@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// DefaultPromptFramework is used when a request names no framework, or one
+// without a template
+const DefaultPromptFramework = "html"
+
+// TemplatePromptBuilder renders prompt templates for generation requests
+type TemplatePromptBuilder struct {
+	templates ai.PromptTemplateRepository
+}
+
+// NewTemplatePromptBuilder creates a new TemplatePromptBuilder
+func NewTemplatePromptBuilder(templates ai.PromptTemplateRepository) *TemplatePromptBuilder {
+	return &TemplatePromptBuilder{
+		templates: templates,
+	}
+}
+
+// Build selects the latest template for the request's framework and language
+// and renders it into a system and a user message
+func (b *TemplatePromptBuilder) Build(ctx context.Context, req ai.GenerationRequest) (ai.Prompt, error) {
+	templates, err := b.templates.ListTemplates(ctx)
+	if err != nil {
+		return ai.Prompt{}, fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+
+	tmpl, ok := selectTemplate(templates, req.Framework, req.Language)
+	if !ok {
+		tmpl, ok = selectTemplate(templates, DefaultPromptFramework, req.Language)
+	}
+	if !ok {
+		return ai.Prompt{}, common.NewNotFoundError(fmt.Sprintf("no prompt template for framework %q", req.Framework))
+	}
+
+	vars := templateVariables(tmpl, req)
+
+	system, err := renderTemplate(tmpl, "system", tmpl.System, vars)
+	if err != nil {
+		return ai.Prompt{}, err
+	}
+	user, err := renderTemplate(tmpl, "user", tmpl.User, vars)
+	if err != nil {
+		return ai.Prompt{}, err
+	}
+
+	messages := make([]ai.ChatMessage, 0, 2)
+	if system != "" {
+		messages = append(messages, ai.ChatMessage{Role: ai.RoleSystem, Content: system})
+	}
+	messages = append(messages, ai.ChatMessage{Role: ai.RoleUser, Content: user})
+
+	return ai.Prompt{Messages: messages, Template: tmpl}, nil
+}
+
+// selectTemplate returns the latest matching template, preferring one written
+// for the exact language over a language-agnostic one
+func selectTemplate(templates []ai.PromptTemplate, framework, language string) (ai.PromptTemplate, bool) {
+	var best ai.PromptTemplate
+	found := false
+
+	for _, t := range templates {
+		if !t.Matches(framework, language) {
+			continue
+		}
+		// Later templates win ties so overrides can replace a listed version
+		if !found || !betterTemplate(best, t) {
+			best = t
+			found = true
+		}
+	}
+	return best, found
+}
+
+// betterTemplate reports whether a should be preferred over b
+func betterTemplate(a, b ai.PromptTemplate) bool {
+	aSpecific, bSpecific := a.Language != "", b.Language != ""
+	if aSpecific != bSpecific {
+		return aSpecific
+	}
+	return a.Version > b.Version
+}
+
+// templateVariables merges the template defaults with the request fields.
+// Request fields win when set.
+func templateVariables(tmpl ai.PromptTemplate, req ai.GenerationRequest) map[string]string {
+	vars := map[string]string{
+		"prompt":     "",
+		"language":   "",
+		"framework":  "",
+		"style":      "",
+		"complexity": "",
+	}
+	for name, value := range tmpl.Variables {
+		vars[name] = value
+	}
+
+	set := func(name, value string) {
+		if value != "" {
+			vars[name] = value
+		}
+	}
+	set("prompt", req.Prompt)
+	set("language", req.Language)
+	set("framework", req.Framework)
+	set("style", req.Style)
+	set("complexity", req.Complexity)
+
+	return vars
+}
+
+// renderTemplate interpolates source with vars. Unknown variables are an error
+// so typos in templates surface instead of producing silent gaps.
+func renderTemplate(tmpl ai.PromptTemplate, part, source string, vars map[string]string) (string, error) {
+	name := fmt.Sprintf("%s.v%d.%s", tmpl.Name, tmpl.Version, part)
+
+	parsed, err := template.New(name).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt template %s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := parsed.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// applyPrompt renders req through prompts, if configured, and returns the
+// metadata identifying the template that was used
+func applyPrompt(ctx context.Context, prompts ai.PromptBuilder, req *ai.GenerationRequest) (map[string]string, error) {
+	if prompts == nil {
+		return nil, nil
+	}
+
+	prompt, err := prompts.Build(ctx, *req)
+	if err != nil {
+		return nil, err
+	}
+	req.Messages = prompt.Messages
+	return prompt.Template.Metadata(), nil
+}
@@ -0,0 +1,231 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"time"
+	_ "time/tzdata" // Quota windows reset in the user's timezone on hosts without zoneinfo too
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// QuotaLimitResponse represents the usage of one limit
+type QuotaLimitResponse struct {
+	Limit     int  `json:"limit"` // Zero when the plan has no such limit
+	Used      int  `json:"used"`
+	Remaining *int `json:"remaining,omitempty"`
+}
+
+// QuotaPeriodResponse represents the token and request usage of a day or month
+type QuotaPeriodResponse struct {
+	Tokens   QuotaLimitResponse `json:"tokens"`
+	Requests QuotaLimitResponse `json:"requests"`
+	ResetsAt string             `json:"resets_at"`
+}
+
+// QuotaResponse represents a user's quota
+type QuotaResponse struct {
+	UserID      string              `json:"user_id"`
+	Tier        string              `json:"tier"`
+	Timezone    string              `json:"timezone"`
+	CanGenerate bool                `json:"can_generate"`
+	Daily       QuotaPeriodResponse `json:"daily"`
+	Monthly     QuotaPeriodResponse `json:"monthly"`
+}
+
+// QuotaService enforces the plan limits of users. Usage is counted in a day
+// and a month window that reset at midnight in the user's timezone.
+type QuotaService struct {
+	plans   ai.QuotaPlanRepository
+	counter ai.QuotaCounter
+}
+
+// NewQuotaService creates a new QuotaService
+func NewQuotaService(plans ai.QuotaPlanRepository, counter ai.QuotaCounter) *QuotaService {
+	return &QuotaService{
+		plans:   plans,
+		counter: counter,
+	}
+}
+
+// Status returns the quota status of a user
+func (s *QuotaService) Status(ctx context.Context, userID common.UserID) (ai.QuotaStatus, error) {
+	account, err := s.plans.GetQuotaAccount(ctx, userID)
+	if err != nil {
+		return ai.QuotaStatus{}, err
+	}
+
+	timezone, windows := quotaWindows(time.Now(), account.Timezone)
+	usage, err := s.counter.Usage(ctx, userID, windows...)
+	if err != nil {
+		return ai.QuotaStatus{}, err
+	}
+	return newQuotaStatus(account, timezone, windows, usage), nil
+}
+
+// Reserve atomically counts one request against the quota of a user unless
+// a day or month limit has been reached. It returns the updated status and
+// whether the request was counted.
+func (s *QuotaService) Reserve(ctx context.Context, userID common.UserID) (ai.QuotaStatus, bool, error) {
+	account, err := s.plans.GetQuotaAccount(ctx, userID)
+	if err != nil {
+		return ai.QuotaStatus{}, false, err
+	}
+
+	plan := account.Plan
+	timezone, windows := quotaWindows(time.Now(), account.Timezone)
+	windows[0].Limit = ai.QuotaUsage{Tokens: plan.DailyTokens, Requests: plan.DailyRequests}
+	windows[1].Limit = ai.QuotaUsage{Tokens: plan.MonthlyTokens, Requests: plan.MonthlyRequests}
+	usage, ok, err := s.counter.Reserve(ctx, userID, windows...)
+	if err != nil {
+		return ai.QuotaStatus{}, false, err
+	}
+	return newQuotaStatus(account, timezone, windows, usage), ok, nil
+}
+
+// Charge counts tokens of model used by a reserved request against the quota
+// of a user and returns the updated status
+func (s *QuotaService) Charge(ctx context.Context, userID common.UserID, model string, tokens int) (ai.QuotaStatus, error) {
+	account, err := s.plans.GetQuotaAccount(ctx, userID)
+	if err != nil {
+		return ai.QuotaStatus{}, err
+	}
+
+	weighted := int(math.Ceil(float64(tokens) * account.Plan.Multiplier(model)))
+	timezone, windows := quotaWindows(time.Now(), account.Timezone)
+	usage, err := s.counter.Add(ctx, userID, ai.QuotaUsage{Tokens: weighted}, windows...)
+	if err != nil {
+		return ai.QuotaStatus{}, err
+	}
+	return newQuotaStatus(account, timezone, windows, usage), nil
+}
+
+// GetQuota returns the quota of a user
+func (s *QuotaService) GetQuota(ctx context.Context, userID common.UserID) (*QuotaResponse, error) {
+	status, err := s.Status(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return newQuotaResponse(status), nil
+}
+
+// quotaWindows returns the day and month containing now in timezone, falling
+// back to UTC when timezone is unknown, along with the timezone used
+func quotaWindows(now time.Time, timezone string) (string, []ai.QuotaWindow) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "" {
+		loc = time.UTC
+	}
+
+	now = now.In(loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	return loc.String(), []ai.QuotaWindow{
+		{Key: "day:" + day.Format("2006-01-02"), ResetAt: day.AddDate(0, 0, 1)},
+		{Key: "month:" + month.Format("2006-01"), ResetAt: month.AddDate(0, 1, 0)},
+	}
+}
+
+// newQuotaStatus builds the status of an account from the usage of its day
+// and month windows
+func newQuotaStatus(account ai.QuotaAccount, timezone string, windows []ai.QuotaWindow, usage []ai.QuotaUsage) ai.QuotaStatus {
+	plan, today, month := account.Plan, usage[0], usage[1]
+
+	remaining := plan.DailyTokens - today.Tokens
+	if plan.MonthlyTokens > 0 {
+		remaining = min(remaining, plan.MonthlyTokens-month.Tokens)
+	}
+
+	return ai.QuotaStatus{
+		UserID:              account.UserID,
+		Tier:                plan.Tier,
+		Timezone:            timezone,
+		DailyLimit:          plan.DailyTokens,
+		UsedToday:           today.Tokens,
+		Remaining:           max(remaining, 0),
+		ResetTime:           windows[0].ResetAt.Format(time.RFC3339),
+		MonthlyLimit:        plan.MonthlyTokens,
+		UsedThisMonth:       month.Tokens,
+		MonthlyResetTime:    windows[1].ResetAt.Format(time.RFC3339),
+		DailyRequestLimit:   plan.DailyRequests,
+		RequestsToday:       today.Requests,
+		MonthlyRequestLimit: plan.MonthlyRequests,
+		RequestsThisMonth:   month.Requests,
+	}
+}
+
+// newQuotaResponse converts a quota status to its response
+func newQuotaResponse(status ai.QuotaStatus) *QuotaResponse {
+	return &QuotaResponse{
+		UserID:      string(status.UserID),
+		Tier:        string(status.Tier),
+		Timezone:    status.Timezone,
+		CanGenerate: status.CanGenerate(),
+		Daily: QuotaPeriodResponse{
+			Tokens:   newQuotaLimitResponse(status.DailyLimit, status.UsedToday),
+			Requests: newQuotaLimitResponse(status.DailyRequestLimit, status.RequestsToday),
+			ResetsAt: status.ResetTime,
+		},
+		Monthly: QuotaPeriodResponse{
+			Tokens:   newQuotaLimitResponse(status.MonthlyLimit, status.UsedThisMonth),
+			Requests: newQuotaLimitResponse(status.MonthlyRequestLimit, status.RequestsThisMonth),
+			ResetsAt: status.MonthlyResetTime,
+		},
+	}
+}
+
+// newQuotaLimitResponse returns the usage of a limit, leaving out what
+// remains when there is no limit
+func newQuotaLimitResponse(limit, used int) QuotaLimitResponse {
+	resp := QuotaLimitResponse{Limit: limit, Used: used}
+	if limit > 0 {
+		remaining := max(limit-used, 0)
+		resp.Remaining = &remaining
+	}
+	return resp
+}
+
+// quotaReservation is a generation counted against the quota of a user
+// whose tokens are charged once it ends
+type quotaReservation struct {
+	quota  *QuotaService
+	repo   ai.Repository
+	userID common.UserID
+}
+
+// reserveQuota counts a generation against the quota of a user with quota, if
+// configured, or else checks the quota in repo. It returns a validation error
+// when the quota is exhausted.
+func reserveQuota(ctx context.Context, quota *QuotaService, repo ai.Repository, userID common.UserID) (*quotaReservation, error) {
+	var status ai.QuotaStatus
+	var err error
+	ok := true
+	if quota != nil {
+		_, ok, err = quota.Reserve(ctx, userID)
+	} else {
+		status, err = repo.GetQuotaUsage(ctx, userID)
+		ok = status.CanGenerate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, common.NewValidationError("quota exceeded", nil)
+	}
+	return &quotaReservation{quota: quota, repo: repo, userID: userID}, nil
+}
+
+// charge counts tokens of model used by the generation. It runs even when ctx
+// is cancelled so failed and cancelled generations are billed too.
+func (r *quotaReservation) charge(ctx context.Context, model string, tokens int) error {
+	if tokens <= 0 {
+		return nil
+	}
+	ctx = context.WithoutCancel(ctx)
+	if r.quota != nil {
+		_, err := r.quota.Charge(ctx, r.userID, model, tokens)
+		return err
+	}
+	return r.repo.UpdateQuotaUsage(ctx, r.userID, tokens)
+}
@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// RefineCodeRequest represents a request to revise a previous generation
+type RefineCodeRequest struct {
+	ParentID    string        `json:"-"`
+	Instruction string        `json:"instruction" validate:"required,min=1,max=10000"`
+	Language    string        `json:"language"`
+	Style       string        `json:"style"`
+	Complexity  string        `json:"complexity"`
+	UserID      common.UserID `json:"-"`
+	GenerationParams
+}
+
+// RefineCodeUseCase generates revisions of previous generations. Each revision
+// is stored as a child of the generation it refines, so any generation can be
+// refined again or branched from.
+type RefineCodeUseCase struct {
+	history        ai.HistoryRepository
+	generateCodeUC *GenerateCodeUseCase
+}
+
+// NewRefineCodeUseCase creates a new RefineCodeUseCase
+func NewRefineCodeUseCase(history ai.HistoryRepository, generateCodeUC *GenerateCodeUseCase) *RefineCodeUseCase {
+	return &RefineCodeUseCase{
+		history:        history,
+		generateCodeUC: generateCodeUC,
+	}
+}
+
+// Execute revises the parent generation's code according to the instruction
+func (uc *RefineCodeUseCase) Execute(ctx context.Context, req RefineCodeRequest) (*GenerateCodeResponse, error) {
+	if req.Instruction == "" {
+		return nil, common.NewValidationError("instruction is required", nil)
+	}
+
+	parent, err := uc.history.GetHistoryEntry(ctx, req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.UserID != req.UserID {
+		return nil, common.NewNotFoundError("generation not found")
+	}
+	if parent.Code == "" || (parent.Status != "" && parent.Status != ai.GenerationCompleted) {
+		return nil, common.NewConflictError("only completed generations can be refined")
+	}
+
+	return uc.generateCodeUC.execute(ctx, GenerateCodeRequest{
+		Prompt:           req.Instruction,
+		Language:         req.Language,
+		Framework:        parent.Framework,
+		Style:            req.Style,
+		Complexity:       req.Complexity,
+		UserID:           req.UserID,
+		ProjectID:        parent.ProjectID,
+		GenerationParams: req.GenerationParams,
+	}, &parent)
+}
+
+// refinementMessages replays the parent generation as a conversation ending in
+// the instruction. System messages from the rendered template are kept.
+func refinementMessages(rendered []ai.ChatMessage, parent ai.GenerationHistory, instruction string) []ai.ChatMessage {
+	messages := make([]ai.ChatMessage, 0, len(rendered)+3)
+	for _, m := range rendered {
+		if m.Role == ai.RoleSystem {
+			messages = append(messages, m)
+		}
+	}
+	return append(messages,
+		ai.ChatMessage{Role: ai.RoleUser, Content: parent.Prompt},
+		ai.ChatMessage{Role: ai.RoleAssistant, Content: parent.Code},
+		ai.ChatMessage{Role: ai.RoleUser, Content: fmt.Sprintf(
+			"Revise the code above: %s\n\nRespond with the complete updated code.", instruction,
+		)},
+	)
+}
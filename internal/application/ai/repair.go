@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+const (
+	// DefaultRepairMaxAttempts is the number of generations per request,
+	// including the first, when repairing invalid code
+	DefaultRepairMaxAttempts = 3
+	// DefaultRepairTokenBudget is the number of tokens after which no further
+	// repair is attempted
+	DefaultRepairTokenBudget = 16000
+	// maxRepairDiagnostics caps the diagnostics sent back to the model
+	maxRepairDiagnostics = 20
+)
+
+// RepairConfig configures the repair loop of GenerateCodeUseCase
+type RepairConfig struct {
+	MaxAttempts int // Generations per request, including the first
+	TokenBudget int // No repair starts once the attempts so far used this many tokens; 0 means no budget
+}
+
+// DefaultRepairConfig returns the default configuration
+func DefaultRepairConfig() *RepairConfig {
+	return &RepairConfig{
+		MaxAttempts: DefaultRepairMaxAttempts,
+		TokenBudget: DefaultRepairTokenBudget,
+	}
+}
+
+// DiagnosticResponse represents a problem the validator found in generated code
+type DiagnosticResponse struct {
+	Path     string `json:"path,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// RepairAttemptResponse represents one version of the code produced while
+// repairing a generation
+type RepairAttemptResponse struct {
+	Attempt     int                  `json:"attempt"`
+	Valid       bool                 `json:"valid"`
+	Score       int                  `json:"score"`
+	Tokens      int                  `json:"tokens"`
+	Diagnostics []DiagnosticResponse `json:"diagnostics,omitempty"`
+}
+
+// newRepairAttemptResponses converts domain repair attempts to their responses
+func newRepairAttemptResponses(attempts []ai.RepairAttempt) []RepairAttemptResponse {
+	if len(attempts) == 0 {
+		return nil
+	}
+	resp := make([]RepairAttemptResponse, len(attempts))
+	for i, a := range attempts {
+		resp[i] = RepairAttemptResponse{
+			Attempt: a.Attempt,
+			Valid:   a.Valid,
+			Score:   a.Score(),
+			Tokens:  a.Tokens,
+		}
+		for _, d := range a.Diagnostics {
+			resp[i].Diagnostics = append(resp[i].Diagnostics, DiagnosticResponse{
+				Path:     d.Path,
+				Line:     d.Line,
+				Column:   d.Column,
+				Severity: string(d.Severity),
+				Rule:     d.Rule,
+				Message:  d.Message,
+			})
+		}
+	}
+	return resp
+}
+
+// repair validates first and, while the code is invalid, sends the
+// diagnostics back to the model and asks it to fix them, within the
+// attempt and token limits of the use case. It returns the first valid
+// candidate or else the best-scoring one, along with every attempt and the
+// tokens and cost of all of them. A failed repair generation ends the loop
+// without failing the request.
+func (uc *GenerateCodeUseCase) repair(ctx context.Context, domainReq ai.GenerationRequest, req GenerateCodeRequest, metadata map[string]string, first candidate) (candidate, []ai.RepairAttempt, int, float64) {
+	var attempts []ai.RepairAttempt
+	tokens, cost := 0, 0.0
+	best, bestScore := first, 0
+
+	current := first
+	for {
+		diagnostics := validateArtifact(ctx, uc.validator, current.artifact)
+		attempt := ai.RepairAttempt{
+			Attempt:     len(attempts) + 1,
+			Code:        current.code,
+			Valid:       !hasErrors(diagnostics),
+			Tokens:      current.result.UsedTokens,
+			Diagnostics: diagnostics,
+		}
+		attempts = append(attempts, attempt)
+		tokens += current.result.UsedTokens
+		cost += current.result.EstimatedCost
+
+		if attempt.Valid {
+			return current, attempts, tokens, cost
+		}
+		if len(attempts) == 1 || attempt.Score() > bestScore {
+			best, bestScore = current, attempt.Score()
+		}
+		if len(attempts) >= uc.repairConfig.MaxAttempts {
+			break
+		}
+		if uc.repairConfig.TokenBudget > 0 && tokens >= uc.repairConfig.TokenBudget {
+			break
+		}
+
+		repairReq := domainReq
+		repairReq.Messages = repairMessages(domainReq, current.result.Code, diagnostics)
+		next, err := uc.generate(ctx, repairReq, req, metadata)
+		if err != nil {
+			break
+		}
+		current = next
+	}
+	return best, attempts, tokens, cost
+}
+
+// validateArtifact validates every file of artifact in the language of its
+// extension. Files in unknown languages are skipped.
+func validateArtifact(ctx context.Context, validator ai.CodeValidator, artifact *ai.Artifact) []ai.Diagnostic {
+	if artifact == nil {
+		return nil
+	}
+	var diagnostics []ai.Diagnostic
+	for _, f := range artifact.Files {
+		language := f.Language
+		if language == "" {
+			language = ai.LanguageForPath(f.Path)
+		}
+		if language == "" {
+			continue
+		}
+		result, err := validator.Validate(ctx, f.Content, language)
+		if err != nil {
+			continue
+		}
+		for _, d := range result.Diagnostics {
+			d.Path = f.Path
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+// hasErrors reports whether any diagnostic is an error
+func hasErrors(diagnostics []ai.Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == ai.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// repairMessages builds the conversation asking the model to fix output,
+// the raw answer it gave to req, using the errors found in it
+func repairMessages(req ai.GenerationRequest, output string, diagnostics []ai.Diagnostic) []ai.ChatMessage {
+	messages := append([]ai.ChatMessage(nil), req.Messages...)
+	if len(messages) == 0 {
+		messages = []ai.ChatMessage{{Role: ai.RoleUser, Content: req.Prompt}}
+	}
+
+	var problems strings.Builder
+	listed := 0
+	for _, d := range diagnostics {
+		if d.Severity != ai.SeverityError {
+			continue
+		}
+		if listed == maxRepairDiagnostics {
+			problems.WriteString("- ...\n")
+			break
+		}
+		fmt.Fprintf(&problems, "- %s\n", d)
+		listed++
+	}
+
+	return append(messages,
+		ai.ChatMessage{Role: ai.RoleAssistant, Content: output},
+		ai.ChatMessage{Role: ai.RoleUser, Content: fmt.Sprintf(
+			"The code above does not pass validation:\n%s\nFix these problems and respond with the complete corrected code in the same format.",
+			problems.String(),
+		)},
+	)
+}
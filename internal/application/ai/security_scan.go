@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"context"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// SecurityFindingResponse represents a risky pattern found in generated code
+type SecurityFindingResponse struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Path     string `json:"path,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// NewSecurityFindingResponses converts domain findings to their responses
+func NewSecurityFindingResponses(findings []ai.SecurityFinding) []SecurityFindingResponse {
+	if len(findings) == 0 {
+		return nil
+	}
+	resp := make([]SecurityFindingResponse, len(findings))
+	for i, f := range findings {
+		resp[i] = SecurityFindingResponse{
+			Rule:     f.Rule,
+			Severity: string(f.Severity),
+			Message:  f.Message,
+			Path:     f.Path,
+			Line:     f.Line,
+			Column:   f.Column,
+		}
+	}
+	return resp
+}
+
+// CodeScanner scans generated artifacts with the security policy of the
+// project they belong to, provided the requesting user owns it
+type CodeScanner struct {
+	scanner  ai.SecurityScanner
+	policies ai.SecurityPolicyRepository
+}
+
+// NewCodeScanner creates a new CodeScanner. Without policies, every project
+// uses ai.DefaultSecurityPolicy.
+func NewCodeScanner(scanner ai.SecurityScanner, policies ai.SecurityPolicyRepository) *CodeScanner {
+	return &CodeScanner{
+		scanner:  scanner,
+		policies: policies,
+	}
+}
+
+// Scan scans the files of artifact generated for userID. A project that
+// userID does not own, or whose policy cannot be loaded, is scanned with the
+// default policy rather than not at all.
+func (s *CodeScanner) Scan(ctx context.Context, userID common.UserID, projectID *common.ProjectID, artifact *ai.Artifact) ([]ai.SecurityFinding, error) {
+	if artifact == nil || artifact.IsEmpty() {
+		return nil, nil
+	}
+
+	policy := ai.DefaultSecurityPolicy()
+	if projectID != nil && s.policies != nil {
+		if p, err := s.policies.GetSecurityPolicy(ctx, userID, *projectID); err == nil {
+			policy = p
+		}
+	}
+	return s.scanner.Scan(ctx, artifact.Files, policy)
+}
+
+// scanArtifact scans artifact with scanner, if configured. Generations are
+// not failed by the scanner, so scan errors leave the findings empty.
+func scanArtifact(ctx context.Context, scanner *CodeScanner, userID common.UserID, projectID *common.ProjectID, artifact *ai.Artifact) []ai.SecurityFinding {
+	if scanner == nil {
+		return nil
+	}
+	findings, err := scanner.Scan(ctx, userID, projectID, artifact)
+	if err != nil {
+		return nil
+	}
+	return findings
+}
@@ -3,6 +3,8 @@ package ai
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
 	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
@@ -17,15 +19,21 @@ type StreamCodeRequest struct {
 	Complexity string            `json:"complexity" validate:"oneof=simple medium complex"`
 	UserID     common.UserID     `json:"user_id" validate:"required"`
 	ProjectID  *common.ProjectID `json:"project_id,omitempty"`
+	GenerationParams
 }
 
 // StreamCodeResponse represents a streaming code generation response chunk
 type StreamCodeResponse struct {
-	Type       string `json:"type"` // "chunk", "complete", "error"
-	Content    string `json:"content"`
-	TokenCount int    `json:"token_count,omitempty"`
-	IsComplete bool   `json:"is_complete"`
-	Error      string `json:"error,omitempty"`
+	Type             string                    `json:"type"` // "started", "chunk", "complete", "cancelled", "error"
+	Content          string                    `json:"content"`
+	TokenCount       int                       `json:"token_count,omitempty"`
+	IsComplete       bool                      `json:"is_complete"`
+	FinishReason     string                    `json:"finish_reason,omitempty"`
+	Error            string                    `json:"error,omitempty"`
+	GenerationID     string                    `json:"generation_id,omitempty"`     // Set on the "started" and "complete" responses of tracked generations
+	Artifact         *ArtifactResponse         `json:"artifact,omitempty"`          // Set on the "complete" response
+	SecurityFindings []SecurityFindingResponse `json:"security_findings,omitempty"` // Set on the "complete" response
+	Code             string                    `json:"code,omitempty"`              // Final code, set on the "complete" response when post-processing changed the streamed content
 }
 
 // StreamCodeUseCase handles streaming code generation
@@ -34,25 +42,47 @@ type StreamCodeUseCase struct {
 	llmService  ai.LLMService
 	rateLimiter ai.RateLimiter
 	publisher   ai.EventPublisher
+	prompts     ai.PromptBuilder
+	tracker     *GenerationTracker
+	post        *CodePostProcessor
+	scanner     *CodeScanner
+	quota       *QuotaService
+	logger      Logger
+}
+
+// StreamCodeDeps holds the dependencies of a StreamCodeUseCase. Repo,
+// LLMService and RateLimiter are required; the others are optional.
+type StreamCodeDeps struct {
+	Repo          ai.Repository
+	LLMService    ai.LLMService
+	RateLimiter   ai.RateLimiter
+	Publisher     ai.EventPublisher
+	Prompts       ai.PromptBuilder   // Renders requests through prompt templates
+	Tracker       *GenerationTracker // Records generations so they can be cancelled while streaming
+	PostProcessor *CodePostProcessor // Cleans up the streamed code once the stream completes
+	Scanner       *CodeScanner       // Scans the completed artifact for risky patterns
+	Quota         *QuotaService      // Enforces plan limits instead of the repository's daily token count
+	Logger        Logger             // Records failures that do not fail the stream
 }
 
 // NewStreamCodeUseCase creates a new StreamCodeUseCase
-func NewStreamCodeUseCase(
-	repo ai.Repository,
-	llmService ai.LLMService,
-	rateLimiter ai.RateLimiter,
-	publisher ai.EventPublisher,
-) *StreamCodeUseCase {
+func NewStreamCodeUseCase(deps StreamCodeDeps) *StreamCodeUseCase {
 	return &StreamCodeUseCase{
-		repo:        repo,
-		llmService:  llmService,
-		rateLimiter: rateLimiter,
-		publisher:   publisher,
+		repo:        deps.Repo,
+		llmService:  deps.LLMService,
+		rateLimiter: deps.RateLimiter,
+		publisher:   deps.Publisher,
+		prompts:     deps.Prompts,
+		tracker:     deps.Tracker,
+		post:        deps.PostProcessor,
+		scanner:     deps.Scanner,
+		quota:       deps.Quota,
+		logger:      deps.Logger,
 	}
 }
 
 // Execute executes the streaming code generation use case
-func (uc *StreamCodeUseCase) Execute(ctx context.Context, req StreamCodeRequest, responseChan chan<- StreamCodeResponse) error {
+func (uc *StreamCodeUseCase) Execute(ctx context.Context, req StreamCodeRequest, responseChan chan<- StreamCodeResponse) (err error) {
 	// Convert to domain request
 	domainReq := ai.GenerationRequest{
 		Prompt:     req.Prompt,
@@ -63,6 +93,7 @@ func (uc *StreamCodeUseCase) Execute(ctx context.Context, req StreamCodeRequest,
 		UserID:     req.UserID,
 		ProjectID:  req.ProjectID,
 	}
+	req.GenerationParams.applyTo(&domainReq)
 
 	// Validate request
 	if err := domainReq.Validate(); err != nil {
@@ -72,6 +103,13 @@ func (uc *StreamCodeUseCase) Execute(ctx context.Context, req StreamCodeRequest,
 		}
 		return common.NewValidationError("invalid generation request", err)
 	}
+	if err := checkParams(uc.llmService, domainReq); err != nil {
+		responseChan <- StreamCodeResponse{
+			Type:  "error",
+			Error: "Invalid request: " + err.Error(),
+		}
+		return err
+	}
 
 	// Check rate limit
 	if !uc.rateLimiter.Allow(req.UserID) {
@@ -82,25 +120,68 @@ func (uc *StreamCodeUseCase) Execute(ctx context.Context, req StreamCodeRequest,
 		return common.NewValidationError("rate limit exceeded", nil)
 	}
 
-	// Check quota
-	quota, err := uc.repo.GetQuotaUsage(ctx, req.UserID)
+	// Reserve quota, charging the tokens streamed however the generation ends
+	reservation, err := reserveQuota(ctx, uc.quota, uc.repo, req.UserID)
 	if err != nil {
+		message := "Failed to check quota"
+		if common.IsValidationError(err) {
+			message = "Quota exceeded"
+		}
 		responseChan <- StreamCodeResponse{
 			Type:  "error",
-			Error: "Failed to check quota",
+			Error: message,
 		}
 		return err
 	}
+	totalTokens := 0
+	var modelName string
+	defer func() {
+		_ = reservation.charge(ctx, modelName, totalTokens)
+	}()
 
-	if !quota.CanGenerate() {
+	// Render the prompt template
+	metadata, err := applyPrompt(ctx, uc.prompts, &domainReq)
+	if err != nil {
 		responseChan <- StreamCodeResponse{
 			Type:  "error",
-			Error: "Quota exceeded",
+			Error: "Failed to build prompt",
 		}
-		return common.NewValidationError("quota exceeded", nil)
+		return err
+	}
+	if req.MultiFile {
+		requestArtifact(&domainReq, uc.llmService)
+	}
+
+	// Track the generation so it can be cancelled from any replica
+	var generationID string
+	if uc.tracker != nil {
+		generation, trackedCtx, startErr := uc.tracker.Start(ctx, ai.Generation{
+			UserID:    req.UserID,
+			Prompt:    req.Prompt,
+			Framework: req.Framework,
+		})
+		if startErr != nil {
+			responseChan <- StreamCodeResponse{
+				Type:  "error",
+				Error: "Failed to start generation",
+			}
+			return startErr
+		}
+		generationID = generation.ID
+		ctx = trackedCtx
+		defer func() {
+			_ = uc.tracker.Finish(ctx, generationID, err)
+		}()
+
+		responseChan <- StreamCodeResponse{
+			Type:         "started",
+			GenerationID: generationID,
+		}
+		_ = uc.tracker.MarkInProgress(ctx, generationID)
 	}
 
 	// Create streaming channel for domain chunks
+	started := time.Now()
 	streamChan := make(chan ai.StreamChunk, 10)
 
 	// Start streaming from LLM service
@@ -115,12 +196,17 @@ func (uc *StreamCodeUseCase) Execute(ctx context.Context, req StreamCodeRequest,
 	}()
 
 	// Forward stream chunks to response channel
-	totalTokens := 0
-	fullContent := ""
-	var modelName string
+	var content strings.Builder
 
 	for chunk := range streamChan {
 		if chunk.Error != nil {
+			if ctx.Err() != nil {
+				responseChan <- StreamCodeResponse{
+					Type:         "cancelled",
+					GenerationID: generationID,
+				}
+				return context.Cause(ctx)
+			}
 			responseChan <- StreamCodeResponse{
 				Type:  "error",
 				Error: chunk.Error.Error(),
@@ -128,7 +214,7 @@ func (uc *StreamCodeUseCase) Execute(ctx context.Context, req StreamCodeRequest,
 			return chunk.Error
 		}
 
-		fullContent += chunk.Content
+		content.WriteString(chunk.Content)
 		totalTokens += chunk.TokenCount
 
 		// Capture model name from the first chunk that has it
@@ -137,38 +223,56 @@ func (uc *StreamCodeUseCase) Execute(ctx context.Context, req StreamCodeRequest,
 		}
 
 		responseChan <- StreamCodeResponse{
-			Type:       "chunk",
-			Content:    chunk.Content,
-			TokenCount: chunk.TokenCount,
-			IsComplete: chunk.IsComplete,
+			Type:         "chunk",
+			Content:      chunk.Content,
+			TokenCount:   chunk.TokenCount,
+			IsComplete:   chunk.IsComplete,
+			FinishReason: chunk.FinishReason,
 		}
 
 		if chunk.IsComplete {
 			break
 		}
 	}
+	// Drain anything sent after the terminal chunk so the producer can return
+	go func() {
+		for range streamChan {
+		}
+	}()
 
 	// Use captured model name or fallback to default
 	if modelName == "" {
 		modelName = "unknown-model"
 	}
 
+	fullContent := content.String()
+	artifact := parseArtifact(fullContent, req.Framework, req.Language)
+	code, metadata := postProcess(ctx, uc.post, fullContent, artifact, ai.CodeTarget{Language: req.Language, Framework: req.Framework}, metadata)
+	findings := scanArtifact(ctx, uc.scanner, req.UserID, req.ProjectID, artifact)
+
 	// Save to history
 	history := ai.GenerationHistory{
-		UserID: req.UserID,
-		Prompt: req.Prompt,
-		Code:   fullContent,
-		Model:  modelName, // Now using actual model from stream
-		Tokens: totalTokens,
+		ID:               generationID, // Completes the tracked generation row, if any
+		UserID:           req.UserID,
+		ProjectID:        req.ProjectID,
+		Prompt:           req.Prompt,
+		Code:             code,
+		Artifact:         artifact,
+		SecurityFindings: findings,
+		Framework:        req.Framework,
+		Model:            modelName, // Now using actual model from stream
+		Tokens:           totalTokens,
+		Status:           ai.GenerationCompleted,
+		ProcessingTime:   time.Since(started),
+		Metadata:         metadata,
 	}
 
 	if err := uc.repo.SaveGeneration(ctx, history); err != nil {
 		// Log error but don't fail the request
-	}
-
-	// Update quota
-	if err := uc.repo.UpdateQuotaUsage(ctx, req.UserID, totalTokens); err != nil {
-		// Log error but don't fail the request
+		logError(uc.logger, "Failed to save generation", err, map[string]interface{}{
+			"generation_id": history.ID,
+			"user_id":       string(req.UserID),
+		})
 	}
 
 	// Publish event
@@ -177,12 +281,19 @@ func (uc *StreamCodeUseCase) Execute(ctx context.Context, req StreamCodeRequest,
 	}
 
 	// Send completion response
-	responseChan <- StreamCodeResponse{
-		Type:       "complete",
-		Content:    "",
-		TokenCount: totalTokens,
-		IsComplete: true,
+	complete := StreamCodeResponse{
+		Type:             "complete",
+		Content:          "",
+		TokenCount:       totalTokens,
+		IsComplete:       true,
+		GenerationID:     generationID,
+		Artifact:         newArtifactResponse(artifact),
+		SecurityFindings: NewSecurityFindingResponses(findings),
+	}
+	if code != fullContent {
+		complete.Code = code
 	}
+	responseChan <- complete
 
 	return nil
 }
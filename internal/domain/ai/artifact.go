@@ -0,0 +1,258 @@
+package ai
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ArtifactFile is a single file of a generated component
+type ArtifactFile struct {
+	Path     string
+	Language string
+	Content  string
+}
+
+// Artifact is the structured output of a generation: the files making up the
+// component and the npm packages it depends on
+type Artifact struct {
+	Files        []ArtifactFile
+	Dependencies map[string]string // Package name to version range
+}
+
+// IsEmpty returns true if the artifact has no files
+func (a Artifact) IsEmpty() bool {
+	return len(a.Files) == 0
+}
+
+// artifactDocument is the JSON shape models are asked to produce
+type artifactDocument struct {
+	Files []struct {
+		Path     string `json:"path"`
+		Language string `json:"language"`
+		Content  string `json:"content"`
+	} `json:"files"`
+	Dependencies json.RawMessage `json:"dependencies"`
+}
+
+var (
+	// fencePattern matches a markdown code fence with its info string
+	fencePattern = regexp.MustCompile("(?ms)^[ \t]*```([^\n`]*)\n(.*?)\n[ \t]*```[ \t]*$")
+	// filePathPattern matches a relative file path with an extension
+	filePathPattern = regexp.MustCompile(`[\w@./-]*\w\.[A-Za-z]{1,10}`)
+)
+
+// languageByExtension maps file extensions to artifact languages
+var languageByExtension = map[string]string{
+	".tsx":    "tsx",
+	".ts":     "typescript",
+	".jsx":    "jsx",
+	".js":     "javascript",
+	".mjs":    "javascript",
+	".vue":    "vue",
+	".svelte": "svelte",
+	".html":   "html",
+	".css":    "css",
+	".scss":   "scss",
+	".json":   "json",
+	".md":     "markdown",
+	".go":     "go",
+	".py":     "python",
+	".java":   "java",
+}
+
+// LanguageForPath returns the artifact language of a file from its extension
+func LanguageForPath(p string) string {
+	return languageByExtension[strings.ToLower(path.Ext(p))]
+}
+
+// ParseArtifact parses model output into an artifact. It accepts the JSON
+// document models are asked for, optionally wrapped in a code fence, then
+// markdown with one fenced block per file. Anything else becomes a single
+// file at fallbackPath. File paths are cleaned and made unique; files whose
+// path is absolute or leaves the artifact's root are dropped.
+func ParseArtifact(output, fallbackPath string) Artifact {
+	output = strings.TrimSpace(strings.ReplaceAll(output, "\r\n", "\n"))
+
+	if artifact, ok := parseArtifactJSON(output); ok {
+		return artifact
+	}
+	if blocks := fencePattern.FindAllStringSubmatchIndex(output, -1); len(blocks) == 1 {
+		// The whole answer may be a single fenced JSON document
+		inner := output[blocks[0][4]:blocks[0][5]]
+		if artifact, ok := parseArtifactJSON(inner); ok {
+			return artifact
+		}
+	}
+	if artifact, ok := parseArtifactFences(output, fallbackPath); ok {
+		return artifact
+	}
+	return Artifact{Files: []ArtifactFile{{
+		Path:     fallbackPath,
+		Language: LanguageForPath(fallbackPath),
+		Content:  output,
+	}}}
+}
+
+// parseArtifactJSON parses an artifact document
+func parseArtifactJSON(s string) (Artifact, bool) {
+	if !strings.HasPrefix(s, "{") {
+		return Artifact{}, false
+	}
+	var doc artifactDocument
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		return Artifact{}, false
+	}
+
+	var artifact Artifact
+	seen := make(map[string]bool)
+	for i, f := range doc.Files {
+		name, ok := cleanArtifactPath(f.Path)
+		if !ok || f.Content == "" {
+			continue
+		}
+		name = uniqueArtifactPath(name, i, seen)
+
+		language := f.Language
+		if language == "" {
+			language = LanguageForPath(name)
+		}
+		artifact.Files = append(artifact.Files, ArtifactFile{Path: name, Language: language, Content: f.Content})
+	}
+	if artifact.IsEmpty() {
+		return Artifact{}, false
+	}
+	artifact.Dependencies = parseDependencies(doc.Dependencies)
+	return artifact, true
+}
+
+// parseDependencies accepts either a package.json style object or a list of
+// package names, which are given the "latest" version
+func parseDependencies(raw json.RawMessage) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var versions map[string]string
+	if err := json.Unmarshal(raw, &versions); err == nil {
+		if len(versions) == 0 {
+			return nil
+		}
+		return versions
+	}
+	var names []string
+	if err := json.Unmarshal(raw, &names); err != nil || len(names) == 0 {
+		return nil
+	}
+	versions = make(map[string]string, len(names))
+	for _, name := range names {
+		versions[name] = "latest"
+	}
+	return versions
+}
+
+// parseArtifactFences turns each fenced code block into a file. File names
+// come from the fence info string (```tsx Button.tsx or ```tsx title="Button.tsx")
+// or from the line just above the fence.
+func parseArtifactFences(output, fallbackPath string) (Artifact, bool) {
+	blocks := fencePattern.FindAllStringSubmatchIndex(output, -1)
+	if len(blocks) == 0 {
+		return Artifact{}, false
+	}
+
+	var artifact Artifact
+	seen := make(map[string]bool)
+	for i, b := range blocks {
+		info := strings.TrimSpace(output[b[2]:b[3]])
+		content := output[b[4]:b[5]]
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+
+		language, name := parseFenceInfo(info)
+		if name == "" {
+			name = fileNameFromHeading(lastLine(output[:b[0]]))
+		}
+		name, _ = cleanArtifactPath(name)
+		if name == "" || seen[name] {
+			name = fallbackPath
+		}
+		name = uniqueArtifactPath(name, i, seen)
+
+		if detected := LanguageForPath(name); detected != "" {
+			language = detected
+		}
+		artifact.Files = append(artifact.Files, ArtifactFile{Path: name, Language: language, Content: content})
+	}
+	return artifact, !artifact.IsEmpty()
+}
+
+// cleanArtifactPath cleans a file path from model output. ok is false for
+// empty paths and paths that are absolute or leave the artifact's root.
+func cleanArtifactPath(p string) (string, bool) {
+	if p == "" {
+		return "", false
+	}
+	p = path.Clean(strings.ReplaceAll(p, `\`, "/"))
+	if path.IsAbs(p) || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return "", false
+	}
+	return p, true
+}
+
+// uniqueArtifactPath returns p, numbered after the position of its file if an
+// earlier file already uses it, and marks the result as used
+func uniqueArtifactPath(p string, i int, seen map[string]bool) string {
+	name := p
+	ext := path.Ext(p)
+	for n := i + 1; seen[name]; n++ {
+		name = strings.TrimSuffix(p, ext) + "-" + strconv.Itoa(n) + ext
+	}
+	seen[name] = true
+	return name
+}
+
+// parseFenceInfo splits a fence info string into its language and file name
+func parseFenceInfo(info string) (language, name string) {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	language = fields[0]
+	if lang, file, ok := strings.Cut(language, ":"); ok {
+		return lang, file
+	}
+	for _, field := range fields[1:] {
+		if _, value, ok := strings.Cut(field, "="); ok {
+			field = value
+		}
+		if file := filePathPattern.FindString(strings.Trim(field, `"'`)); file != "" {
+			return language, file
+		}
+	}
+	return language, ""
+}
+
+// fileNameFromHeading returns the file name of a line that names nothing but
+// a file, such as "**src/Button.tsx**", "### Button.tsx" or "// File: Button.tsx"
+func fileNameFromHeading(line string) string {
+	line = strings.Trim(line, " \t#*`:/-<>!")
+	for _, prefix := range []string{"File:", "file:", "Filename:", "filename:"} {
+		line = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+	}
+	line = strings.Trim(line, " *`")
+	if filePathPattern.FindString(line) != line {
+		return ""
+	}
+	return line
+}
+
+// lastLine returns the last non-empty line of s
+func lastLine(s string) string {
+	s = strings.TrimRight(s, " \t\n")
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return s
+}
@@ -2,6 +2,8 @@
 package ai
 
 import (
+	"time"
+
 	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
 )
 
@@ -17,6 +19,17 @@ type GenerationRequest struct {
 	Model       string
 	Temperature *float64
 	MaxTokens   *int
+
+	// Optional sampling parameters; nil or empty values use the provider default
+	TopP             *float64
+	Stop             []string
+	Seed             *int
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+	ResponseFormat   string // "text" or "json_object"
+
+	// Messages holds the rendered prompt. When empty, providers send Prompt as a single user message.
+	Messages []ChatMessage
 }
 
 // Validate validates the generation request
@@ -30,7 +43,23 @@ func (r GenerationRequest) Validate() error {
 	if r.Temperature != nil && (*r.Temperature < 0 || *r.Temperature > 2) {
 		return common.ErrInvalidInput
 	}
-	if r.MaxTokens != nil && (*r.MaxTokens < 1 || *r.MaxTokens > 4096) {
+	// The upper bound depends on the model and is checked by the provider's parameter allow-list
+	if r.MaxTokens != nil && *r.MaxTokens < 1 {
+		return common.ErrInvalidInput
+	}
+	if r.TopP != nil && (*r.TopP <= 0 || *r.TopP > 1) {
+		return common.ErrInvalidInput
+	}
+	if len(r.Stop) > 4 {
+		return common.ErrInvalidInput
+	}
+	if r.PresencePenalty != nil && (*r.PresencePenalty < -2 || *r.PresencePenalty > 2) {
+		return common.ErrInvalidInput
+	}
+	if r.FrequencyPenalty != nil && (*r.FrequencyPenalty < -2 || *r.FrequencyPenalty > 2) {
+		return common.ErrInvalidInput
+	}
+	if r.ResponseFormat != "" && r.ResponseFormat != "text" && r.ResponseFormat != "json_object" {
 		return common.ErrInvalidInput
 	}
 	return nil
@@ -62,11 +91,13 @@ func (r GenerationRequest) GetMaxTokens() int {
 
 // GenerationResult represents the result of code generation
 type GenerationResult struct {
-	ID            string
-	Code          string
-	Model         string
-	UsedTokens    int
-	EstimatedCost float64
+	ID               string
+	Code             string
+	Model            string
+	UsedTokens       int
+	PromptTokens     int
+	CompletionTokens int
+	EstimatedCost    float64
 	common.Timestamps
 }
 
@@ -89,40 +120,72 @@ func (r ValidationRequest) Validate() error {
 
 // ValidationResult represents the result of code validation
 type ValidationResult struct {
-	Valid  bool
-	Errors []string
+	Valid       bool
+	Errors      []string // Messages of the error diagnostics
+	Language    string   // Language the code was validated as, empty when unrecognized
+	Diagnostics []Diagnostic
 }
 
 // GenerationHistory represents a user's generation history entry
 type GenerationHistory struct {
-	ID     string
-	UserID common.UserID
-	Prompt string
-	Code   string
-	Model  string
-	Tokens int
+	ID               string
+	ParentID         string // Generation this one refines, if any
+	UserID           common.UserID
+	ProjectID        *common.ProjectID
+	Prompt           string
+	Code             string
+	Artifact         *Artifact // Code parsed into files and dependencies
+	SecurityFindings []SecurityFinding
+	RepairAttempts   []RepairAttempt // Versions produced by the repair loop, if it ran
+	Framework        string
+	Model            string
+	Tokens           int
+	Status           GenerationStatus // Empty is recorded as completed
+	ErrorMessage     string
+	ProcessingTime   time.Duration
+	Metadata         map[string]string // e.g. the prompt template and version used
 	common.Timestamps
 }
 
-// QuotaStatus represents a user's quota status
+// QuotaStatus represents a user's quota status. Token fields count tokens
+// after model multipliers; limits of zero other than DailyLimit mean the
+// plan has no such limit.
 type QuotaStatus struct {
 	UserID     common.UserID
+	Tier       PlanTier
+	Timezone   string
 	DailyLimit int
 	UsedToday  int
-	Remaining  int
+	Remaining  int // Tokens left today, capped by what is left this month
 	ResetTime  string
+
+	MonthlyLimit     int
+	UsedThisMonth    int
+	MonthlyResetTime string
+
+	DailyRequestLimit   int
+	RequestsToday       int
+	MonthlyRequestLimit int
+	RequestsThisMonth   int
 }
 
 // CanGenerate returns true if the user can generate more content
 func (q QuotaStatus) CanGenerate() bool {
+	if q.DailyRequestLimit > 0 && q.RequestsToday >= q.DailyRequestLimit {
+		return false
+	}
+	if q.MonthlyRequestLimit > 0 && q.RequestsThisMonth >= q.MonthlyRequestLimit {
+		return false
+	}
 	return q.Remaining > 0
 }
 
 // StreamChunk represents a chunk of streaming content
 type StreamChunk struct {
-	Content    string
-	TokenCount int
-	IsComplete bool
-	Model      string // Model name used for generation
-	Error      error
+	Content      string
+	TokenCount   int
+	IsComplete   bool
+	Model        string // Model name used for generation
+	FinishReason string // Set on the terminal chunk, e.g. "stop" or "length"
+	Error        error
 }
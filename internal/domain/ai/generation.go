@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// GenerationStatus is the lifecycle state of a tracked generation. The values
+// match the generation_status enum of the ui_generations table.
+type GenerationStatus string
+
+const (
+	GenerationPending    GenerationStatus = "pending"
+	GenerationInProgress GenerationStatus = "in_progress"
+	GenerationCompleted  GenerationStatus = "completed"
+	GenerationFailed     GenerationStatus = "failed"
+	GenerationCancelled  GenerationStatus = "cancelled"
+)
+
+// IsValid returns true for the statuses of the generation_status enum
+func (s GenerationStatus) IsValid() bool {
+	switch s {
+	case GenerationPending, GenerationInProgress, GenerationCompleted, GenerationFailed, GenerationCancelled:
+		return true
+	}
+	return false
+}
+
+// IsTerminal returns true once a generation can no longer change state
+func (s GenerationStatus) IsTerminal() bool {
+	return s == GenerationCompleted || s == GenerationFailed || s == GenerationCancelled
+}
+
+// Generation is a server-side record of a single generation and its status
+type Generation struct {
+	ID           string
+	UserID       common.UserID
+	Prompt       string
+	Framework    string
+	Status       GenerationStatus
+	ErrorMessage string
+	common.Timestamps
+}
+
+// CanCancel returns true if the generation has not finished yet
+func (g Generation) CanCancel() bool {
+	return !g.Status.IsTerminal()
+}
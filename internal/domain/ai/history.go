@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+const (
+	// DefaultHistoryPageSize is the page size of history queries that do not set one
+	DefaultHistoryPageSize = 20
+	// MaxHistoryPageSize caps the page size of history queries
+	MaxHistoryPageSize = 100
+)
+
+// HistoryCursor marks a position in generation history, which is ordered
+// newest first. Pages continue with the entries after the cursor.
+type HistoryCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Encode returns the opaque form of the cursor handed to clients
+func (c HistoryCursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseHistoryCursor decodes a cursor returned by Encode
+func ParseHistoryCursor(s string) (HistoryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return HistoryCursor{}, common.NewValidationError("invalid history cursor", err)
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return HistoryCursor{}, common.NewValidationError("invalid history cursor", nil)
+	}
+	if err := uuid.Validate(id); err != nil {
+		return HistoryCursor{}, common.NewValidationError("invalid history cursor", err)
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return HistoryCursor{}, common.NewValidationError("invalid history cursor", err)
+	}
+	return HistoryCursor{CreatedAt: time.Unix(0, n).UTC(), ID: id}, nil
+}
+
+// HistoryQuery selects a page of generation history. Zero-valued filters
+// match every entry.
+type HistoryQuery struct {
+	UserID        common.UserID // Empty matches every user
+	ProjectID     *common.ProjectID
+	Framework     string
+	Model         string
+	Status        GenerationStatus
+	CreatedAfter  time.Time      // Inclusive
+	CreatedBefore time.Time      // Exclusive
+	Search        string         // Full-text search over prompts
+	Cursor        *HistoryCursor // Nil starts from the newest entry
+	Limit         int
+}
+
+// PageSize returns the number of entries to return, with a default value
+func (q HistoryQuery) PageSize() int {
+	switch {
+	case q.Limit <= 0:
+		return DefaultHistoryPageSize
+	case q.Limit > MaxHistoryPageSize:
+		return MaxHistoryPageSize
+	}
+	return q.Limit
+}
+
+// HistoryPage is one page of generation history
+type HistoryPage struct {
+	Items      []GenerationHistory
+	NextCursor string // Empty on the last page
+}
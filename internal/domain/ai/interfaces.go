@@ -15,20 +15,78 @@ type Repository interface {
 	UpdateQuotaUsage(ctx context.Context, userID common.UserID, tokens int) error
 }
 
+// HistoryRepository queries and removes persisted generation history
+type HistoryRepository interface {
+	ListHistory(ctx context.Context, query HistoryQuery) (HistoryPage, error)
+	GetHistoryEntry(ctx context.Context, id string) (GenerationHistory, error)
+	// ListRevisions returns every generation in the revision tree containing id, oldest first
+	ListRevisions(ctx context.Context, id string) ([]GenerationHistory, error)
+	// DeleteHistoryEntry hides an entry from history; its usage still counts towards quotas
+	DeleteHistoryEntry(ctx context.Context, id string) error
+}
+
 // LLMService defines the interface for LLM interactions
 type LLMService interface {
 	Generate(ctx context.Context, req GenerationRequest) (GenerationResult, error)
+	// GenerateStream sends content chunks followed by exactly one chunk with
+	// IsComplete set. The caller owns ch and closes it once GenerateStream returns.
 	GenerateStream(ctx context.Context, req GenerationRequest, ch chan<- StreamChunk) error
 	Stream(ctx context.Context, req GenerationRequest, ch chan<- string) error // Legacy method
 	Validate(ctx context.Context, code string) (ValidationResult, error)
 }
 
+// ParamChecker is implemented by LLM services that know which sampling
+// parameters the provider accepts, so requests can be rejected up front
+type ParamChecker interface {
+	// CheckParams returns the error the provider would reject req with
+	CheckParams(req GenerationRequest) error
+	// AcceptsParam reports whether the model req resolves to accepts the named parameter
+	AcceptsParam(req GenerationRequest, name string) bool
+}
+
 // RateLimiter defines rate limiting interface
 type RateLimiter interface {
 	Allow(userID common.UserID) bool
 	Reset(userID common.UserID)
 }
 
+// GenerationRepository stores tracked generations and their status
+type GenerationRepository interface {
+	CreateGeneration(ctx context.Context, generation Generation) error
+	GetGeneration(ctx context.Context, id string) (Generation, error)
+	UpdateGenerationStatus(ctx context.Context, id string, status GenerationStatus, errorMessage string) error
+}
+
+// CancellationBus signals generation cancellations to every replica
+type CancellationBus interface {
+	PublishCancel(ctx context.Context, generationID string) error
+	// SubscribeCancels delivers cancelled generation IDs until ctx is done
+	SubscribeCancels(ctx context.Context) (<-chan string, error)
+}
+
+// JobQueue stores generation jobs and hands pending ones to workers by
+// priority, then by age. A dequeued job is leased to its worker until it is
+// updated with a terminal status; a job whose lease is not renewed, such as
+// one held by a crashed replica, is queued again.
+type JobQueue interface {
+	Enqueue(ctx context.Context, job GenerationJob) error
+	// Dequeue leases the next pending job whose model is not in exclude.
+	// ok is false when no such job is waiting.
+	Dequeue(ctx context.Context, exclude []string) (job GenerationJob, ok bool, err error)
+	// RenewLease extends the lease of a dequeued job
+	RenewLease(ctx context.Context, id string) error
+	// Requeue stores a leased job and returns it to the pending jobs
+	Requeue(ctx context.Context, job GenerationJob) error
+	GetJob(ctx context.Context, id string) (GenerationJob, error)
+	// UpdateJob stores a job, ending its lease once it has a terminal status
+	UpdateJob(ctx context.Context, job GenerationJob) error
+}
+
+// JobEventPublisher notifies subscribers of generation job status changes
+type JobEventPublisher interface {
+	PublishJobEvent(ctx context.Context, job GenerationJob) error
+}
+
 // EventPublisher defines event publishing interface
 type EventPublisher interface {
 	PublishGenerationEvent(ctx context.Context, userID common.UserID, tokens int) error
@@ -0,0 +1,32 @@
+package ai
+
+import (
+	"encoding/json"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// JobPriority orders queued generation jobs; higher priorities run first
+type JobPriority int
+
+const (
+	JobPriorityNormal JobPriority = 0
+	JobPriorityHigh   JobPriority = 10
+)
+
+// DefaultJobModel is the queue partition of jobs that do not request a model
+const DefaultJobModel = "default"
+
+// GenerationJob is a generation queued to run outside the request that submitted it.
+// The request and result are encoded by the application layer.
+type GenerationJob struct {
+	ID       string
+	UserID   common.UserID
+	Model    string // Jobs are partitioned by model so each model can have its own concurrency limit
+	Priority JobPriority
+	Status   GenerationStatus
+	Payload  json.RawMessage
+	Result   json.RawMessage
+	Error    string
+	common.Timestamps
+}
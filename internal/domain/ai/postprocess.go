@@ -0,0 +1,23 @@
+package ai
+
+import (
+	"context"
+)
+
+// MetadataPostProcessors records the comma-separated names of the
+// post-processors that changed a generation's code
+const MetadataPostProcessors = "post_processors"
+
+// CodeTarget describes the code a processor is given
+type CodeTarget struct {
+	Language  string // Request language such as "typescript", or file language such as "tsx"
+	Framework string
+	RawOutput bool // Set for the model's whole answer, unset for a file of its artifact
+}
+
+// CodeProcessor cleans up or formats generated code. Processors return the
+// code unchanged when it does not apply to them.
+type CodeProcessor interface {
+	Name() string
+	Process(ctx context.Context, code string, target CodeTarget) (string, error)
+}
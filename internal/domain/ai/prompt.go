@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// Chat message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Metadata keys recorded for every templated generation
+const (
+	MetadataTemplate        = "template"
+	MetadataTemplateVersion = "template_version"
+)
+
+// ChatMessage is a single role-tagged message sent to the model
+type ChatMessage struct {
+	Role    string
+	Content string
+}
+
+// PromptTemplate is a versioned set of instructions that turns a generation
+// request into chat messages. System and User are text/template sources
+// interpolated with the request fields and the template's default variables.
+type PromptTemplate struct {
+	Name      string            // Template family, e.g. "react-tailwind"
+	Framework string            // Framework the template targets, e.g. "react"
+	Language  string            // Language the template targets; empty matches any language
+	Version   int               // Increases with every change to the template
+	System    string            // System message template
+	User      string            // User message template
+	Variables map[string]string // Default values for template variables
+	common.Timestamps
+}
+
+// Matches reports whether the template applies to framework and language
+func (t PromptTemplate) Matches(framework, language string) bool {
+	if !strings.EqualFold(t.Framework, framework) {
+		return false
+	}
+	return t.Language == "" || strings.EqualFold(t.Language, language)
+}
+
+// Metadata returns the generation metadata identifying the template version
+func (t PromptTemplate) Metadata() map[string]string {
+	return map[string]string{
+		MetadataTemplate:        t.Name,
+		MetadataTemplateVersion: strconv.Itoa(t.Version),
+	}
+}
+
+// Prompt is the result of rendering a template for a request
+type Prompt struct {
+	Messages []ChatMessage
+	Template PromptTemplate
+}
+
+// PromptTemplateRepository provides access to prompt templates
+type PromptTemplateRepository interface {
+	ListTemplates(ctx context.Context) ([]PromptTemplate, error)
+}
+
+// PromptBuilder turns a generation request into chat messages
+type PromptBuilder interface {
+	Build(ctx context.Context, req GenerationRequest) (Prompt, error)
+}
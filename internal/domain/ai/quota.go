@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// PlanTier identifies a quota plan
+type PlanTier string
+
+const (
+	PlanFree PlanTier = "free"
+	PlanPro  PlanTier = "pro"
+	PlanTeam PlanTier = "team"
+)
+
+// QuotaPlan holds the limits of a plan tier. Token limits count tokens after
+// the model multiplier is applied. Every plan has a daily token limit; the
+// other limits are unlimited when zero.
+type QuotaPlan struct {
+	Tier             PlanTier
+	DailyTokens      int
+	MonthlyTokens    int
+	DailyRequests    int
+	MonthlyRequests  int
+	ModelMultipliers map[string]float64 // Keyed by model name prefix, e.g. "gpt-4o"
+}
+
+// Multiplier returns the factor tokens of model are counted with. The
+// longest matching prefix wins; models without one count at 1.
+func (p QuotaPlan) Multiplier(model string) float64 {
+	multiplier, matched := 1.0, -1
+	for prefix, m := range p.ModelMultipliers {
+		if len(prefix) > matched && strings.HasPrefix(model, prefix) {
+			multiplier, matched = m, len(prefix)
+		}
+	}
+	return multiplier
+}
+
+// QuotaAccount is a user's plan and the timezone their quotas reset in
+type QuotaAccount struct {
+	UserID   common.UserID
+	Plan     QuotaPlan
+	Timezone string // IANA name such as "Europe/Berlin"
+}
+
+// QuotaPlanRepository loads the plans of users
+type QuotaPlanRepository interface {
+	GetQuotaAccount(ctx context.Context, userID common.UserID) (QuotaAccount, error)
+}
+
+// QuotaWindow is a period usage is counted over, such as a day or a month
+type QuotaWindow struct {
+	Key     string // Unique per period, e.g. "day:2025-01-31"
+	ResetAt time.Time
+	Limit   QuotaUsage // Checked by Reserve; zero fields are unlimited
+}
+
+// QuotaUsage is the tokens and requests counted in a window
+type QuotaUsage struct {
+	Tokens   int
+	Requests int
+}
+
+// QuotaCounter counts usage per user and window. Counts are dropped once
+// their window resets.
+type QuotaCounter interface {
+	// Usage returns the usage of each window
+	Usage(ctx context.Context, userID common.UserID, windows ...QuotaWindow) ([]QuotaUsage, error)
+	// Add atomically adds usage to every window and returns the new totals
+	Add(ctx context.Context, userID common.UserID, usage QuotaUsage, windows ...QuotaWindow) ([]QuotaUsage, error)
+	// Reserve atomically adds one request to every window unless a window
+	// has used up its token limit or has no requests left, and returns the
+	// totals and whether the request was added
+	Reserve(ctx context.Context, userID common.UserID, windows ...QuotaWindow) ([]QuotaUsage, bool, error)
+}
+
+// Allows reports whether usage leaves room for another request within limit
+func (limit QuotaUsage) Allows(usage QuotaUsage) bool {
+	if limit.Tokens > 0 && usage.Tokens >= limit.Tokens {
+		return false
+	}
+	return limit.Requests <= 0 || usage.Requests < limit.Requests
+}
@@ -0,0 +1,27 @@
+package ai
+
+// RepairAttempt records one version of generated code produced while
+// repairing a generation, together with what the validator found in it.
+// Attempt 1 is the original generation.
+type RepairAttempt struct {
+	Attempt     int
+	Code        string
+	Valid       bool
+	Tokens      int
+	Diagnostics []Diagnostic
+}
+
+// Score rates the attempt for picking the best one when none is valid.
+// Higher is better: each error costs 10 points and each warning 1.
+func (a RepairAttempt) Score() int {
+	score := 0
+	for _, d := range a.Diagnostics {
+		switch d.Severity {
+		case SeverityError:
+			score -= 10
+		case SeverityWarning:
+			score--
+		}
+	}
+	return score
+}
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// SecurityFinding is a risky pattern found in generated code
+type SecurityFinding struct {
+	Rule     string
+	Severity DiagnosticSeverity
+	Message  string
+	Path     string // Artifact file the finding is in
+	Line     int
+	Column   int
+}
+
+// String formats the finding as "path:line:column: message"
+func (f SecurityFinding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", f.Path, f.Line, f.Column, f.Message)
+}
+
+// SecurityPolicy configures the security scanner for a project. The zero
+// value runs every rule at its default severity.
+type SecurityPolicy struct {
+	DisabledRules  []string
+	Severities     map[string]DiagnosticSeverity // Overrides the default severity of rules
+	AllowedOrigins []string                      // Hosts scripts and styles may load from, on top of the scanner defaults
+}
+
+// DefaultSecurityPolicy returns the policy code is scanned with when it
+// belongs to no project, or to one that configures none
+func DefaultSecurityPolicy() SecurityPolicy {
+	return SecurityPolicy{}
+}
+
+// RuleEnabled reports whether the rule with id should run
+func (p SecurityPolicy) RuleEnabled(id string) bool {
+	return !slices.Contains(p.DisabledRules, id)
+}
+
+// Severity returns the severity of the rule with id, which is def unless
+// the policy overrides it
+func (p SecurityPolicy) Severity(id string, def DiagnosticSeverity) DiagnosticSeverity {
+	if severity, ok := p.Severities[id]; ok {
+		return severity
+	}
+	return def
+}
+
+// HasSecurityErrors reports whether any finding has error severity
+func HasSecurityErrors(findings []SecurityFinding) bool {
+	return slices.ContainsFunc(findings, func(f SecurityFinding) bool {
+		return f.Severity == SeverityError
+	})
+}
+
+// SecurityScanner scans generated files for risky patterns
+type SecurityScanner interface {
+	Scan(ctx context.Context, files []ArtifactFile, policy SecurityPolicy) ([]SecurityFinding, error)
+}
+
+// SecurityPolicyRepository loads the security policies of projects
+type SecurityPolicyRepository interface {
+	// GetSecurityPolicy returns the policy of a project owned by userID, or
+	// the zero policy if it configures none. Projects of other users are
+	// not found.
+	GetSecurityPolicy(ctx context.Context, userID common.UserID, projectID common.ProjectID) (SecurityPolicy, error)
+}
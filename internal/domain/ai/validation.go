@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DiagnosticSeverity is how serious a diagnostic is
+type DiagnosticSeverity string
+
+const (
+	SeverityError   DiagnosticSeverity = "error"
+	SeverityWarning DiagnosticSeverity = "warning"
+	SeverityInfo    DiagnosticSeverity = "info"
+)
+
+// Diagnostic is a problem found in code. Line and Column are 1-based and
+// zero when the problem is not tied to a position.
+type Diagnostic struct {
+	Path     string // Artifact file the diagnostic is in, if validated as part of one
+	Line     int
+	Column   int
+	Severity DiagnosticSeverity
+	Rule     string // Short identifier of the check, such as "syntax" or "unclosed-tag"
+	Message  string
+}
+
+// String formats the diagnostic as "path:line:column: message", leaving
+// out the parts it does not have
+func (d Diagnostic) String() string {
+	var location string
+	if d.Path != "" {
+		location = d.Path + ":"
+	}
+	if d.Line != 0 {
+		location += fmt.Sprintf("%d:%d:", d.Line, d.Column)
+	}
+	if location == "" {
+		return d.Message
+	}
+	return location + " " + d.Message
+}
+
+// Position returns the 1-based line and byte column of offset in code,
+// clamping offsets outside of it
+func Position(code string, offset int) (line, column int) {
+	offset = min(max(offset, 0), len(code))
+	before := code[:offset]
+	return strings.Count(before, "\n") + 1, offset - strings.LastIndex(before, "\n")
+}
+
+// CodeValidator checks code written in language. An empty language is
+// detected from the code itself.
+type CodeValidator interface {
+	Validate(ctx context.Context, code, language string) (ValidationResult, error)
+}
+
+// NewValidationResult builds the result for diagnostics found in code
+// validated as language. The code is valid when none of them is an error.
+func NewValidationResult(language string, diagnostics []Diagnostic) ValidationResult {
+	result := ValidationResult{
+		Valid:       true,
+		Language:    language,
+		Diagnostics: diagnostics,
+	}
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			result.Valid = false
+			result.Errors = append(result.Errors, d.String())
+		}
+	}
+	return result
+}
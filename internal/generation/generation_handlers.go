@@ -1,13 +1,16 @@
 package generation
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 
 	"github.com/EliasRanz/ai-code-gen/internal/llm"
@@ -37,14 +40,27 @@ func (s *Service) StreamGenerationHandler(c *gin.Context) {
 		return
 	}
 
+	// Reconnecting clients resume their stream instead of starting a new generation
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		s.resumeStream(c, userObj, lastEventID)
+		return
+	}
+
 	var req struct {
-		Model       string                 `json:"model" binding:"required"`
-		Prompt      string                 `json:"prompt" binding:"required"`
-		MaxTokens   int                    `json:"max_tokens"`
-		Temperature float64                `json:"temperature"`
-		UserID      string                 `json:"user_id"`
-		ProjectID   string                 `json:"project_id"`
-		Metadata    map[string]interface{} `json:"metadata"`
+		Model            string                 `json:"model" binding:"required"`
+		Prompt           string                 `json:"prompt" binding:"required_without=Messages"`
+		Messages         []llm.Message          `json:"messages"`
+		MaxTokens        int                    `json:"max_tokens"`
+		Temperature      *float64               `json:"temperature"`
+		TopP             *float64               `json:"top_p"`
+		Stop             []string               `json:"stop"`
+		Seed             *int                   `json:"seed"`
+		PresencePenalty  *float64               `json:"presence_penalty"`
+		FrequencyPenalty *float64               `json:"frequency_penalty"`
+		ResponseFormat   *llm.ResponseFormat    `json:"response_format"`
+		UserID           string                 `json:"user_id"`
+		ProjectID        string                 `json:"project_id"`
+		Metadata         map[string]interface{} `json:"metadata"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -58,33 +74,94 @@ func (s *Service) StreamGenerationHandler(c *gin.Context) {
 		Str("user_id", req.UserID).
 		Str("project_id", req.ProjectID).
 		Int("prompt_length", len(req.Prompt)).
+		Int("message_count", len(req.Messages)).
 		Msg("Streaming generation request")
 
 	// Create generation request
 	genReq := &llm.GenerationRequest{
-		Model:       req.Model,
-		Prompt:      req.Prompt,
-		MaxTokens:   req.MaxTokens,
-		Temperature: req.Temperature,
-		Metadata:    req.Metadata,
+		Model:            req.Model,
+		Prompt:           req.Prompt,
+		Messages:         req.Messages,
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		Stop:             req.Stop,
+		Seed:             req.Seed,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		ResponseFormat:   req.ResponseFormat,
+		Metadata:         req.Metadata,
+	}
+
+	// With a stream buffer the generation outlives the connection, so the
+	// client can reconnect and pick up where it left off
+	streamCtx := c.Request.Context()
+	if s.streamBuffer != nil {
+		streamCtx = context.WithoutCancel(streamCtx)
 	}
 
 	// Start streaming
-	respChan, err := s.llmClient.GenerateStream(c.Request.Context(), genReq)
+	respChan, err := s.llmClient.GenerateStream(streamCtx, genReq)
+	if llm.IsInvalidRequest(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to start stream generation")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start generation"})
 		return
 	}
 
-	// Set headers for SSE
+	setSSEHeaders(c)
+
+	// Stream responses
+	s.streamResponse(c, respChan, s.newEventStream(userObj.ID), req.UserID, req.ProjectID)
+}
+
+// resumeStream replays the events of a buffered stream after lastEventID and
+// then follows it live
+func (s *Service) resumeStream(c *gin.Context, userObj *user.User, lastEventID string) {
+	if s.streamBuffer == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream resumption is not enabled"})
+		return
+	}
+
+	streamID, after, err := parseEventID(lastEventID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := s.streamBuffer.Follow(c.Request.Context(), streamKey(userObj.ID, streamID), after)
+	if errors.Is(err, ErrStreamNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found or expired"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("stream", streamID).Msg("Failed to resume stream")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume stream"})
+		return
+	}
+
+	log.Info().
+		Str("user_id", userObj.ID).
+		Str("stream", streamID).
+		Int64("after", after).
+		Msg("Resuming generation stream")
+
+	setSSEHeaders(c)
+	for event := range events {
+		s.writeStreamEvent(c, streamID, event)
+		c.Writer.Flush()
+	}
+}
+
+// setSSEHeaders sets the response headers of an SSE stream
+func setSSEHeaders(c *gin.Context) {
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("Access-Control-Allow-Origin", "*")
-
-	// Stream responses
-	s.streamResponse(c, respChan, req.UserID, req.ProjectID)
 }
 
 // NonStreamGenerationHandler handles non-streaming AI generation requests
@@ -111,13 +188,20 @@ func (s *Service) NonStreamGenerationHandler(c *gin.Context) {
 	}
 
 	var req struct {
-		Model       string                 `json:"model" binding:"required"`
-		Prompt      string                 `json:"prompt" binding:"required"`
-		MaxTokens   int                    `json:"max_tokens"`
-		Temperature float64                `json:"temperature"`
-		UserID      string                 `json:"user_id"`
-		ProjectID   string                 `json:"project_id"`
-		Metadata    map[string]interface{} `json:"metadata"`
+		Model            string                 `json:"model" binding:"required"`
+		Prompt           string                 `json:"prompt" binding:"required_without=Messages"`
+		Messages         []llm.Message          `json:"messages"`
+		MaxTokens        int                    `json:"max_tokens"`
+		Temperature      *float64               `json:"temperature"`
+		TopP             *float64               `json:"top_p"`
+		Stop             []string               `json:"stop"`
+		Seed             *int                   `json:"seed"`
+		PresencePenalty  *float64               `json:"presence_penalty"`
+		FrequencyPenalty *float64               `json:"frequency_penalty"`
+		ResponseFormat   *llm.ResponseFormat    `json:"response_format"`
+		UserID           string                 `json:"user_id"`
+		ProjectID        string                 `json:"project_id"`
+		Metadata         map[string]interface{} `json:"metadata"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -131,19 +215,31 @@ func (s *Service) NonStreamGenerationHandler(c *gin.Context) {
 		Str("user_id", req.UserID).
 		Str("project_id", req.ProjectID).
 		Int("prompt_length", len(req.Prompt)).
+		Int("message_count", len(req.Messages)).
 		Msg("Non-streaming generation request")
 
 	// Create generation request
 	genReq := &llm.GenerationRequest{
-		Model:       req.Model,
-		Prompt:      req.Prompt,
-		MaxTokens:   req.MaxTokens,
-		Temperature: req.Temperature,
-		Metadata:    req.Metadata,
+		Model:            req.Model,
+		Prompt:           req.Prompt,
+		Messages:         req.Messages,
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		Stop:             req.Stop,
+		Seed:             req.Seed,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		ResponseFormat:   req.ResponseFormat,
+		Metadata:         req.Metadata,
 	}
 
 	// Generate response
 	resp, err := s.llmClient.Generate(c.Request.Context(), genReq)
+	if llm.IsInvalidRequest(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate response")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Generation failed"})
@@ -207,8 +303,75 @@ func (s *Service) HealthHandler(c *gin.Context) {
 	c.JSON(statusCode, health)
 }
 
+// errStreamBuffer is wrapped by next when an event cannot be buffered
+var errStreamBuffer = errors.New("failed to buffer stream event")
+
+// eventStream numbers the events of one generation stream and, when the
+// service has a stream buffer, buffers them for resumption
+type eventStream struct {
+	id     string // Prefix of every event ID sent to the client
+	key    string // Buffer key, scoped to the owning user
+	buffer StreamBuffer
+	seq    int64
+}
+
+// newEventStream starts numbering a new stream owned by userID
+func (s *Service) newEventStream(userID string) *eventStream {
+	id := uuid.NewString()
+	return &eventStream{id: id, key: streamKey(userID, id), buffer: s.streamBuffer}
+}
+
+// streamKey scopes a stream to its owner so other users cannot resume it
+func streamKey(userID, streamID string) string {
+	return userID + ":" + streamID
+}
+
+// next numbers an event and buffers it. With a buffer, sequence numbers come
+// from the buffer alone, so a failed append fails the event rather than
+// numbering it locally and misaligning resumed streams.
+func (st *eventStream) next(event string, data interface{}) (StreamEvent, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return StreamEvent{}, err
+	}
+
+	ev := StreamEvent{Event: event, Data: raw}
+	if st.buffer == nil {
+		st.seq++
+		ev.Seq = st.seq
+		return ev, nil
+	}
+
+	buffered, err := st.buffer.Append(context.Background(), st.key, ev)
+	if err != nil {
+		return StreamEvent{}, fmt.Errorf("%w: %v", errStreamBuffer, err)
+	}
+	st.seq = buffered.Seq
+	return buffered, nil
+}
+
+// fail returns the error event that ends a stream after next failed. It is
+// numbered after the last event sent but not buffered.
+func (st *eventStream) fail(err error) StreamEvent {
+	code, message := "marshal_error", "Failed to encode response"
+	if errors.Is(err, errStreamBuffer) {
+		code, message = "stream_error", "Failed to buffer stream"
+	}
+	raw, _ := json.Marshal(gin.H{"error_code": code, "message": message})
+
+	st.seq++
+	return StreamEvent{Seq: st.seq, Event: "error", Data: raw}
+}
+
+// drainStream discards the rest of a stream that ended early so the upstream
+// generation is not left blocked on sending
+func drainStream(respChan <-chan *llm.GenerationResponse) {
+	for range respChan {
+	}
+}
+
 // streamResponse handles streaming responses to client
-func (s *Service) streamResponse(c *gin.Context, respChan <-chan *llm.GenerationResponse, userID, projectID string) {
+func (s *Service) streamResponse(c *gin.Context, respChan <-chan *llm.GenerationResponse, stream *eventStream, userID, projectID string) {
 	ctx := c.Request.Context()
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
@@ -221,27 +384,73 @@ func (s *Service) streamResponse(c *gin.Context, respChan <-chan *llm.Generation
 		select {
 		case <-ctx.Done():
 			log.Info().Msg("Client disconnected")
+			if stream.buffer != nil {
+				// Keep buffering so the client can resume the stream
+				go s.bufferStream(respChan, stream, userID, projectID)
+			}
 			return
 		case resp, ok := <-respChan:
-			if !ok {
-				// Channel closed, send final event
-				s.writeSSEEvent(c, "done", gin.H{"message": "Generation complete"}, "")
-				flusher.Flush()
+			event, done := s.nextStreamEvent(stream, resp, ok, userID, projectID)
+			s.writeStreamEvent(c, stream.id, event)
+			flusher.Flush()
+			if done {
+				if ok {
+					go drainStream(respChan)
+				}
 				return
 			}
+		}
+	}
+}
 
-			// Send response
-			s.writeSSEEvent(c, "data", resp, resp.ID)
-			flusher.Flush()
-
-			// Publish to Redis if configured
-			if userID != "" || projectID != "" {
-				s.publishToRedis(resp, userID, projectID)
+// bufferStream buffers the rest of a stream after its client disconnected
+func (s *Service) bufferStream(respChan <-chan *llm.GenerationResponse, stream *eventStream, userID, projectID string) {
+	for {
+		resp, ok := <-respChan
+		if _, done := s.nextStreamEvent(stream, resp, ok, userID, projectID); done {
+			if ok {
+				drainStream(respChan)
 			}
+			return
 		}
 	}
 }
 
+// nextStreamEvent turns the next upstream response into a numbered event,
+// reporting whether it ends the stream. ok is false once respChan is closed.
+func (s *Service) nextStreamEvent(stream *eventStream, resp *llm.GenerationResponse, ok bool, userID, projectID string) (StreamEvent, bool) {
+	var event StreamEvent
+	var err error
+	done := true
+	switch {
+	case !ok:
+		// Channel closed, send final event
+		event, err = stream.next("done", gin.H{"message": "Generation complete"})
+	case resp.Error != nil:
+		// Upstream errors end the stream
+		log.Error().Str("code", resp.Error.Code).Str("message", resp.Error.Message).Msg("LLM stream failed")
+		event, err = stream.next("error", resp.Error)
+	default:
+		event, err = stream.next("data", resp)
+		done = false
+	}
+	if err != nil {
+		log.Error().Err(err).Str("stream", stream.id).Msg("Failed to send stream event, ending the stream")
+		return stream.fail(err), true
+	}
+
+	// Publish to Redis if configured
+	if !done && (userID != "" || projectID != "") {
+		s.publishToRedis(resp, userID, projectID)
+	}
+	return event, done
+}
+
+// writeStreamEvent writes a numbered stream event
+func (s *Service) writeStreamEvent(c *gin.Context, streamID string, event StreamEvent) {
+	s.writeSSEEvent(c, event.Event, event.Data, formatEventID(streamID, event.Seq))
+}
+
 // writeSSEEvent writes a Server-Sent Event
 func (s *Service) writeSSEEvent(c *gin.Context, event string, data interface{}, id string) {
 	if id != "" {
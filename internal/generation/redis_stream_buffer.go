@@ -0,0 +1,148 @@
+package generation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// RedisStreamBuffer is a StreamBuffer kept in Redis, so a stream can be
+// resumed on any replica. Each stream is a list of events that expires ttl
+// after its last append; appends are announced on a pub/sub channel so
+// followers continue live without polling.
+type RedisStreamBuffer struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+// NewRedisStreamBuffer creates a new Redis stream buffer. A ttl of zero uses
+// DefaultStreamBufferTTL.
+func NewRedisStreamBuffer(client redis.UniversalClient, ttl time.Duration) *RedisStreamBuffer {
+	if ttl <= 0 {
+		ttl = DefaultStreamBufferTTL
+	}
+	return &RedisStreamBuffer{client: client, ttl: ttl}
+}
+
+// eventsKey returns the key of the list holding a stream's events
+func (b *RedisStreamBuffer) eventsKey(streamKey string) string {
+	return fmt.Sprintf("generation:stream:%s:events", streamKey)
+}
+
+// notifyChannel returns the channel appends to a stream are announced on
+func (b *RedisStreamBuffer) notifyChannel(streamKey string) string {
+	return fmt.Sprintf("generation:stream:%s", streamKey)
+}
+
+// Append buffers an event and returns it with its sequence number set. The
+// sequence number is the event's position in the list.
+func (b *RedisStreamBuffer) Append(ctx context.Context, streamKey string, event StreamEvent) (StreamEvent, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return StreamEvent{}, fmt.Errorf("failed to encode stream event: %w", err)
+	}
+
+	key := b.eventsKey(streamKey)
+	var push *redis.IntCmd
+	if _, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		push = pipe.RPush(ctx, key, data)
+		pipe.Expire(ctx, key, b.ttl)
+		return nil
+	}); err != nil {
+		return StreamEvent{}, fmt.Errorf("failed to buffer stream event: %w", err)
+	}
+	event.Seq = push.Val()
+
+	if err := b.client.Publish(ctx, b.notifyChannel(streamKey), event.Seq).Err(); err != nil {
+		// Followers still see the event on the next append
+		log.Warn().Err(err).Str("stream", streamKey).Msg("Failed to announce stream event")
+	}
+	return event, nil
+}
+
+// Follow delivers the events numbered above after, then new events as they are appended
+func (b *RedisStreamBuffer) Follow(ctx context.Context, streamKey string, after int64) (<-chan StreamEvent, error) {
+	key := b.eventsKey(streamKey)
+
+	// Subscribe before reading so no append is missed in between
+	pubsub := b.client.Subscribe(ctx, b.notifyChannel(streamKey))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to stream: %w", err)
+	}
+
+	exists, err := b.client.Exists(ctx, key).Result()
+	if err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to look up stream: %w", err)
+	}
+	if exists == 0 {
+		pubsub.Close()
+		return nil, ErrStreamNotFound
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+
+		notifications := pubsub.Channel()
+		for {
+			values, err := b.client.LRange(ctx, key, after, -1).Result()
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Error().Err(err).Str("stream", streamKey).Msg("Failed to read buffered stream events")
+				}
+				return
+			}
+
+			for _, value := range values {
+				var event StreamEvent
+				if err := json.Unmarshal([]byte(value), &event); err != nil {
+					log.Error().Err(err).Str("stream", streamKey).Msg("Failed to decode buffered stream event")
+					return
+				}
+				event.Seq = after + 1
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+				after = event.Seq
+				if event.IsTerminal() {
+					return
+				}
+			}
+
+			// A finished stream has nothing more to deliver
+			if len(values) == 0 {
+				last, err := b.client.LIndex(ctx, key, -1).Result()
+				if err == nil && isTerminalEvent(last) {
+					return
+				}
+			}
+
+			select {
+			case _, ok := <-notifications:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// isTerminalEvent reports whether an encoded event ends its stream
+func isTerminalEvent(value string) bool {
+	var event StreamEvent
+	return json.Unmarshal([]byte(value), &event) == nil && event.IsTerminal()
+}
@@ -24,6 +24,7 @@ type Service struct {
 	llmClient    llm.LLMClient
 	redisClient  RedisClient
 	authService  *auth.Service
+	streamBuffer StreamBuffer
 }
 
 // NewService creates a new generation service  
@@ -35,6 +36,14 @@ func NewService(llmClient llm.LLMClient, redisClient RedisClient, authService *a
 	}
 }
 
+// NewServiceWithStreamBuffer creates a new generation service whose streams
+// are buffered so clients can resume them with Last-Event-ID
+func NewServiceWithStreamBuffer(llmClient llm.LLMClient, redisClient RedisClient, authService *auth.Service, streamBuffer StreamBuffer) *Service {
+	s := NewService(llmClient, redisClient, authService)
+	s.streamBuffer = streamBuffer
+	return s
+}
+
 // Close shuts down the service gracefully
 func (s *Service) Close() error {
 	var err error
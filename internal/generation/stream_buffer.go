@@ -0,0 +1,170 @@
+package generation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultStreamBufferTTL is how long buffered stream events stay resumable
+// after the last event is appended
+const DefaultStreamBufferTTL = 10 * time.Minute
+
+// ErrStreamNotFound is returned when resuming a stream that was never
+// buffered or has expired
+var ErrStreamNotFound = errors.New("stream not found")
+
+// StreamEvent is one numbered SSE event of a generation stream
+type StreamEvent struct {
+	Seq   int64           `json:"seq"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// IsTerminal returns true for the event that ends a stream
+func (e StreamEvent) IsTerminal() bool {
+	return e.Event == "done" || e.Event == "error"
+}
+
+// StreamBuffer keeps the events of generation streams so a client that
+// reconnects with Last-Event-ID can replay what it missed and continue live
+type StreamBuffer interface {
+	// Append buffers an event and returns it with its sequence number set.
+	// Sequence numbers start at 1 and increase by one per stream.
+	Append(ctx context.Context, streamKey string, event StreamEvent) (StreamEvent, error)
+	// Follow delivers the events numbered above after, then new events as they are
+	// appended. The channel is closed after the terminal event or once ctx is done.
+	Follow(ctx context.Context, streamKey string, after int64) (<-chan StreamEvent, error)
+}
+
+// formatEventID returns the SSE event ID of an event in a stream
+func formatEventID(streamID string, seq int64) string {
+	return fmt.Sprintf("%s:%d", streamID, seq)
+}
+
+// parseEventID splits an SSE event ID into its stream ID and sequence number
+func parseEventID(id string) (string, int64, error) {
+	i := strings.LastIndexByte(id, ':')
+	if i <= 0 {
+		return "", 0, fmt.Errorf("invalid event ID %q", id)
+	}
+	seq, err := strconv.ParseInt(id[i+1:], 10, 64)
+	if err != nil || seq < 0 {
+		return "", 0, fmt.Errorf("invalid event ID %q", id)
+	}
+	return id[:i], seq, nil
+}
+
+// MemoryStreamBuffer is a StreamBuffer held in process memory. Streams can
+// only be resumed on the replica that produced them; use RedisStreamBuffer
+// when running more than one. Expired streams are removed at most once per
+// ttl, so appends do not scan every stream.
+type MemoryStreamBuffer struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	streams   map[string]*memoryStream
+	nextSweep time.Time // When expired streams are next removed
+}
+
+// memoryStream holds the events of one stream
+type memoryStream struct {
+	events  []StreamEvent
+	expires time.Time
+	updated chan struct{} // Closed and replaced whenever an event is appended
+}
+
+// NewMemoryStreamBuffer creates a new in-memory stream buffer. A ttl of zero
+// uses DefaultStreamBufferTTL.
+func NewMemoryStreamBuffer(ttl time.Duration) *MemoryStreamBuffer {
+	if ttl <= 0 {
+		ttl = DefaultStreamBufferTTL
+	}
+	return &MemoryStreamBuffer{
+		ttl:     ttl,
+		streams: make(map[string]*memoryStream),
+	}
+}
+
+// Append buffers an event and returns it with its sequence number set
+func (b *MemoryStreamBuffer) Append(ctx context.Context, streamKey string, event StreamEvent) (StreamEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.After(b.nextSweep) {
+		for key, stream := range b.streams {
+			if now.After(stream.expires) {
+				delete(b.streams, key)
+			}
+		}
+		b.nextSweep = now.Add(b.ttl)
+	}
+
+	stream, ok := b.streams[streamKey]
+	if !ok || now.After(stream.expires) {
+		stream = &memoryStream{updated: make(chan struct{})}
+		b.streams[streamKey] = stream
+	}
+
+	event.Seq = int64(len(stream.events)) + 1
+	stream.events = append(stream.events, event)
+	stream.expires = now.Add(b.ttl)
+	close(stream.updated)
+	stream.updated = make(chan struct{})
+
+	return event, nil
+}
+
+// Follow delivers the events numbered above after, then new events as they are appended
+func (b *MemoryStreamBuffer) Follow(ctx context.Context, streamKey string, after int64) (<-chan StreamEvent, error) {
+	b.mu.Lock()
+	stream, ok := b.streams[streamKey]
+	if !ok || time.Now().After(stream.expires) {
+		b.mu.Unlock()
+		return nil, ErrStreamNotFound
+	}
+	b.mu.Unlock()
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		for {
+			b.mu.Lock()
+			n := int64(len(stream.events))
+			pending := append([]StreamEvent(nil), stream.events[min(after, n):]...)
+			finished := n > 0 && stream.events[n-1].IsTerminal()
+			updated := stream.updated
+			b.mu.Unlock()
+
+			for _, event := range pending {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+				after = event.Seq
+				if event.IsTerminal() {
+					return
+				}
+			}
+			// The client already has every event of a finished stream
+			if finished {
+				return
+			}
+
+			select {
+			case <-updated:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,595 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+const (
+	// maxGenerationNameLength is the size of the ui_generations.name column
+	maxGenerationNameLength = 255
+	// DefaultDailyTokenLimit is the number of tokens a user may generate per UTC day
+	DefaultDailyTokenLimit = 100000
+)
+
+// GenerationModel represents the database model for UI generations
+type GenerationModel struct {
+	ID               string         `gorm:"primaryKey;column:id" json:"id"`
+	ParentID         *string        `gorm:"column:parent_id" json:"parent_id"`
+	UserID           string         `gorm:"column:user_id;not null" json:"user_id"`
+	ProjectID        *string        `gorm:"column:project_id" json:"project_id"`
+	Name             string         `gorm:"column:name;not null" json:"name"`
+	Prompt           string         `gorm:"column:prompt;not null" json:"prompt"`
+	Status           string         `gorm:"column:status;type:generation_status;default:pending" json:"status"`
+	Framework        *string        `gorm:"column:framework" json:"framework"`
+	GeneratedCode    *string        `gorm:"column:generated_code" json:"generated_code"`
+	Model            *string        `gorm:"column:model" json:"model"`
+	Tokens           int            `gorm:"column:tokens;not null;default:0" json:"tokens"`
+	Assets           string         `gorm:"column:assets;type:jsonb;default:'{}'" json:"assets"`
+	SecurityFindings string         `gorm:"column:security_findings;type:jsonb;default:'[]'" json:"security_findings"`
+	RepairAttempts   string         `gorm:"column:repair_attempts;type:jsonb;default:'[]'" json:"repair_attempts"`
+	Metadata         string         `gorm:"column:metadata;type:jsonb;default:'{}'" json:"metadata"`
+	ErrorMessage     *string        `gorm:"column:error_message" json:"error_message"`
+	ProcessingTimeMS *int           `gorm:"column:processing_time_ms" json:"processing_time_ms"`
+	CreatedAt        time.Time      `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt        time.Time      `gorm:"column:updated_at" json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"column:deleted_at;index" json:"-"`
+}
+
+// generationAssets is the document stored in ui_generations.assets
+type generationAssets struct {
+	Files        []generationAssetFile `json:"files,omitempty"`
+	Dependencies map[string]string     `json:"dependencies,omitempty"`
+}
+
+// generationAssetFile is a generated file stored in ui_generations.assets
+type generationAssetFile struct {
+	Path     string `json:"path"`
+	Language string `json:"language,omitempty"`
+	Content  string `json:"content"`
+}
+
+// generationSecurityFinding is a finding stored in ui_generations.security_findings
+type generationSecurityFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Path     string `json:"path,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// generationRepairAttempt is a repair attempt stored in ui_generations.repair_attempts
+type generationRepairAttempt struct {
+	Attempt     int                    `json:"attempt"`
+	Code        string                 `json:"code"`
+	Valid       bool                   `json:"valid"`
+	Tokens      int                    `json:"tokens"`
+	Diagnostics []generationDiagnostic `json:"diagnostics,omitempty"`
+}
+
+// generationDiagnostic is a validator diagnostic of a repair attempt
+type generationDiagnostic struct {
+	Path     string `json:"path,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// TableName returns the table name for the GenerationModel
+func (GenerationModel) TableName() string {
+	return "ui_generations"
+}
+
+// ToGeneration converts GenerationModel to domain ai.Generation
+func (m *GenerationModel) ToGeneration() ai.Generation {
+	generation := ai.Generation{
+		ID:     m.ID,
+		UserID: common.UserID(m.UserID),
+		Prompt: m.Prompt,
+		Status: ai.GenerationStatus(m.Status),
+	}
+	if m.Framework != nil {
+		generation.Framework = *m.Framework
+	}
+	if m.ErrorMessage != nil {
+		generation.ErrorMessage = *m.ErrorMessage
+	}
+	generation.Timestamps.CreatedAt = m.CreatedAt
+	generation.Timestamps.UpdatedAt = m.UpdatedAt
+	return generation
+}
+
+// FromGeneration converts domain ai.Generation to GenerationModel
+func (m *GenerationModel) FromGeneration(g ai.Generation) {
+	m.ID = g.ID
+	m.UserID = string(g.UserID)
+	m.Name = generationName(g.Prompt)
+	m.Prompt = g.Prompt
+	m.Status = string(g.Status)
+	m.Framework = nullableString(g.Framework)
+	m.ErrorMessage = nullableString(g.ErrorMessage)
+	m.Assets = "{}"
+	m.SecurityFindings = "[]"
+	m.RepairAttempts = "[]"
+	m.Metadata = "{}"
+	m.CreatedAt = g.CreatedAt
+	m.UpdatedAt = g.UpdatedAt
+}
+
+// ToGenerationHistory converts GenerationModel to domain ai.GenerationHistory
+func (m *GenerationModel) ToGenerationHistory() ai.GenerationHistory {
+	history := ai.GenerationHistory{
+		ID:     m.ID,
+		UserID: common.UserID(m.UserID),
+		Prompt: m.Prompt,
+		Tokens: m.Tokens,
+		Status: ai.GenerationStatus(m.Status),
+	}
+	if m.ParentID != nil {
+		history.ParentID = *m.ParentID
+	}
+	if m.ProjectID != nil {
+		projectID := common.ProjectID(*m.ProjectID)
+		history.ProjectID = &projectID
+	}
+	if m.GeneratedCode != nil {
+		history.Code = *m.GeneratedCode
+	}
+	if m.Framework != nil {
+		history.Framework = *m.Framework
+	}
+	if m.Model != nil {
+		history.Model = *m.Model
+	}
+	if m.ErrorMessage != nil {
+		history.ErrorMessage = *m.ErrorMessage
+	}
+	if m.ProcessingTimeMS != nil {
+		history.ProcessingTime = time.Duration(*m.ProcessingTimeMS) * time.Millisecond
+	}
+	if m.Metadata != "" {
+		// Rows written elsewhere may hold non-string values; keep what decodes
+		_ = json.Unmarshal([]byte(m.Metadata), &history.Metadata)
+	}
+	var assets generationAssets
+	if err := json.Unmarshal([]byte(m.Assets), &assets); err == nil && len(assets.Files) > 0 {
+		artifact := &ai.Artifact{Dependencies: assets.Dependencies}
+		for _, f := range assets.Files {
+			artifact.Files = append(artifact.Files, ai.ArtifactFile{Path: f.Path, Language: f.Language, Content: f.Content})
+		}
+		history.Artifact = artifact
+	}
+	var findings []generationSecurityFinding
+	if err := json.Unmarshal([]byte(m.SecurityFindings), &findings); err == nil {
+		for _, f := range findings {
+			history.SecurityFindings = append(history.SecurityFindings, ai.SecurityFinding{
+				Rule:     f.Rule,
+				Severity: ai.DiagnosticSeverity(f.Severity),
+				Message:  f.Message,
+				Path:     f.Path,
+				Line:     f.Line,
+				Column:   f.Column,
+			})
+		}
+	}
+	var attempts []generationRepairAttempt
+	if err := json.Unmarshal([]byte(m.RepairAttempts), &attempts); err == nil {
+		for _, a := range attempts {
+			attempt := ai.RepairAttempt{Attempt: a.Attempt, Code: a.Code, Valid: a.Valid, Tokens: a.Tokens}
+			for _, d := range a.Diagnostics {
+				attempt.Diagnostics = append(attempt.Diagnostics, ai.Diagnostic{
+					Path:     d.Path,
+					Line:     d.Line,
+					Column:   d.Column,
+					Severity: ai.DiagnosticSeverity(d.Severity),
+					Rule:     d.Rule,
+					Message:  d.Message,
+				})
+			}
+			history.RepairAttempts = append(history.RepairAttempts, attempt)
+		}
+	}
+	history.Timestamps.CreatedAt = m.CreatedAt
+	history.Timestamps.UpdatedAt = m.UpdatedAt
+	return history
+}
+
+// FromGenerationHistory converts domain ai.GenerationHistory to GenerationModel
+func (m *GenerationModel) FromGenerationHistory(h ai.GenerationHistory) error {
+	metadata := []byte("{}")
+	if len(h.Metadata) > 0 {
+		var err error
+		if metadata, err = json.Marshal(h.Metadata); err != nil {
+			return fmt.Errorf("failed to encode generation metadata: %w", err)
+		}
+	}
+
+	assets := []byte("{}")
+	if h.Artifact != nil && !h.Artifact.IsEmpty() {
+		doc := generationAssets{Dependencies: h.Artifact.Dependencies}
+		for _, f := range h.Artifact.Files {
+			doc.Files = append(doc.Files, generationAssetFile{Path: f.Path, Language: f.Language, Content: f.Content})
+		}
+		var err error
+		if assets, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("failed to encode generation assets: %w", err)
+		}
+	}
+
+	doc := make([]generationSecurityFinding, 0, len(h.SecurityFindings))
+	for _, f := range h.SecurityFindings {
+		doc = append(doc, generationSecurityFinding{
+			Rule:     f.Rule,
+			Severity: string(f.Severity),
+			Message:  f.Message,
+			Path:     f.Path,
+			Line:     f.Line,
+			Column:   f.Column,
+		})
+	}
+	findings, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode generation security findings: %w", err)
+	}
+
+	attemptDocs := make([]generationRepairAttempt, 0, len(h.RepairAttempts))
+	for _, a := range h.RepairAttempts {
+		attempt := generationRepairAttempt{Attempt: a.Attempt, Code: a.Code, Valid: a.Valid, Tokens: a.Tokens}
+		for _, d := range a.Diagnostics {
+			attempt.Diagnostics = append(attempt.Diagnostics, generationDiagnostic{
+				Path:     d.Path,
+				Line:     d.Line,
+				Column:   d.Column,
+				Severity: string(d.Severity),
+				Rule:     d.Rule,
+				Message:  d.Message,
+			})
+		}
+		attemptDocs = append(attemptDocs, attempt)
+	}
+	attempts, err := json.Marshal(attemptDocs)
+	if err != nil {
+		return fmt.Errorf("failed to encode generation repair attempts: %w", err)
+	}
+
+	status := h.Status
+	if status == "" {
+		status = ai.GenerationCompleted
+	}
+
+	m.ID = h.ID
+	m.ParentID = nullableString(h.ParentID)
+	m.UserID = string(h.UserID)
+	m.ProjectID = nil
+	if h.ProjectID != nil {
+		m.ProjectID = nullableString(string(*h.ProjectID))
+	}
+	m.Name = generationName(h.Prompt)
+	m.Prompt = h.Prompt
+	m.Status = string(status)
+	m.Framework = nullableString(h.Framework)
+	m.GeneratedCode = nullableString(h.Code)
+	m.Model = nullableString(h.Model)
+	m.Tokens = h.Tokens
+	m.Assets = string(assets)
+	m.SecurityFindings = string(findings)
+	m.RepairAttempts = string(attempts)
+	m.Metadata = string(metadata)
+	m.ErrorMessage = nullableString(h.ErrorMessage)
+	m.ProcessingTimeMS = nil
+	if h.ProcessingTime > 0 {
+		ms := int(h.ProcessingTime.Milliseconds())
+		m.ProcessingTimeMS = &ms
+	}
+	m.CreatedAt = h.CreatedAt
+	m.UpdatedAt = h.UpdatedAt
+	return nil
+}
+
+// generationName derives the required name of a generation from the first line of its prompt
+func generationName(prompt string) string {
+	name := strings.TrimSpace(prompt)
+	if i := strings.IndexByte(name, '\n'); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	if runes := []rune(name); len(runes) > maxGenerationNameLength {
+		name = string(runes[:maxGenerationNameLength])
+	}
+	if name == "" {
+		name = "Untitled generation"
+	}
+	return name
+}
+
+// nullableString maps an empty string to NULL
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// GenerationRepositoryConfig holds configuration for the generation repository
+type GenerationRepositoryConfig struct {
+	DailyTokenLimit int // Tokens a user may generate per UTC day
+}
+
+// DefaultGenerationRepositoryConfig returns the default configuration
+func DefaultGenerationRepositoryConfig() *GenerationRepositoryConfig {
+	return &GenerationRepositoryConfig{DailyTokenLimit: DefaultDailyTokenLimit}
+}
+
+// PostgreSQLGenerationRepository implements ai.GenerationRepository, ai.Repository
+// and ai.HistoryRepository using GORM
+type PostgreSQLGenerationRepository struct {
+	db     *gorm.DB
+	config *GenerationRepositoryConfig
+}
+
+// NewPostgreSQLGenerationRepository creates a new PostgreSQL generation repository.
+// The ui_generations table is created by migration 005.
+func NewPostgreSQLGenerationRepository(db *gorm.DB) *PostgreSQLGenerationRepository {
+	return NewPostgreSQLGenerationRepositoryWithConfig(db, nil)
+}
+
+// NewPostgreSQLGenerationRepositoryWithConfig creates a new PostgreSQL generation
+// repository with the given quota configuration
+func NewPostgreSQLGenerationRepositoryWithConfig(db *gorm.DB, config *GenerationRepositoryConfig) *PostgreSQLGenerationRepository {
+	if config == nil {
+		config = DefaultGenerationRepositoryConfig()
+	}
+	if config.DailyTokenLimit <= 0 {
+		config.DailyTokenLimit = DefaultDailyTokenLimit
+	}
+	return &PostgreSQLGenerationRepository{db: db, config: config}
+}
+
+// CreateGeneration records a new generation
+func (r *PostgreSQLGenerationRepository) CreateGeneration(ctx context.Context, generation ai.Generation) error {
+	model := &GenerationModel{}
+	model.FromGeneration(generation)
+
+	if err := r.db.WithContext(ctx).Create(model).Error; err != nil {
+		if isUniqueViolation(err) {
+			return common.NewConflictError("generation already exists")
+		}
+		return fmt.Errorf("failed to create generation: %w", err)
+	}
+	return nil
+}
+
+// GetGeneration retrieves a generation by ID
+func (r *PostgreSQLGenerationRepository) GetGeneration(ctx context.Context, id string) (ai.Generation, error) {
+	var model GenerationModel
+	if err := r.db.WithContext(ctx).First(&model, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ai.Generation{}, common.NewNotFoundError("generation not found")
+		}
+		return ai.Generation{}, fmt.Errorf("failed to get generation: %w", err)
+	}
+	return model.ToGeneration(), nil
+}
+
+// UpdateGenerationStatus sets the status and error message of a generation
+func (r *PostgreSQLGenerationRepository) UpdateGenerationStatus(ctx context.Context, id string, status ai.GenerationStatus, errorMessage string) error {
+	result := r.db.WithContext(ctx).
+		Model(&GenerationModel{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":        string(status),
+			"error_message": nullableString(errorMessage),
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update generation status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return common.NewNotFoundError("generation not found")
+	}
+	return nil
+}
+
+// SaveGeneration records a finished generation. A generation that is already
+// tracked under the same ID for the same user is completed with the result.
+func (r *PostgreSQLGenerationRepository) SaveGeneration(ctx context.Context, generation ai.GenerationHistory) error {
+	if generation.ID == "" {
+		generation.ID = uuid.NewString()
+	}
+
+	model := &GenerationModel{}
+	if err := model.FromGenerationHistory(generation); err != nil {
+		return err
+	}
+
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "id"}},
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "ui_generations.user_id = EXCLUDED.user_id"},
+			}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"parent_id", "project_id", "status", "framework", "generated_code", "assets", "security_findings",
+				"repair_attempts", "model", "tokens", "metadata", "error_message", "processing_time_ms", "updated_at",
+			}),
+		}).
+		Create(model)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save generation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return common.NewConflictError("generation belongs to another user")
+	}
+	return nil
+}
+
+// GetHistory returns a user's most recent generations, newest first
+func (r *PostgreSQLGenerationRepository) GetHistory(ctx context.Context, userID common.UserID, limit int) ([]ai.GenerationHistory, error) {
+	page, err := r.ListHistory(ctx, ai.HistoryQuery{UserID: userID, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListHistory returns a page of the generations matching query, newest first
+func (r *PostgreSQLGenerationRepository) ListHistory(ctx context.Context, query ai.HistoryQuery) (ai.HistoryPage, error) {
+	size := query.PageSize()
+
+	db := r.db.WithContext(ctx)
+	if query.UserID != "" {
+		db = db.Where("user_id = ?", string(query.UserID))
+	}
+	if query.ProjectID != nil {
+		db = db.Where("project_id = ?", string(*query.ProjectID))
+	}
+	if query.Framework != "" {
+		db = db.Where("framework = ?", query.Framework)
+	}
+	if query.Model != "" {
+		db = db.Where("model = ?", query.Model)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", string(query.Status))
+	}
+	if !query.CreatedAfter.IsZero() {
+		db = db.Where("created_at >= ?", query.CreatedAfter)
+	}
+	if !query.CreatedBefore.IsZero() {
+		db = db.Where("created_at < ?", query.CreatedBefore)
+	}
+	if query.Search != "" {
+		// Matches idx_ui_generations_prompt_search
+		db = db.Where("to_tsvector('english', prompt) @@ websearch_to_tsquery('english', ?)", query.Search)
+	}
+	if query.Cursor != nil {
+		db = db.Where("(created_at, id) < (?, ?)", query.Cursor.CreatedAt, query.Cursor.ID)
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	var models []GenerationModel
+	if err := db.Order("created_at DESC, id DESC").Limit(size + 1).Find(&models).Error; err != nil {
+		return ai.HistoryPage{}, fmt.Errorf("failed to list generation history: %w", err)
+	}
+
+	page := ai.HistoryPage{}
+	if len(models) > size {
+		models = models[:size]
+		last := models[size-1]
+		page.NextCursor = ai.HistoryCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	page.Items = make([]ai.GenerationHistory, len(models))
+	for i := range models {
+		page.Items[i] = models[i].ToGenerationHistory()
+	}
+	return page, nil
+}
+
+// GetHistoryEntry retrieves a generation that has not been deleted from history
+func (r *PostgreSQLGenerationRepository) GetHistoryEntry(ctx context.Context, id string) (ai.GenerationHistory, error) {
+	var model GenerationModel
+	if err := r.db.WithContext(ctx).First(&model, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ai.GenerationHistory{}, common.NewNotFoundError("generation not found")
+		}
+		return ai.GenerationHistory{}, fmt.Errorf("failed to get generation: %w", err)
+	}
+	return model.ToGenerationHistory(), nil
+}
+
+// revisionTreeQuery walks up from a generation to the root of its revision
+// tree, then down to every descendant of the root
+const revisionTreeQuery = `
+WITH RECURSIVE ancestors AS (
+	SELECT id, parent_id FROM ui_generations WHERE id = ?
+	UNION ALL
+	SELECT g.id, g.parent_id FROM ui_generations g JOIN ancestors a ON g.id = a.parent_id
+), tree AS (
+	SELECT g.* FROM ui_generations g JOIN ancestors a ON g.id = a.id WHERE a.parent_id IS NULL
+	UNION ALL
+	SELECT g.* FROM ui_generations g JOIN tree t ON g.parent_id = t.id
+)
+SELECT * FROM tree WHERE deleted_at IS NULL ORDER BY created_at, id`
+
+// ListRevisions returns the revision tree containing a generation, oldest first.
+// Generations deleted from history are left out, but their children are kept.
+func (r *PostgreSQLGenerationRepository) ListRevisions(ctx context.Context, id string) ([]ai.GenerationHistory, error) {
+	var models []GenerationModel
+	if err := r.db.WithContext(ctx).Raw(revisionTreeQuery, id).Scan(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list generation revisions: %w", err)
+	}
+	if len(models) == 0 {
+		return nil, common.NewNotFoundError("generation not found")
+	}
+
+	revisions := make([]ai.GenerationHistory, len(models))
+	for i := range models {
+		revisions[i] = models[i].ToGenerationHistory()
+	}
+	return revisions, nil
+}
+
+// DeleteHistoryEntry soft deletes a generation
+func (r *PostgreSQLGenerationRepository) DeleteHistoryEntry(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Delete(&GenerationModel{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete generation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return common.NewNotFoundError("generation not found")
+	}
+	return nil
+}
+
+// GetQuotaUsage returns the tokens a user has used since midnight UTC
+func (r *PostgreSQLGenerationRepository) GetQuotaUsage(ctx context.Context, userID common.UserID) (ai.QuotaStatus, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	var used int64
+	err := r.db.WithContext(ctx).
+		Table("daily_token_usage").
+		Select("COALESCE(SUM(tokens), 0)").
+		Where("user_id = ? AND day = ?", string(userID), today).
+		Scan(&used).Error
+	if err != nil {
+		return ai.QuotaStatus{}, fmt.Errorf("failed to get quota usage: %w", err)
+	}
+
+	return ai.QuotaStatus{
+		UserID:     userID,
+		DailyLimit: r.config.DailyTokenLimit,
+		UsedToday:  int(used),
+		Remaining:  max(r.config.DailyTokenLimit-int(used), 0),
+		ResetTime:  today.Add(24 * time.Hour).Format(time.RFC3339),
+	}, nil
+}
+
+// addTokenUsageQuery adds tokens to a user's usage for a day
+const addTokenUsageQuery = `
+INSERT INTO daily_token_usage (user_id, day, tokens) VALUES (?, ?, ?)
+ON CONFLICT (user_id, day) DO UPDATE SET tokens = daily_token_usage.tokens + EXCLUDED.tokens`
+
+// UpdateQuotaUsage adds tokens to a user's usage for the current UTC day
+func (r *PostgreSQLGenerationRepository) UpdateQuotaUsage(ctx context.Context, userID common.UserID, tokens int) error {
+	if tokens <= 0 {
+		return nil
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if err := r.db.WithContext(ctx).Exec(addTokenUsageQuery, string(userID), today, tokens).Error; err != nil {
+		return fmt.Errorf("failed to update quota usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// PromptTemplateModel represents the database model for prompt templates
+type PromptTemplateModel struct {
+	ID             string    `gorm:"primaryKey;column:id;default:gen_random_uuid()" json:"id"`
+	Name           string    `gorm:"column:name;not null" json:"name"`
+	Framework      string    `gorm:"column:framework;not null" json:"framework"`
+	Language       string    `gorm:"column:language" json:"language"`
+	Version        int       `gorm:"column:version;not null" json:"version"`
+	SystemTemplate string    `gorm:"column:system_template" json:"system_template"`
+	UserTemplate   string    `gorm:"column:user_template;not null" json:"user_template"`
+	Variables      string    `gorm:"column:variables;type:jsonb" json:"variables"` // Store as JSON string
+	IsActive       bool      `gorm:"column:is_active;default:true" json:"is_active"`
+	CreatedAt      time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName returns the table name for the PromptTemplateModel
+func (PromptTemplateModel) TableName() string {
+	return "prompt_templates"
+}
+
+// ToPromptTemplate converts PromptTemplateModel to domain ai.PromptTemplate
+func (m *PromptTemplateModel) ToPromptTemplate() (ai.PromptTemplate, error) {
+	var variables map[string]string
+	if m.Variables != "" {
+		if err := json.Unmarshal([]byte(m.Variables), &variables); err != nil {
+			return ai.PromptTemplate{}, fmt.Errorf("failed to decode variables of prompt template %s v%d: %w", m.Name, m.Version, err)
+		}
+	}
+
+	tmpl := ai.PromptTemplate{
+		Name:      m.Name,
+		Framework: m.Framework,
+		Language:  m.Language,
+		Version:   m.Version,
+		System:    m.SystemTemplate,
+		User:      m.UserTemplate,
+		Variables: variables,
+	}
+	tmpl.Timestamps.CreatedAt = m.CreatedAt
+	tmpl.Timestamps.UpdatedAt = m.UpdatedAt
+
+	return tmpl, nil
+}
+
+// PostgreSQLPromptTemplateRepository implements ai.PromptTemplateRepository using GORM
+type PostgreSQLPromptTemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgreSQLPromptTemplateRepository creates a new PostgreSQL prompt template repository.
+// The prompt_templates table is created by migration 008.
+func NewPostgreSQLPromptTemplateRepository(db *gorm.DB) *PostgreSQLPromptTemplateRepository {
+	return &PostgreSQLPromptTemplateRepository{db: db}
+}
+
+// ListTemplates returns every active template
+func (r *PostgreSQLPromptTemplateRepository) ListTemplates(ctx context.Context) ([]ai.PromptTemplate, error) {
+	var models []PromptTemplateModel
+	if err := r.db.WithContext(ctx).
+		Where("is_active = ?", true).
+		Order("name, version").
+		Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+
+	templates := make([]ai.PromptTemplate, 0, len(models))
+	for i := range models {
+		tmpl, err := models[i].ToPromptTemplate()
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+	return templates, nil
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// quotaAccountRow is a user joined with their plan and settings
+type quotaAccountRow struct {
+	Tier             string
+	DailyTokens      int
+	MonthlyTokens    int
+	DailyRequests    int
+	MonthlyRequests  int
+	ModelMultipliers *string
+	Timezone         *string
+}
+
+// PostgreSQLQuotaPlanRepository implements ai.QuotaPlanRepository
+type PostgreSQLQuotaPlanRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgreSQLQuotaPlanRepository creates a new PostgreSQL quota plan repository.
+// Plans live in the quota_plans table created by migration 014.
+func NewPostgreSQLQuotaPlanRepository(db *gorm.DB) *PostgreSQLQuotaPlanRepository {
+	return &PostgreSQLQuotaPlanRepository{db: db}
+}
+
+// GetQuotaAccount returns the plan of a user and the timezone from their
+// settings, defaulting to UTC
+func (r *PostgreSQLQuotaPlanRepository) GetQuotaAccount(ctx context.Context, userID common.UserID) (ai.QuotaAccount, error) {
+	var row quotaAccountRow
+	result := r.db.WithContext(ctx).
+		Raw(`SELECT p.tier, p.daily_tokens, p.monthly_tokens, p.daily_requests, p.monthly_requests,
+			p.model_multipliers, s.timezone
+			FROM users u
+			JOIN quota_plans p ON p.tier = u.plan_tier
+			LEFT JOIN user_settings s ON s.user_id = u.id
+			WHERE u.id = ?`, string(userID)).
+		Scan(&row)
+	if result.Error != nil {
+		return ai.QuotaAccount{}, fmt.Errorf("failed to get quota plan: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ai.QuotaAccount{}, common.NewNotFoundError("user not found")
+	}
+
+	account := ai.QuotaAccount{
+		UserID: userID,
+		Plan: ai.QuotaPlan{
+			Tier:            ai.PlanTier(row.Tier),
+			DailyTokens:     row.DailyTokens,
+			MonthlyTokens:   row.MonthlyTokens,
+			DailyRequests:   row.DailyRequests,
+			MonthlyRequests: row.MonthlyRequests,
+		},
+		Timezone: "UTC",
+	}
+	if row.Timezone != nil && *row.Timezone != "" {
+		account.Timezone = *row.Timezone
+	}
+	if row.ModelMultipliers != nil {
+		if err := json.Unmarshal([]byte(*row.ModelMultipliers), &account.Plan.ModelMultipliers); err != nil {
+			return ai.QuotaAccount{}, fmt.Errorf("failed to decode model multipliers of plan %s: %w", row.Tier, err)
+		}
+	}
+	return account, nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// securityPolicyDocument is the scanner configuration stored under
+// projects.config->'security'
+type securityPolicyDocument struct {
+	DisabledRules  []string          `json:"disabled_rules"`
+	Severities     map[string]string `json:"severities"`
+	AllowedOrigins []string          `json:"allowed_origins"`
+}
+
+// toSecurityPolicy converts the document to a domain ai.SecurityPolicy
+func (d securityPolicyDocument) toSecurityPolicy() ai.SecurityPolicy {
+	policy := ai.SecurityPolicy{
+		DisabledRules:  d.DisabledRules,
+		AllowedOrigins: d.AllowedOrigins,
+	}
+	if len(d.Severities) > 0 {
+		policy.Severities = make(map[string]ai.DiagnosticSeverity, len(d.Severities))
+		for rule, severity := range d.Severities {
+			policy.Severities[rule] = ai.DiagnosticSeverity(severity)
+		}
+	}
+	return policy
+}
+
+// PostgreSQLSecurityPolicyRepository implements ai.SecurityPolicyRepository
+// by reading the security section of project configs
+type PostgreSQLSecurityPolicyRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgreSQLSecurityPolicyRepository creates a new PostgreSQL security policy repository.
+// Policies live in projects.config, created by migration 002.
+func NewPostgreSQLSecurityPolicyRepository(db *gorm.DB) *PostgreSQLSecurityPolicyRepository {
+	return &PostgreSQLSecurityPolicyRepository{db: db}
+}
+
+// GetSecurityPolicy returns the scanner policy of a project owned by userID
+func (r *PostgreSQLSecurityPolicyRepository) GetSecurityPolicy(ctx context.Context, userID common.UserID, projectID common.ProjectID) (ai.SecurityPolicy, error) {
+	var row struct {
+		Security *string
+	}
+	result := r.db.WithContext(ctx).
+		Raw("SELECT config->'security' AS security FROM projects WHERE id = ? AND user_id = ?", string(projectID), string(userID)).
+		Scan(&row)
+	if result.Error != nil {
+		return ai.SecurityPolicy{}, fmt.Errorf("failed to get security policy: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ai.SecurityPolicy{}, common.NewNotFoundError("project not found")
+	}
+	if row.Security == nil {
+		return ai.SecurityPolicy{}, nil
+	}
+
+	var doc securityPolicyDocument
+	if err := json.Unmarshal([]byte(*row.Security), &doc); err != nil {
+		return ai.SecurityPolicy{}, fmt.Errorf("failed to decode security policy of project %s: %w", projectID, err)
+	}
+	return doc.toSecurityPolicy(), nil
+}
@@ -0,0 +1,281 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/validation"
+	llmclient "github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+// ClientService implements LLMService on top of an llm.LLMClient, so
+// providers written for the generation gateway can serve the use cases too
+type ClientService struct {
+	client    llmclient.LLMClient
+	model     string
+	prices    llmclient.PriceTable
+	validator ai.CodeValidator
+	params    llmclient.ParamAllowList
+}
+
+// ClientServiceConfig holds configuration for a ClientService
+type ClientServiceConfig struct {
+	Model     string // Used when a request names no model
+	Prices    llmclient.PriceTable
+	Validator ai.CodeValidator // Defaults to the language-aware validation service
+	// Params should match the client's allow-list, so requests can be checked
+	// before they are sent; defaults to accepting every parameter
+	Params llmclient.ParamAllowList
+}
+
+// NewClientService creates a new service backed by client
+func NewClientService(client llmclient.LLMClient, config *ClientServiceConfig) *ClientService {
+	if config.Prices == nil {
+		config.Prices = llmclient.DefaultPriceTable()
+	}
+	if config.Validator == nil {
+		config.Validator = validation.NewService()
+	}
+	if config.Params == nil {
+		config.Params = llmclient.ParamAllowList{}
+	}
+
+	return &ClientService{
+		client:    client,
+		model:     config.Model,
+		prices:    config.Prices,
+		validator: config.Validator,
+		params:    config.Params,
+	}
+}
+
+// AnthropicConfig holds configuration for the Anthropic service
+type AnthropicConfig struct {
+	llmclient.AnthropicConfig
+	Model  string
+	Prices llmclient.PriceTable
+}
+
+// NewAnthropicService creates a new service using the Anthropic Messages API
+func NewAnthropicService(config *AnthropicConfig) *ClientService {
+	if config.Model == "" {
+		config.Model = "claude-3-5-sonnet-latest"
+	}
+
+	client := llmclient.NewAnthropicClient(&config.AnthropicConfig)
+	return NewClientService(client, &ClientServiceConfig{
+		Model:  config.Model,
+		Prices: config.Prices,
+		Params: config.AnthropicConfig.Params,
+	})
+}
+
+// OllamaConfig holds configuration for the Ollama service
+type OllamaConfig struct {
+	llmclient.OllamaConfig
+	Model string
+}
+
+// NewOllamaService creates a new service using a local Ollama install
+func NewOllamaService(config *OllamaConfig) *ClientService {
+	if config.Model == "" {
+		config.Model = "qwen2.5-coder"
+	}
+
+	client := llmclient.NewOllamaClient(&config.OllamaConfig)
+	return NewClientService(client, &ClientServiceConfig{
+		Model:  config.Model,
+		Params: config.OllamaConfig.Params,
+	})
+}
+
+// LlamaCppConfig holds configuration for the llama.cpp service
+type LlamaCppConfig struct {
+	llmclient.LlamaCppConfig
+	Model string // Only labels results; the server decides which model runs
+}
+
+// NewLlamaCppService creates a new service using a llama.cpp server
+func NewLlamaCppService(config *LlamaCppConfig) *ClientService {
+	client := llmclient.NewLlamaCppClient(&config.LlamaCppConfig)
+	return NewClientService(client, &ClientServiceConfig{
+		Model:  config.Model,
+		Params: config.LlamaCppConfig.Params,
+	})
+}
+
+// Generate implements non-streaming code generation
+func (s *ClientService) Generate(ctx context.Context, req ai.GenerationRequest) (ai.GenerationResult, error) {
+	clientReq := s.buildRequest(req)
+
+	resp, err := s.client.Generate(ctx, clientReq)
+	if err != nil {
+		return ai.GenerationResult{}, err
+	}
+	if resp.Error != nil {
+		return ai.GenerationResult{}, resp.Error
+	}
+	if len(resp.Choices) == 0 {
+		return ai.GenerationResult{}, fmt.Errorf("no choices in response")
+	}
+
+	code := resp.Choices[0].Content()
+	usage := resolveClientUsage(resp.Usage, clientReq, code)
+
+	return ai.GenerationResult{
+		ID:               resp.ID,
+		Code:             code,
+		Model:            resp.Model,
+		UsedTokens:       usage.TotalTokens,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		EstimatedCost:    s.prices.Cost(resp.Model, usage.PromptTokens, usage.CompletionTokens),
+	}, nil
+}
+
+// GenerateStream implements streaming code generation
+func (s *ClientService) GenerateStream(ctx context.Context, req ai.GenerationRequest, ch chan<- ai.StreamChunk) error {
+	clientReq := s.buildRequest(req)
+
+	respChan, err := s.client.GenerateStream(ctx, clientReq)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	var usage *llmclient.Usage
+	var finishReason string
+	model := clientReq.Model
+
+	for resp := range respChan {
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if resp.Model != "" {
+			model = resp.Model
+		}
+		if resp.Usage != nil {
+			usage = resp.Usage
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+
+		choice := resp.Choices[0]
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			finishReason = *choice.FinishReason
+		}
+		if delta := choice.Content(); delta != "" {
+			content.WriteString(delta)
+			if err := sendChunk(ctx, ch, ai.StreamChunk{Content: delta, Model: model}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	total := resolveClientUsage(usage, clientReq, content.String())
+	return sendChunk(ctx, ch, ai.StreamChunk{
+		TokenCount:   total.TotalTokens,
+		Model:        model,
+		FinishReason: finishReason,
+		IsComplete:   true,
+	})
+}
+
+// Stream implements legacy streaming interface
+func (s *ClientService) Stream(ctx context.Context, req ai.GenerationRequest, ch chan<- string) error {
+	defer close(ch)
+
+	streamCh := make(chan ai.StreamChunk, 10)
+	done := make(chan error, 1)
+
+	go func() {
+		defer close(streamCh)
+		done <- s.GenerateStream(ctx, req, streamCh)
+	}()
+
+	for chunk := range streamCh {
+		if chunk.Content != "" {
+			select {
+			case ch <- chunk.Content:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return <-done
+}
+
+// Validate checks the syntax of code, detecting its language
+func (s *ClientService) Validate(ctx context.Context, code string) (ai.ValidationResult, error) {
+	return s.validator.Validate(ctx, code, "")
+}
+
+// CheckParams validates the sampling parameters of req against the model's allow-list
+func (s *ClientService) CheckParams(req ai.GenerationRequest) error {
+	clientReq := s.buildRequest(req)
+	return s.params.Validate(clientReq.Model, clientReq.SamplingParams())
+}
+
+// AcceptsParam reports whether the model req resolves to accepts the named parameter
+func (s *ClientService) AcceptsParam(req ai.GenerationRequest, name string) bool {
+	return s.params.Accepts(s.buildRequest(req).Model, name)
+}
+
+// buildRequest converts a generation request into the client request format
+func (s *ClientService) buildRequest(req ai.GenerationRequest) *llmclient.GenerationRequest {
+	clientReq := &llmclient.GenerationRequest{
+		Model:            req.Model,
+		Prompt:           req.Prompt,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		Stop:             req.Stop,
+		Seed:             req.Seed,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		UserID:           string(req.UserID),
+	}
+	if clientReq.Model == "" {
+		clientReq.Model = s.model
+	}
+	if req.MaxTokens != nil {
+		clientReq.MaxTokens = *req.MaxTokens
+	}
+	if req.ResponseFormat != "" {
+		clientReq.ResponseFormat = &llmclient.ResponseFormat{Type: req.ResponseFormat}
+	}
+	if req.ProjectID != nil {
+		clientReq.ProjectID = string(*req.ProjectID)
+	}
+
+	for _, msg := range req.Messages {
+		clientReq.Messages = append(clientReq.Messages, llmclient.Message{Role: msg.Role, Content: msg.Content})
+	}
+	return clientReq
+}
+
+// resolveClientUsage returns the usage reported by the provider, or a local
+// estimate when it omitted it
+func resolveClientUsage(usage *llmclient.Usage, req *llmclient.GenerationRequest, completion string) llmclient.Usage {
+	if usage != nil && usage.TotalTokens > 0 {
+		return *usage
+	}
+
+	prompt := req.Messages
+	if !req.IsChat() {
+		prompt = []llmclient.Message{{Role: llmclient.RoleUser, Content: req.Prompt}}
+	}
+
+	estimated := llmclient.Usage{
+		PromptTokens:     llmclient.EstimateMessagesTokens(prompt),
+		CompletionTokens: llmclient.EstimateTokens(completion),
+	}
+	estimated.TotalTokens = estimated.PromptTokens + estimated.CompletionTokens
+	return estimated
+}
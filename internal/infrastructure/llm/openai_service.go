@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -10,33 +11,98 @@ import (
 	"time"
 
 	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/validation"
+	llmclient "github.com/EliasRanz/ai-code-gen/internal/llm"
 )
 
 // OpenAIService implements LLMService using OpenAI API
 type OpenAIService struct {
-	apiKey     string
-	baseURL    string
-	model      string
-	httpClient *http.Client
+	apiKey    string
+	baseURL   string
+	model     string
+	prices    llmclient.PriceTable
+	params    llmclient.ParamAllowList
+	client    *llmclient.ResilientClient
+	validator ai.CodeValidator
+}
+
+// OpenAIConfig holds configuration for the OpenAI service
+type OpenAIConfig struct {
+	APIKey  string
+	BaseURL string
+	Model   string
+	Timeout time.Duration
+	Prices  llmclient.PriceTable
+	Params  llmclient.ParamAllowList
+	// HTTPClient overrides the default transport, e.g. with a Cassette in tests
+	HTTPClient llmclient.HTTPClientInterface
+	Validator  ai.CodeValidator // Defaults to the language-aware validation service
 }
 
 // NewOpenAIService creates a new OpenAI service
 func NewOpenAIService(apiKey string) *OpenAIService {
+	return NewOpenAIServiceWithConfig(&OpenAIConfig{APIKey: apiKey})
+}
+
+// NewOpenAIServiceWithConfig creates a new OpenAI service from config,
+// e.g. to target an OpenAI-compatible server
+func NewOpenAIServiceWithConfig(config *OpenAIConfig) *OpenAIService {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.openai.com/v1"
+	}
+	if config.Model == "" {
+		config.Model = "gpt-3.5-turbo"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 60 * time.Second
+	}
+	if config.Prices == nil {
+		config.Prices = llmclient.DefaultPriceTable()
+	}
+	if config.Params == nil {
+		config.Params = llmclient.DefaultParamAllowList()
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = llmclient.NewDefaultHTTPClient(config.Timeout)
+	}
+	if config.Validator == nil {
+		config.Validator = validation.NewService()
+	}
+
 	return &OpenAIService{
-		apiKey:  apiKey,
-		baseURL: "https://api.openai.com/v1",
-		model:   "gpt-3.5-turbo",
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		apiKey:    config.APIKey,
+		baseURL:   config.BaseURL,
+		model:     config.Model,
+		prices:    config.Prices,
+		params:    config.Params,
+		validator: config.Validator,
+		client: llmclient.NewResilientClient(
+			config.HTTPClient,
+			llmclient.DefaultRetryPolicy(),
+			llmclient.NewCircuitBreaker("OpenAI", llmclient.CircuitBreakerConfig{}),
+		),
 	}
 }
 
 // OpenAIRequest represents the request format for OpenAI API
 type OpenAIRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-	Stream   bool      `json:"stream,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	llmclient.SamplingParams
+}
+
+// StreamOptions asks the API to append a usage chunk to the stream
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// Usage represents the token usage reported by the API
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // Message represents a chat message
@@ -52,13 +118,15 @@ type OpenAIResponse struct {
 	Created int64    `json:"created"`
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
 }
 
 // Choice represents a completion choice
 type Choice struct {
-	Index   int         `json:"index"`
-	Message Message     `json:"message"`
-	Delta   MessageDelta `json:"delta,omitempty"`
+	Index        int          `json:"index"`
+	Message      Message      `json:"message"`
+	Delta        MessageDelta `json:"delta,omitempty"`
+	FinishReason *string      `json:"finish_reason,omitempty"`
 }
 
 // MessageDelta represents a partial message in streaming
@@ -68,12 +136,9 @@ type MessageDelta struct {
 
 // Generate implements non-streaming code generation
 func (s *OpenAIService) Generate(ctx context.Context, req ai.GenerationRequest) (ai.GenerationResult, error) {
-	openAIReq := OpenAIRequest{
-		Model: s.model,
-		Messages: []Message{
-			{Role: "user", Content: req.Prompt},
-		},
-		Stream: false,
+	openAIReq, err := s.buildRequest(req)
+	if err != nil {
+		return ai.GenerationResult{}, err
 	}
 
 	resp, err := s.makeRequest(ctx, openAIReq)
@@ -91,24 +156,28 @@ func (s *OpenAIService) Generate(ctx context.Context, req ai.GenerationRequest)
 		return ai.GenerationResult{}, fmt.Errorf("no choices in response")
 	}
 
+	code := openAIResp.Choices[0].Message.Content
+	usage := s.resolveUsage(openAIResp.Usage, openAIReq.Messages, code)
+
 	return ai.GenerationResult{
-		Code:       openAIResp.Choices[0].Message.Content,
-		Model:      openAIResp.Model,
-		UsedTokens: 0, // OpenAI doesn't return token count in this response format
+		ID:               openAIResp.ID,
+		Code:             code,
+		Model:            openAIResp.Model,
+		UsedTokens:       usage.TotalTokens,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		EstimatedCost:    s.prices.Cost(openAIResp.Model, usage.PromptTokens, usage.CompletionTokens),
 	}, nil
 }
 
 // GenerateStream implements streaming code generation
 func (s *OpenAIService) GenerateStream(ctx context.Context, req ai.GenerationRequest, ch chan<- ai.StreamChunk) error {
-	defer close(ch)
-
-	openAIReq := OpenAIRequest{
-		Model: s.model,
-		Messages: []Message{
-			{Role: "user", Content: req.Prompt},
-		},
-		Stream: true,
+	openAIReq, err := s.buildRequest(req)
+	if err != nil {
+		return err
 	}
+	openAIReq.Stream = true
+	openAIReq.StreamOptions = &StreamOptions{IncludeUsage: true}
 
 	resp, err := s.makeRequest(ctx, openAIReq)
 	if err != nil {
@@ -116,7 +185,94 @@ func (s *OpenAIService) GenerateStream(ctx context.Context, req ai.GenerationReq
 	}
 	defer resp.Body.Close()
 
-	return s.processStreamResponse(ctx, resp.Body, ch)
+	return s.processStreamResponse(ctx, resp.Body, openAIReq, ch)
+}
+
+// buildRequest converts a generation request into an OpenAI request, using the
+// requested model or the configured default and validating the sampling
+// parameters against the model's allow-list
+func (s *OpenAIService) buildRequest(req ai.GenerationRequest) (OpenAIRequest, error) {
+	model := s.resolveModel(req)
+
+	params := llmclient.SamplingParams{
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		Stop:             req.Stop,
+		Seed:             req.Seed,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+	}
+	if req.MaxTokens != nil {
+		params.MaxTokens = *req.MaxTokens
+	}
+	if req.ResponseFormat != "" {
+		params.ResponseFormat = &llmclient.ResponseFormat{Type: req.ResponseFormat}
+	}
+
+	if err := s.params.Validate(model, params); err != nil {
+		return OpenAIRequest{}, err
+	}
+
+	return OpenAIRequest{
+		Model:          model,
+		Messages:       s.buildMessages(req),
+		SamplingParams: params,
+	}, nil
+}
+
+// resolveModel returns the requested model or the configured default
+func (s *OpenAIService) resolveModel(req ai.GenerationRequest) string {
+	if req.Model == "" {
+		return s.model
+	}
+	return req.Model
+}
+
+// CheckParams validates the sampling parameters of req against the model's allow-list
+func (s *OpenAIService) CheckParams(req ai.GenerationRequest) error {
+	_, err := s.buildRequest(req)
+	return err
+}
+
+// AcceptsParam reports whether the model req resolves to accepts the named parameter
+func (s *OpenAIService) AcceptsParam(req ai.GenerationRequest, name string) bool {
+	return s.params.Accepts(s.resolveModel(req), name)
+}
+
+// buildMessages converts a generation request into chat messages, using the
+// rendered prompt when one is attached
+func (s *OpenAIService) buildMessages(req ai.GenerationRequest) []Message {
+	if len(req.Messages) == 0 {
+		return []Message{
+			{Role: ai.RoleUser, Content: req.Prompt},
+		}
+	}
+
+	messages := make([]Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = Message{Role: msg.Role, Content: msg.Content}
+	}
+	return messages
+}
+
+// resolveUsage returns the usage reported by the API, or a local estimate
+// when the provider omitted it
+func (s *OpenAIService) resolveUsage(usage *Usage, messages []Message, completion string) Usage {
+	if usage != nil && usage.TotalTokens > 0 {
+		return *usage
+	}
+
+	prompt := make([]llmclient.Message, len(messages))
+	for i, msg := range messages {
+		prompt[i] = llmclient.Message{Role: msg.Role, Content: msg.Content}
+	}
+
+	estimated := Usage{
+		PromptTokens:     llmclient.EstimateMessagesTokens(prompt),
+		CompletionTokens: llmclient.EstimateTokens(completion),
+	}
+	estimated.TotalTokens = estimated.PromptTokens + estimated.CompletionTokens
+	return estimated
 }
 
 // Stream implements legacy streaming interface
@@ -128,6 +284,7 @@ func (s *OpenAIService) Stream(ctx context.Context, req ai.GenerationRequest, ch
 	done := make(chan error, 1)
 
 	go func() {
+		defer close(streamCh)
 		done <- s.GenerateStream(ctx, req, streamCh)
 	}()
 
@@ -148,20 +305,9 @@ func (s *OpenAIService) Stream(ctx context.Context, req ai.GenerationRequest, ch
 	}
 }
 
-// Validate implements code validation (placeholder implementation)
+// Validate checks the syntax of code, detecting its language
 func (s *OpenAIService) Validate(ctx context.Context, code string) (ai.ValidationResult, error) {
-	// Simple validation - check if code is not empty and has basic structure
-	isValid := strings.TrimSpace(code) != "" && (strings.Contains(code, "func") || strings.Contains(code, "class") || strings.Contains(code, "def"))
-	
-	var issues []string
-	if !isValid {
-		issues = append(issues, "Code appears to be empty or malformed")
-	}
-
-	return ai.ValidationResult{
-		Valid:  isValid,
-		Errors: issues,
-	}, nil
+	return s.validator.Validate(ctx, code, "")
 }
 
 // makeRequest creates and sends an HTTP request to OpenAI API
@@ -171,15 +317,16 @@ func (s *OpenAIService) makeRequest(ctx context.Context, reqBody OpenAIRequest)
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/chat/completions", strings.NewReader(string(body)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	resp, err := s.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/chat/completions", bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
 
-	resp, err := s.httpClient.Do(req)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+		return req, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -187,55 +334,82 @@ func (s *OpenAIService) makeRequest(ctx context.Context, reqBody OpenAIRequest)
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, llmclient.NewHTTPError("OpenAI", resp.StatusCode, body)
 	}
 
 	return resp, nil
 }
 
-// processStreamResponse processes Server-Sent Events from OpenAI streaming API
-func (s *OpenAIService) processStreamResponse(ctx context.Context, body io.Reader, ch chan<- ai.StreamChunk) error {
-	decoder := json.NewDecoder(body)
-	
+// processStreamResponse processes Server-Sent Events from OpenAI streaming API.
+// Content chunks carry no token count; the single terminal chunk carries the
+// total usage and finish reason so consumers summing TokenCount charge the
+// whole generation once.
+func (s *OpenAIService) processStreamResponse(ctx context.Context, body io.Reader, req OpenAIRequest, ch chan<- ai.StreamChunk) error {
+	reader := llmclient.NewSSEReader(body)
+
+	var content strings.Builder
+	var usage *Usage
+	var finishReason string
+	model := req.Model
+
 	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			var line map[string]interface{}
-			if err := decoder.Decode(&line); err != nil {
-				if err == io.EOF {
-					return nil
-				}
-				return fmt.Errorf("failed to decode stream line: %w", err)
-			}
+		event, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read stream: %w", err)
+		}
+		if event.IsDone() {
+			break
+		}
+		if llmErr := llmclient.StreamErrorFromEvent(event); llmErr != nil {
+			return llmErr
+		}
 
-			// Parse streaming response
-			if choices, ok := line["choices"].([]interface{}); ok && len(choices) > 0 {
-				if choice, ok := choices[0].(map[string]interface{}); ok {
-					if delta, ok := choice["delta"].(map[string]interface{}); ok {
-						if content, ok := delta["content"].(string); ok && content != "" {
-							chunk := ai.StreamChunk{
-								Content:    content,
-								Model:      s.model, // Use configured model name
-								IsComplete: false,
-							}
-							ch <- chunk
-						}
-					}
-				}
-			}
+		var line OpenAIResponse
+		if err := json.Unmarshal([]byte(event.Data), &line); err != nil {
+			return fmt.Errorf("failed to decode stream line: %w", err)
+		}
+
+		if line.Model != "" {
+			model = line.Model
+		}
+		if line.Usage != nil {
+			usage = line.Usage
+		}
+		if len(line.Choices) == 0 {
+			// The usage chunk arrives after the finish chunk with no choices
+			continue
+		}
 
-			// Check for completion
-			if finishReason, ok := line["finish_reason"]; ok && finishReason != nil {
-				chunk := ai.StreamChunk{
-					Content:    "",
-					Model:      s.model,
-					IsComplete: true,
-				}
-				ch <- chunk
-				return nil
+		choice := line.Choices[0]
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			finishReason = *choice.FinishReason
+		}
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			if err := sendChunk(ctx, ch, ai.StreamChunk{Content: choice.Delta.Content, Model: model}); err != nil {
+				return err
 			}
 		}
 	}
+
+	total := s.resolveUsage(usage, req.Messages, content.String())
+	return sendChunk(ctx, ch, ai.StreamChunk{
+		TokenCount:   total.TotalTokens,
+		Model:        model,
+		FinishReason: finishReason,
+		IsComplete:   true,
+	})
+}
+
+// sendChunk delivers a chunk unless the context is cancelled first
+func sendChunk(ctx context.Context, ch chan<- ai.StreamChunk, chunk ai.StreamChunk) error {
+	select {
+	case ch <- chunk:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
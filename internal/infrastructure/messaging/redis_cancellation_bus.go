@@ -0,0 +1,65 @@
+// Package messaging provides cross-replica messaging implementations
+package messaging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// GenerationCancelChannel is the Redis channel generation cancellations are published on
+const GenerationCancelChannel = "generation:cancel"
+
+// RedisCancellationBus implements ai.CancellationBus using Redis pub/sub
+type RedisCancellationBus struct {
+	client redis.UniversalClient
+}
+
+// NewRedisCancellationBus creates a new Redis cancellation bus
+func NewRedisCancellationBus(client redis.UniversalClient) *RedisCancellationBus {
+	return &RedisCancellationBus{client: client}
+}
+
+// PublishCancel asks every replica to cancel a generation
+func (b *RedisCancellationBus) PublishCancel(ctx context.Context, generationID string) error {
+	if err := b.client.Publish(ctx, GenerationCancelChannel, generationID).Err(); err != nil {
+		return fmt.Errorf("failed to publish generation cancellation: %w", err)
+	}
+	return nil
+}
+
+// SubscribeCancels delivers cancelled generation IDs until ctx is done
+func (b *RedisCancellationBus) SubscribeCancels(ctx context.Context) (<-chan string, error) {
+	pubsub := b.client.Subscribe(ctx, GenerationCancelChannel)
+	// Wait for the subscription to be confirmed so no cancellation is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to generation cancellations: %w", err)
+	}
+
+	ids := make(chan string)
+	go func() {
+		defer close(ids)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case ids <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ids, nil
+}
@@ -0,0 +1,57 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// jobEvent is the message published when a generation job changes status.
+// Subscribers fetch the result from the job endpoint once it has completed.
+type jobEvent struct {
+	Type      string    `json:"type"`
+	JobID     string    `json:"job_id"`
+	Status    string    `json:"status"`
+	Model     string    `json:"model"`
+	Error     string    `json:"error,omitempty"`
+	UserID    string    `json:"user_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RedisJobEventPublisher implements ai.JobEventPublisher by publishing job
+// status changes to the user's generation:user:<id> channel
+type RedisJobEventPublisher struct {
+	client redis.UniversalClient
+}
+
+// NewRedisJobEventPublisher creates a new Redis job event publisher
+func NewRedisJobEventPublisher(client redis.UniversalClient) *RedisJobEventPublisher {
+	return &RedisJobEventPublisher{client: client}
+}
+
+// PublishJobEvent publishes the current status of a job
+func (p *RedisJobEventPublisher) PublishJobEvent(ctx context.Context, job ai.GenerationJob) error {
+	message, err := json.Marshal(jobEvent{
+		Type:      "job",
+		JobID:     job.ID,
+		Status:    string(job.Status),
+		Model:     job.Model,
+		Error:     job.Error,
+		UserID:    string(job.UserID),
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode job event: %w", err)
+	}
+
+	channel := fmt.Sprintf("generation:user:%s", job.UserID)
+	if err := p.client.Publish(ctx, channel, message).Err(); err != nil {
+		return fmt.Errorf("failed to publish job event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// Package postprocess provides processors that clean up and format generated code
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go/format"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// DefaultProcessors returns the processors run on every generation, in order
+func DefaultProcessors() []ai.CodeProcessor {
+	return []ai.CodeProcessor{
+		LineEndingNormalizer{},
+		FenceStripper{},
+		ProseStripper{},
+		GoFormatter{},
+		JSONFormatter{},
+	}
+}
+
+// fencePattern matches a markdown code fence and captures its contents
+var fencePattern = regexp.MustCompile("(?ms)^[ \t]*```[^\n`]*\n(.*?)\n?[ \t]*```[ \t]*$")
+
+// textLanguages are languages whose fences and prose are content, not noise
+var textLanguages = map[string]bool{
+	"markdown":  true,
+	"md":        true,
+	"text":      true,
+	"txt":       true,
+	"plaintext": true,
+}
+
+// cleansOutput reports whether fences and prose should be stripped from code
+// for target: only the model's raw answer is cleaned, unless it is text
+func cleansOutput(target ai.CodeTarget) bool {
+	return target.RawOutput && !textLanguages[strings.ToLower(target.Language)]
+}
+
+// FenceStripper replaces markdown with the contents of its fenced code
+// blocks, dropping the prose around them. Several blocks are joined with a
+// blank line. Only the raw model output is stripped; artifact files and
+// markdown or text are left alone.
+type FenceStripper struct{}
+
+// Name returns the processor name
+func (FenceStripper) Name() string { return "strip_fences" }
+
+// Process strips markdown code fences
+func (FenceStripper) Process(ctx context.Context, code string, target ai.CodeTarget) (string, error) {
+	if !cleansOutput(target) {
+		return code, nil
+	}
+	matches := fencePattern.FindAllStringSubmatch(code, -1)
+	if len(matches) == 0 {
+		return code, nil
+	}
+	blocks := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if strings.TrimSpace(m[1]) != "" {
+			blocks = append(blocks, m[1])
+		}
+	}
+	if len(blocks) == 0 {
+		return code, nil
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// ProseStripper drops chatty paragraphs before and after the code, such as
+// "Here is your component:" or "This component uses Tailwind for styling."
+// Only whole paragraphs in which every line reads as a sentence are removed,
+// and only from the raw model output unless it is markdown or text.
+type ProseStripper struct{}
+
+// Name returns the processor name
+func (ProseStripper) Name() string { return "strip_prose" }
+
+// Process strips leading and trailing prose paragraphs
+func (ProseStripper) Process(ctx context.Context, code string, target ai.CodeTarget) (string, error) {
+	if !cleansOutput(target) {
+		return code, nil
+	}
+	lines := strings.Split(code, "\n")
+	paragraphs := splitParagraphs(lines)
+	start, end := 0, len(paragraphs)
+	for start < end-1 && isProse(lines, paragraphs[start]) {
+		start++
+	}
+	for end > start+1 && isProse(lines, paragraphs[end-1]) {
+		end--
+	}
+	if start == 0 && end == len(paragraphs) {
+		return code, nil
+	}
+	return strings.Join(lines[paragraphs[start].first:paragraphs[end-1].last+1], "\n"), nil
+}
+
+// paragraph is a run of non-blank lines
+type paragraph struct {
+	first, last int
+}
+
+// splitParagraphs returns the runs of non-blank lines
+func splitParagraphs(lines []string) []paragraph {
+	var paragraphs []paragraph
+	inside := false
+	for i, line := range lines {
+		blank := strings.TrimSpace(line) == ""
+		switch {
+		case !blank && !inside:
+			paragraphs = append(paragraphs, paragraph{first: i, last: i})
+			inside = true
+		case !blank:
+			paragraphs[len(paragraphs)-1].last = i
+		default:
+			inside = false
+		}
+	}
+	return paragraphs
+}
+
+// isProse reports whether every line of a paragraph reads as a sentence
+func isProse(lines []string, p paragraph) bool {
+	for _, line := range lines[p.first : p.last+1] {
+		if !isProseLine(strings.TrimSpace(line)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isProseLine reports whether a line reads as a sentence rather than code:
+// it starts with a capital letter or markdown emphasis, has several words
+// and contains none of the punctuation code is made of. Lines starting with
+// "#" are comments or headings, not prose.
+func isProseLine(line string) bool {
+	line = strings.TrimLeft(line, "*_ ")
+	if line == "" || !unicode.IsUpper([]rune(line)[0]) {
+		return false
+	}
+	if strings.Count(line, " ") < 2 {
+		return false
+	}
+	return !strings.ContainsAny(line, "=;{}<>")
+}
+
+// LineEndingNormalizer converts CRLF and CR line endings to LF
+type LineEndingNormalizer struct{}
+
+// Name returns the processor name
+func (LineEndingNormalizer) Name() string { return "normalize_line_endings" }
+
+// Process normalizes line endings
+func (LineEndingNormalizer) Process(ctx context.Context, code string, target ai.CodeTarget) (string, error) {
+	code = strings.ReplaceAll(code, "\r\n", "\n")
+	return strings.ReplaceAll(code, "\r", "\n"), nil
+}
+
+// GoFormatter formats Go code with gofmt
+type GoFormatter struct{}
+
+// Name returns the processor name
+func (GoFormatter) Name() string { return "gofmt" }
+
+// Process formats Go code
+func (GoFormatter) Process(ctx context.Context, code string, target ai.CodeTarget) (string, error) {
+	if target.Language != "go" {
+		return code, nil
+	}
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return code, err
+	}
+	return strings.TrimRight(string(formatted), "\n"), nil
+}
+
+// JSONFormatter indents JSON documents with two spaces
+type JSONFormatter struct{}
+
+// Name returns the processor name
+func (JSONFormatter) Name() string { return "format_json" }
+
+// Process formats JSON
+func (JSONFormatter) Process(ctx context.Context, code string, target ai.CodeTarget) (string, error) {
+	if target.Language != "json" {
+		return code, nil
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(code), "", "  "); err != nil {
+		return code, err
+	}
+	return buf.String(), nil
+}
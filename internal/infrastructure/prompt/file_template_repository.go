@@ -0,0 +1,162 @@
+// Package prompt provides prompt template storage implementations
+package prompt
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"text/template"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// builtinTemplates holds the templates shipped with the service
+//
+//go:embed templates/*.json
+var builtinTemplates embed.FS
+
+// templateFile is the on-disk JSON representation of a prompt template
+type templateFile struct {
+	Name      string            `json:"name"`
+	Framework string            `json:"framework"`
+	Language  string            `json:"language,omitempty"`
+	Version   int               `json:"version"`
+	System    string            `json:"system"`
+	User      string            `json:"user"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// toPromptTemplate converts the file into a domain template
+func (f templateFile) toPromptTemplate() ai.PromptTemplate {
+	return ai.PromptTemplate{
+		Name:      f.Name,
+		Framework: f.Framework,
+		Language:  f.Language,
+		Version:   f.Version,
+		System:    f.System,
+		User:      f.User,
+		Variables: f.Variables,
+	}
+}
+
+// FileTemplateRepository implements ai.PromptTemplateRepository over JSON files.
+// Templates are loaded once and validated up front, so a broken template
+// fails at startup rather than on the first request that selects it.
+type FileTemplateRepository struct {
+	templates []ai.PromptTemplate
+}
+
+// NewFileTemplateRepository loads every *.json template in the root of fsys
+func NewFileTemplateRepository(fsys fs.FS) (*FileTemplateRepository, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+
+	templates := make([]ai.PromptTemplate, 0, len(files))
+	for _, name := range files {
+		tmpl, err := loadTemplate(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+
+	return &FileTemplateRepository{templates: templates}, nil
+}
+
+// NewDirTemplateRepository loads the templates stored in dir
+func NewDirTemplateRepository(dir string) (*FileTemplateRepository, error) {
+	return NewFileTemplateRepository(os.DirFS(dir))
+}
+
+// NewBuiltinTemplateRepository loads the templates shipped with the service
+func NewBuiltinTemplateRepository() *FileTemplateRepository {
+	templates, err := fs.Sub(builtinTemplates, "templates")
+	if err != nil {
+		panic(err)
+	}
+
+	repo, err := NewFileTemplateRepository(templates)
+	if err != nil {
+		// Built-in templates are covered by tests; failing here is a build defect
+		panic(err)
+	}
+	return repo
+}
+
+// ListTemplates returns every loaded template
+func (r *FileTemplateRepository) ListTemplates(ctx context.Context) ([]ai.PromptTemplate, error) {
+	templates := make([]ai.PromptTemplate, len(r.templates))
+	copy(templates, r.templates)
+	return templates, nil
+}
+
+// loadTemplate reads and validates a single template file
+func loadTemplate(fsys fs.FS, name string) (ai.PromptTemplate, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return ai.PromptTemplate{}, fmt.Errorf("failed to read prompt template %s: %w", name, err)
+	}
+
+	var file templateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return ai.PromptTemplate{}, fmt.Errorf("failed to decode prompt template %s: %w", name, err)
+	}
+
+	tmpl := file.toPromptTemplate()
+	if err := ValidateTemplate(tmpl); err != nil {
+		return ai.PromptTemplate{}, fmt.Errorf("invalid prompt template %s: %w", path.Base(name), err)
+	}
+	return tmpl, nil
+}
+
+// ValidateTemplate checks that a template is complete and that its sources parse
+func ValidateTemplate(tmpl ai.PromptTemplate) error {
+	switch {
+	case tmpl.Name == "":
+		return fmt.Errorf("name is required")
+	case tmpl.Framework == "":
+		return fmt.Errorf("framework is required")
+	case tmpl.Version < 1:
+		return fmt.Errorf("version must be positive")
+	case tmpl.User == "":
+		return fmt.Errorf("user template is required")
+	}
+
+	for part, source := range map[string]string{"system": tmpl.System, "user": tmpl.User} {
+		if _, err := template.New(part).Parse(source); err != nil {
+			return fmt.Errorf("failed to parse %s template: %w", part, err)
+		}
+	}
+	return nil
+}
+
+// CompositeTemplateRepository lists the templates of several repositories,
+// e.g. the built-in templates together with ones managed in the database.
+// Repositories listed later override earlier ones for the same version.
+type CompositeTemplateRepository struct {
+	repos []ai.PromptTemplateRepository
+}
+
+// NewCompositeTemplateRepository creates a repository combining repos
+func NewCompositeTemplateRepository(repos ...ai.PromptTemplateRepository) *CompositeTemplateRepository {
+	return &CompositeTemplateRepository{repos: repos}
+}
+
+// ListTemplates returns the templates of every repository
+func (r *CompositeTemplateRepository) ListTemplates(ctx context.Context) ([]ai.PromptTemplate, error) {
+	var templates []ai.PromptTemplate
+	for _, repo := range r.repos {
+		list, err := repo.ListTemplates(ctx)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, list...)
+	}
+	return templates, nil
+}
@@ -0,0 +1,184 @@
+// Package queue provides generation job queue implementations
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+const (
+	// DefaultJobRetention is how long finished jobs can still be polled
+	DefaultJobRetention = 24 * time.Hour
+	// DefaultJobLease is how long a dequeued job stays with its worker
+	// without the lease being renewed
+	DefaultJobLease = 2 * time.Minute
+)
+
+// MemoryJobQueue implements ai.JobQueue in process memory. Jobs are lost on
+// restart and only this replica's workers see them, so leases never expire;
+// use RedisJobQueue when running more than one.
+type MemoryJobQueue struct {
+	retention time.Duration
+
+	mu       sync.Mutex
+	jobs     map[string]ai.GenerationJob
+	pending  map[string][]string  // Job IDs per model, in dequeue order
+	finished map[string]time.Time // When each finished job expires
+}
+
+// NewMemoryJobQueue creates a new in-memory job queue. A retention of zero
+// uses DefaultJobRetention.
+func NewMemoryJobQueue(retention time.Duration) *MemoryJobQueue {
+	if retention <= 0 {
+		retention = DefaultJobRetention
+	}
+	return &MemoryJobQueue{
+		retention: retention,
+		jobs:      make(map[string]ai.GenerationJob),
+		pending:   make(map[string][]string),
+		finished:  make(map[string]time.Time),
+	}
+}
+
+// Enqueue stores a job and queues it behind older jobs of the same or higher priority
+func (q *MemoryJobQueue) Enqueue(ctx context.Context, job ai.GenerationJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for id, expires := range q.finished {
+		if now.After(expires) {
+			delete(q.jobs, id)
+			delete(q.finished, id)
+		}
+	}
+
+	q.jobs[job.ID] = job
+	q.insert(job)
+	return nil
+}
+
+// Dequeue removes the next pending job whose model is not in exclude
+func (q *MemoryJobQueue) Dequeue(ctx context.Context, exclude []string) (ai.GenerationJob, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	skip := make(map[string]bool, len(exclude))
+	for _, model := range exclude {
+		skip[model] = true
+	}
+
+	var next *ai.GenerationJob
+	for model, ids := range q.pending {
+		if skip[model] || len(ids) == 0 {
+			continue
+		}
+		head := q.jobs[ids[0]]
+		if next == nil || before(head, *next) {
+			next = &head
+		}
+	}
+	if next == nil {
+		return ai.GenerationJob{}, false, nil
+	}
+
+	q.pending[next.Model] = q.pending[next.Model][1:]
+	if len(q.pending[next.Model]) == 0 {
+		delete(q.pending, next.Model)
+	}
+	return *next, true, nil
+}
+
+// RenewLease extends the lease of a dequeued job
+func (q *MemoryJobQueue) RenewLease(ctx context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.jobs[id]; !ok {
+		return common.NewNotFoundError("job not found")
+	}
+	return nil
+}
+
+// Requeue stores a leased job and queues it behind older jobs of the same or
+// higher priority
+func (q *MemoryJobQueue) Requeue(ctx context.Context, job ai.GenerationJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.jobs[job.ID]; !ok {
+		return common.NewNotFoundError("job not found")
+	}
+	q.jobs[job.ID] = job
+	q.insert(job)
+	return nil
+}
+
+// GetJob retrieves a job by ID
+func (q *MemoryJobQueue) GetJob(ctx context.Context, id string) (ai.GenerationJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return ai.GenerationJob{}, common.NewNotFoundError("job not found")
+	}
+	return job, nil
+}
+
+// UpdateJob replaces a stored job. A finished job leaves its model's pending jobs.
+func (q *MemoryJobQueue) UpdateJob(ctx context.Context, job ai.GenerationJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.jobs[job.ID]; !ok {
+		return common.NewNotFoundError("job not found")
+	}
+	q.jobs[job.ID] = job
+	if job.Status.IsTerminal() {
+		q.finished[job.ID] = time.Now().Add(q.retention)
+		q.remove(job)
+	}
+	return nil
+}
+
+// insert adds a job to its model's pending jobs in dequeue order
+func (q *MemoryJobQueue) insert(job ai.GenerationJob) {
+	ids := q.pending[job.Model]
+	i := len(ids)
+	for i > 0 && before(job, q.jobs[ids[i-1]]) {
+		i--
+	}
+	ids = append(ids, "")
+	copy(ids[i+1:], ids[i:])
+	ids[i] = job.ID
+	q.pending[job.Model] = ids
+}
+
+// remove drops a job from its model's pending jobs, if it is queued
+func (q *MemoryJobQueue) remove(job ai.GenerationJob) {
+	ids := q.pending[job.Model]
+	for i, id := range ids {
+		if id == job.ID {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(q.pending, job.Model)
+	} else {
+		q.pending[job.Model] = ids
+	}
+}
+
+// before reports whether a should run before b
+func before(a, b ai.GenerationJob) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
@@ -0,0 +1,348 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+const (
+	// redisQueueTag is the hash tag shared by every queue key, so that the
+	// scripts and transactions touching several keys run on one Redis Cluster slot
+	redisQueueTag = "{genqueue}"
+	// redisQueueModelsKey is the set of models that have had jobs queued
+	redisQueueModelsKey = "generation:" + redisQueueTag + ":queue:models"
+	// redisProcessingKey is the sorted set of leased job IDs scored by the
+	// Unix millisecond their lease expires at
+	redisProcessingKey = "generation:" + redisQueueTag + ":queue:processing"
+	// redisPriorityWeight spaces priorities far enough apart in queue scores
+	// that no enqueue time within a priority reaches the next one
+	redisPriorityWeight = 1e13
+)
+
+// redisJob is the stored representation of a generation job
+type redisJob struct {
+	ID        string          `json:"id"`
+	UserID    string          `json:"user_id"`
+	Model     string          `json:"model"`
+	Priority  int             `json:"priority"`
+	Status    string          `json:"status"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// leaseScript moves a job from its model's pending set to the processing
+// set unless another worker took it first
+var leaseScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+return 1
+`)
+
+// renewScript extends the lease of a job that is still being processed
+var renewScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return 1
+`)
+
+// requeueScript stores a leased job and moves it back to its model's
+// pending set, unless it was requeued or finished in the meantime
+var requeueScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('SET', KEYS[3], ARGV[3], 'XX', 'KEEPTTL')
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+return 1
+`)
+
+// RedisJobQueue implements ai.JobQueue in Redis so every replica's workers
+// share one queue. Each model has a sorted set of pending job IDs scored by
+// priority, then enqueue time; jobs are stored as JSON strings that expire
+// once the job has been finished for the retention period. Dequeued jobs are
+// leased in a processing set and queued again once their lease expires.
+type RedisJobQueue struct {
+	client    redis.UniversalClient
+	retention time.Duration
+	lease     time.Duration
+}
+
+// NewRedisJobQueue creates a new Redis job queue. A retention of zero uses
+// DefaultJobRetention and a lease of zero DefaultJobLease.
+func NewRedisJobQueue(client redis.UniversalClient, retention, lease time.Duration) *RedisJobQueue {
+	if retention <= 0 {
+		retention = DefaultJobRetention
+	}
+	if lease <= 0 {
+		lease = DefaultJobLease
+	}
+	return &RedisJobQueue{client: client, retention: retention, lease: lease}
+}
+
+// jobKey returns the key a job is stored under
+func jobKey(id string) string {
+	return "generation:" + redisQueueTag + ":job:" + id
+}
+
+// pendingKey returns the key of a model's pending jobs
+func pendingKey(model string) string {
+	return "generation:" + redisQueueTag + ":queue:" + model
+}
+
+// score orders jobs by priority, highest first, then by age
+func score(job ai.GenerationJob) float64 {
+	return -float64(job.Priority)*redisPriorityWeight + float64(job.CreatedAt.UnixMilli())
+}
+
+// Enqueue stores a job and adds it to its model's pending set
+func (q *RedisJobQueue) Enqueue(ctx context.Context, job ai.GenerationJob) error {
+	data, err := encodeJob(job)
+	if err != nil {
+		return err
+	}
+
+	if _, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, jobKey(job.ID), data, 0)
+		pipe.ZAdd(ctx, pendingKey(job.Model), redis.Z{Score: score(job), Member: job.ID})
+		pipe.SAdd(ctx, redisQueueModelsKey, job.Model)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return nil
+}
+
+// Dequeue leases the next pending job whose model is not in exclude, after
+// queueing jobs with expired leases again
+func (q *RedisJobQueue) Dequeue(ctx context.Context, exclude []string) (ai.GenerationJob, bool, error) {
+	if err := q.requeueExpired(ctx); err != nil {
+		return ai.GenerationJob{}, false, err
+	}
+
+	models, err := q.client.SMembers(ctx, redisQueueModelsKey).Result()
+	if err != nil {
+		return ai.GenerationJob{}, false, fmt.Errorf("failed to list queued models: %w", err)
+	}
+
+	skip := make(map[string]bool, len(exclude))
+	for _, model := range exclude {
+		skip[model] = true
+	}
+
+	for {
+		// Find the best head across the models with capacity
+		var best *redis.Z
+		var bestModel string
+		for _, model := range models {
+			if skip[model] {
+				continue
+			}
+			heads, err := q.client.ZRangeWithScores(ctx, pendingKey(model), 0, 0).Result()
+			if err != nil {
+				return ai.GenerationJob{}, false, fmt.Errorf("failed to read queue: %w", err)
+			}
+			if len(heads) > 0 && (best == nil || heads[0].Score < best.Score) {
+				best, bestModel = &heads[0], model
+			}
+		}
+		if best == nil {
+			return ai.GenerationJob{}, false, nil
+		}
+
+		// Another worker may take the job first; look again if it did
+		id := best.Member.(string)
+		leased, err := leaseScript.Run(ctx, q.client,
+			[]string{pendingKey(bestModel), redisProcessingKey},
+			id, q.deadline(),
+		).Int()
+		if err != nil {
+			return ai.GenerationJob{}, false, fmt.Errorf("failed to dequeue job: %w", err)
+		}
+		if leased == 0 {
+			continue
+		}
+
+		// Drop jobs that expired, or finished after their lease was requeued
+		job, err := q.GetJob(ctx, id)
+		if err == nil && job.Status.IsTerminal() {
+			err = common.NewNotFoundError("job already " + string(job.Status))
+		}
+		if common.IsNotFoundError(err) {
+			q.client.ZRem(ctx, redisProcessingKey, id)
+			continue
+		}
+		if err != nil {
+			return ai.GenerationJob{}, false, err
+		}
+		return job, true, nil
+	}
+}
+
+// RenewLease extends the lease of a dequeued job
+func (q *RedisJobQueue) RenewLease(ctx context.Context, id string) error {
+	renewed, err := renewScript.Run(ctx, q.client, []string{redisProcessingKey}, id, q.deadline()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to renew job lease: %w", err)
+	}
+	if renewed == 0 {
+		return common.NewNotFoundError("job not leased")
+	}
+	return nil
+}
+
+// Requeue stores a leased job and returns it to its model's pending set
+func (q *RedisJobQueue) Requeue(ctx context.Context, job ai.GenerationJob) error {
+	data, err := encodeJob(job)
+	if err != nil {
+		return err
+	}
+
+	requeued, err := requeueScript.Run(ctx, q.client,
+		[]string{redisProcessingKey, pendingKey(job.Model), jobKey(job.ID)},
+		job.ID, score(job), data,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
+	if requeued == 0 {
+		return common.NewNotFoundError("job not leased")
+	}
+	return nil
+}
+
+// requeueExpired queues jobs whose lease expired, such as those of a crashed
+// replica, again
+func (q *RedisJobQueue) requeueExpired(ctx context.Context) error {
+	ids, err := q.client.ZRangeByScore(ctx, redisProcessingKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list expired jobs: %w", err)
+	}
+
+	for _, id := range ids {
+		job, err := q.GetJob(ctx, id)
+		if common.IsNotFoundError(err) {
+			q.client.ZRem(ctx, redisProcessingKey, id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		job.Status = ai.GenerationPending
+		job.Touch()
+		if err := q.Requeue(ctx, job); err != nil && !common.IsNotFoundError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// deadline returns when a lease taken or renewed now expires, in Unix milliseconds
+func (q *RedisJobQueue) deadline() int64 {
+	return time.Now().Add(q.lease).UnixMilli()
+}
+
+// GetJob retrieves a job by ID
+func (q *RedisJobQueue) GetJob(ctx context.Context, id string) (ai.GenerationJob, error) {
+	data, err := q.client.Get(ctx, jobKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return ai.GenerationJob{}, common.NewNotFoundError("job not found")
+	}
+	if err != nil {
+		return ai.GenerationJob{}, fmt.Errorf("failed to get job: %w", err)
+	}
+	return decodeJob(data)
+}
+
+// UpdateJob replaces a stored job. Once the job has finished its lease ends,
+// it leaves its model's pending set and its retention period starts.
+func (q *RedisJobQueue) UpdateJob(ctx context.Context, job ai.GenerationJob) error {
+	data, err := encodeJob(job)
+	if err != nil {
+		return err
+	}
+
+	args := redis.SetArgs{Mode: "XX", KeepTTL: true}
+	if job.Status.IsTerminal() {
+		args = redis.SetArgs{Mode: "XX", TTL: q.retention}
+	}
+
+	var set *redis.StatusCmd
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		set = pipe.SetArgs(ctx, jobKey(job.ID), data, args)
+		if job.Status.IsTerminal() {
+			pipe.ZRem(ctx, redisProcessingKey, job.ID)
+			pipe.ZRem(ctx, pendingKey(job.Model), job.ID)
+		}
+		return nil
+	})
+	updated, setErr := set.Result()
+	if errors.Is(setErr, redis.Nil) || (err == nil && updated != "OK") {
+		return common.NewNotFoundError("job not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	return nil
+}
+
+// encodeJob encodes a job for storage
+func encodeJob(job ai.GenerationJob) ([]byte, error) {
+	data, err := json.Marshal(redisJob{
+		ID:        job.ID,
+		UserID:    string(job.UserID),
+		Model:     job.Model,
+		Priority:  int(job.Priority),
+		Status:    string(job.Status),
+		Payload:   job.Payload,
+		Result:    job.Result,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job: %w", err)
+	}
+	return data, nil
+}
+
+// decodeJob decodes a stored job
+func decodeJob(data []byte) (ai.GenerationJob, error) {
+	var stored redisJob
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return ai.GenerationJob{}, fmt.Errorf("failed to decode job: %w", err)
+	}
+
+	job := ai.GenerationJob{
+		ID:       stored.ID,
+		UserID:   common.UserID(stored.UserID),
+		Model:    stored.Model,
+		Priority: ai.JobPriority(stored.Priority),
+		Status:   ai.GenerationStatus(stored.Status),
+		Payload:  stored.Payload,
+		Result:   stored.Result,
+		Error:    stored.Error,
+	}
+	job.CreatedAt = stored.CreatedAt
+	job.UpdatedAt = stored.UpdatedAt
+	return job, nil
+}
@@ -0,0 +1,101 @@
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// memoryWindow is the usage counted in one window
+type memoryWindow struct {
+	usage   ai.QuotaUsage
+	resetAt time.Time
+}
+
+// MemoryQuotaCounter implements ai.QuotaCounter in process memory. Counts are
+// lost on restart and not shared between replicas; use RedisQuotaCounter when
+// running more than one.
+type MemoryQuotaCounter struct {
+	mu      sync.Mutex
+	windows map[string]memoryWindow
+}
+
+// NewMemoryQuotaCounter creates a new in-memory quota counter
+func NewMemoryQuotaCounter() *MemoryQuotaCounter {
+	return &MemoryQuotaCounter{windows: make(map[string]memoryWindow)}
+}
+
+// Usage returns the usage of each window
+func (c *MemoryQuotaCounter) Usage(ctx context.Context, userID common.UserID, windows ...ai.QuotaWindow) ([]ai.QuotaUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	usage := make([]ai.QuotaUsage, len(windows))
+	for i, window := range windows {
+		if w, ok := c.windows[usageKey(userID, window)]; ok && now.Before(w.resetAt) {
+			usage[i] = w.usage
+		}
+	}
+	return usage, nil
+}
+
+// Add adds usage to every window and returns the new totals
+func (c *MemoryQuotaCounter) Add(ctx context.Context, userID common.UserID, usage ai.QuotaUsage, windows ...ai.QuotaWindow) ([]ai.QuotaUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire()
+	totals := make([]ai.QuotaUsage, len(windows))
+	for i, window := range windows {
+		key := usageKey(userID, window)
+		w := c.windows[key]
+		w.usage.Tokens += usage.Tokens
+		w.usage.Requests += usage.Requests
+		w.resetAt = window.ResetAt
+		c.windows[key] = w
+		totals[i] = w.usage
+	}
+	return totals, nil
+}
+
+// Reserve adds one request to every window unless a window has reached its
+// limits
+func (c *MemoryQuotaCounter) Reserve(ctx context.Context, userID common.UserID, windows ...ai.QuotaWindow) ([]ai.QuotaUsage, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire()
+	totals := make([]ai.QuotaUsage, len(windows))
+	allowed := true
+	for i, window := range windows {
+		totals[i] = c.windows[usageKey(userID, window)].usage
+		allowed = allowed && window.Limit.Allows(totals[i])
+	}
+	if !allowed {
+		return totals, false, nil
+	}
+
+	for i, window := range windows {
+		key := usageKey(userID, window)
+		w := c.windows[key]
+		w.usage.Requests++
+		w.resetAt = window.ResetAt
+		c.windows[key] = w
+		totals[i] = w.usage
+	}
+	return totals, true, nil
+}
+
+// expire drops the windows that have reset. c.mu must be held.
+func (c *MemoryQuotaCounter) expire() {
+	now := time.Now()
+	for key, w := range c.windows {
+		if !now.Before(w.resetAt) {
+			delete(c.windows, key)
+		}
+	}
+}
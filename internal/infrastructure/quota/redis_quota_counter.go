@@ -0,0 +1,140 @@
+// Package quota provides quota usage counters
+package quota
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// addUsageScript adds ARGV[1] tokens and ARGV[2] requests to the hash at each
+// key, sets the key to expire at the matching ARGV[2+i] unix time and returns
+// the new totals as a flat list of tokens and requests
+var addUsageScript = redis.NewScript(`
+local totals = {}
+for i, key in ipairs(KEYS) do
+	local tokens = redis.call('HINCRBY', key, 'tokens', ARGV[1])
+	local requests = redis.call('HINCRBY', key, 'requests', ARGV[2])
+	redis.call('EXPIREAT', key, ARGV[2 + i])
+	totals[#totals + 1] = tokens
+	totals[#totals + 1] = requests
+end
+return totals
+`)
+
+// reserveScript adds one request to the hash at each key unless a key has
+// reached its limits, given for the i-th key as ARGV[3i-2] tokens and
+// ARGV[3i-1] requests with zero for unlimited. Keys are set to expire at
+// ARGV[3i]. It returns 1 when the request was added or 0 otherwise, followed
+// by the totals as a flat list of tokens and requests.
+var reserveScript = redis.NewScript(`
+local totals, allowed = {}, 1
+for i, key in ipairs(KEYS) do
+	local counts = redis.call('HMGET', key, 'tokens', 'requests')
+	local tokens, requests = tonumber(counts[1]) or 0, tonumber(counts[2]) or 0
+	local tokenLimit, requestLimit = tonumber(ARGV[3 * i - 2]), tonumber(ARGV[3 * i - 1])
+	if (tokenLimit > 0 and tokens >= tokenLimit) or (requestLimit > 0 and requests >= requestLimit) then
+		allowed = 0
+	end
+	totals[#totals + 1] = tokens
+	totals[#totals + 1] = requests
+end
+if allowed == 1 then
+	for i, key in ipairs(KEYS) do
+		totals[2 * i] = redis.call('HINCRBY', key, 'requests', 1)
+		redis.call('EXPIREAT', key, ARGV[3 * i])
+	end
+end
+table.insert(totals, 1, allowed)
+return totals
+`)
+
+// RedisQuotaCounter implements ai.QuotaCounter in Redis so every replica
+// counts against the same totals. Each user and window is a hash of tokens
+// and requests that expires when the window resets.
+type RedisQuotaCounter struct {
+	client redis.UniversalClient
+}
+
+// NewRedisQuotaCounter creates a new Redis quota counter
+func NewRedisQuotaCounter(client redis.UniversalClient) *RedisQuotaCounter {
+	return &RedisQuotaCounter{client: client}
+}
+
+// usageKey returns the key usage of a window is counted under. The user ID is
+// a hash tag so all of a user's windows live in one cluster slot.
+func usageKey(userID common.UserID, window ai.QuotaWindow) string {
+	return "quota:{" + string(userID) + "}:" + window.Key
+}
+
+// Usage returns the usage of each window
+func (c *RedisQuotaCounter) Usage(ctx context.Context, userID common.UserID, windows ...ai.QuotaWindow) ([]ai.QuotaUsage, error) {
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(windows))
+	for i, window := range windows {
+		cmds[i] = pipe.HMGet(ctx, usageKey(userID, window), "tokens", "requests")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get quota usage: %w", err)
+	}
+
+	usage := make([]ai.QuotaUsage, len(windows))
+	for i, cmd := range cmds {
+		var counts struct {
+			Tokens   int `redis:"tokens"`
+			Requests int `redis:"requests"`
+		}
+		if err := cmd.Scan(&counts); err != nil {
+			return nil, fmt.Errorf("failed to decode quota usage: %w", err)
+		}
+		usage[i] = ai.QuotaUsage{Tokens: counts.Tokens, Requests: counts.Requests}
+	}
+	return usage, nil
+}
+
+// Add atomically adds usage to every window and returns the new totals
+func (c *RedisQuotaCounter) Add(ctx context.Context, userID common.UserID, usage ai.QuotaUsage, windows ...ai.QuotaWindow) ([]ai.QuotaUsage, error) {
+	keys := make([]string, len(windows))
+	args := []interface{}{usage.Tokens, usage.Requests}
+	for i, window := range windows {
+		keys[i] = usageKey(userID, window)
+		args = append(args, window.ResetAt.Unix())
+	}
+
+	totals, err := addUsageScript.Run(ctx, c.client, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to add quota usage: %w", err)
+	}
+
+	result := make([]ai.QuotaUsage, len(windows))
+	for i := range result {
+		result[i] = ai.QuotaUsage{Tokens: int(totals[2*i]), Requests: int(totals[2*i+1])}
+	}
+	return result, nil
+}
+
+// Reserve atomically adds one request to every window unless a window has
+// reached its limits
+func (c *RedisQuotaCounter) Reserve(ctx context.Context, userID common.UserID, windows ...ai.QuotaWindow) ([]ai.QuotaUsage, bool, error) {
+	keys := make([]string, len(windows))
+	args := make([]interface{}, 0, 3*len(windows))
+	for i, window := range windows {
+		keys[i] = usageKey(userID, window)
+		args = append(args, window.Limit.Tokens, window.Limit.Requests, window.ResetAt.Unix())
+	}
+
+	totals, err := reserveScript.Run(ctx, c.client, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve quota: %w", err)
+	}
+
+	result := make([]ai.QuotaUsage, len(windows))
+	for i := range result {
+		result[i] = ai.QuotaUsage{Tokens: int(totals[2*i+1]), Requests: int(totals[2*i+2])}
+	}
+	return result, totals[0] == 1, nil
+}
@@ -0,0 +1,159 @@
+// Package ratelimit provides a GCRA rate limiter shared across replicas
+// through Redis
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+const (
+	// DefaultFallbackSize is how many keys the in-memory fallback tracks
+	// before evicting the least recently used
+	DefaultFallbackSize = 10000
+	// DefaultRedisRetryInterval is how long the limiter uses the in-memory
+	// fallback after Redis fails before trying Redis again
+	DefaultRedisRetryInterval = 5 * time.Second
+)
+
+// Policy allows Limit requests per Period. Requests are spread evenly over
+// the period, with up to Burst of them allowed at once.
+type Policy struct {
+	Name   string // Keeps the counts of policies with the same key apart, e.g. a route
+	Limit  int
+	Period time.Duration
+	Burst  int // Defaults to Limit
+}
+
+// interval returns the time the policy allows between requests
+func (p Policy) interval() time.Duration {
+	return p.Period / time.Duration(max(p.Limit, 1))
+}
+
+// burst returns how many requests the policy allows at once
+func (p Policy) burst() int {
+	if p.Burst > 0 {
+		return p.Burst
+	}
+	return max(p.Limit, 1)
+}
+
+// String returns the policy in RateLimit-Policy header form, e.g. "10;w=60"
+func (p Policy) String() string {
+	return fmt.Sprintf("%d;w=%d", p.Limit, int(p.Period.Seconds()))
+}
+
+// Result is the outcome of taking a request from a limit
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int           // Requests that could still be made at once
+	ResetAfter time.Duration // Until the limit is back to its full burst
+	RetryAfter time.Duration // Until a denied request would be allowed
+}
+
+// Store keeps the state of rate limits
+type Store interface {
+	// Take counts one request against key under policy
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+	// Reset forgets the state of key
+	Reset(ctx context.Context, key string) error
+}
+
+// Limiter implements ai.RateLimiter with a generic cell rate algorithm.
+// Limits are kept in Redis so that every replica enforces the same ones;
+// while Redis is unreachable each replica falls back to limits in its own
+// memory.
+type Limiter struct {
+	store    Store
+	fallback *MemoryStore
+	policy   Policy
+
+	mu         sync.Mutex
+	retryRedis time.Time
+}
+
+// NewLimiter creates a new Limiter keeping limits in Redis. Allow applies
+// policy to each user.
+func NewLimiter(client redis.UniversalClient, policy Policy) *Limiter {
+	return NewLimiterWithStore(NewRedisStore(client), policy)
+}
+
+// NewLimiterWithStore creates a new Limiter keeping limits in store. A nil
+// store keeps them in memory only.
+func NewLimiterWithStore(store Store, policy Policy) *Limiter {
+	return &Limiter{
+		store:    store,
+		fallback: NewMemoryStore(DefaultFallbackSize),
+		policy:   policy,
+	}
+}
+
+// Take counts one request against key under policy. Requests whose ctx is
+// done are counted by the fallback without taking the store out of use.
+func (l *Limiter) Take(ctx context.Context, key string, policy Policy) Result {
+	key = policy.Name + ":" + key
+	if ctx.Err() == nil && l.useStore() {
+		result, err := l.store.Take(ctx, key, policy)
+		if err == nil {
+			return result
+		}
+		if ctx.Err() == nil {
+			l.storeFailed()
+		}
+	}
+	result, _ := l.fallback.Take(ctx, key, policy)
+	return result
+}
+
+// Allow reports whether a user may make another request
+func (l *Limiter) Allow(userID common.UserID) bool {
+	return l.Take(context.Background(), UserKey(userID), l.policy).Allowed
+}
+
+// Reset clears the limit of a user
+func (l *Limiter) Reset(userID common.UserID) {
+	key := l.policy.Name + ":" + UserKey(userID)
+	if l.store != nil {
+		_ = l.store.Reset(context.Background(), key)
+	}
+	_ = l.fallback.Reset(context.Background(), key)
+}
+
+// useStore reports whether requests should go to the store rather than the fallback
+func (l *Limiter) useStore() bool {
+	if l.store == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !time.Now().Before(l.retryRedis)
+}
+
+// storeFailed sends requests to the fallback until the retry interval passes
+func (l *Limiter) storeFailed() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.retryRedis = time.Now().Add(DefaultRedisRetryInterval)
+}
+
+// UserKey returns the key an authenticated user is limited by
+func UserKey(userID common.UserID) string {
+	return "user:" + string(userID)
+}
+
+// APIKeyKey returns the key an API key is limited by
+func APIKeyKey(keyID string) string {
+	return "key:" + keyID
+}
+
+// IPKey returns the key an anonymous client is limited by
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryEntry is the theoretical arrival time of the next request of a key
+type memoryEntry struct {
+	key string
+	tat time.Time
+}
+
+// MemoryStore keeps rate limits in process memory, evicting the least
+// recently used key once it holds size keys. Limits are not shared between
+// replicas; use RedisStore when running more than one.
+type MemoryStore struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // Most recently used first
+}
+
+// NewMemoryStore creates a new in-memory rate limit store holding up to
+// size keys. A size of zero uses DefaultFallbackSize.
+func NewMemoryStore(size int) *MemoryStore {
+	if size <= 0 {
+		size = DefaultFallbackSize
+	}
+	return &MemoryStore{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Take counts one request against key under policy
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	interval := policy.interval()
+	tolerance := interval * time.Duration(policy.burst())
+
+	tat := now
+	element, ok := s.entries[key]
+	if ok {
+		// Denied clients are kept too so that eviction cannot lift their limit
+		s.lru.MoveToFront(element)
+		if element.Value.(*memoryEntry).tat.After(now) {
+			tat = element.Value.(*memoryEntry).tat
+		}
+	}
+
+	next := tat.Add(interval)
+	if next.Sub(now) > tolerance {
+		return newResult(policy, false, tat.Sub(now), next.Add(-tolerance).Sub(now)), nil
+	}
+
+	if ok {
+		element.Value.(*memoryEntry).tat = next
+	} else {
+		s.entries[key] = s.lru.PushFront(&memoryEntry{key: key, tat: next})
+		if s.lru.Len() > s.size {
+			oldest := s.lru.Back()
+			s.lru.Remove(oldest)
+			delete(s.entries, oldest.Value.(*memoryEntry).key)
+		}
+	}
+	return newResult(policy, true, next.Sub(now), 0), nil
+}
+
+// Reset forgets the state of key
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.lru.Remove(element)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// Len returns the number of keys held
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript applies the generic cell rate algorithm to the theoretical
+// arrival time stored at KEYS[1], using Redis' clock so every replica agrees.
+// ARGV holds the emission interval and burst tolerance in microseconds. It
+// returns whether the request is allowed, the time until the limit is back
+// to its full burst and, when denied, the time until a request is allowed.
+var takeScript = redis.NewScript(`
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000000 + tonumber(clock[2])
+local interval = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+
+local next_tat = tat + interval
+if next_tat - now > tolerance then
+	return {0, tat - now, next_tat - tolerance - now}
+end
+
+redis.call('SET', KEYS[1], string.format('%.0f', next_tat), 'PX', math.ceil((next_tat - now) / 1000))
+return {1, next_tat - now, 0}
+`)
+
+// RedisStore keeps rate limits in Redis as the theoretical arrival time of
+// the next request, expiring once the limit is back to its full burst
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore creates a new Redis rate limit store
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// limitKey returns the Redis key of a rate limit
+func limitKey(key string) string {
+	return "ratelimit:" + key
+}
+
+// Take counts one request against key under policy
+func (s *RedisStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	interval := policy.interval()
+	tolerance := interval * time.Duration(policy.burst())
+	values, err := takeScript.Run(ctx, s.client, []string{limitKey(key)},
+		interval.Microseconds(), tolerance.Microseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit: %w", err)
+	}
+	return newResult(policy, values[0] == 1, time.Duration(values[1])*time.Microsecond, time.Duration(values[2])*time.Microsecond), nil
+}
+
+// Reset forgets the state of key
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, limitKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to reset rate limit: %w", err)
+	}
+	return nil
+}
+
+// newResult builds a result from the time until the limit is back to its
+// full burst and, for denied requests, the time until one is allowed
+func newResult(policy Policy, allowed bool, resetAfter, retryAfter time.Duration) Result {
+	interval := policy.interval()
+	tolerance := interval * time.Duration(policy.burst())
+	return Result{
+		Allowed:    allowed,
+		Limit:      policy.Limit,
+		Remaining:  max(int((tolerance-resetAfter)/interval), 0),
+		ResetAfter: resetAfter,
+		RetryAfter: retryAfter,
+	}
+}
@@ -0,0 +1,201 @@
+// Package security scans generated frontend code for risky patterns
+package security
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// DefaultAllowedOrigins are the hosts generated code may load scripts and
+// styles from without a finding
+var DefaultAllowedOrigins = []string{
+	"cdn.jsdelivr.net",
+	"cdnjs.cloudflare.com",
+	"fonts.googleapis.com",
+	"fonts.gstatic.com",
+	"unpkg.com",
+}
+
+// Rule flags a risky pattern in generated code
+type Rule struct {
+	ID       string
+	Severity ai.DiagnosticSeverity
+	Message  string
+	Pattern  *regexp.Regexp
+	// Allow reports whether a match is acceptable after all, given its
+	// submatches and the allowed origins. Nil flags every match.
+	Allow func(submatches []string, allowedOrigins []string) bool
+}
+
+// DefaultRules returns the rules the scanner runs unless a project disables them
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			ID:       "dangerous-inner-html",
+			Severity: ai.SeverityWarning,
+			Message:  "dangerouslySetInnerHTML renders unescaped HTML; sanitize it or render text instead",
+			Pattern:  regexp.MustCompile(`\bdangerouslySetInnerHTML\b`),
+		},
+		{
+			ID:       "eval",
+			Severity: ai.SeverityError,
+			Message:  "eval executes arbitrary strings as code",
+			Pattern:  regexp.MustCompile(`\beval\s*\(`),
+		},
+		{
+			ID:       "new-function",
+			Severity: ai.SeverityError,
+			Message:  "new Function executes arbitrary strings as code",
+			Pattern:  regexp.MustCompile(`\bnew\s+Function\s*\(`),
+		},
+		{
+			ID:       "javascript-url",
+			Severity: ai.SeverityError,
+			Message:  "javascript: URLs run inline script; use an event handler instead",
+			Pattern:  regexp.MustCompile(`(?i)\b(?:on[a-z]+|href|src|action|formaction)\s*=\s*\{?\s*["'\x60]\s*javascript:`),
+		},
+		{
+			ID:       "inline-script",
+			Severity: ai.SeverityError,
+			Message:  "<script> tags are not allowed",
+			Pattern:  regexp.MustCompile(`(?i)<script\b[^>]*>`),
+			Allow: func(submatches []string, allowedOrigins []string) bool {
+				// External scripts are checked by external-origin
+				return srcAttribute.MatchString(submatches[0])
+			},
+		},
+		{
+			ID:       "external-origin",
+			Severity: ai.SeverityWarning,
+			Message:  "Loads a script or stylesheet from an origin outside the allow-list",
+			Pattern:  regexp.MustCompile(`(?i)(?:<script\b[^>]*?\bsrc|<link\b[^>]*?\bhref)\s*=\s*\{?\s*["'\x60]((?:https?:)?//[^/"'\x60\s>]+)|@import\s+(?:url\(\s*)?["']?((?:https?:)?//[^/"')\s]+)`),
+			Allow: func(submatches []string, allowedOrigins []string) bool {
+				origin := submatches[1] + submatches[2]
+				return originAllowed(origin, allowedOrigins)
+			},
+		},
+		{
+			ID:       "hardcoded-secret",
+			Severity: ai.SeverityError,
+			Message:  "Looks like a hard-coded secret; load it from configuration instead",
+			Pattern:  regexp.MustCompile(`AKIA[0-9A-Z]{16}|\b(?:sk|rk)_live_[0-9A-Za-z]{16,}|\bsk-[A-Za-z0-9_-]{20,}|\bgh[pousr]_[A-Za-z0-9]{36}|\bAIza[0-9A-Za-z_-]{35}|\bxox[abprs]-[A-Za-z0-9-]{10,}|-----BEGIN (?:RSA |EC |OPENSSH )?PRIVATE KEY-----|(?i:\b(?:api[_-]?key|secret(?:[_-]?key)?|client[_-]?secret|access[_-]?token|auth[_-]?token|password)\b["']?\s*[:=]\s*["'\x60]([^"'\x60\s]{8,})["'\x60])`),
+			Allow: func(submatches []string, allowedOrigins []string) bool {
+				return isPlaceholder(submatches[1])
+			},
+		},
+		{
+			ID:       "target-blank",
+			Severity: ai.SeverityWarning,
+			Message:  `target="_blank" without rel="noopener" lets the opened page control this one`,
+			Pattern:  regexp.MustCompile(`(?i)<a\b[^>]*\btarget\s*=\s*\{?\s*["'\x60]_blank["'\x60][^>]*>`),
+			Allow: func(submatches []string, allowedOrigins []string) bool {
+				// noreferrer implies noopener
+				return noopener.MatchString(submatches[0])
+			},
+		},
+	}
+}
+
+var (
+	// srcAttribute matches a src attribute
+	srcAttribute = regexp.MustCompile(`(?i)\bsrc\s*=`)
+	// noopener matches a rel attribute containing noopener or noreferrer
+	noopener = regexp.MustCompile(`(?i)\brel\s*=\s*\{?\s*["'\x60][^"'\x60]*\bno(?:opener|referrer)\b`)
+	// placeholderPattern matches example values that are not real secrets
+	placeholderPattern = regexp.MustCompile(`(?i)your|example|placeholder|changeme|x{4,}|\*{4,}|\.{3}|<.*>|\$\{|process\.env|import\.meta\.env`)
+)
+
+// originAllowed reports whether origin, such as "https://unpkg.com", is one
+// of the allowed hosts or a subdomain of one
+func originAllowed(origin string, allowedOrigins []string) bool {
+	host := strings.ToLower(origin[strings.Index(origin, "//")+2:])
+	for _, allowed := range allowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if i := strings.Index(allowed, "//"); i >= 0 {
+			allowed = allowed[i+2:]
+		}
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPlaceholder reports whether a matched secret value is a placeholder.
+// Values matched by a provider-specific pattern have no submatch and are
+// never placeholders.
+func isPlaceholder(value string) bool {
+	return value != "" && placeholderPattern.MatchString(value)
+}
+
+// Scanner implements ai.SecurityScanner with regular expression rules
+type Scanner struct {
+	rules          []Rule
+	allowedOrigins []string
+}
+
+// NewScanner creates a new Scanner running the default rules
+func NewScanner() *Scanner {
+	return NewScannerWithRules(DefaultRules(), DefaultAllowedOrigins)
+}
+
+// NewScannerWithRules creates a new Scanner running rules, allowing scripts
+// and styles from allowedOrigins in every project
+func NewScannerWithRules(rules []Rule, allowedOrigins []string) *Scanner {
+	return &Scanner{
+		rules:          rules,
+		allowedOrigins: allowedOrigins,
+	}
+}
+
+// Scan runs the rules enabled by policy over files. Findings are ordered by
+// file, then position.
+func (s *Scanner) Scan(ctx context.Context, files []ai.ArtifactFile, policy ai.SecurityPolicy) ([]ai.SecurityFinding, error) {
+	allowedOrigins := append(slices.Clone(s.allowedOrigins), policy.AllowedOrigins...)
+
+	var findings []ai.SecurityFinding
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var fileFindings []ai.SecurityFinding
+		for _, rule := range s.rules {
+			if !policy.RuleEnabled(rule.ID) {
+				continue
+			}
+			for _, m := range rule.Pattern.FindAllStringSubmatchIndex(f.Content, -1) {
+				submatches := make([]string, len(m)/2)
+				for i := range submatches {
+					if m[2*i] >= 0 {
+						submatches[i] = f.Content[m[2*i]:m[2*i+1]]
+					}
+				}
+				if rule.Allow != nil && rule.Allow(submatches, allowedOrigins) {
+					continue
+				}
+
+				line, column := ai.Position(f.Content, m[0])
+				fileFindings = append(fileFindings, ai.SecurityFinding{
+					Rule:     rule.ID,
+					Severity: policy.Severity(rule.ID, rule.Severity),
+					Message:  rule.Message,
+					Path:     f.Path,
+					Line:     line,
+					Column:   column,
+				})
+			}
+		}
+		slices.SortStableFunc(fileFindings, func(a, b ai.SecurityFinding) int {
+			if a.Line != b.Line {
+				return a.Line - b.Line
+			}
+			return a.Column - b.Column
+		})
+		findings = append(findings, fileFindings...)
+	}
+	return findings, nil
+}
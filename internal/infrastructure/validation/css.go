@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"strings"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// CSSValidator checks that comments, strings and blocks in CSS are closed and
+// that the innermost blocks hold "property: value" declarations. At-rules
+// such as Tailwind's @apply are accepted as declarations.
+type CSSValidator struct {
+	LineComments bool // Allow // comments, as in SCSS and Less
+}
+
+// cssBlock is an open curly brace
+type cssBlock struct {
+	offset int
+	nested bool // Holds other blocks, so holds rules rather than declarations
+}
+
+// Validate scans code as CSS
+func (v CSSValidator) Validate(code string) []ai.Diagnostic {
+	masked, diagnostics := v.mask(code)
+
+	var blocks []cssBlock
+	for i := 0; i < len(masked); i++ {
+		switch masked[i] {
+		case '{':
+			if len(blocks) > 0 {
+				blocks[len(blocks)-1].nested = true
+			}
+			blocks = append(blocks, cssBlock{offset: i})
+		case '}':
+			if len(blocks) == 0 {
+				diagnostics = append(diagnostics, cssDiagnostic(code, i, "unexpected-brace", "Unexpected }"))
+				continue
+			}
+			block := blocks[len(blocks)-1]
+			blocks = blocks[:len(blocks)-1]
+			if !block.nested {
+				diagnostics = append(diagnostics, checkDeclarations(code, masked, block.offset+1, i)...)
+			}
+		}
+	}
+	for _, block := range blocks {
+		diagnostics = append(diagnostics, cssDiagnostic(code, block.offset, "unclosed-block", "{ is never closed"))
+	}
+	return diagnostics
+}
+
+// mask blanks out comments and the contents of strings so that braces and
+// semicolons inside them are ignored, keeping offsets intact
+func (v CSSValidator) mask(code string) (string, []ai.Diagnostic) {
+	masked := []byte(code)
+	blank := func(from, to int) {
+		for j := from; j < to; j++ {
+			if masked[j] != '\n' {
+				masked[j] = ' '
+			}
+		}
+	}
+
+	for i := 0; i < len(code); i++ {
+		switch {
+		case strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return string(masked[:i]), []ai.Diagnostic{cssDiagnostic(code, i, "unterminated-comment", "Comment is never closed")}
+			}
+			blank(i, i+end+4)
+			i += end + 3
+		case v.LineComments && strings.HasPrefix(code[i:], "//") && (i == 0 || code[i-1] != ':'):
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				end = len(code) - i
+			}
+			blank(i, i+end)
+			i += end
+		case code[i] == '"' || code[i] == '\'':
+			end := i + 1
+			for end < len(code) && code[end] != code[i] && code[end] != '\n' {
+				if code[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(code) || code[end] != code[i] {
+				return string(masked[:i]), []ai.Diagnostic{cssDiagnostic(code, i, "unterminated-string", "String is never closed")}
+			}
+			blank(i+1, end)
+			i = end
+		}
+	}
+	return string(masked), nil
+}
+
+// checkDeclarations checks the declarations in masked[from:to]
+func checkDeclarations(code, masked string, from, to int) []ai.Diagnostic {
+	var diagnostics []ai.Diagnostic
+	for from < to {
+		end := strings.IndexByte(masked[from:to], ';')
+		if end < 0 {
+			end = to - from
+		}
+		declaration := masked[from : from+end]
+		trimmed := strings.TrimSpace(declaration)
+		start := from + strings.Index(declaration, trimmed)
+		from += end + 1
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "@") {
+			continue
+		}
+		property, value, ok := strings.Cut(trimmed, ":")
+		switch {
+		case !ok:
+			diagnostics = append(diagnostics, cssDiagnostic(code, start, "invalid-declaration", "Expected \"property: value\" but found \""+firstLine(trimmed)+"\""))
+		case strings.TrimSpace(property) == "":
+			diagnostics = append(diagnostics, cssDiagnostic(code, start, "invalid-declaration", "Declaration has no property"))
+		case strings.TrimSpace(value) == "":
+			diagnostics = append(diagnostics, cssDiagnostic(code, start, "invalid-declaration", "Declaration of "+strings.TrimSpace(property)+" has no value"))
+		}
+	}
+	return diagnostics
+}
+
+// cssDiagnostic creates an error diagnostic at offset
+func cssDiagnostic(code string, offset int, rule, message string) ai.Diagnostic {
+	line, column := ai.Position(code, offset)
+	return ai.Diagnostic{Line: line, Column: column, Severity: ai.SeverityError, Rule: rule, Message: message}
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
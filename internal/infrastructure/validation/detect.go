@@ -0,0 +1,61 @@
+package validation
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+var (
+	// goPattern matches a package clause or a top-level Go function
+	goPattern = regexp.MustCompile(`(?m)^(package\s+\w+\s*$|func\s+(\([^)]*\)\s*)?\w+\s*[\[(])`)
+	// pythonPattern matches a Python definition or import
+	pythonPattern = regexp.MustCompile(`(?m)^\s*((async\s+)?def\s+\w+\s*\(.*\)\s*(->.*)?:|class\s+\w+(\(.*\))?:|from\s+[\w.]+\s+import\s)`)
+	// cssPattern matches a CSS rule or at-rule at the start of the code
+	cssPattern = regexp.MustCompile(`^(@(media|import|tailwind|font-face|keyframes|layer|supports)\b|:root\s*\{|[\w.#*:\[\]="'\s,>+~-]+\{[^{}]*:[^{}]*\})`)
+	// scriptPattern matches JavaScript and TypeScript keywords and arrows
+	scriptPattern = regexp.MustCompile(`(^|[\s;({])(import|export|const|let|var|function|class|return|interface|type)\s|=>`)
+	// typeScriptPattern matches TypeScript-only syntax
+	typeScriptPattern = regexp.MustCompile(`(?m)(^|\s)(interface\s+\w+|type\s+\w+\s*=|enum\s+\w+)|\w\s*:\s*(string|number|boolean|any|unknown|React\.\w+)\b|\bas\s+const\b`)
+	// jsxPattern matches JSX attributes that plain HTML does not have
+	jsxPattern = regexp.MustCompile(`\bclassName=|=\{`)
+	// jsxElementPattern matches closing and self-closing JSX elements, which
+	// TypeScript generics and type assertions never look like
+	jsxElementPattern = regexp.MustCompile(`</[A-Za-z]|<[A-Za-z][\w.]*(\s[^<>]*)?/>`)
+)
+
+// Detect guesses the language of code and returns "" when it does not look
+// like code at all. Scripts are reported as typescript when they use
+// TypeScript syntax, or tsx when they also contain JSX, and as javascript
+// otherwise.
+func Detect(code string) string {
+	trimmed := strings.TrimSpace(code)
+	lower := strings.ToLower(trimmed)
+
+	switch {
+	case trimmed == "":
+		return ""
+	case (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)):
+		return "json"
+	case strings.HasPrefix(trimmed, `{"`):
+		return "json"
+	case goPattern.MatchString(trimmed):
+		return "go"
+	case pythonPattern.MatchString(trimmed):
+		return "python"
+	case strings.HasPrefix(lower, "<!doctype") || strings.HasPrefix(lower, "<html"):
+		return "html"
+	case strings.HasPrefix(trimmed, "<") && !jsxPattern.MatchString(trimmed):
+		return "html"
+	case cssPattern.MatchString(trimmed) && !scriptPattern.MatchString(trimmed):
+		return "css"
+	case typeScriptPattern.MatchString(trimmed):
+		if jsxPattern.MatchString(trimmed) || jsxElementPattern.MatchString(trimmed) {
+			return "tsx"
+		}
+		return "typescript"
+	case scriptPattern.MatchString(trimmed) || strings.HasPrefix(trimmed, "<"):
+		return "javascript"
+	}
+	return ""
+}
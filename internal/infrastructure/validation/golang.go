@@ -0,0 +1,60 @@
+package validation
+
+import (
+	"errors"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// snippetPackage is prepended to Go code that has no package clause
+const snippetPackage = "package main\n"
+
+// GoValidator checks Go syntax with go/parser. Snippets without a package
+// clause are parsed as part of package main.
+type GoValidator struct{}
+
+// Validate parses code as a Go source file
+func (GoValidator) Validate(code string) []ai.Diagnostic {
+	err := parseGo(code)
+	offset := 0
+	if err != nil && !hasPackageClause(code) {
+		err = parseGo(snippetPackage + code)
+		offset = 1
+	}
+
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return nil
+	}
+	diagnostics := make([]ai.Diagnostic, 0, len(list))
+	for _, e := range list {
+		diagnostics = append(diagnostics, ai.Diagnostic{
+			Line:     e.Pos.Line - offset,
+			Column:   e.Pos.Column,
+			Severity: ai.SeverityError,
+			Rule:     "syntax",
+			Message:  e.Msg,
+		})
+	}
+	return diagnostics
+}
+
+// parseGo parses src as a Go file
+func parseGo(src string) error {
+	_, err := parser.ParseFile(token.NewFileSet(), "main.go", src, parser.AllErrors)
+	return err
+}
+
+// hasPackageClause reports whether the first token of code, after any
+// comments, is the package keyword
+func hasPackageClause(code string) bool {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("main.go", -1, len(code))
+	s.Init(file, []byte(code), nil, 0)
+	_, tok, _ := s.Scan()
+	return tok == token.PACKAGE
+}
@@ -0,0 +1,120 @@
+package validation
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// voidElements never have a closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// optionalEndTags may be left open; browsers close them implicitly
+var optionalEndTags = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true,
+	"dt": true, "dd": true, "option": true, "optgroup": true, "tr": true,
+	"td": true, "th": true, "thead": true, "tbody": true, "tfoot": true,
+	"colgroup": true, "caption": true, "rt": true, "rp": true,
+}
+
+// openTag is an element waiting for its closing tag
+type openTag struct {
+	name         string
+	line, column int
+}
+
+// HTMLValidator checks that HTML elements are properly nested and closed and
+// that no element repeats an attribute
+type HTMLValidator struct{}
+
+// Validate tokenizes code as HTML
+func (HTMLValidator) Validate(code string) []ai.Diagnostic {
+	var diagnostics []ai.Diagnostic
+	var open []openTag
+
+	z := html.NewTokenizer(strings.NewReader(code))
+	offset := 0
+	for {
+		tt := z.Next()
+		line, column := ai.Position(code, offset)
+		offset += len(z.Raw())
+
+		switch tt {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				diagnostics = append(diagnostics, ai.Diagnostic{
+					Line: line, Column: column, Severity: ai.SeverityError, Rule: "syntax", Message: err.Error(),
+				})
+			}
+			for _, t := range open {
+				if !optionalEndTags[t.name] {
+					diagnostics = append(diagnostics, ai.Diagnostic{
+						Line: t.line, Column: t.column, Severity: ai.SeverityError, Rule: "unclosed-tag",
+						Message: "<" + t.name + "> is never closed",
+					})
+				}
+			}
+			return diagnostics
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			diagnostics = append(diagnostics, duplicateAttributes(z, hasAttr, tag, line, column)...)
+			if tt == html.StartTagToken && !voidElements[tag] {
+				open = append(open, openTag{name: tag, line: line, column: column})
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if voidElements[tag] {
+				continue
+			}
+			i := len(open) - 1
+			for i >= 0 && open[i].name != tag {
+				i--
+			}
+			if i < 0 {
+				diagnostics = append(diagnostics, ai.Diagnostic{
+					Line: line, Column: column, Severity: ai.SeverityError, Rule: "unexpected-end-tag",
+					Message: "Unexpected closing tag </" + tag + ">",
+				})
+				continue
+			}
+			for _, t := range open[i+1:] {
+				if !optionalEndTags[t.name] {
+					diagnostics = append(diagnostics, ai.Diagnostic{
+						Line: t.line, Column: t.column, Severity: ai.SeverityError, Rule: "unclosed-tag",
+						Message: "<" + t.name + "> is not closed before </" + tag + ">",
+					})
+				}
+			}
+			open = open[:i]
+		}
+	}
+}
+
+// duplicateAttributes reports attributes repeated on the current tag
+func duplicateAttributes(z *html.Tokenizer, hasAttr bool, tag string, line, column int) []ai.Diagnostic {
+	var diagnostics []ai.Diagnostic
+	seen := make(map[string]bool)
+	for hasAttr {
+		var key []byte
+		key, _, hasAttr = z.TagAttr()
+		if seen[string(key)] {
+			diagnostics = append(diagnostics, ai.Diagnostic{
+				Line: line, Column: column, Severity: ai.SeverityWarning, Rule: "duplicate-attribute",
+				Message: "<" + tag + "> repeats the " + string(key) + " attribute",
+			})
+		}
+		seen[string(key)] = true
+	}
+	return diagnostics
+}
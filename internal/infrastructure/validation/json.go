@@ -0,0 +1,32 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// JSONValidator checks that code is a single well-formed JSON document
+type JSONValidator struct{}
+
+// Validate parses code as JSON
+func (JSONValidator) Validate(code string) []ai.Diagnostic {
+	var v any
+	err := json.Unmarshal([]byte(code), &v)
+	if err == nil {
+		return nil
+	}
+
+	diagnostic := ai.Diagnostic{
+		Severity: ai.SeverityError,
+		Rule:     "syntax",
+		Message:  err.Error(),
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// Offset counts the bytes read, including the offending one
+		diagnostic.Line, diagnostic.Column = ai.Position(code, int(syntaxErr.Offset)-1)
+	}
+	return []ai.Diagnostic{diagnostic}
+}
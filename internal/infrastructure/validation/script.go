@@ -0,0 +1,44 @@
+package validation
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// ScriptValidator checks JavaScript and TypeScript syntax, including JSX,
+// with the esbuild parser
+type ScriptValidator struct {
+	Loader api.Loader // api.LoaderJSX, api.LoaderTS or api.LoaderTSX
+}
+
+// Validate parses code with esbuild. Errors and warnings keep the esbuild
+// message ID as their rule when there is one.
+func (v ScriptValidator) Validate(code string) []ai.Diagnostic {
+	result := api.Transform(code, api.TransformOptions{
+		Loader:   v.Loader,
+		LogLevel: api.LogLevelSilent,
+	})
+
+	diagnostics := make([]ai.Diagnostic, 0, len(result.Errors)+len(result.Warnings))
+	for _, m := range result.Errors {
+		diagnostics = append(diagnostics, scriptDiagnostic(m, ai.SeverityError))
+	}
+	for _, m := range result.Warnings {
+		diagnostics = append(diagnostics, scriptDiagnostic(m, ai.SeverityWarning))
+	}
+	return diagnostics
+}
+
+// scriptDiagnostic converts an esbuild message
+func scriptDiagnostic(m api.Message, severity ai.DiagnosticSeverity) ai.Diagnostic {
+	d := ai.Diagnostic{Severity: severity, Rule: "syntax", Message: m.Text}
+	if m.ID != "" {
+		d.Rule = m.ID
+	}
+	if m.Location != nil {
+		d.Line = m.Location.Line
+		d.Column = m.Location.Column + 1
+	}
+	return d
+}
@@ -0,0 +1,91 @@
+// Package validation checks generated code with one validator per language
+package validation
+
+import (
+	"context"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// Validator finds problems in code of a single language. Positions are
+// 1-based, with columns counted in bytes.
+type Validator interface {
+	Validate(code string) []ai.Diagnostic
+}
+
+// languageAliases maps alternative language names to the ones validators
+// are registered under
+var languageAliases = map[string]string{
+	"golang": "go",
+	"js":     "javascript",
+	"mjs":    "javascript",
+	"ts":     "typescript",
+	"htm":    "html",
+}
+
+// Service implements ai.CodeValidator by dispatching to the validator for
+// the language of the code
+type Service struct {
+	validators map[string]Validator
+}
+
+// NewService creates a new Service with validators for Go, JSON, HTML, CSS,
+// SCSS, JavaScript, TypeScript, JSX and TSX
+func NewService() *Service {
+	jsx := ScriptValidator{Loader: api.LoaderJSX}
+
+	return &Service{
+		validators: map[string]Validator{
+			"go":         GoValidator{},
+			"json":       JSONValidator{},
+			"html":       HTMLValidator{},
+			"css":        CSSValidator{},
+			"scss":       CSSValidator{LineComments: true},
+			"javascript": jsx,
+			"jsx":        jsx,
+			"typescript": ScriptValidator{Loader: api.LoaderTS},
+			"tsx":        ScriptValidator{Loader: api.LoaderTSX},
+		},
+	}
+}
+
+// Validate checks code written in language, detecting the language when it
+// is empty. Code in a language without a validator is reported valid with an
+// informational diagnostic.
+func (s *Service) Validate(ctx context.Context, code, language string) (ai.ValidationResult, error) {
+	if strings.TrimSpace(code) == "" {
+		return ai.NewValidationResult(language, []ai.Diagnostic{{
+			Severity: ai.SeverityError,
+			Rule:     "empty",
+			Message:  "Code is empty",
+		}}), nil
+	}
+
+	language = strings.ToLower(strings.TrimSpace(language))
+	if alias, ok := languageAliases[language]; ok {
+		language = alias
+	}
+	if language == "" {
+		language = Detect(code)
+	}
+	if language == "" {
+		return ai.NewValidationResult("", []ai.Diagnostic{{
+			Severity: ai.SeverityError,
+			Rule:     "unrecognized",
+			Message:  "Code does not look like any supported language",
+		}}), nil
+	}
+
+	validator, ok := s.validators[language]
+	if !ok {
+		return ai.NewValidationResult(language, []ai.Diagnostic{{
+			Severity: ai.SeverityInfo,
+			Rule:     "unsupported-language",
+			Message:  "No validator for " + language + "; syntax was not checked",
+		}}), nil
+	}
+	return ai.NewValidationResult(language, validator.Validate(code)), nil
+}
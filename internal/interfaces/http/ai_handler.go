@@ -9,25 +9,45 @@ import (
 	"github.com/EliasRanz/ai-code-gen/internal/application/ai"
 	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
 	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/observability"
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
 )
 
 // AIHandler handles HTTP requests for AI operations
 type AIHandler struct {
 	generateCodeUC *ai.GenerateCodeUseCase
 	streamCodeUC   *ai.StreamCodeUseCase
+	tracker        *ai.GenerationTracker
+	jobs           *ai.GenerationJobService
+	history        *ai.GenerationHistoryService
+	refineCodeUC   *ai.RefineCodeUseCase
+	quota          *ai.QuotaService
 	logger         observability.Logger
 }
 
+// AIHandlerDeps holds the dependencies of an AIHandler. The endpoints of
+// optional services that are left out respond with an error.
+type AIHandlerDeps struct {
+	GenerateCode *ai.GenerateCodeUseCase
+	StreamCode   *ai.StreamCodeUseCase
+	Tracker      *ai.GenerationTracker        // Cancels running generations
+	Jobs         *ai.GenerationJobService     // Queues generations for asynchronous processing
+	History      *ai.GenerationHistoryService // Serves the generation history
+	RefineCode   *ai.RefineCodeUseCase        // Refines previous generations
+	Quota        *ai.QuotaService             // Reports the plan quota of users
+	Logger       observability.Logger
+}
+
 // NewAIHandler creates a new AI handler
-func NewAIHandler(
-	generateCodeUC *ai.GenerateCodeUseCase,
-	streamCodeUC *ai.StreamCodeUseCase,
-	logger observability.Logger,
-) *AIHandler {
+func NewAIHandler(deps AIHandlerDeps) *AIHandler {
 	return &AIHandler{
-		generateCodeUC: generateCodeUC,
-		streamCodeUC:   streamCodeUC,
-		logger:         logger,
+		generateCodeUC: deps.GenerateCode,
+		streamCodeUC:   deps.StreamCode,
+		tracker:        deps.Tracker,
+		jobs:           deps.Jobs,
+		history:        deps.History,
+		refineCodeUC:   deps.RefineCode,
+		quota:          deps.Quota,
+		logger:         deps.Logger,
 	}
 }
 
@@ -41,6 +61,13 @@ func (h *AIHandler) GenerateCode(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	// Generations are billed to the authenticated user, never the body's
+	req.UserID = authenticatedUserID(c)
+
+	if c.Query("async") == "true" {
+		h.submitGenerationJob(c, req)
+		return
+	}
 
 	resp, err := h.generateCodeUC.Execute(c.Request.Context(), req)
 	if err != nil {
@@ -66,6 +93,8 @@ func (h *AIHandler) StreamCode(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	// Generations are billed to the authenticated user, never the body's
+	req.UserID = authenticatedUserID(c)
 
 	// Set headers for Server-Sent Events
 	c.Header("Content-Type", "text/event-stream")
@@ -115,11 +144,200 @@ func (h *AIHandler) StreamCode(c *gin.Context) {
 			}
 
 		case <-c.Request.Context().Done():
-			// Client disconnected
+			// Client disconnected; drain the remaining responses so the use
+			// case can record the generation as cancelled
 			h.logger.Info("Client disconnected during streaming")
+			go func() {
+				for range responseChan {
+				}
+			}()
+			return
+		}
+	}
+}
+
+// submitGenerationJob queues a generation and responds with its job
+func (h *AIHandler) submitGenerationJob(c *gin.Context, req ai.GenerateCodeRequest) {
+	if h.jobs == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Asynchronous generation is not enabled"})
+		return
+	}
+
+	job, err := h.jobs.Submit(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	h.logger.Info("Generation job queued", map[string]interface{}{
+		"job_id":   job.ID,
+		"model":    job.Model,
+		"priority": job.Priority,
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetGenerationJob handles GET /ai/jobs/:id
+func (h *AIHandler) GetGenerationJob(c *gin.Context) {
+	if h.jobs == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+
+	job, err := h.jobs.Get(c.Request.Context(), authenticatedUserID(c), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// ListGenerations handles GET /ai/generations
+func (h *AIHandler) ListGenerations(c *gin.Context) {
+	if h.history == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Generation history is not enabled"})
+		return
+	}
+
+	var req ai.ListGenerationsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	resp, err := h.history.List(c.Request.Context(), authenticatedUserID(c), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetGeneration handles GET /ai/generations/:id
+func (h *AIHandler) GetGeneration(c *gin.Context) {
+	if h.history == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "generation not found"})
+		return
+	}
+
+	resp, err := h.history.Get(c.Request.Context(), authenticatedUserID(c), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RefineGeneration handles POST /ai/generations/:id/refine
+func (h *AIHandler) RefineGeneration(c *gin.Context) {
+	if h.refineCodeUC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Refinement is not enabled"})
+		return
+	}
+
+	var req ai.RefineCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid refine request", map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	req.ParentID = c.Param("id")
+	req.UserID = authenticatedUserID(c)
+
+	resp, err := h.refineCodeUC.Execute(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	h.logger.Info("Generation refined", map[string]interface{}{
+		"parent_id":     req.ParentID,
+		"generation_id": resp.GenerationID,
+	})
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListGenerationRevisions handles GET /ai/generations/:id/revisions
+func (h *AIHandler) ListGenerationRevisions(c *gin.Context) {
+	if h.history == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "generation not found"})
+		return
+	}
+
+	resp, err := h.history.Revisions(c.Request.Context(), authenticatedUserID(c), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetQuota handles GET /ai/quota
+func (h *AIHandler) GetQuota(c *gin.Context) {
+	if h.quota == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quotas are not enabled"})
+		return
+	}
+
+	resp, err := h.quota.GetQuota(c.Request.Context(), authenticatedUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteGeneration handles DELETE /ai/generations/:id. A running generation is
+// cancelled wherever it runs; a finished one is removed from history.
+func (h *AIHandler) DeleteGeneration(c *gin.Context) {
+	userID, generationID := authenticatedUserID(c), c.Param("id")
+
+	if h.tracker != nil {
+		err := h.tracker.Cancel(c.Request.Context(), userID, generationID)
+		if err == nil {
+			h.logger.Info("Generation cancellation requested", map[string]interface{}{
+				"generation_id": generationID,
+			})
+			c.JSON(http.StatusAccepted, gin.H{"id": generationID, "status": "cancelling"})
+			return
+		}
+		// Finished or untracked generations are deleted from history instead
+		if !common.IsConflictError(err) && !common.IsNotFoundError(err) {
+			h.handleError(c, err)
 			return
 		}
 	}
+
+	if h.history == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "generation not found"})
+		return
+	}
+	if err := h.history.Delete(c.Request.Context(), userID, generationID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	h.logger.Info("Generation deleted from history", map[string]interface{}{
+		"generation_id": generationID,
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// authenticatedUserID returns the user ID set by the auth middleware
+func authenticatedUserID(c *gin.Context) common.UserID {
+	value, _ := c.Get("user_id")
+	userID, _ := value.(common.UserID)
+	return userID
 }
 
 // handleError handles different types of domain errors
@@ -129,7 +347,8 @@ func (h *AIHandler) handleError(c *gin.Context, err error) {
 		"method": c.Request.Method,
 	})
 
-	if common.IsValidationError(err) {
+	// Validation errors, including parameters the model does not accept
+	if common.IsValidationError(err) || llm.IsInvalidRequest(err) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -139,6 +358,11 @@ func (h *AIHandler) handleError(c *gin.Context, err error) {
 		return
 	}
 
+	if common.IsConflictError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Rate limiting or quota exceeded
 	if err.Error() == "rate_limit_exceeded" || err.Error() == "quota_exceeded" {
 		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
@@ -21,6 +21,7 @@ type Router struct {
 	aiHandler     *AIHandler
 	logger        observability.Logger
 	tokenProvider auth.TokenProvider
+	rateLimit     gin.HandlerFunc
 }
 
 // NewRouter creates a new HTTP router
@@ -30,11 +31,39 @@ func NewRouter(
 	aiHandler *AIHandler,
 	tokenProvider auth.TokenProvider,
 	logger observability.Logger,
+) *Router {
+	return newRouter(userHandler, authHandler, aiHandler, tokenProvider, nil, logger)
+}
+
+// NewRouterWithRateLimit creates a new HTTP router that runs rateLimit on
+// every API request, after authentication on protected routes
+func NewRouterWithRateLimit(
+	userHandler *UserHandler,
+	authHandler *AuthHandler,
+	aiHandler *AIHandler,
+	tokenProvider auth.TokenProvider,
+	rateLimit gin.HandlerFunc,
+	logger observability.Logger,
+) *Router {
+	return newRouter(userHandler, authHandler, aiHandler, tokenProvider, rateLimit, logger)
+}
+
+// newRouter creates a new HTTP router, with rateLimit if not nil
+func newRouter(
+	userHandler *UserHandler,
+	authHandler *AuthHandler,
+	aiHandler *AIHandler,
+	tokenProvider auth.TokenProvider,
+	rateLimit gin.HandlerFunc,
+	logger observability.Logger,
 ) *Router {
 	// Set gin mode based on environment
 	gin.SetMode(gin.ReleaseMode)
 
 	engine := gin.New()
+	// Trust no proxy headers so clients cannot pick the IP they are rate
+	// limited by. Deployments behind a proxy set theirs through Engine.
+	_ = engine.SetTrustedProxies(nil)
 
 	// Add middleware
 	engine.Use(gin.Recovery())
@@ -47,6 +76,7 @@ func NewRouter(
 		authHandler:   authHandler,
 		aiHandler:     aiHandler,
 		tokenProvider: tokenProvider,
+		rateLimit:     rateLimit,
 		logger:        logger,
 	}
 
@@ -64,6 +94,9 @@ func (r *Router) setupRoutes() {
 
 	// Public auth routes
 	auth := v1.Group("/auth")
+	if r.rateLimit != nil {
+		auth.Use(r.rateLimit)
+	}
 	{
 		auth.POST("/login", r.authHandler.Login)
 		auth.POST("/refresh", r.authHandler.RefreshToken)
@@ -72,6 +105,9 @@ func (r *Router) setupRoutes() {
 	// Protected routes (require authentication)
 	protected := v1.Group("/")
 	protected.Use(r.authMiddleware())
+	if r.rateLimit != nil {
+		protected.Use(r.rateLimit)
+	}
 	{
 		// Auth routes
 		protected.POST("/auth/logout", r.authHandler.Logout)
@@ -91,6 +127,13 @@ func (r *Router) setupRoutes() {
 		{
 			ai.POST("/generate", r.aiHandler.GenerateCode)
 			ai.POST("/stream", r.aiHandler.StreamCode)
+			ai.GET("/generations", r.aiHandler.ListGenerations)
+			ai.GET("/generations/:id", r.aiHandler.GetGeneration)
+			ai.DELETE("/generations/:id", r.aiHandler.DeleteGeneration)
+			ai.POST("/generations/:id/refine", r.aiHandler.RefineGeneration)
+			ai.GET("/generations/:id/revisions", r.aiHandler.ListGenerationRevisions)
+			ai.GET("/jobs/:id", r.aiHandler.GetGenerationJob)
+			ai.GET("/quota", r.aiHandler.GetQuota)
 		}
 	}
 }
@@ -115,7 +158,7 @@ func corsMiddleware() gin.HandlerFunc {
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:8080"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
@@ -0,0 +1,574 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// AnthropicVersion is the Messages API version sent with every request
+const AnthropicVersion = "2023-06-01"
+
+// AnthropicClient implements LLMClient for the Anthropic Messages API
+type AnthropicClient struct {
+	baseURL          string
+	apiKey           string
+	defaultMaxTokens int
+	healthTimeout    time.Duration
+	params           ParamAllowList
+	httpClient       HTTPClientInterface
+	client           *ResilientClient
+	models           *modelCache
+}
+
+// AnthropicConfig holds configuration for the Anthropic client
+type AnthropicConfig struct {
+	BaseURL          string               `json:"base_url"`
+	APIKey           string               `json:"api_key"`
+	Timeout          time.Duration        `json:"timeout"`
+	MaxRetries       int                  `json:"max_retries"`
+	RetryBaseDelay   time.Duration        `json:"retry_base_delay"`
+	RetryMaxDelay    time.Duration        `json:"retry_max_delay"`
+	CircuitBreaker   CircuitBreakerConfig `json:"circuit_breaker"`
+	ModelsCacheTTL   time.Duration        `json:"models_cache_ttl"`
+	HealthTimeout    time.Duration        `json:"health_timeout"`
+	DefaultMaxTokens int                  `json:"default_max_tokens"` // Sent when a request sets no max_tokens, which the API requires
+	Params           ParamAllowList       `json:"params"`
+	HTTPClient       HTTPClientInterface  `json:"-"` // Overrides the default transport, e.g. with a Cassette in tests
+}
+
+// anthropicRequest represents a request to the Messages API
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Metadata      *anthropicMetadata `json:"metadata,omitempty"`
+}
+
+// anthropicMessage represents a conversation turn
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// anthropicMetadata identifies the end user to Anthropic for abuse detection
+type anthropicMetadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+// anthropicResponse represents a Messages API response, also sent in message_start events
+type anthropicResponse struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []anthropicContentBlock `json:"content"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        anthropicUsage          `json:"usage"`
+}
+
+// anthropicContentBlock represents a block of generated content
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// anthropicUsage represents token usage reported by Anthropic
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicError represents the error body returned by Anthropic
+type anthropicError struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicStreamEvent represents any event of a Messages API stream
+type anthropicStreamEvent struct {
+	Type    string             `json:"type"`
+	Message *anthropicResponse `json:"message,omitempty"` // message_start
+	Index   int                `json:"index"`
+	Delta   *struct {
+		Type         string  `json:"type"`
+		Text         string  `json:"text,omitempty"`          // content_block_delta
+		StopReason   *string `json:"stop_reason,omitempty"`   // message_delta
+		StopSequence *string `json:"stop_sequence,omitempty"` // message_delta
+	} `json:"delta,omitempty"`
+	Usage *anthropicUsage `json:"usage,omitempty"` // message_delta
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"` // error
+}
+
+// anthropicModelList represents the response of GET /v1/models
+type anthropicModelList struct {
+	Data []struct {
+		ID          string    `json:"id"`
+		DisplayName string    `json:"display_name"`
+		CreatedAt   time.Time `json:"created_at"`
+	} `json:"data"`
+}
+
+// NewAnthropicClient creates a new Anthropic client
+func NewAnthropicClient(config *AnthropicConfig) *AnthropicClient {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.anthropic.com"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 120 * time.Second
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.ModelsCacheTTL == 0 {
+		config.ModelsCacheTTL = 5 * time.Minute
+	}
+	if config.HealthTimeout == 0 {
+		config.HealthTimeout = 5 * time.Second
+	}
+	if config.DefaultMaxTokens == 0 {
+		config.DefaultMaxTokens = 4096
+	}
+	if config.Params == nil {
+		config.Params = DefaultParamAllowList()
+	}
+
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = config.MaxRetries
+	if config.RetryBaseDelay > 0 {
+		policy.BaseDelay = config.RetryBaseDelay
+	}
+	if config.RetryMaxDelay > 0 {
+		policy.MaxDelay = config.RetryMaxDelay
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = NewDefaultHTTPClient(config.Timeout)
+	}
+
+	return &AnthropicClient{
+		baseURL:          strings.TrimRight(config.BaseURL, "/"),
+		apiKey:           config.APIKey,
+		defaultMaxTokens: config.DefaultMaxTokens,
+		healthTimeout:    config.HealthTimeout,
+		params:           config.Params,
+		httpClient:       httpClient,
+		client: NewResilientClient(
+			httpClient,
+			policy,
+			NewCircuitBreaker("Anthropic", config.CircuitBreaker),
+		),
+		models: newModelCache(config.ModelsCacheTTL),
+	}
+}
+
+// Generate performs a single generation request
+func (c *AnthropicClient) Generate(ctx context.Context, req *GenerationRequest) (*GenerationResponse, error) {
+	log.Info().
+		Str("model", req.Model).
+		Int("messages", len(req.Messages)).
+		Int("max_tokens", req.MaxTokens).
+		Msg("Anthropic Generate request")
+
+	body, err := c.buildRequestBody(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, "POST", "/v1/messages", body)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, newAnthropicHTTPError(httpResp)
+	}
+
+	var resp anthropicResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return resp.toGenerationResponse(), nil
+}
+
+// GenerateStream performs a streaming generation request. The channel carries
+// one response per text delta, followed by exactly one response with the
+// finish reason and usage, or by a response carrying an error.
+func (c *AnthropicClient) GenerateStream(ctx context.Context, req *GenerationRequest) (<-chan *GenerationResponse, error) {
+	log.Info().
+		Str("model", req.Model).
+		Int("messages", len(req.Messages)).
+		Msg("Anthropic GenerateStream request")
+
+	body, err := c.buildRequestBody(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retries only apply until the stream is established
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		httpReq, err := c.newRequest(ctx, "POST", "/v1/messages", body)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Accept", "text/event-stream")
+		return httpReq, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		defer httpResp.Body.Close()
+		return nil, newAnthropicHTTPError(httpResp)
+	}
+
+	responseChan := make(chan *GenerationResponse, 10)
+	go c.processStreamResponse(ctx, httpResp.Body, responseChan)
+
+	return responseChan, nil
+}
+
+// processStreamResponse converts Messages API stream events into responses
+func (c *AnthropicClient) processStreamResponse(ctx context.Context, body io.ReadCloser, responseChan chan<- *GenerationResponse) {
+	defer body.Close()
+	defer close(responseChan)
+
+	send := func(resp *GenerationResponse) bool {
+		select {
+		case responseChan <- resp:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var id, model string
+	var usage anthropicUsage
+	var stopReason *string
+
+	reader := NewSSEReader(body)
+	for {
+		event, err := reader.Next()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			llmErr := &LLMError{Code: "incomplete_stream", Message: "stream ended before message_stop"}
+			if err != io.EOF {
+				log.Error().Err(err).Msg("Failed to read Anthropic stream")
+				llmErr = &LLMError{Code: "stream_read_error", Message: "failed to read stream", Details: err.Error()}
+			}
+			send(&GenerationResponse{ID: id, Model: model, Error: llmErr})
+			return
+		}
+
+		var streamEvent anthropicStreamEvent
+		if err := json.Unmarshal([]byte(event.Data), &streamEvent); err != nil {
+			log.Error().Err(err).Str("data", truncateString(event.Data, 200)).Msg("Failed to decode Anthropic stream event")
+			send(&GenerationResponse{
+				ID:    id,
+				Model: model,
+				Error: &LLMError{Code: "invalid_stream_chunk", Message: "failed to decode stream event", Details: err.Error()},
+			})
+			return
+		}
+
+		switch streamEvent.Type {
+		case "message_start":
+			if streamEvent.Message != nil {
+				id = streamEvent.Message.ID
+				model = streamEvent.Message.Model
+				usage = streamEvent.Message.Usage
+			}
+
+		case "content_block_delta":
+			if streamEvent.Delta == nil || streamEvent.Delta.Text == "" {
+				continue
+			}
+			if !send(&GenerationResponse{
+				ID:      id,
+				Object:  "chat.completion.chunk",
+				Model:   model,
+				Choices: []Choice{{Index: 0, Delta: &Delta{Content: streamEvent.Delta.Text}}},
+			}) {
+				return
+			}
+
+		case "message_delta":
+			if streamEvent.Delta != nil && streamEvent.Delta.StopReason != nil {
+				stopReason = streamEvent.Delta.StopReason
+			}
+			if streamEvent.Usage != nil {
+				// Output tokens are cumulative in message_delta events
+				usage.OutputTokens = streamEvent.Usage.OutputTokens
+			}
+
+		case "message_stop":
+			send(&GenerationResponse{
+				ID:     id,
+				Object: "chat.completion.chunk",
+				Model:  model,
+				Choices: []Choice{{
+					Index:        0,
+					Delta:        &Delta{},
+					FinishReason: finishReason(stopReason),
+				}},
+				Usage: usage.toUsage(),
+			})
+			return
+
+		case "error":
+			llmErr := &LLMError{Code: "stream_error", Message: event.Data}
+			if streamEvent.Error != nil {
+				llmErr = &LLMError{Code: streamEvent.Error.Type, Message: streamEvent.Error.Message}
+			}
+			log.Warn().Str("code", llmErr.Code).Str("message", llmErr.Message).Msg("Anthropic stream reported an error")
+			send(&GenerationResponse{ID: id, Model: model, Error: llmErr})
+			return
+		}
+		// ping, content_block_start and content_block_stop carry nothing we forward
+	}
+}
+
+// buildRequestBody converts req into a Messages API payload. System messages
+// are moved into the top-level system prompt, as the API requires.
+func (c *AnthropicClient) buildRequestBody(req *GenerationRequest, stream bool) ([]byte, error) {
+	if err := req.ValidateMessages(); err != nil {
+		return nil, err
+	}
+	if err := c.params.Validate(req.Model, req.SamplingParams()); err != nil {
+		return nil, err
+	}
+
+	payload := &anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     req.MaxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+		Stream:        stream,
+	}
+	if payload.MaxTokens == 0 {
+		payload.MaxTokens = c.defaultMaxTokens
+	}
+	if req.UserID != "" {
+		payload.Metadata = &anthropicMetadata{UserID: req.UserID}
+	}
+
+	if !req.IsChat() {
+		payload.Messages = []anthropicMessage{{Role: RoleUser, Content: req.Prompt}}
+	} else {
+		var system []string
+		for _, msg := range req.Messages {
+			if msg.Role == RoleSystem {
+				system = append(system, msg.Content)
+				continue
+			}
+			payload.Messages = append(payload.Messages, anthropicMessage{Role: msg.Role, Content: msg.Content})
+		}
+		payload.System = strings.Join(system, "\n\n")
+	}
+
+	if len(payload.Messages) == 0 {
+		return nil, &LLMError{Code: "invalid_request", Message: "no user message", Details: "Anthropic requires at least one user or assistant message"}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return body, nil
+}
+
+// newRequest builds an HTTP request to the Anthropic API with a fresh body reader
+func (c *AnthropicClient) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", AnthropicVersion)
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	return httpReq, nil
+}
+
+// GetModels returns the models available to the API key. Results are cached
+// for the configured TTL; a stale list is served if a refresh fails.
+func (c *AnthropicClient) GetModels(ctx context.Context) ([]Model, error) {
+	if models, ok := c.models.get(); ok {
+		return models, nil
+	}
+
+	models, err := c.fetchModels(ctx)
+	if err != nil {
+		if stale, ok := c.models.stale(); ok {
+			log.Warn().Err(err).Msg("Failed to refresh Anthropic models, serving cached list")
+			return stale, nil
+		}
+		return nil, err
+	}
+
+	c.models.set(models)
+	return models, nil
+}
+
+// fetchModels lists the models from GET /v1/models
+func (c *AnthropicClient) fetchModels(ctx context.Context) ([]Model, error) {
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, "GET", "/v1/models", nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, newAnthropicHTTPError(httpResp)
+	}
+
+	var list anthropicModelList
+	if err := json.NewDecoder(httpResp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+
+	models := make([]Model, len(list.Data))
+	for i, m := range list.Data {
+		models[i] = Model{
+			ID:        m.ID,
+			Name:      m.DisplayName,
+			Provider:  "Anthropic",
+			CreatedAt: m.CreatedAt,
+		}
+		if params, ok := c.params.Lookup(m.ID); ok {
+			models[i].MaxTokens = params.MaxTokens
+		}
+	}
+	return models, nil
+}
+
+// Health checks that the API is reachable and the key is accepted.
+// Anthropic has no health endpoint, so the models list is probed instead.
+func (c *AnthropicClient) Health(ctx context.Context) error {
+	if err := c.client.Health(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.healthTimeout)
+	defer cancel()
+
+	httpReq, err := c.newRequest(ctx, "GET", "/v1/models?limit=1", nil)
+	if err != nil {
+		return err
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return &LLMError{Code: "unreachable", Message: "Anthropic health check failed", Details: err.Error()}
+	}
+	defer drainAndClose(httpResp.Body)
+
+	if httpResp.StatusCode != http.StatusOK {
+		return newAnthropicHTTPError(httpResp)
+	}
+	return nil
+}
+
+// Close closes the AnthropicClient connection
+func (c *AnthropicClient) Close() error {
+	return nil
+}
+
+// toGenerationResponse converts a Messages API response into the standard response format
+func (r *anthropicResponse) toGenerationResponse() *GenerationResponse {
+	var text strings.Builder
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	return &GenerationResponse{
+		ID:     r.ID,
+		Object: "chat.completion",
+		Model:  r.Model,
+		Choices: []Choice{{
+			Index:        0,
+			Message:      &Message{Role: RoleAssistant, Content: text.String()},
+			FinishReason: finishReason(r.StopReason),
+		}},
+		Usage: r.Usage.toUsage(),
+	}
+}
+
+// toUsage converts Anthropic usage into the standard format
+func (u anthropicUsage) toUsage() *Usage {
+	return &Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+	}
+}
+
+// finishReason maps an Anthropic stop reason to the OpenAI finish reason
+func finishReason(stopReason *string) *string {
+	if stopReason == nil {
+		return nil
+	}
+
+	switch *stopReason {
+	case "end_turn", "stop_sequence":
+		return stringPtr("stop")
+	case "max_tokens":
+		return stringPtr("length")
+	case "tool_use":
+		return stringPtr("tool_calls")
+	}
+	return stopReason
+}
+
+// newAnthropicHTTPError builds an LLMError for a non-200 response, keeping
+// Anthropic's error type (e.g. "overloaded_error") as the code
+func newAnthropicHTTPError(resp *http.Response) *LLMError {
+	body, _ := io.ReadAll(resp.Body)
+	llmErr := NewHTTPError("Anthropic", resp.StatusCode, body)
+
+	var apiErr anthropicError
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error.Type != "" {
+		llmErr.Code = apiErr.Error.Type
+		llmErr.Details = apiErr.Error.Message
+	}
+	return llmErr
+}
@@ -0,0 +1,372 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CassetteMode selects whether a cassette records or replays HTTP exchanges
+type CassetteMode string
+
+const (
+	// CassetteReplay serves recorded responses and never touches the network
+	CassetteReplay CassetteMode = "replay"
+	// CassetteRecord forwards requests to the provider and records the exchanges
+	CassetteRecord CassetteMode = "record"
+)
+
+// cassetteVersion is the version of the cassette file format
+const cassetteVersion = 1
+
+// CassetteConfig holds configuration for a cassette
+type CassetteConfig struct {
+	Path         string              `json:"path"`
+	Mode         CassetteMode        `json:"mode"`
+	Upstream     HTTPClientInterface `json:"-"`             // Sends requests while recording
+	Realtime     bool                `json:"realtime"`      // Replay with the recorded chunk timing instead of as fast as possible
+	IgnoreFields []string            `json:"ignore_fields"` // Top-level JSON body fields left out of the request hash
+}
+
+// DefaultCassetteIgnoreFields returns the request body fields that identify
+// the caller rather than the generation, and so do not affect matching
+func DefaultCassetteIgnoreFields() []string {
+	return []string{"user", "metadata"}
+}
+
+// CassetteInteraction is one recorded request and the response it received
+type CassetteInteraction struct {
+	Hash     string           `json:"hash"`
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest describes a recorded request. Headers are never recorded,
+// so API keys do not end up in cassette files.
+type CassetteRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"` // Path and query; the host is not part of the match
+	Body   string `json:"body,omitempty"`
+}
+
+// CassetteResponse holds a recorded response body as the chunks it arrived in
+type CassetteResponse struct {
+	StatusCode int               `json:"status_code"`
+	Header     map[string]string `json:"header,omitempty"`
+	Chunks     []CassetteChunk   `json:"chunks"`
+}
+
+// CassetteChunk is one read of a response body and the delay before it
+type CassetteChunk struct {
+	DelayMS int64  `json:"delay_ms"`
+	Data    string `json:"data"`
+}
+
+// cassetteFile is the on-disk representation of a cassette
+type cassetteFile struct {
+	Version      int                    `json:"version"`
+	Interactions []*CassetteInteraction `json:"interactions"`
+}
+
+// Cassette implements HTTPClientInterface by recording provider HTTP exchanges
+// to a file, or by replaying them from one. Passing a cassette as the
+// HTTPClient of any provider config lets that provider, and the services and
+// handlers built on it, run offline and deterministically in tests.
+//
+// Requests are matched by a hash of the method, path, query and canonical JSON
+// body. Identical requests replay their recordings in order, the last one
+// repeating once the others are used up.
+type Cassette struct {
+	path     string
+	mode     CassetteMode
+	upstream HTTPClientInterface
+	realtime bool
+	ignore   map[string]bool
+
+	mu           sync.Mutex
+	interactions []*CassetteInteraction
+	played       map[string]int
+}
+
+// NewCassette creates a cassette. In replay mode the cassette file must exist;
+// in record mode it is written by Save.
+func NewCassette(config *CassetteConfig) (*Cassette, error) {
+	if config.Mode == "" {
+		config.Mode = CassetteReplay
+	}
+	if config.IgnoreFields == nil {
+		config.IgnoreFields = DefaultCassetteIgnoreFields()
+	}
+	if config.Upstream == nil {
+		config.Upstream = NewDefaultHTTPClient(5 * time.Minute)
+	}
+
+	c := &Cassette{
+		path:     config.Path,
+		mode:     config.Mode,
+		upstream: config.Upstream,
+		realtime: config.Realtime,
+		ignore:   make(map[string]bool, len(config.IgnoreFields)),
+		played:   make(map[string]int),
+	}
+	for _, field := range config.IgnoreFields {
+		c.ignore[field] = true
+	}
+
+	switch config.Mode {
+	case CassetteRecord:
+		return c, nil
+	case CassetteReplay:
+		data, err := os.ReadFile(config.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		var file cassetteFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to decode cassette %s: %w", config.Path, err)
+		}
+		if file.Version != cassetteVersion {
+			return nil, fmt.Errorf("unsupported cassette version %d in %s", file.Version, config.Path)
+		}
+		c.interactions = file.Interactions
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown cassette mode %q", config.Mode)
+}
+
+// Mode returns whether the cassette records or replays
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Do records or replays a single HTTP exchange
+func (c *Cassette) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	url := req.URL.Path
+	if url == "" {
+		url = "/"
+	}
+	if query := req.URL.Query().Encode(); query != "" {
+		url += "?" + query
+	}
+	hash := c.requestHash(req.Method, url, body)
+
+	if c.mode == CassetteRecord {
+		return c.record(req, CassetteRequest{Method: req.Method, URL: url, Body: string(body)}, hash)
+	}
+	return c.replay(req, url, hash)
+}
+
+// record sends req upstream and records the response as it is read.
+// Transport errors are returned without being recorded.
+func (c *Cassette) record(req *http.Request, recorded CassetteRequest, hash string) (*http.Response, error) {
+	resp, err := c.upstream.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &CassetteInteraction{
+		Hash:    hash,
+		Request: recorded,
+		Response: CassetteResponse{
+			StatusCode: resp.StatusCode,
+			Header:     make(map[string]string),
+		},
+	}
+	for _, name := range []string{"Content-Type", "Retry-After"} {
+		if value := resp.Header.Get(name); value != "" {
+			interaction.Response.Header[name] = value
+		}
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, interaction)
+	c.mu.Unlock()
+
+	resp.Body = &recordingBody{
+		body:     resp.Body,
+		cassette: c,
+		response: &interaction.Response,
+		last:     time.Now(),
+	}
+	return resp, nil
+}
+
+// replay serves the next recording for hash
+func (c *Cassette) replay(req *http.Request, url, hash string) (*http.Response, error) {
+	c.mu.Lock()
+	var matches []*CassetteInteraction
+	for _, interaction := range c.interactions {
+		if interaction.Hash == hash {
+			matches = append(matches, interaction)
+		}
+	}
+	n := c.played[hash]
+	c.played[hash]++
+	c.mu.Unlock()
+
+	if len(matches) == 0 {
+		return nil, &LLMError{
+			Code:    "cassette_miss",
+			Message: "no recorded interaction for request",
+			Details: fmt.Sprintf("%s %s (hash %s) in %s", req.Method, url, hash, c.path),
+		}
+	}
+	if n >= len(matches) {
+		n = len(matches) - 1
+	}
+	recorded := matches[n].Response
+
+	header := make(http.Header, len(recorded.Header))
+	for name, value := range recorded.Header {
+		header.Set(name, value)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          &replayBody{ctx: req.Context(), chunks: recorded.Chunks, realtime: c.realtime},
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+// requestHash returns the normalized hash used to match requests. JSON bodies
+// are re-encoded with sorted keys and without the ignored fields, so key order
+// and caller identity do not affect matching.
+func (c *Cassette) requestHash(method, url string, body []byte) string {
+	var object map[string]interface{}
+	if err := json.Unmarshal(body, &object); err == nil && object != nil {
+		for field := range c.ignore {
+			delete(object, field)
+		}
+		if canonical, err := json.Marshal(object); err == nil {
+			body = canonical
+		}
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", method, url)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Save writes the recorded interactions to the cassette file. It is a no-op
+// in replay mode.
+func (c *Cassette) Save() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+
+	c.mu.Lock()
+	data, err := json.MarshalIndent(cassetteFile{Version: cassetteVersion, Interactions: c.interactions}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// recordingBody records each read of a response body as a chunk
+type recordingBody struct {
+	body     io.ReadCloser
+	cassette *Cassette
+	response *CassetteResponse
+	last     time.Time
+}
+
+// Read reads from the upstream body, recording what was read and when
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		now := time.Now()
+		b.cassette.mu.Lock()
+		b.response.Chunks = append(b.response.Chunks, CassetteChunk{
+			DelayMS: now.Sub(b.last).Milliseconds(),
+			Data:    string(p[:n]),
+		})
+		b.cassette.mu.Unlock()
+		b.last = now
+	}
+	return n, err
+}
+
+// Close closes the upstream body
+func (b *recordingBody) Close() error {
+	return b.body.Close()
+}
+
+// replayBody serves recorded chunks, never returning more than one chunk per
+// read so stream parsers see the same boundaries as when recording
+type replayBody struct {
+	ctx      context.Context
+	chunks   []CassetteChunk
+	realtime bool
+	pending  []byte
+}
+
+// Read returns the rest of the current chunk, or the next one
+func (b *replayBody) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(b.pending) == 0 {
+		if len(b.chunks) == 0 {
+			return 0, io.EOF
+		}
+		chunk := b.chunks[0]
+		b.chunks = b.chunks[1:]
+
+		if b.realtime && chunk.DelayMS > 0 {
+			timer := time.NewTimer(time.Duration(chunk.DelayMS) * time.Millisecond)
+			select {
+			case <-timer.C:
+			case <-b.ctx.Done():
+				timer.Stop()
+				return 0, b.ctx.Err()
+			}
+		}
+		b.pending = []byte(chunk.Data)
+	}
+
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+// Close discards the remaining chunks
+func (b *replayBody) Close() error {
+	b.chunks = nil
+	b.pending = nil
+	return nil
+}
@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState represents the state of a circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets every request through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects requests until the open timeout has passed
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe request through
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// CircuitBreakerConfig holds configuration for a circuit breaker
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `json:"failure_threshold"` // consecutive failures before opening
+	OpenTimeout      time.Duration `json:"open_timeout"`      // how long to stay open before probing
+}
+
+// CircuitBreaker tracks provider failures and stops sending requests to a
+// provider that keeps failing. It is safe for concurrent use.
+type CircuitBreaker struct {
+	name             string
+	failureThreshold int
+	openTimeout      time.Duration
+	now              func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a new circuit breaker for the named provider
+func NewCircuitBreaker(name string, config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+
+	return &CircuitBreaker{
+		name:             name,
+		failureThreshold: config.FailureThreshold,
+		openTimeout:      config.OpenTimeout,
+		now:              time.Now,
+		state:            BreakerClosed,
+	}
+}
+
+// Allow returns an error if the breaker is rejecting requests
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case BreakerOpen:
+		return b.openError()
+	case BreakerHalfOpen:
+		if b.probing {
+			return b.openError()
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+	}
+	return nil
+}
+
+// RecordSuccess closes the breaker and resets the failure count
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// RecordFailure counts a failure, opening the breaker once the threshold is
+// reached. A failed half-open probe re-opens the breaker immediately.
+func (b *CircuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.currentState() == BreakerHalfOpen || b.failures >= b.failureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		b.probing = false
+	}
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+// Health returns an error while the breaker is open
+func (b *CircuitBreaker) Health() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.currentState() == BreakerOpen {
+		return b.openError()
+	}
+	return nil
+}
+
+// releaseProbe gives up a half-open probe whose outcome says nothing about
+// provider health, such as a request cancelled by the caller
+func (b *CircuitBreaker) releaseProbe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// currentState resolves an expired open state to half-open. Callers must hold mu.
+func (b *CircuitBreaker) currentState() BreakerState {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// openError builds the error returned while the breaker is open. Callers must hold mu.
+func (b *CircuitBreaker) openError() *LLMError {
+	return &LLMError{
+		Code:    "circuit_open",
+		Message: fmt.Sprintf("%s circuit breaker is open", b.name),
+		Details: fmt.Sprintf("%d consecutive failures", b.failures),
+	}
+}
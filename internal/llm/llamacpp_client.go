@@ -0,0 +1,532 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// LlamaCppClient implements LLMClient for the llama.cpp server's native
+// /completion endpoint. The server hosts a single model, so the request
+// model is only used to label responses.
+type LlamaCppClient struct {
+	baseURL       string
+	apiKey        string
+	healthTimeout time.Duration
+	params        ParamAllowList
+	httpClient    HTTPClientInterface
+	client        *ResilientClient
+	models        *modelCache
+}
+
+// LlamaCppConfig holds configuration for the llama.cpp client
+type LlamaCppConfig struct {
+	BaseURL        string               `json:"base_url"`
+	APIKey         string               `json:"api_key"` // Only needed when the server runs with --api-key
+	Timeout        time.Duration        `json:"timeout"`
+	MaxRetries     int                  `json:"max_retries"`
+	RetryBaseDelay time.Duration        `json:"retry_base_delay"`
+	RetryMaxDelay  time.Duration        `json:"retry_max_delay"`
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"`
+	ModelsCacheTTL time.Duration        `json:"models_cache_ttl"`
+	HealthTimeout  time.Duration        `json:"health_timeout"`
+	Params         ParamAllowList       `json:"params"`
+	HTTPClient     HTTPClientInterface  `json:"-"` // Overrides the default transport, e.g. with a Cassette in tests
+}
+
+// llamaCppRequest represents a request to /completion
+type llamaCppRequest struct {
+	Prompt           string          `json:"prompt"`
+	NPredict         int             `json:"n_predict,omitempty"`
+	Temperature      *float64        `json:"temperature,omitempty"`
+	TopP             *float64        `json:"top_p,omitempty"`
+	Stop             []string        `json:"stop,omitempty"`
+	Seed             *int            `json:"seed,omitempty"`
+	PresencePenalty  *float64        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64        `json:"frequency_penalty,omitempty"`
+	JSONSchema       json.RawMessage `json:"json_schema,omitempty"`
+	Stream           bool            `json:"stream,omitempty"`
+}
+
+// llamaCppResponse represents a /completion response, also sent as the data
+// of each stream event
+type llamaCppResponse struct {
+	Content         string `json:"content"`
+	Model           string `json:"model"`
+	Stop            bool   `json:"stop"`
+	StoppedLimit    bool   `json:"stopped_limit"`
+	TokensPredicted int    `json:"tokens_predicted"`
+	TokensEvaluated int    `json:"tokens_evaluated"`
+}
+
+// llamaCppModelList represents the response of GET /v1/models
+type llamaCppModelList struct {
+	Data []struct {
+		ID      string `json:"id"`
+		Created int64  `json:"created"`
+		Meta    struct {
+			NCtxTrain int `json:"n_ctx_train"`
+		} `json:"meta"`
+	} `json:"data"`
+}
+
+// NewLlamaCppClient creates a new llama.cpp client
+func NewLlamaCppClient(config *LlamaCppConfig) *LlamaCppClient {
+	if config.BaseURL == "" {
+		config.BaseURL = "http://localhost:8080"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Minute
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 1
+	}
+	if config.ModelsCacheTTL == 0 {
+		config.ModelsCacheTTL = 30 * time.Second
+	}
+	if config.HealthTimeout == 0 {
+		config.HealthTimeout = 2 * time.Second
+	}
+	if config.Params == nil {
+		config.Params = ParamAllowList{}
+	}
+
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = config.MaxRetries
+	if config.RetryBaseDelay > 0 {
+		policy.BaseDelay = config.RetryBaseDelay
+	}
+	if config.RetryMaxDelay > 0 {
+		policy.MaxDelay = config.RetryMaxDelay
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = NewDefaultHTTPClient(config.Timeout)
+	}
+
+	return &LlamaCppClient{
+		baseURL:       strings.TrimRight(config.BaseURL, "/"),
+		apiKey:        config.APIKey,
+		healthTimeout: config.HealthTimeout,
+		params:        config.Params,
+		httpClient:    httpClient,
+		client: NewResilientClient(
+			httpClient,
+			policy,
+			NewCircuitBreaker("llama.cpp", config.CircuitBreaker),
+		),
+		models: newModelCache(config.ModelsCacheTTL),
+	}
+}
+
+// Generate performs a single generation request
+func (c *LlamaCppClient) Generate(ctx context.Context, req *GenerationRequest) (*GenerationResponse, error) {
+	log.Info().
+		Str("model", req.Model).
+		Bool("chat", req.IsChat()).
+		Int("max_tokens", req.MaxTokens).
+		Msg("llama.cpp Generate request")
+
+	body, err := c.buildRequestBody(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, "POST", "/completion", body)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, newLlamaCppHTTPError(httpResp)
+	}
+
+	var resp llamaCppResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &GenerationResponse{
+		ID:     newLlamaCppID(),
+		Object: "text_completion",
+		Model:  resp.modelOr(req.Model),
+		Choices: []Choice{{
+			Index:        0,
+			Text:         resp.Content,
+			FinishReason: resp.finishReason(),
+		}},
+		Usage: resp.toUsage(),
+	}, nil
+}
+
+// GenerateStream performs a streaming generation request. The channel carries
+// one response per content event, followed by exactly one response with the
+// finish reason and usage, or by a response carrying an error.
+func (c *LlamaCppClient) GenerateStream(ctx context.Context, req *GenerationRequest) (<-chan *GenerationResponse, error) {
+	log.Info().
+		Str("model", req.Model).
+		Bool("chat", req.IsChat()).
+		Msg("llama.cpp GenerateStream request")
+
+	body, err := c.buildRequestBody(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retries only apply until the stream is established
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		httpReq, err := c.newRequest(ctx, "POST", "/completion", body)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Accept", "text/event-stream")
+		return httpReq, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		defer httpResp.Body.Close()
+		return nil, newLlamaCppHTTPError(httpResp)
+	}
+
+	responseChan := make(chan *GenerationResponse, 10)
+	go c.processStreamResponse(ctx, httpResp.Body, req.Model, responseChan)
+
+	return responseChan, nil
+}
+
+// processStreamResponse converts /completion stream events into responses
+func (c *LlamaCppClient) processStreamResponse(ctx context.Context, body io.ReadCloser, model string, responseChan chan<- *GenerationResponse) {
+	defer body.Close()
+	defer close(responseChan)
+
+	send := func(resp *GenerationResponse) bool {
+		select {
+		case responseChan <- resp:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	id := newLlamaCppID()
+	reader := NewSSEReader(body)
+	for {
+		event, err := reader.Next()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			llmErr := &LLMError{Code: "incomplete_stream", Message: "stream ended before the final event"}
+			if err != io.EOF {
+				log.Error().Err(err).Msg("Failed to read llama.cpp stream")
+				llmErr = &LLMError{Code: "stream_read_error", Message: "failed to read stream", Details: err.Error()}
+			}
+			send(&GenerationResponse{ID: id, Model: model, Error: llmErr})
+			return
+		}
+
+		if llmErr := StreamErrorFromEvent(event); llmErr != nil {
+			log.Warn().Str("code", llmErr.Code).Str("message", llmErr.Message).Msg("llama.cpp stream reported an error")
+			send(&GenerationResponse{ID: id, Model: model, Error: llmErr})
+			return
+		}
+
+		var chunk llamaCppResponse
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			log.Error().Err(err).Str("data", truncateString(event.Data, 200)).Msg("Failed to decode llama.cpp stream event")
+			send(&GenerationResponse{
+				ID:    id,
+				Model: model,
+				Error: &LLMError{Code: "invalid_stream_chunk", Message: "failed to decode stream event", Details: err.Error()},
+			})
+			return
+		}
+		model = chunk.modelOr(model)
+
+		if chunk.Content != "" {
+			if !send(&GenerationResponse{
+				ID:      id,
+				Object:  "text_completion",
+				Model:   model,
+				Choices: []Choice{{Index: 0, Text: chunk.Content}},
+			}) {
+				return
+			}
+		}
+
+		if chunk.Stop {
+			send(&GenerationResponse{
+				ID:      id,
+				Object:  "text_completion",
+				Model:   model,
+				Choices: []Choice{{Index: 0, FinishReason: chunk.finishReason()}},
+				Usage:   chunk.toUsage(),
+			})
+			return
+		}
+	}
+}
+
+// buildRequestBody converts req into a /completion payload. Chat requests are
+// rendered with the model's own chat template first.
+func (c *LlamaCppClient) buildRequestBody(ctx context.Context, req *GenerationRequest, stream bool) ([]byte, error) {
+	if err := req.ValidateMessages(); err != nil {
+		return nil, err
+	}
+	params := req.SamplingParams()
+	if err := c.params.Validate(req.Model, params); err != nil {
+		return nil, err
+	}
+
+	prompt := req.Prompt
+	if req.IsChat() {
+		var err error
+		if prompt, err = c.applyTemplate(ctx, req.Messages); err != nil {
+			return nil, err
+		}
+	}
+
+	payload := &llamaCppRequest{
+		Prompt:           prompt,
+		NPredict:         params.MaxTokens,
+		Temperature:      params.Temperature,
+		TopP:             params.TopP,
+		Stop:             params.Stop,
+		Seed:             params.Seed,
+		PresencePenalty:  params.PresencePenalty,
+		FrequencyPenalty: params.FrequencyPenalty,
+		Stream:           stream,
+	}
+
+	if format := params.ResponseFormat; format != nil {
+		switch format.Type {
+		case ResponseFormatText:
+		case ResponseFormatJSONObject:
+			// An empty schema constrains the output to any JSON value
+			payload.JSONSchema = json.RawMessage(`{}`)
+		case ResponseFormatJSONSchema:
+			// OpenAI wraps the schema as {"name": ..., "schema": {...}}
+			schema := format.JSONSchema
+			if inner, ok := schema["schema"].(map[string]interface{}); ok {
+				schema = inner
+			}
+			data, err := json.Marshal(schema)
+			if err != nil {
+				return nil, invalidParam(ParamResponseFormat, fmt.Sprintf("invalid JSON schema: %v", err))
+			}
+			payload.JSONSchema = data
+		default:
+			return nil, invalidParam(ParamResponseFormat, fmt.Sprintf("unsupported type %q", format.Type))
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return body, nil
+}
+
+// applyTemplate renders messages into a prompt with POST /apply-template
+func (c *LlamaCppClient) applyTemplate(ctx context.Context, messages []Message) (string, error) {
+	body, err := json.Marshal(map[string][]Message{"messages": messages})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal messages: %w", err)
+	}
+
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, "POST", "/apply-template", body)
+	})
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return "", newLlamaCppHTTPError(httpResp)
+	}
+
+	var rendered struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(&rendered); err != nil {
+		return "", fmt.Errorf("failed to decode chat template: %w", err)
+	}
+	return rendered.Prompt, nil
+}
+
+// newRequest builds an HTTP request to the llama.cpp server with a fresh body reader
+func (c *LlamaCppClient) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	return httpReq, nil
+}
+
+// GetModels returns the model loaded by the server. Results are cached for
+// the configured TTL; a stale list is served if a refresh fails.
+func (c *LlamaCppClient) GetModels(ctx context.Context) ([]Model, error) {
+	if models, ok := c.models.get(); ok {
+		return models, nil
+	}
+
+	models, err := c.fetchModels(ctx)
+	if err != nil {
+		if stale, ok := c.models.stale(); ok {
+			log.Warn().Err(err).Msg("Failed to refresh llama.cpp models, serving cached list")
+			return stale, nil
+		}
+		return nil, err
+	}
+
+	c.models.set(models)
+	return models, nil
+}
+
+// fetchModels lists the models from GET /v1/models
+func (c *LlamaCppClient) fetchModels(ctx context.Context) ([]Model, error) {
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, "GET", "/v1/models", nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, newLlamaCppHTTPError(httpResp)
+	}
+
+	var list llamaCppModelList
+	if err := json.NewDecoder(httpResp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+
+	models := make([]Model, len(list.Data))
+	for i, m := range list.Data {
+		models[i] = Model{
+			ID:        m.ID,
+			Name:      m.ID,
+			Provider:  "llama.cpp",
+			MaxTokens: m.Meta.NCtxTrain,
+		}
+		if m.Created > 0 {
+			models[i].CreatedAt = time.Unix(m.Created, 0).UTC()
+		}
+		if params, ok := c.params.Lookup(m.ID); ok {
+			models[i].MaxTokens = params.MaxTokens
+		}
+	}
+	return models, nil
+}
+
+// Health checks that the server is up and has finished loading its model
+func (c *LlamaCppClient) Health(ctx context.Context) error {
+	if err := c.client.Health(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.healthTimeout)
+	defer cancel()
+
+	httpReq, err := c.newRequest(ctx, "GET", "/health", nil)
+	if err != nil {
+		return err
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return &LLMError{Code: "unreachable", Message: "llama.cpp health check failed", Details: err.Error()}
+	}
+	defer drainAndClose(httpResp.Body)
+
+	if httpResp.StatusCode != http.StatusOK {
+		// 503 while the model is still loading
+		return newLlamaCppHTTPError(httpResp)
+	}
+	return nil
+}
+
+// Close closes the LlamaCppClient connection
+func (c *LlamaCppClient) Close() error {
+	return nil
+}
+
+// modelOr returns the model reported by the server, or fallback for servers
+// that do not report one
+func (r *llamaCppResponse) modelOr(fallback string) string {
+	if r.Model != "" {
+		return r.Model
+	}
+	return fallback
+}
+
+// finishReason maps llama.cpp's stop flags to the OpenAI finish reason
+func (r *llamaCppResponse) finishReason() *string {
+	if !r.Stop {
+		return nil
+	}
+	if r.StoppedLimit {
+		return stringPtr("length")
+	}
+	return stringPtr("stop")
+}
+
+// toUsage converts llama.cpp token counts into the standard format
+func (r *llamaCppResponse) toUsage() *Usage {
+	return &Usage{
+		PromptTokens:     r.TokensEvaluated,
+		CompletionTokens: r.TokensPredicted,
+		TotalTokens:      r.TokensEvaluated + r.TokensPredicted,
+	}
+}
+
+// newLlamaCppID returns an identifier for a generation, which llama.cpp does not assign
+func newLlamaCppID() string {
+	return "llamacpp-" + uuid.NewString()
+}
+
+// newLlamaCppHTTPError builds an LLMError for a non-200 response, using the
+// server's {"error": {"message": ...}} message as the details when present
+func newLlamaCppHTTPError(resp *http.Response) *LLMError {
+	body, _ := io.ReadAll(resp.Body)
+	llmErr := NewHTTPError("llama.cpp", resp.StatusCode, body)
+
+	var apiErr struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error.Message != "" {
+		llmErr.Details = apiErr.Error.Message
+	}
+	return llmErr
+}
@@ -0,0 +1,570 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// OllamaClient implements LLMClient for Ollama's native API, so the whole
+// generation pipeline can run against models served on a developer machine
+type OllamaClient struct {
+	baseURL       string
+	keepAlive     string
+	healthTimeout time.Duration
+	params        ParamAllowList
+	httpClient    HTTPClientInterface
+	client        *ResilientClient
+	models        *modelCache
+}
+
+// OllamaConfig holds configuration for the Ollama client
+type OllamaConfig struct {
+	BaseURL        string               `json:"base_url"`
+	Timeout        time.Duration        `json:"timeout"`
+	MaxRetries     int                  `json:"max_retries"`
+	RetryBaseDelay time.Duration        `json:"retry_base_delay"`
+	RetryMaxDelay  time.Duration        `json:"retry_max_delay"`
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"`
+	ModelsCacheTTL time.Duration        `json:"models_cache_ttl"`
+	HealthTimeout  time.Duration        `json:"health_timeout"`
+	KeepAlive      string               `json:"keep_alive"` // How long Ollama keeps the model loaded, e.g. "10m"
+	Params         ParamAllowList       `json:"params"`
+	HTTPClient     HTTPClientInterface  `json:"-"` // Overrides the default transport, e.g. with a Cassette in tests
+}
+
+// ollamaRequest represents a request to /api/generate or /api/chat
+type ollamaRequest struct {
+	Model     string          `json:"model"`
+	Prompt    string          `json:"prompt,omitempty"`
+	Messages  []ollamaMessage `json:"messages,omitempty"`
+	Stream    bool            `json:"stream"` // Ollama streams unless told otherwise
+	Format    json.RawMessage `json:"format,omitempty"`
+	Options   *ollamaOptions  `json:"options,omitempty"`
+	KeepAlive string          `json:"keep_alive,omitempty"`
+}
+
+// ollamaMessage represents a chat turn
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaOptions holds the sampling parameters, which Ollama takes under "options"
+type ollamaOptions struct {
+	NumPredict       int      `json:"num_predict,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+}
+
+// ollamaResponse represents a response, or one NDJSON line of a stream, from
+// /api/generate (Response) or /api/chat (Message)
+type ollamaResponse struct {
+	Model           string         `json:"model"`
+	Response        string         `json:"response"`
+	Message         *ollamaMessage `json:"message,omitempty"`
+	Done            bool           `json:"done"`
+	DoneReason      string         `json:"done_reason"`
+	PromptEvalCount int            `json:"prompt_eval_count"`
+	EvalCount       int            `json:"eval_count"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// ollamaTagList represents the response of GET /api/tags
+type ollamaTagList struct {
+	Models []struct {
+		Name       string    `json:"name"`
+		Model      string    `json:"model"`
+		ModifiedAt time.Time `json:"modified_at"`
+		Size       int64     `json:"size"`
+		Details    struct {
+			Family            string `json:"family"`
+			ParameterSize     string `json:"parameter_size"`
+			QuantizationLevel string `json:"quantization_level"`
+		} `json:"details"`
+	} `json:"models"`
+}
+
+// NewOllamaClient creates a new Ollama client
+func NewOllamaClient(config *OllamaConfig) *OllamaClient {
+	if config.BaseURL == "" {
+		config.BaseURL = "http://localhost:11434"
+	}
+	if config.Timeout == 0 {
+		// Local models load on the first request, which can take minutes
+		config.Timeout = 5 * time.Minute
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 1
+	}
+	if config.ModelsCacheTTL == 0 {
+		config.ModelsCacheTTL = 30 * time.Second
+	}
+	if config.HealthTimeout == 0 {
+		config.HealthTimeout = 2 * time.Second
+	}
+	if config.Params == nil {
+		config.Params = ParamAllowList{}
+	}
+
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = config.MaxRetries
+	if config.RetryBaseDelay > 0 {
+		policy.BaseDelay = config.RetryBaseDelay
+	}
+	if config.RetryMaxDelay > 0 {
+		policy.MaxDelay = config.RetryMaxDelay
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = NewDefaultHTTPClient(config.Timeout)
+	}
+
+	return &OllamaClient{
+		baseURL:       strings.TrimRight(config.BaseURL, "/"),
+		keepAlive:     config.KeepAlive,
+		healthTimeout: config.HealthTimeout,
+		params:        config.Params,
+		httpClient:    httpClient,
+		client: NewResilientClient(
+			httpClient,
+			policy,
+			NewCircuitBreaker("Ollama", config.CircuitBreaker),
+		),
+		models: newModelCache(config.ModelsCacheTTL),
+	}
+}
+
+// Generate performs a single generation request
+func (c *OllamaClient) Generate(ctx context.Context, req *GenerationRequest) (*GenerationResponse, error) {
+	log.Info().
+		Str("model", req.Model).
+		Bool("chat", req.IsChat()).
+		Int("max_tokens", req.MaxTokens).
+		Msg("Ollama Generate request")
+
+	path, body, err := c.buildRequestBody(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, "POST", path, body)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, newOllamaHTTPError(httpResp)
+	}
+
+	var resp ollamaResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, &LLMError{Code: "upstream_error", Message: "Ollama reported an error", Details: resp.Error}
+	}
+
+	return &GenerationResponse{
+		ID:     newOllamaID(),
+		Object: "chat.completion",
+		Model:  resp.Model,
+		Choices: []Choice{{
+			Index:        0,
+			Message:      &Message{Role: RoleAssistant, Content: resp.content()},
+			FinishReason: resp.finishReason(),
+		}},
+		Usage: resp.toUsage(),
+	}, nil
+}
+
+// GenerateStream performs a streaming generation request. The channel carries
+// one response per NDJSON line with content, followed by exactly one response
+// with the finish reason and usage, or by a response carrying an error.
+func (c *OllamaClient) GenerateStream(ctx context.Context, req *GenerationRequest) (<-chan *GenerationResponse, error) {
+	log.Info().
+		Str("model", req.Model).
+		Bool("chat", req.IsChat()).
+		Msg("Ollama GenerateStream request")
+
+	path, body, err := c.buildRequestBody(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retries only apply until the stream is established
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		httpReq, err := c.newRequest(ctx, "POST", path, body)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Accept", "application/x-ndjson")
+		return httpReq, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		defer httpResp.Body.Close()
+		return nil, newOllamaHTTPError(httpResp)
+	}
+
+	responseChan := make(chan *GenerationResponse, 10)
+	go c.processStreamResponse(ctx, httpResp.Body, req.Model, responseChan)
+
+	return responseChan, nil
+}
+
+// processStreamResponse converts NDJSON stream lines into responses
+func (c *OllamaClient) processStreamResponse(ctx context.Context, body io.ReadCloser, model string, responseChan chan<- *GenerationResponse) {
+	defer body.Close()
+	defer close(responseChan)
+
+	send := func(resp *GenerationResponse) bool {
+		select {
+		case responseChan <- resp:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	id := newOllamaID()
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+
+		if len(line) > 0 {
+			var chunk ollamaResponse
+			if jsonErr := json.Unmarshal(line, &chunk); jsonErr != nil {
+				log.Error().Err(jsonErr).Str("data", truncateString(string(line), 200)).Msg("Failed to decode Ollama stream line")
+				send(&GenerationResponse{
+					ID:    id,
+					Model: model,
+					Error: &LLMError{Code: "invalid_stream_chunk", Message: "failed to decode stream line", Details: jsonErr.Error()},
+				})
+				return
+			}
+
+			if chunk.Error != "" {
+				log.Warn().Str("message", chunk.Error).Msg("Ollama stream reported an error")
+				send(&GenerationResponse{
+					ID:    id,
+					Model: model,
+					Error: &LLMError{Code: "stream_error", Message: "Ollama reported an error", Details: chunk.Error},
+				})
+				return
+			}
+			if chunk.Model != "" {
+				model = chunk.Model
+			}
+
+			if content := chunk.content(); content != "" {
+				if !send(&GenerationResponse{
+					ID:      id,
+					Object:  "chat.completion.chunk",
+					Model:   model,
+					Choices: []Choice{{Index: 0, Delta: &Delta{Content: content}}},
+				}) {
+					return
+				}
+			}
+
+			if chunk.Done {
+				send(&GenerationResponse{
+					ID:     id,
+					Object: "chat.completion.chunk",
+					Model:  model,
+					Choices: []Choice{{
+						Index:        0,
+						Delta:        &Delta{},
+						FinishReason: chunk.finishReason(),
+					}},
+					Usage: chunk.toUsage(),
+				})
+				return
+			}
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			llmErr := &LLMError{Code: "incomplete_stream", Message: "stream ended before done"}
+			if err != io.EOF {
+				log.Error().Err(err).Msg("Failed to read Ollama stream")
+				llmErr = &LLMError{Code: "stream_read_error", Message: "failed to read stream", Details: err.Error()}
+			}
+			send(&GenerationResponse{ID: id, Model: model, Error: llmErr})
+			return
+		}
+	}
+}
+
+// buildRequestBody converts req into an /api/chat payload for chat requests
+// or an /api/generate payload otherwise, returning the endpoint path and body
+func (c *OllamaClient) buildRequestBody(req *GenerationRequest, stream bool) (string, []byte, error) {
+	if err := req.ValidateMessages(); err != nil {
+		return "", nil, err
+	}
+	params := req.SamplingParams()
+	if err := c.params.Validate(req.Model, params); err != nil {
+		return "", nil, err
+	}
+
+	format, err := ollamaFormat(params.ResponseFormat)
+	if err != nil {
+		return "", nil, err
+	}
+
+	payload := &ollamaRequest{
+		Model:     req.Model,
+		Stream:    stream,
+		Format:    format,
+		Options:   ollamaOptionsFrom(params),
+		KeepAlive: c.keepAlive,
+	}
+
+	path := "/api/generate"
+	if req.IsChat() {
+		path = "/api/chat"
+		payload.Messages = make([]ollamaMessage, len(req.Messages))
+		for i, msg := range req.Messages {
+			payload.Messages[i] = ollamaMessage{Role: msg.Role, Content: msg.Content}
+		}
+	} else {
+		payload.Prompt = req.Prompt
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return path, body, nil
+}
+
+// newRequest builds an HTTP request to the Ollama API with a fresh body reader
+func (c *OllamaClient) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	return httpReq, nil
+}
+
+// GetModels returns the models pulled into the local Ollama install. Results
+// are cached for the configured TTL; a stale list is served if a refresh fails.
+func (c *OllamaClient) GetModels(ctx context.Context) ([]Model, error) {
+	if models, ok := c.models.get(); ok {
+		return models, nil
+	}
+
+	models, err := c.fetchModels(ctx)
+	if err != nil {
+		if stale, ok := c.models.stale(); ok {
+			log.Warn().Err(err).Msg("Failed to refresh Ollama models, serving cached list")
+			return stale, nil
+		}
+		return nil, err
+	}
+
+	c.models.set(models)
+	return models, nil
+}
+
+// fetchModels lists the models from GET /api/tags
+func (c *OllamaClient) fetchModels(ctx context.Context) ([]Model, error) {
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, "GET", "/api/tags", nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, newOllamaHTTPError(httpResp)
+	}
+
+	var list ollamaTagList
+	if err := json.NewDecoder(httpResp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+
+	models := make([]Model, len(list.Models))
+	for i, m := range list.Models {
+		id := m.Model
+		if id == "" {
+			id = m.Name
+		}
+
+		var details []string
+		for _, d := range []string{m.Details.Family, m.Details.ParameterSize, m.Details.QuantizationLevel} {
+			if d != "" {
+				details = append(details, d)
+			}
+		}
+
+		models[i] = Model{
+			ID:          id,
+			Name:        m.Name,
+			Description: strings.Join(details, " "),
+			Provider:    "Ollama",
+			CreatedAt:   m.ModifiedAt,
+		}
+		if params, ok := c.params.Lookup(id); ok {
+			models[i].MaxTokens = params.MaxTokens
+		}
+	}
+	return models, nil
+}
+
+// Health checks that the Ollama server is running
+func (c *OllamaClient) Health(ctx context.Context) error {
+	if err := c.client.Health(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.healthTimeout)
+	defer cancel()
+
+	httpReq, err := c.newRequest(ctx, "GET", "/api/version", nil)
+	if err != nil {
+		return err
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return &LLMError{Code: "unreachable", Message: "Ollama health check failed", Details: err.Error()}
+	}
+	defer drainAndClose(httpResp.Body)
+
+	if httpResp.StatusCode != http.StatusOK {
+		return newOllamaHTTPError(httpResp)
+	}
+	return nil
+}
+
+// Close closes the OllamaClient connection
+func (c *OllamaClient) Close() error {
+	return nil
+}
+
+// content returns the text carried by a generate or chat response
+func (r *ollamaResponse) content() string {
+	if r.Message != nil {
+		return r.Message.Content
+	}
+	return r.Response
+}
+
+// finishReason maps Ollama's done reason to the OpenAI finish reason
+func (r *ollamaResponse) finishReason() *string {
+	if !r.Done {
+		return nil
+	}
+	if r.DoneReason == "" {
+		// Older Ollama versions omit done_reason
+		return stringPtr("stop")
+	}
+	return stringPtr(r.DoneReason)
+}
+
+// toUsage converts Ollama's evaluation counts into the standard format
+func (r *ollamaResponse) toUsage() *Usage {
+	return &Usage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+// ollamaOptionsFrom maps sampling parameters onto Ollama options
+func ollamaOptionsFrom(params SamplingParams) *ollamaOptions {
+	return &ollamaOptions{
+		NumPredict:       params.MaxTokens,
+		Temperature:      params.Temperature,
+		TopP:             params.TopP,
+		Stop:             params.Stop,
+		Seed:             params.Seed,
+		PresencePenalty:  params.PresencePenalty,
+		FrequencyPenalty: params.FrequencyPenalty,
+	}
+}
+
+// ollamaFormat maps a response format onto Ollama's format field: "json" for
+// JSON mode, or the JSON schema itself for structured outputs
+func ollamaFormat(format *ResponseFormat) (json.RawMessage, error) {
+	if format == nil {
+		return nil, nil
+	}
+
+	switch format.Type {
+	case ResponseFormatText:
+		return nil, nil
+	case ResponseFormatJSONObject:
+		return json.RawMessage(`"json"`), nil
+	case ResponseFormatJSONSchema:
+		// OpenAI wraps the schema as {"name": ..., "schema": {...}}
+		schema := format.JSONSchema
+		if inner, ok := schema["schema"].(map[string]interface{}); ok {
+			schema = inner
+		}
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return nil, invalidParam(ParamResponseFormat, fmt.Sprintf("invalid JSON schema: %v", err))
+		}
+		return data, nil
+	}
+	return nil, invalidParam(ParamResponseFormat, fmt.Sprintf("unsupported type %q", format.Type))
+}
+
+// newOllamaID returns an identifier for a generation, which Ollama does not assign
+func newOllamaID() string {
+	return "ollama-" + uuid.NewString()
+}
+
+// newOllamaHTTPError builds an LLMError for a non-200 response, using
+// Ollama's {"error": "..."} message as the details when present
+func newOllamaHTTPError(resp *http.Response) *LLMError {
+	body, _ := io.ReadAll(resp.Body)
+	llmErr := NewHTTPError("Ollama", resp.StatusCode, body)
+
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error != "" {
+		llmErr.Details = apiErr.Error
+		if resp.StatusCode == http.StatusNotFound {
+			llmErr.Code = "model_not_found"
+		}
+	}
+	return llmErr
+}
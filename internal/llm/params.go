@@ -0,0 +1,238 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Generation parameter names as they appear in OpenAI-compatible requests
+const (
+	ParamMaxTokens        = "max_tokens"
+	ParamTemperature      = "temperature"
+	ParamTopP             = "top_p"
+	ParamStop             = "stop"
+	ParamSeed             = "seed"
+	ParamPresencePenalty  = "presence_penalty"
+	ParamFrequencyPenalty = "frequency_penalty"
+	ParamResponseFormat   = "response_format"
+)
+
+// Response format types
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// maxStopSequences is the number of stop sequences accepted by OpenAI-compatible APIs
+const maxStopSequences = 4
+
+// ResponseFormat constrains the shape of the generated output
+type ResponseFormat struct {
+	Type       string                 `json:"type"`
+	JSONSchema map[string]interface{} `json:"json_schema,omitempty"`
+}
+
+// SamplingParams holds the optional tuning parameters of a generation.
+// Nil or zero values are omitted so the provider default applies.
+type SamplingParams struct {
+	MaxTokens        int             `json:"max_tokens,omitempty"`
+	Temperature      *float64        `json:"temperature,omitempty"`
+	TopP             *float64        `json:"top_p,omitempty"`
+	Stop             []string        `json:"stop,omitempty"`
+	Seed             *int            `json:"seed,omitempty"`
+	PresencePenalty  *float64        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64        `json:"frequency_penalty,omitempty"`
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// setParams returns the names of the parameters that are set
+func (p SamplingParams) setParams() []string {
+	var names []string
+	if p.MaxTokens != 0 {
+		names = append(names, ParamMaxTokens)
+	}
+	if p.Temperature != nil {
+		names = append(names, ParamTemperature)
+	}
+	if p.TopP != nil {
+		names = append(names, ParamTopP)
+	}
+	if len(p.Stop) > 0 {
+		names = append(names, ParamStop)
+	}
+	if p.Seed != nil {
+		names = append(names, ParamSeed)
+	}
+	if p.PresencePenalty != nil {
+		names = append(names, ParamPresencePenalty)
+	}
+	if p.FrequencyPenalty != nil {
+		names = append(names, ParamFrequencyPenalty)
+	}
+	if p.ResponseFormat != nil {
+		names = append(names, ParamResponseFormat)
+	}
+	return names
+}
+
+// validateRanges checks every set parameter against the range accepted by OpenAI-compatible APIs
+func (p SamplingParams) validateRanges() error {
+	switch {
+	case p.MaxTokens < 0:
+		return invalidParam(ParamMaxTokens, "must be positive")
+	case p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2):
+		return invalidParam(ParamTemperature, "must be between 0 and 2")
+	case p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1):
+		return invalidParam(ParamTopP, "must be greater than 0 and at most 1")
+	case len(p.Stop) > maxStopSequences:
+		return invalidParam(ParamStop, fmt.Sprintf("at most %d sequences are allowed", maxStopSequences))
+	case p.PresencePenalty != nil && (*p.PresencePenalty < -2 || *p.PresencePenalty > 2):
+		return invalidParam(ParamPresencePenalty, "must be between -2 and 2")
+	case p.FrequencyPenalty != nil && (*p.FrequencyPenalty < -2 || *p.FrequencyPenalty > 2):
+		return invalidParam(ParamFrequencyPenalty, "must be between -2 and 2")
+	}
+
+	for _, stop := range p.Stop {
+		if stop == "" {
+			return invalidParam(ParamStop, "sequences must not be empty")
+		}
+	}
+
+	if p.ResponseFormat != nil {
+		switch p.ResponseFormat.Type {
+		case ResponseFormatText, ResponseFormatJSONObject:
+		case ResponseFormatJSONSchema:
+			if len(p.ResponseFormat.JSONSchema) == 0 {
+				return invalidParam(ParamResponseFormat, "json_schema requires a schema")
+			}
+		default:
+			return invalidParam(ParamResponseFormat, fmt.Sprintf("unknown type %q", p.ResponseFormat.Type))
+		}
+	}
+	return nil
+}
+
+// ModelParams lists the parameters a model accepts
+type ModelParams struct {
+	// MaxTokens caps max_tokens; zero means no cap
+	MaxTokens int `json:"max_tokens"`
+	// Allowed lists the accepted parameter names
+	Allowed []string `json:"allowed"`
+}
+
+// allows reports whether name is in the allow-list
+func (m ModelParams) allows(name string) bool {
+	for _, allowed := range m.Allowed {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParamAllowList maps model IDs, or model ID prefixes, to the parameters they accept.
+// Models that are not listed accept every parameter, since self-hosted servers
+// decide for themselves what they support.
+type ParamAllowList map[string]ModelParams
+
+// AllParams lists every supported generation parameter
+func AllParams() []string {
+	return []string{
+		ParamMaxTokens, ParamTemperature, ParamTopP, ParamStop, ParamSeed,
+		ParamPresencePenalty, ParamFrequencyPenalty, ParamResponseFormat,
+	}
+}
+
+// DefaultParamAllowList returns the allow-list for hosted models
+func DefaultParamAllowList() ParamAllowList {
+	legacy := []string{
+		ParamMaxTokens, ParamTemperature, ParamTopP, ParamStop, ParamSeed,
+		ParamPresencePenalty, ParamFrequencyPenalty,
+	}
+
+	// The Messages API has no seed, penalties or response_format
+	anthropic := []string{ParamMaxTokens, ParamTemperature, ParamTopP, ParamStop}
+
+	return ParamAllowList{
+		"gpt-3.5-turbo":     {MaxTokens: 4096, Allowed: AllParams()},
+		"gpt-4":             {MaxTokens: 8192, Allowed: legacy},
+		"gpt-4-turbo":       {MaxTokens: 4096, Allowed: AllParams()},
+		"gpt-4o":            {MaxTokens: 16384, Allowed: AllParams()},
+		"gpt-4o-mini":       {MaxTokens: 16384, Allowed: AllParams()},
+		"claude-3-haiku":    {MaxTokens: 4096, Allowed: anthropic},
+		"claude-3-opus":     {MaxTokens: 4096, Allowed: anthropic},
+		"claude-3-5-haiku":  {MaxTokens: 8192, Allowed: anthropic},
+		"claude-3-5-sonnet": {MaxTokens: 8192, Allowed: anthropic},
+		"claude-3-7-sonnet": {MaxTokens: 64000, Allowed: anthropic},
+		"claude-sonnet-4":   {MaxTokens: 64000, Allowed: anthropic},
+		"claude-opus-4":     {MaxTokens: 32000, Allowed: anthropic},
+	}
+}
+
+// Lookup returns the parameters accepted by model. Dated snapshots such as
+// "gpt-4o-2024-08-06" match the longest listed prefix.
+func (l ParamAllowList) Lookup(model string) (ModelParams, bool) {
+	if params, ok := l[model]; ok {
+		return params, true
+	}
+
+	var best string
+	for id := range l {
+		if strings.HasPrefix(model, id+"-") && len(id) > len(best) {
+			best = id
+		}
+	}
+	if best == "" {
+		return ModelParams{}, false
+	}
+	return l[best], true
+}
+
+// Accepts reports whether model accepts the named parameter
+func (l ParamAllowList) Accepts(model, name string) bool {
+	allowed, ok := l.Lookup(model)
+	return !ok || allowed.allows(name)
+}
+
+// Validate checks params against their ranges and the allow-list of model
+func (l ParamAllowList) Validate(model string, params SamplingParams) error {
+	if err := params.validateRanges(); err != nil {
+		return err
+	}
+
+	allowed, ok := l.Lookup(model)
+	if !ok {
+		return nil
+	}
+
+	for _, name := range params.setParams() {
+		if !allowed.allows(name) {
+			return &LLMError{
+				Code:    "invalid_request",
+				Message: "unsupported parameter",
+				Details: fmt.Sprintf("model %q does not accept %s", model, name),
+			}
+		}
+	}
+	if allowed.MaxTokens > 0 && params.MaxTokens > allowed.MaxTokens {
+		return invalidParam(ParamMaxTokens, fmt.Sprintf("model %q accepts at most %d", model, allowed.MaxTokens))
+	}
+	return nil
+}
+
+// invalidParam builds the error returned for an out-of-range parameter
+func invalidParam(name, reason string) *LLMError {
+	return &LLMError{
+		Code:    "invalid_request",
+		Message: "invalid parameter " + name,
+		Details: reason,
+	}
+}
+
+// IsInvalidRequest reports whether err was caused by an invalid request
+func IsInvalidRequest(err error) bool {
+	var llmErr *LLMError
+	return errors.As(err, &llmErr) && llmErr.Code == "invalid_request"
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"strings"
+)
+
+// ModelPrice holds the price of a model in USD per million tokens
+type ModelPrice struct {
+	PromptPerMillion     float64 `json:"prompt_per_million"`
+	CompletionPerMillion float64 `json:"completion_per_million"`
+}
+
+// PriceTable maps model IDs, or model ID prefixes, to prices
+type PriceTable map[string]ModelPrice
+
+// DefaultPriceTable returns list prices for hosted models.
+// Self-hosted models are not listed and therefore cost nothing.
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		"gpt-3.5-turbo": {PromptPerMillion: 0.50, CompletionPerMillion: 1.50},
+		"gpt-4":         {PromptPerMillion: 30.00, CompletionPerMillion: 60.00},
+		"gpt-4-turbo":   {PromptPerMillion: 10.00, CompletionPerMillion: 30.00},
+		"gpt-4o":        {PromptPerMillion: 2.50, CompletionPerMillion: 10.00},
+		"gpt-4o-mini":   {PromptPerMillion: 0.15, CompletionPerMillion: 0.60},
+
+		"claude-3-haiku":    {PromptPerMillion: 0.25, CompletionPerMillion: 1.25},
+		"claude-3-opus":     {PromptPerMillion: 15.00, CompletionPerMillion: 75.00},
+		"claude-3-5-haiku":  {PromptPerMillion: 0.80, CompletionPerMillion: 4.00},
+		"claude-3-5-sonnet": {PromptPerMillion: 3.00, CompletionPerMillion: 15.00},
+		"claude-3-7-sonnet": {PromptPerMillion: 3.00, CompletionPerMillion: 15.00},
+		"claude-sonnet-4":   {PromptPerMillion: 3.00, CompletionPerMillion: 15.00},
+		"claude-opus-4":     {PromptPerMillion: 15.00, CompletionPerMillion: 75.00},
+	}
+}
+
+// Lookup returns the price for model. Dated snapshots such as
+// "gpt-4o-2024-08-06" match the longest listed prefix.
+func (t PriceTable) Lookup(model string) (ModelPrice, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+
+	var best string
+	for id := range t {
+		if strings.HasPrefix(model, id+"-") && len(id) > len(best) {
+			best = id
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return t[best], true
+}
+
+// Cost returns the estimated cost in USD of a generation
+func (t PriceTable) Cost(model string, promptTokens, completionTokens int) float64 {
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0
+	}
+	return (float64(promptTokens)*price.PromptPerMillion + float64(completionTokens)*price.CompletionPerMillion) / 1_000_000
+}
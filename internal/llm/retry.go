@@ -0,0 +1,214 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RetryPolicy controls how failed LLM HTTP calls are retried
+type RetryPolicy struct {
+	MaxRetries int           `json:"max_retries"`
+	BaseDelay  time.Duration `json:"base_delay"` // delay before the first retry
+	MaxDelay   time.Duration `json:"max_delay"`  // cap for backoff and Retry-After waits
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+	}
+}
+
+// Backoff returns the wait before retry number attempt (0-based) using
+// exponential backoff with full jitter
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// RequestFactory builds a fresh HTTP request for each attempt, so request
+// bodies are never re-sent after being consumed
+type RequestFactory func(ctx context.Context) (*http.Request, error)
+
+// ResilientClient wraps an HTTP client with retries and a circuit breaker.
+// It is shared by every LLM HTTP client in the project.
+type ResilientClient struct {
+	httpClient HTTPClientInterface
+	policy     RetryPolicy
+	breaker    *CircuitBreaker
+}
+
+// NewResilientClient creates a new resilient client. A nil breaker disables circuit breaking.
+func NewResilientClient(httpClient HTTPClientInterface, policy RetryPolicy, breaker *CircuitBreaker) *ResilientClient {
+	return &ResilientClient{
+		httpClient: httpClient,
+		policy:     policy,
+		breaker:    breaker,
+	}
+}
+
+// Breaker returns the client's circuit breaker, which may be nil
+func (c *ResilientClient) Breaker() *CircuitBreaker {
+	return c.breaker
+}
+
+// Health reports the circuit breaker state as an error while it is open
+func (c *ResilientClient) Health() error {
+	if c.breaker == nil {
+		return nil
+	}
+	return c.breaker.Health()
+}
+
+// Do sends the request built by newRequest, retrying connection errors,
+// timeouts, 429 and 5xx responses. Non-retryable responses, and the last
+// response once retries are exhausted, are returned to the caller unread.
+func (c *ResilientClient) Do(ctx context.Context, newRequest RequestFactory) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if c.breaker != nil {
+			if err := c.breaker.Allow(); err != nil {
+				return nil, err
+			}
+		}
+
+		req, err := newRequest(ctx)
+		if err != nil {
+			c.releaseProbe()
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.releaseProbe()
+				return nil, ctx.Err()
+			}
+			c.recordFailure()
+			if !IsRetryableError(err) || attempt >= c.policy.MaxRetries {
+				return nil, err
+			}
+			if !c.wait(ctx, attempt, c.policy.Backoff(attempt), err) {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		if !isRetryableStatus(resp.StatusCode) {
+			c.recordSuccess()
+			return resp, nil
+		}
+
+		// Rate limiting says nothing about provider health
+		if resp.StatusCode >= 500 {
+			c.recordFailure()
+		} else {
+			c.releaseProbe()
+		}
+
+		delay := c.policy.Backoff(attempt)
+		if retryAfter, ok := parseRetryAfter(resp); ok {
+			if retryAfter > c.policy.MaxDelay {
+				// The provider asked for a longer pause than we are willing to wait
+				return resp, nil
+			}
+			delay = retryAfter
+		}
+		if attempt >= c.policy.MaxRetries {
+			return resp, nil
+		}
+
+		drainAndClose(resp.Body)
+		if !c.wait(ctx, attempt, delay, &LLMError{Code: "upstream_error", Message: http.StatusText(resp.StatusCode), StatusCode: resp.StatusCode}) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// wait sleeps before the next attempt, returning false if ctx ends first
+func (c *ResilientClient) wait(ctx context.Context, attempt int, delay time.Duration, cause error) bool {
+	log.Warn().
+		Err(cause).
+		Int("attempt", attempt+1).
+		Dur("wait", delay).
+		Msg("LLM request failed, retrying")
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (c *ResilientClient) recordSuccess() {
+	if c.breaker != nil {
+		c.breaker.RecordSuccess()
+	}
+}
+
+func (c *ResilientClient) recordFailure() {
+	if c.breaker != nil {
+		c.breaker.RecordFailure()
+	}
+}
+
+func (c *ResilientClient) releaseProbe() {
+	if c.breaker != nil {
+		c.breaker.releaseProbe()
+	}
+}
+
+// isRetryableStatus reports whether an HTTP status is worth retrying
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// parseRetryAfter reads the Retry-After header of a 429 or 503 response.
+// Both the delay-seconds and HTTP-date forms are supported.
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// drainAndClose discards the rest of body so the connection can be reused
+func drainAndClose(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, 64<<10))
+	body.Close()
+}
@@ -0,0 +1,262 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Metadata keys set by Router on every response
+const (
+	MetadataProvider        = "provider"
+	MetadataFailedProviders = "failed_providers"
+)
+
+// Provider is a named LLM backend registered with a Router
+type Provider struct {
+	Name   string
+	Client LLMClient
+	// Models lists the model IDs served by this provider.
+	// An empty list means the provider accepts any model.
+	Models []string
+}
+
+// serves reports whether the provider can handle the given model
+func (p Provider) serves(model string) bool {
+	if len(p.Models) == 0 {
+		return true
+	}
+	for _, m := range p.Models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// Router implements LLMClient on top of an ordered list of providers.
+// Requests go to the first provider serving the requested model and fall
+// back to the next one on 5xx responses, timeouts and connection errors.
+type Router struct {
+	providers []Provider
+}
+
+// NewRouter creates a new router over providers, tried in the given order
+func NewRouter(providers []Provider) *Router {
+	return &Router{providers: providers}
+}
+
+// Generate performs a single generation request
+func (r *Router) Generate(ctx context.Context, req *GenerationRequest) (*GenerationResponse, error) {
+	candidates, err := r.candidates(req.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []string
+	var lastErr error
+	for _, provider := range candidates {
+		resp, err := provider.Client.Generate(ctx, req)
+		if err == nil {
+			annotateResponse(resp, provider.Name, failed)
+			return resp, nil
+		}
+
+		lastErr = err
+		if !r.shouldFallback(ctx, err) {
+			return nil, err
+		}
+		log.Warn().Err(err).Str("provider", provider.Name).Str("model", req.Model).Msg("LLM provider failed, trying next provider")
+		failed = append(failed, provider.Name)
+	}
+
+	return nil, fmt.Errorf("all providers failed for model %q: %w", req.Model, lastErr)
+}
+
+// GenerateStream performs a streaming generation request.
+// Fallback only applies while establishing the stream; once a provider has
+// started sending chunks the stream is bound to it.
+func (r *Router) GenerateStream(ctx context.Context, req *GenerationRequest) (<-chan *GenerationResponse, error) {
+	candidates, err := r.candidates(req.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []string
+	var lastErr error
+	for _, provider := range candidates {
+		upstream, err := provider.Client.GenerateStream(ctx, req)
+		if err == nil {
+			return r.annotateStream(ctx, upstream, provider.Name, failed), nil
+		}
+
+		lastErr = err
+		if !r.shouldFallback(ctx, err) {
+			return nil, err
+		}
+		log.Warn().Err(err).Str("provider", provider.Name).Str("model", req.Model).Msg("LLM provider failed to start stream, trying next provider")
+		failed = append(failed, provider.Name)
+	}
+
+	return nil, fmt.Errorf("all providers failed for model %q: %w", req.Model, lastErr)
+}
+
+// GetModels returns the models of every reachable provider, de-duplicated by ID
+func (r *Router) GetModels(ctx context.Context) ([]Model, error) {
+	var models []Model
+	var errs []error
+	seen := make(map[string]bool)
+
+	for _, provider := range r.providers {
+		providerModels, err := provider.Client.GetModels(ctx)
+		if err != nil {
+			log.Warn().Err(err).Str("provider", provider.Name).Msg("Failed to list provider models")
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name, err))
+			continue
+		}
+		for _, model := range providerModels {
+			if seen[model.ID] {
+				continue
+			}
+			seen[model.ID] = true
+			if model.Provider == "" {
+				model.Provider = provider.Name
+			}
+			models = append(models, model)
+		}
+	}
+
+	if len(models) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return models, nil
+}
+
+// Health reports healthy as long as at least one provider is healthy
+func (r *Router) Health(ctx context.Context) error {
+	if len(r.providers) == 0 {
+		return &LLMError{Code: "no_providers", Message: "no LLM providers configured"}
+	}
+
+	var unhealthy []string
+	for _, provider := range r.providers {
+		if err := provider.Client.Health(ctx); err != nil {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s: %v", provider.Name, err))
+			continue
+		}
+		return nil
+	}
+
+	return &LLMError{
+		Code:    "all_providers_unhealthy",
+		Message: "all LLM providers are unhealthy",
+		Details: strings.Join(unhealthy, "; "),
+	}
+}
+
+// Close closes every provider client and returns the first error
+func (r *Router) Close() error {
+	var err error
+	for _, provider := range r.providers {
+		if closeErr := provider.Client.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// candidates returns the providers serving model, in routing order
+func (r *Router) candidates(model string) ([]Provider, error) {
+	var candidates []Provider
+	for _, provider := range r.providers {
+		if provider.serves(model) {
+			candidates = append(candidates, provider)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, &LLMError{
+			Code:    "model_not_found",
+			Message: "no provider serves the requested model",
+			Details: model,
+		}
+	}
+	return candidates, nil
+}
+
+// shouldFallback reports whether err allows trying the next provider
+func (r *Router) shouldFallback(ctx context.Context, err error) bool {
+	// The caller gave up; another provider will not help
+	if ctx.Err() != nil {
+		return false
+	}
+	return IsRetryableError(err)
+}
+
+// annotateStream forwards upstream chunks, recording the answering provider on each
+func (r *Router) annotateStream(ctx context.Context, upstream <-chan *GenerationResponse, provider string, failed []string) <-chan *GenerationResponse {
+	out := make(chan *GenerationResponse, cap(upstream))
+	go func() {
+		defer close(out)
+		for resp := range upstream {
+			annotateResponse(resp, provider, failed)
+			select {
+			case out <- resp:
+			case <-ctx.Done():
+				// Drain so the upstream producer can exit
+				for range upstream {
+				}
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// annotateResponse records which provider answered a request
+func annotateResponse(resp *GenerationResponse, provider string, failed []string) {
+	if resp == nil {
+		return
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]interface{})
+	}
+	resp.Metadata[MetadataProvider] = provider
+	if len(failed) > 0 {
+		resp.Metadata[MetadataFailedProviders] = failed
+	}
+}
+
+// IsRetryableError reports whether err is a transient provider failure:
+// a 5xx response, a timeout, a connection error or an open circuit breaker.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		if llmErr.Code == "circuit_open" {
+			return true
+		}
+		if llmErr.StatusCode != 0 {
+			return llmErr.StatusCode >= 500
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr)
+}
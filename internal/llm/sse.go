@@ -0,0 +1,137 @@
+package llm
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// SSEDone marks the end of an OpenAI-compatible event stream
+const SSEDone = "[DONE]"
+
+// IsDone reports whether the event is the [DONE] sentinel
+func (e *StreamEvent) IsDone() bool {
+	return strings.TrimSpace(e.Data) == SSEDone
+}
+
+// SSEReader reads Server-Sent Events from a response body.
+// Lines are buffered until a full event has arrived, so events split across
+// network reads are reassembled before they are returned.
+type SSEReader struct {
+	reader *bufio.Reader
+}
+
+// NewSSEReader creates a new SSE reader over body
+func NewSSEReader(body io.Reader) *SSEReader {
+	return &SSEReader{reader: bufio.NewReader(body)}
+}
+
+// Next returns the next event in the stream. Comment lines are skipped and
+// multiple data lines are joined with a newline, as described by the SSE spec.
+// It returns io.EOF once the stream ends without a pending event.
+func (r *SSEReader) Next() (*StreamEvent, error) {
+	event := &StreamEvent{}
+	var data []string
+	hasData := false
+
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		eof := err == io.EOF
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			// A blank line dispatches the pending event
+			if hasData {
+				event.Data = strings.Join(data, "\n")
+				return event, nil
+			}
+			if eof {
+				return nil, io.EOF
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(line, ":") {
+			field, value := parseSSEField(line)
+			switch field {
+			case "data":
+				data = append(data, value)
+				hasData = true
+			case "event":
+				event.Event = value
+			case "id":
+				event.ID = value
+			}
+		}
+
+		if eof {
+			// Dispatch an unterminated final event rather than dropping it
+			if hasData {
+				event.Data = strings.Join(data, "\n")
+				return event, nil
+			}
+			return nil, io.EOF
+		}
+	}
+}
+
+// parseSSEField splits an SSE line into its field name and value
+func parseSSEField(line string) (string, string) {
+	field, value, found := strings.Cut(line, ":")
+	if !found {
+		return line, ""
+	}
+	return field, strings.TrimPrefix(value, " ")
+}
+
+// sseErrorPayload covers the error shapes sent by OpenAI-compatible servers.
+// vLLM sends {"object":"error","message":...} while OpenAI nests the
+// details under "error".
+type sseErrorPayload struct {
+	Object  string `json:"object"`
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Error   *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// StreamErrorFromEvent returns the error carried by an event, or nil if the
+// event is a regular data chunk. Events of type "error" whose data is not
+// JSON are reported with the raw data as message.
+func StreamErrorFromEvent(event *StreamEvent) *LLMError {
+	data := strings.TrimSpace(event.Data)
+
+	var payload sseErrorPayload
+	if err := json.Unmarshal([]byte(data), &payload); err != nil {
+		if event.Event == "error" {
+			return &LLMError{Code: "stream_error", Message: data}
+		}
+		return nil
+	}
+
+	switch {
+	case payload.Error != nil:
+		return &LLMError{Code: streamErrorCode(payload.Error.Type), Message: payload.Error.Message}
+	case payload.Object == "error" || event.Event == "error":
+		message := payload.Message
+		if message == "" {
+			message = data
+		}
+		return &LLMError{Code: streamErrorCode(payload.Type), Message: message}
+	}
+	return nil
+}
+
+// streamErrorCode returns the upstream error type or a generic stream error code
+func streamErrorCode(errType string) string {
+	if errType == "" {
+		return "stream_error"
+	}
+	return errType
+}
@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"unicode"
+)
+
+// Per-message overhead used by chat models when counting prompt tokens:
+// every message is wrapped in role/separator tokens and the reply is primed
+// with a few more.
+const (
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+)
+
+// runeClass groups runes the way BPE pre-tokenizers split text
+type runeClass int
+
+const (
+	classSpace runeClass = iota
+	classLetter
+	classDigit
+	classWide
+	classSymbol
+)
+
+// classify returns the pre-tokenizer class of r
+func classify(r rune) runeClass {
+	switch {
+	case unicode.IsSpace(r):
+		return classSpace
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return classWide
+	case unicode.IsLetter(r):
+		return classLetter
+	case unicode.IsDigit(r):
+		return classDigit
+	}
+	return classSymbol
+}
+
+// EstimateTokens approximates the number of BPE tokens in text without a
+// model-specific vocabulary. Text is pre-tokenized into letter, digit,
+// symbol and whitespace runs like GPT tokenizers do, then each run is
+// charged using the average piece length observed for that class.
+// It is used when a provider does not report usage.
+func EstimateTokens(text string) int {
+	tokens := 0
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		class := classify(runes[i])
+		j := i + 1
+		for j < len(runes) && classify(runes[j]) == class {
+			j++
+		}
+		n := j - i
+
+		switch class {
+		case classLetter:
+			// Common words are one token; long identifiers split every ~4 letters
+			tokens += ceilDiv(n, 4)
+		case classDigit:
+			// Numbers are split into groups of up to three digits
+			tokens += ceilDiv(n, 3)
+		case classWide:
+			// CJK text is roughly one token per character
+			tokens += n
+		case classSymbol:
+			// Operators such as "=>" or "</" usually merge into one token
+			tokens += ceilDiv(n, 2)
+		case classSpace:
+			// A single space merges into the following word; longer runs
+			// such as indentation are tokenized separately
+			if n > 1 {
+				tokens += ceilDiv(n, 4)
+			}
+		}
+		i = j
+	}
+
+	return tokens
+}
+
+// EstimateMessagesTokens approximates the prompt tokens of a chat request
+func EstimateMessagesTokens(messages []Message) int {
+	if len(messages) == 0 {
+		return 0
+	}
+
+	tokens := tokensPerReply
+	for _, msg := range messages {
+		tokens += tokensPerMessage + EstimateTokens(msg.Role) + EstimateTokens(msg.Content)
+	}
+	return tokens
+}
+
+// ceilDiv returns a/b rounded up
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
@@ -2,19 +2,83 @@ package llm
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
-// GenerationRequest represents a request to generate content
+// Message roles understood by chat completion APIs
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message represents a single role-tagged turn in a chat conversation
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// GenerationRequest represents a request to generate content.
+// When Messages is set the request is sent as a chat completion and Prompt is ignored.
 type GenerationRequest struct {
-	Model       string                 `json:"model"`
-	Prompt      string                 `json:"prompt"`
-	MaxTokens   int                    `json:"max_tokens,omitempty"`
-	Temperature float64                `json:"temperature,omitempty"`
-	Stream      bool                   `json:"stream,omitempty"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
-	UserID      string                 `json:"user_id,omitempty"`
-	ProjectID   string                 `json:"project_id,omitempty"`
+	Model            string                 `json:"model"`
+	Prompt           string                 `json:"prompt"`
+	Messages         []Message              `json:"messages,omitempty"`
+	MaxTokens        int                    `json:"max_tokens,omitempty"`
+	Temperature      *float64               `json:"temperature,omitempty"`
+	TopP             *float64               `json:"top_p,omitempty"`
+	Stop             []string               `json:"stop,omitempty"`
+	Seed             *int                   `json:"seed,omitempty"`
+	PresencePenalty  *float64               `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64               `json:"frequency_penalty,omitempty"`
+	ResponseFormat   *ResponseFormat        `json:"response_format,omitempty"`
+	Stream           bool                   `json:"stream,omitempty"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+	UserID           string                 `json:"user_id,omitempty"`
+	ProjectID        string                 `json:"project_id,omitempty"`
+}
+
+// SamplingParams returns the tuning parameters of the request
+func (r *GenerationRequest) SamplingParams() SamplingParams {
+	return SamplingParams{
+		MaxTokens:        r.MaxTokens,
+		Temperature:      r.Temperature,
+		TopP:             r.TopP,
+		Stop:             r.Stop,
+		Seed:             r.Seed,
+		PresencePenalty:  r.PresencePenalty,
+		FrequencyPenalty: r.FrequencyPenalty,
+		ResponseFormat:   r.ResponseFormat,
+	}
+}
+
+// IsChat reports whether the request should use the chat completions API
+func (r *GenerationRequest) IsChat() bool {
+	return len(r.Messages) > 0
+}
+
+// ValidateMessages checks that every message has a known role and content
+func (r *GenerationRequest) ValidateMessages() error {
+	for i, msg := range r.Messages {
+		switch msg.Role {
+		case RoleSystem, RoleUser, RoleAssistant:
+		default:
+			return &LLMError{
+				Code:    "invalid_request",
+				Message: "invalid message role",
+				Details: fmt.Sprintf("message %d has role %q", i, msg.Role),
+			}
+		}
+		if msg.Content == "" {
+			return &LLMError{
+				Code:    "invalid_request",
+				Message: "empty message content",
+				Details: fmt.Sprintf("message %d has no content", i),
+			}
+		}
+	}
+	return nil
 }
 
 // GenerationResponse represents a single response chunk from the LLM
@@ -25,18 +89,32 @@ type GenerationResponse struct {
 	Choices  []Choice               `json:"choices"`
 	Usage    *Usage                 `json:"usage,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Error    *LLMError              `json:"error,omitempty"`
 }
 
 // Choice represents a single choice in the response
 type Choice struct {
-	Index        int     `json:"index"`
-	Text         string  `json:"text,omitempty"`
-	Delta        *Delta  `json:"delta,omitempty"`
-	FinishReason *string `json:"finish_reason,omitempty"`
+	Index        int      `json:"index"`
+	Text         string   `json:"text,omitempty"`
+	Message      *Message `json:"message,omitempty"`
+	Delta        *Delta   `json:"delta,omitempty"`
+	FinishReason *string  `json:"finish_reason,omitempty"`
+}
+
+// Content returns the generated text regardless of which API produced the choice
+func (c Choice) Content() string {
+	switch {
+	case c.Message != nil:
+		return c.Message.Content
+	case c.Delta != nil:
+		return c.Delta.Content
+	}
+	return c.Text
 }
 
 // Delta represents the incremental content in streaming responses
 type Delta struct {
+	Role    string `json:"role,omitempty"`
 	Content string `json:"content,omitempty"`
 }
 
@@ -77,9 +155,20 @@ type Model struct {
 
 // LLMError represents an error from the LLM service
 type LLMError struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Details    string `json:"details,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"` // HTTP status returned by the provider, if any
+}
+
+// NewHTTPError builds an LLMError for a non-200 provider response
+func NewHTTPError(provider string, statusCode int, body []byte) *LLMError {
+	return &LLMError{
+		Code:       "upstream_error",
+		Message:    fmt.Sprintf("%s API error %d", provider, statusCode),
+		Details:    string(body),
+		StatusCode: statusCode,
+	}
 }
 
 func (e *LLMError) Error() string {
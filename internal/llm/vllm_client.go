@@ -14,20 +14,30 @@ import (
 
 // VLLMClient implements LLMClient for VLLM (Volunteer-run LLM) service
 type VLLMClient struct {
-	baseURL    string
-	apiKey     string
-	timeout    time.Duration
-	maxRetries int
-	httpClient *http.Client
+	baseURL       string
+	apiKey        string
+	timeout       time.Duration
+	healthTimeout time.Duration
+	params        ParamAllowList
+	httpClient    HTTPClientInterface
+	client        *ResilientClient
+	models        *modelCache
 }
 
 // vllmRequest represents a request to the VLLM API
 type vllmRequest struct {
-	Model       string  `json:"model"`
-	Prompt      string  `json:"prompt"`
-	MaxTokens   int     `json:"max_tokens,omitempty"`
-	Temperature float64 `json:"temperature,omitempty"`
-	Stream      bool    `json:"stream,omitempty"`
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Stream bool   `json:"stream,omitempty"`
+	SamplingParams
+}
+
+// vllmChatRequest represents a request to the VLLM chat completions API
+type vllmChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream,omitempty"`
+	SamplingParams
 }
 
 // vllmResponse represents a response from the VLLM API
@@ -41,10 +51,11 @@ type vllmResponse struct {
 
 // vllmChoice represents a choice in the VLLM response
 type vllmChoice struct {
-	Index        int     `json:"index"`
-	Text         string  `json:"text,omitempty"`
-	Delta        *Delta  `json:"delta,omitempty"`
-	FinishReason *string `json:"finish_reason,omitempty"`
+	Index        int      `json:"index"`
+	Text         string   `json:"text,omitempty"`
+	Message      *Message `json:"message,omitempty"`
+	Delta        *Delta   `json:"delta,omitempty"`
+	FinishReason *string  `json:"finish_reason,omitempty"`
 }
 
 // vllmUsage represents usage statistics from VLLM
@@ -56,10 +67,17 @@ type vllmUsage struct {
 
 // VLLMConfig holds configuration for VLLM client
 type VLLMConfig struct {
-	BaseURL    string        `json:"base_url"`
-	APIKey     string        `json:"api_key"`
-	Timeout    time.Duration `json:"timeout"`
-	MaxRetries int           `json:"max_retries"`
+	BaseURL        string               `json:"base_url"`
+	APIKey         string               `json:"api_key"`
+	Timeout        time.Duration        `json:"timeout"`
+	MaxRetries     int                  `json:"max_retries"`
+	RetryBaseDelay time.Duration        `json:"retry_base_delay"`
+	RetryMaxDelay  time.Duration        `json:"retry_max_delay"`
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"`
+	ModelsCacheTTL time.Duration        `json:"models_cache_ttl"`
+	HealthTimeout  time.Duration        `json:"health_timeout"`
+	Params         ParamAllowList       `json:"params"` // Per-model parameter allow-list; unlisted models accept all
+	HTTPClient     HTTPClientInterface  `json:"-"`      // Overrides the default transport, e.g. with a Cassette in tests
 }
 
 // NewVLLMClient creates a new VLLM client
@@ -70,15 +88,40 @@ func NewVLLMClient(config *VLLMConfig) *VLLMClient {
 	if config.MaxRetries == 0 {
 		config.MaxRetries = 3
 	}
+	if config.ModelsCacheTTL == 0 {
+		config.ModelsCacheTTL = 5 * time.Minute
+	}
+	if config.HealthTimeout == 0 {
+		config.HealthTimeout = 5 * time.Second
+	}
+
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = config.MaxRetries
+	if config.RetryBaseDelay > 0 {
+		policy.BaseDelay = config.RetryBaseDelay
+	}
+	if config.RetryMaxDelay > 0 {
+		policy.MaxDelay = config.RetryMaxDelay
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = NewDefaultHTTPClient(config.Timeout)
+	}
 
 	return &VLLMClient{
-		baseURL:    config.BaseURL,
-		apiKey:     config.APIKey,
-		timeout:    config.Timeout,
-		maxRetries: config.MaxRetries,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		baseURL:       config.BaseURL,
+		apiKey:        config.APIKey,
+		timeout:       config.Timeout,
+		healthTimeout: config.HealthTimeout,
+		params:        config.Params,
+		httpClient:    httpClient,
+		client: NewResilientClient(
+			httpClient,
+			policy,
+			NewCircuitBreaker("VLLM", config.CircuitBreaker),
+		),
+		models: newModelCache(config.ModelsCacheTTL),
 	}
 }
 
@@ -87,6 +130,7 @@ func (c *VLLMClient) Generate(ctx context.Context, req *GenerationRequest) (*Gen
 	log.Info().
 		Str("model", req.Model).
 		Str("prompt_preview", truncateString(req.Prompt, 100)).
+		Int("messages", len(req.Messages)).
 		Int("max_tokens", req.MaxTokens).
 		Msg("VLLM Generate request")
 
@@ -95,65 +139,24 @@ func (c *VLLMClient) Generate(ctx context.Context, req *GenerationRequest) (*Gen
 		return c.generateStubResponse(req), nil
 	}
 
-	// Create VLLM API request
-	vllmReq := &vllmRequest{
-		Model:       req.Model,
-		Prompt:      req.Prompt,
-		MaxTokens:   req.MaxTokens,
-		Temperature: req.Temperature,
-		Stream:      false,
-	}
-
-	// Marshal request
-	reqBody, err := json.Marshal(vllmReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/completions", bytes.NewReader(reqBody))
+	// Build the completions or chat completions payload
+	path, reqBody, err := c.buildRequestBody(req, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	if c.apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
-
-	// Execute request with retries
-	var httpResp *http.Response
-	for attempt := 0; attempt <= c.maxRetries; attempt++ {
-		httpResp, err = c.httpClient.Do(httpReq)
-		if err == nil && httpResp.StatusCode < 500 {
-			break
-		}
-
-		if attempt < c.maxRetries {
-			waitTime := time.Duration(attempt+1) * time.Second
-			log.Warn().
-				Err(err).
-				Int("attempt", attempt+1).
-				Dur("wait", waitTime).
-				Msg("Request failed, retrying")
-
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(waitTime):
-			}
-		}
+		return nil, err
 	}
 
+	// Execute request with retries, rebuilding the body for each attempt
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, path, reqBody, false)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("request failed after %d attempts: %w", c.maxRetries+1, err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer httpResp.Body.Close()
 
 	if httpResp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(httpResp.Body)
-		return nil, fmt.Errorf("VLLM API error %d: %s", httpResp.StatusCode, string(body))
+		return nil, NewHTTPError("VLLM", httpResp.StatusCode, body)
 	}
 
 	// Parse response
@@ -162,27 +165,7 @@ func (c *VLLMClient) Generate(ctx context.Context, req *GenerationRequest) (*Gen
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Convert to standard response format
-	response := &GenerationResponse{
-		ID:     vllmResp.ID,
-		Object: vllmResp.Object,
-		Model:  vllmResp.Model,
-		Choices: make([]Choice, len(vllmResp.Choices)),
-	}
-
-	for i, choice := range vllmResp.Choices {
-		response.Choices[i] = Choice(choice)
-	}
-
-	if vllmResp.Usage != nil {
-		response.Usage = &Usage{
-			PromptTokens:     vllmResp.Usage.PromptTokens,
-			CompletionTokens: vllmResp.Usage.CompletionTokens,
-			TotalTokens:      vllmResp.Usage.TotalTokens,
-		}
-	}
-
-	return response, nil
+	return vllmResp.toGenerationResponse(), nil
 }
 
 // GenerateStream performs a streaming generation request
@@ -190,6 +173,7 @@ func (c *VLLMClient) GenerateStream(ctx context.Context, req *GenerationRequest)
 	log.Info().
 		Str("model", req.Model).
 		Str("prompt_preview", truncateString(req.Prompt, 100)).
+		Int("messages", len(req.Messages)).
 		Msg("VLLM GenerateStream request")
 
 	if c.baseURL == "" {
@@ -197,45 +181,24 @@ func (c *VLLMClient) GenerateStream(ctx context.Context, req *GenerationRequest)
 		return c.generateStubStreamResponse(ctx, req), nil
 	}
 
-	// Create VLLM API request for streaming
-	vllmReq := &vllmRequest{
-		Model:       req.Model,
-		Prompt:      req.Prompt,
-		MaxTokens:   req.MaxTokens,
-		Temperature: req.Temperature,
-		Stream:      true,
-	}
-
-	// Marshal request
-	reqBody, err := json.Marshal(vllmReq)
+	// Build the completions or chat completions payload for streaming
+	path, reqBody, err := c.buildRequestBody(req, true)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/completions", bytes.NewReader(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers for streaming
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "text/event-stream")
-	httpReq.Header.Set("Cache-Control", "no-cache")
-	if c.apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
-
-	// Execute request
-	httpResp, err := c.httpClient.Do(httpReq)
+	// Execute request; retries only apply until the stream is established
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, path, reqBody, true)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 
 	if httpResp.StatusCode != http.StatusOK {
-		httpResp.Body.Close()
 		body, _ := io.ReadAll(httpResp.Body)
-		return nil, fmt.Errorf("VLLM API error %d: %s", httpResp.StatusCode, string(body))
+		httpResp.Body.Close()
+		return nil, NewHTTPError("VLLM", httpResp.StatusCode, body)
 	}
 
 	// Create response channel
@@ -247,6 +210,62 @@ func (c *VLLMClient) GenerateStream(ctx context.Context, req *GenerationRequest)
 	return responseChan, nil
 }
 
+// buildRequestBody selects the VLLM endpoint for req and marshals its payload.
+// Requests carrying messages use the chat completions API, others the legacy prompt API.
+func (c *VLLMClient) buildRequestBody(req *GenerationRequest, stream bool) (string, []byte, error) {
+	var path string
+	var payload interface{}
+
+	if err := c.params.Validate(req.Model, req.SamplingParams()); err != nil {
+		return "", nil, err
+	}
+
+	if req.IsChat() {
+		if err := req.ValidateMessages(); err != nil {
+			return "", nil, err
+		}
+		path = "/v1/chat/completions"
+		payload = &vllmChatRequest{
+			Model:          req.Model,
+			Messages:       req.Messages,
+			Stream:         stream,
+			SamplingParams: req.SamplingParams(),
+		}
+	} else {
+		path = "/v1/completions"
+		payload = &vllmRequest{
+			Model:          req.Model,
+			Prompt:         req.Prompt,
+			Stream:         stream,
+			SamplingParams: req.SamplingParams(),
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return path, body, nil
+}
+
+// newRequest builds an HTTP request to the VLLM API with a fresh body reader
+func (c *VLLMClient) newRequest(ctx context.Context, path string, body []byte, stream bool) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+		httpReq.Header.Set("Cache-Control", "no-cache")
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return httpReq, nil
+}
+
 // Helper functions for VLLM client operations
 
 // truncateString truncates a string to maxLen with ellipsis
@@ -290,7 +309,77 @@ func (c *VLLMClient) processStreamResponse(ctx context.Context, body io.ReadClos
 	// TODO: Move to vllm_helpers.go
 	defer body.Close()
 	defer close(responseChan)
-	// Stub implementation for now
+
+	reader := NewSSEReader(body)
+	for {
+		event, err := reader.Next()
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				log.Error().Err(err).Msg("Failed to read VLLM stream")
+				c.sendStreamResponse(ctx, responseChan, &GenerationResponse{
+					Error: &LLMError{Code: "stream_read_error", Message: "failed to read stream", Details: err.Error()},
+				})
+			}
+			return
+		}
+
+		if event.IsDone() {
+			return
+		}
+
+		if llmErr := StreamErrorFromEvent(event); llmErr != nil {
+			log.Warn().Str("code", llmErr.Code).Str("message", llmErr.Message).Msg("VLLM stream reported an error")
+			c.sendStreamResponse(ctx, responseChan, &GenerationResponse{Error: llmErr})
+			return
+		}
+
+		var chunk vllmResponse
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			log.Error().Err(err).Str("data", truncateString(event.Data, 200)).Msg("Failed to decode VLLM stream chunk")
+			c.sendStreamResponse(ctx, responseChan, &GenerationResponse{
+				Error: &LLMError{Code: "invalid_stream_chunk", Message: "failed to decode stream chunk", Details: err.Error()},
+			})
+			return
+		}
+
+		if !c.sendStreamResponse(ctx, responseChan, chunk.toGenerationResponse()) {
+			return
+		}
+	}
+}
+
+// sendStreamResponse delivers a response unless the context is cancelled first
+func (c *VLLMClient) sendStreamResponse(ctx context.Context, responseChan chan<- *GenerationResponse, resp *GenerationResponse) bool {
+	select {
+	case responseChan <- resp:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// toGenerationResponse converts a VLLM response into the standard response format
+func (r *vllmResponse) toGenerationResponse() *GenerationResponse {
+	response := &GenerationResponse{
+		ID:      r.ID,
+		Object:  r.Object,
+		Model:   r.Model,
+		Choices: make([]Choice, len(r.Choices)),
+	}
+
+	for i, choice := range r.Choices {
+		response.Choices[i] = Choice(choice)
+	}
+
+	if r.Usage != nil {
+		response.Usage = &Usage{
+			PromptTokens:     r.Usage.PromptTokens,
+			CompletionTokens: r.Usage.CompletionTokens,
+			TotalTokens:      r.Usage.TotalTokens,
+		}
+	}
+
+	return response
 }
 
 // stringPtr returns a pointer to a string
@@ -305,26 +394,109 @@ func (c *VLLMClient) Close() error {
 	return nil
 }
 
-// GetModels returns available models from the VLLM service
+// GetModels returns the models served by VLLM from the /v1/models endpoint.
+// Results are cached for the configured TTL; a stale list is served if a refresh fails.
 func (c *VLLMClient) GetModels(ctx context.Context) ([]Model, error) {
-	// TODO: Implement actual models endpoint call
-	// For now, return a default model
-	models := []Model{
-		{
-			ID:          "default",
-			Name:        "Default VLLM Model",
-			Description: "Default model served by VLLM",
-			Provider:    "VLLM",
-			MaxTokens:   4096,
-			CreatedAt:   time.Now(),
-		},
+	if c.baseURL == "" {
+		// Fallback to a stubbed model if no baseURL configured
+		return []Model{
+			{
+				ID:          "default",
+				Name:        "Default VLLM Model",
+				Description: "Default model served by VLLM",
+				Provider:    "VLLM",
+				MaxTokens:   4096,
+				CreatedAt:   time.Now(),
+			},
+		}, nil
+	}
+
+	if models, ok := c.models.get(); ok {
+		return models, nil
 	}
+
+	models, err := c.fetchModels(ctx)
+	if err != nil {
+		if stale, ok := c.models.stale(); ok {
+			log.Warn().Err(err).Msg("Failed to refresh VLLM models, serving cached list")
+			return stale, nil
+		}
+		return nil, err
+	}
+
+	c.models.set(models)
 	return models, nil
 }
 
-// Health checks if the VLLM service is healthy
+// fetchModels calls the OpenAI-compatible models endpoint
+func (c *VLLMClient) fetchModels(ctx context.Context) ([]Model, error) {
+	httpResp, err := c.client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return c.newGetRequest(ctx, "/v1/models")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(httpResp.Body)
+		return nil, NewHTTPError("VLLM", httpResp.StatusCode, body)
+	}
+
+	var list vllmModelList
+	if err := json.NewDecoder(httpResp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
+	}
+
+	models := make([]Model, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, m.toModel())
+	}
+	return models, nil
+}
+
+// Health checks if the VLLM service is healthy by probing its /health
+// endpoint with a bounded timeout. An open circuit breaker is reported
+// without contacting the server.
 func (c *VLLMClient) Health(ctx context.Context) error {
-	// TODO: Implement actual health check endpoint
-	// For now, assume the service is healthy
+	if c.baseURL == "" {
+		// The stubbed client is always available
+		return nil
+	}
+
+	if err := c.client.Health(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.healthTimeout)
+	defer cancel()
+
+	httpReq, err := c.newGetRequest(ctx, "/health")
+	if err != nil {
+		return err
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return &LLMError{Code: "unreachable", Message: "VLLM health check failed", Details: err.Error()}
+	}
+	defer drainAndClose(httpResp.Body)
+
+	if httpResp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return NewHTTPError("VLLM", httpResp.StatusCode, body)
+	}
 	return nil
 }
+
+// newGetRequest builds a GET request to the VLLM API
+func (c *VLLMClient) newGetRequest(ctx context.Context, path string) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return httpReq, nil
+}
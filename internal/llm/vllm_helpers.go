@@ -30,9 +30,3 @@ func generateStubbedResponse(prompt string) string {
 
 	return responses[hash%len(responses)]
 }
-
-// estimateTokens provides a rough estimation of token count
-func estimateTokens(text string) int {
-	// Rough estimation: ~4 characters per token
-	return len(text) / 4
-}
@@ -1,7 +1,9 @@
 package llm
 
 import (
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -15,11 +17,16 @@ type DefaultHTTPClient struct {
 	client *http.Client
 }
 
-// NewDefaultHTTPClient creates a new default HTTP client
+// NewDefaultHTTPClient creates a new default HTTP client. timeout bounds
+// waiting for the response headers rather than the whole request, so that
+// streamed bodies are read for as long as generation takes; the request
+// context bounds the rest.
 func NewDefaultHTTPClient(timeout time.Duration) *DefaultHTTPClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
 	return &DefaultHTTPClient{
 		client: &http.Client{
-			Timeout: timeout,
+			Transport: transport,
 		},
 	}
 }
@@ -28,3 +35,79 @@ func NewDefaultHTTPClient(timeout time.Duration) *DefaultHTTPClient {
 func (c *DefaultHTTPClient) Do(req *http.Request) (*http.Response, error) {
 	return c.client.Do(req)
 }
+
+// vllmModelList represents the response of the /v1/models endpoint
+type vllmModelList struct {
+	Object string      `json:"object"`
+	Data   []vllmModel `json:"data"`
+}
+
+// vllmModel represents a single model entry served by VLLM
+type vllmModel struct {
+	ID          string `json:"id"`
+	Object      string `json:"object"`
+	Created     int64  `json:"created"`
+	OwnedBy     string `json:"owned_by"`
+	Root        string `json:"root,omitempty"`
+	MaxModelLen int    `json:"max_model_len,omitempty"`
+}
+
+// toModel converts a VLLM model entry into the standard model format
+func (m vllmModel) toModel() Model {
+	description := "Model served by VLLM"
+	if m.Root != "" && m.Root != m.ID {
+		description = fmt.Sprintf("Model served by VLLM from %s", m.Root)
+	}
+
+	model := Model{
+		ID:          m.ID,
+		Name:        m.ID,
+		Description: description,
+		Provider:    "VLLM",
+		MaxTokens:   m.MaxModelLen,
+	}
+	if m.Created > 0 {
+		model.CreatedAt = time.Unix(m.Created, 0).UTC()
+	}
+	return model
+}
+
+// modelCache holds a model list for a limited time
+type modelCache struct {
+	ttl time.Duration
+
+	mu        sync.RWMutex
+	models    []Model
+	fetchedAt time.Time
+}
+
+// newModelCache creates a new model cache with the given TTL
+func newModelCache(ttl time.Duration) *modelCache {
+	return &modelCache{ttl: ttl}
+}
+
+// get returns the cached models if they have not expired
+func (c *modelCache) get() ([]Model, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.models == nil || time.Since(c.fetchedAt) > c.ttl {
+		return nil, false
+	}
+	return c.models, true
+}
+
+// stale returns the cached models regardless of age
+func (c *modelCache) stale() ([]Model, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.models, c.models != nil
+}
+
+// set replaces the cached models
+func (c *modelCache) set(models []Model) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.models = models
+	c.fetchedAt = time.Now()
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/ratelimit"
+)
+
+// APIKeyIDContextKey is the context key API key authentication stores the
+// ID of the key a request was made with under
+const APIKeyIDContextKey = "api_key_id"
+
+const (
+	// DefaultPlanCacheTTL is how long DistributedRateLimit reuses the plan
+	// of a user before looking it up again
+	DefaultPlanCacheTTL = 30 * time.Second
+	// maxCachedPlans is how many users' plans are cached before expired
+	// ones are dropped
+	maxCachedPlans = 10000
+)
+
+// RateLimitPolicies holds a default policy and overrides per plan tier
+type RateLimitPolicies struct {
+	Default ratelimit.Policy
+	Plans   map[ai.PlanTier]ratelimit.Policy
+}
+
+// RateLimitConfig configures DistributedRateLimit. Routes are keyed by
+// method and route pattern, e.g. "POST /api/v1/ai/generate", and are counted
+// separately from the rest of the API. A policy with a zero limit does not
+// limit requests.
+type RateLimitConfig struct {
+	RateLimitPolicies
+	Routes       map[string]RateLimitPolicies
+	PlanCacheTTL time.Duration // Defaults to DefaultPlanCacheTTL
+}
+
+// DistributedRateLimit limits requests per authenticated user, API key or
+// client IP with limits shared by every replica through limiter. It must run
+// after authentication to limit users by their plan, which is looked up with
+// plans when the matching policies have plan overrides and cached for
+// config.PlanCacheTTL. Responses carry RateLimit-* headers and rejected
+// requests a Retry-After header.
+func DistributedRateLimit(limiter *ratelimit.Limiter, config RateLimitConfig, plans ai.QuotaPlanRepository) gin.HandlerFunc {
+	if config.PlanCacheTTL <= 0 {
+		config.PlanCacheTTL = DefaultPlanCacheTTL
+	}
+	tiers := &planCache{plans: plans, ttl: config.PlanCacheTTL, tiers: make(map[common.UserID]cachedTier)}
+
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		policies, ok := config.Routes[route]
+		name := route
+		if !ok {
+			policies, name = config.RateLimitPolicies, "global"
+		}
+
+		userID := rateLimitUserID(c)
+		policy := policies.Default
+		if len(policies.Plans) > 0 && userID != "" && plans != nil {
+			tier, err := tiers.get(c.Request.Context(), userID)
+			if err != nil {
+				log.Debug().
+					Str("user_id", string(userID)).
+					Err(err).
+					Msg("Failed to get plan for rate limiting")
+			} else if planPolicy, ok := policies.Plans[tier]; ok {
+				policy = planPolicy
+			}
+		}
+		if policy.Limit <= 0 {
+			c.Next()
+			return
+		}
+		if policy.Name == "" {
+			policy.Name = name
+		}
+
+		result := limiter.Take(c.Request.Context(), rateLimitKey(c, userID), policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		c.Header("RateLimit-Policy", policy.String())
+
+		if !result.Allowed {
+			retryAfter := max(ceilSeconds(result.RetryAfter), 1)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "rate limit exceeded",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// cachedTier is the plan tier of a user and when it must be looked up again
+type cachedTier struct {
+	tier      ai.PlanTier
+	expiresAt time.Time
+}
+
+// planCache looks up the plan tiers of users, reusing them for ttl so plans
+// are not loaded on every request. Failed lookups are not cached.
+type planCache struct {
+	plans ai.QuotaPlanRepository
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	tiers map[common.UserID]cachedTier
+}
+
+// get returns the plan tier of a user
+func (p *planCache) get(ctx context.Context, userID common.UserID) (ai.PlanTier, error) {
+	now := time.Now()
+	p.mu.Lock()
+	cached, ok := p.tiers[userID]
+	p.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.tier, nil
+	}
+
+	account, err := p.plans.GetQuotaAccount(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.tiers) >= maxCachedPlans {
+		for id, t := range p.tiers {
+			if !now.Before(t.expiresAt) {
+				delete(p.tiers, id)
+			}
+		}
+		if len(p.tiers) >= maxCachedPlans {
+			clear(p.tiers)
+		}
+	}
+	p.tiers[userID] = cachedTier{tier: account.Plan.Tier, expiresAt: now.Add(p.ttl)}
+	return account.Plan.Tier, nil
+}
+
+// rateLimitUserID returns the authenticated user of a request, if any
+func rateLimitUserID(c *gin.Context) common.UserID {
+	value, _ := c.Get("user_id")
+	switch userID := value.(type) {
+	case common.UserID:
+		return userID
+	case string:
+		return common.UserID(userID)
+	}
+	return ""
+}
+
+// rateLimitKey returns the key a request is limited by: its authenticated
+// user, else its API key, else its client IP
+func rateLimitKey(c *gin.Context, userID common.UserID) string {
+	if userID != "" {
+		return ratelimit.UserKey(userID)
+	}
+	if keyID := c.GetString(APIKeyIDContextKey); keyID != "" {
+		return ratelimit.APIKeyKey(keyID)
+	}
+	return ratelimit.IPKey(c.ClientIP())
+}
+
+// ceilSeconds rounds d up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
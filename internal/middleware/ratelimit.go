@@ -10,6 +10,9 @@ import (
 )
 
 // RateLimiter stores rate limiters for different clients
+//
+// Deprecated: RateLimiter keeps a limiter per client in this replica's memory
+// and never evicts them. Use DistributedRateLimit instead.
 type RateLimiter struct {
 	limiters map[string]*rate.Limiter
 	mu       sync.RWMutex
@@ -61,6 +64,8 @@ func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 }
 
 // CreateRateLimitMiddleware creates a rate limit middleware with specified limits
+//
+// Deprecated: Use DistributedRateLimit instead.
 func CreateRateLimitMiddleware(requestsPerSecond int, burst int) gin.HandlerFunc {
 	rl := NewRateLimiter(rate.Limit(requestsPerSecond), burst)
 	return rl.RateLimit()
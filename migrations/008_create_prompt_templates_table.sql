@@ -0,0 +1,31 @@
+-- +migrate Up
+-- Create prompt_templates table for versioned prompt templates managed at runtime
+CREATE TABLE prompt_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL, -- Template family, e.g. 'react-tailwind'
+    framework VARCHAR(50) NOT NULL, -- 'react', 'vue', 'svelte', 'html', etc.
+    language VARCHAR(50) NOT NULL DEFAULT '', -- Empty matches any language
+    version INTEGER NOT NULL CHECK (version > 0),
+    system_template TEXT NOT NULL DEFAULT '',
+    user_template TEXT NOT NULL,
+    variables JSONB DEFAULT '{}', -- Default values for template variables
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (name, language, version)
+);
+
+-- Create indexes for performance
+CREATE INDEX idx_prompt_templates_framework ON prompt_templates(framework);
+CREATE INDEX idx_prompt_templates_is_active ON prompt_templates(is_active);
+
+-- Create trigger to automatically update updated_at
+CREATE TRIGGER update_prompt_templates_updated_at
+    BEFORE UPDATE ON prompt_templates
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- +migrate Down
+-- Drop prompt_templates table and related objects
+DROP TRIGGER IF EXISTS update_prompt_templates_updated_at ON prompt_templates;
+DROP TABLE IF EXISTS prompt_templates;
@@ -0,0 +1,14 @@
+-- +migrate Up
+-- Add model and token usage to ui_generations so history and quota usage are persisted
+ALTER TABLE ui_generations ADD COLUMN model VARCHAR(255);
+ALTER TABLE ui_generations ADD COLUMN tokens INTEGER NOT NULL DEFAULT 0;
+
+-- Create index for cursor-paginated history and daily quota queries
+CREATE INDEX idx_ui_generations_user_created ON ui_generations(user_id, created_at DESC, id DESC);
+
+-- +migrate Down
+-- Remove usage columns
+DROP INDEX IF EXISTS idx_ui_generations_user_created;
+
+ALTER TABLE ui_generations DROP COLUMN IF EXISTS tokens;
+ALTER TABLE ui_generations DROP COLUMN IF EXISTS model;
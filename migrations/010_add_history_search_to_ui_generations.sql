@@ -0,0 +1,16 @@
+-- +migrate Up
+-- Add soft delete to ui_generations so history entries can be removed without losing quota usage
+ALTER TABLE ui_generations ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Create indexes for history filters and full-text search over prompts
+CREATE INDEX idx_ui_generations_deleted_at ON ui_generations(deleted_at);
+CREATE INDEX idx_ui_generations_model ON ui_generations(model);
+CREATE INDEX idx_ui_generations_prompt_search ON ui_generations USING GIN(to_tsvector('english', prompt));
+
+-- +migrate Down
+-- Remove history search indexes and soft delete
+DROP INDEX IF EXISTS idx_ui_generations_prompt_search;
+DROP INDEX IF EXISTS idx_ui_generations_model;
+DROP INDEX IF EXISTS idx_ui_generations_deleted_at;
+
+ALTER TABLE ui_generations DROP COLUMN IF EXISTS deleted_at;
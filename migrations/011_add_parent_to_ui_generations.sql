@@ -0,0 +1,12 @@
+-- +migrate Up
+-- Link refined generations to the generation they revise, forming a revision tree
+ALTER TABLE ui_generations ADD COLUMN parent_id UUID REFERENCES ui_generations(id) ON DELETE SET NULL;
+
+-- Create index for walking revision trees
+CREATE INDEX idx_ui_generations_parent_id ON ui_generations(parent_id);
+
+-- +migrate Down
+-- Remove revision links
+DROP INDEX IF EXISTS idx_ui_generations_parent_id;
+
+ALTER TABLE ui_generations DROP COLUMN IF EXISTS parent_id;
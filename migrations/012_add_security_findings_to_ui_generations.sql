@@ -0,0 +1,10 @@
+-- +migrate Up
+-- Store the security scanner findings of each generation alongside its code
+ALTER TABLE ui_generations ADD COLUMN security_findings JSONB NOT NULL DEFAULT '[]';
+
+-- Projects configure the scanner under config->'security', e.g.
+-- {"security": {"disabled_rules": ["target-blank"], "severities": {"eval": "warning"}, "allowed_origins": ["cdn.example.com"]}}
+
+-- +migrate Down
+-- Remove security findings
+ALTER TABLE ui_generations DROP COLUMN IF EXISTS security_findings;
@@ -0,0 +1,8 @@
+-- +migrate Up
+-- Record every version produced while repairing invalid generated code,
+-- with the validator diagnostics of each
+ALTER TABLE ui_generations ADD COLUMN repair_attempts JSONB NOT NULL DEFAULT '[]';
+
+-- +migrate Down
+-- Remove repair attempts
+ALTER TABLE ui_generations DROP COLUMN IF EXISTS repair_attempts;
@@ -0,0 +1,36 @@
+-- +migrate Up
+-- Quota plans with daily and monthly token and request limits. Limits other
+-- than daily_tokens are unlimited when 0. model_multipliers maps model name
+-- prefixes to the factor their tokens count with.
+CREATE TABLE quota_plans (
+    tier VARCHAR(50) PRIMARY KEY,
+    daily_tokens INTEGER NOT NULL,
+    monthly_tokens INTEGER NOT NULL DEFAULT 0,
+    daily_requests INTEGER NOT NULL DEFAULT 0,
+    monthly_requests INTEGER NOT NULL DEFAULT 0,
+    model_multipliers JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO quota_plans (tier, daily_tokens, monthly_tokens, daily_requests, monthly_requests, model_multipliers) VALUES
+    ('free', 100000, 1000000, 50, 1000, '{"gpt-4o-mini": 0.5, "gpt-4o": 2, "gpt-4": 4, "claude-3-opus": 5, "claude-3-5-sonnet": 2}'),
+    ('pro', 1000000, 20000000, 500, 10000, '{"gpt-4o-mini": 0.5, "gpt-4o": 2, "gpt-4": 4, "claude-3-opus": 5, "claude-3-5-sonnet": 2}'),
+    ('team', 5000000, 100000000, 0, 0, '{"gpt-4o-mini": 0.5, "gpt-4o": 1.5, "gpt-4": 3, "claude-3-opus": 4, "claude-3-5-sonnet": 1.5}');
+
+-- Every user is on a plan, free unless upgraded
+ALTER TABLE users ADD COLUMN plan_tier VARCHAR(50) NOT NULL DEFAULT 'free' REFERENCES quota_plans(tier);
+
+CREATE INDEX idx_users_plan_tier ON users(plan_tier);
+
+CREATE TRIGGER update_quota_plans_updated_at
+    BEFORE UPDATE ON quota_plans
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- +migrate Down
+-- Drop quota plans
+DROP INDEX IF EXISTS idx_users_plan_tier;
+ALTER TABLE users DROP COLUMN IF EXISTS plan_tier;
+DROP TRIGGER IF EXISTS update_quota_plans_updated_at ON quota_plans;
+DROP TABLE IF EXISTS quota_plans;
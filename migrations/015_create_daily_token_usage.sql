@@ -0,0 +1,21 @@
+-- +migrate Up
+-- Tokens each user has used per UTC day, for the daily quota used without
+-- quota plans. Usage is counted however a generation ends, including failed
+-- and cancelled ones, and outlives deleted history.
+CREATE TABLE daily_token_usage (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    tokens BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+-- Carry over the usage recorded on generations so far
+INSERT INTO daily_token_usage (user_id, day, tokens)
+SELECT user_id, (created_at AT TIME ZONE 'UTC')::date, SUM(tokens)
+FROM ui_generations
+WHERE tokens > 0
+GROUP BY 1, 2;
+
+-- +migrate Down
+-- Drop daily token usage
+DROP TABLE IF EXISTS daily_token_usage;
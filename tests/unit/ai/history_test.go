@@ -22,9 +22,23 @@ func setupHistoryTest() (*ai.Handler, *ai.Service, *gin.Engine) {
 	r := gin.New()
 	group := r.Group("")
 	h.RegisterRoutes(group)
+	group.GET("/ai/history", h.History)
 	return h, svc, r
 }
 
+func TestRegisterRoutesLeavesOutDeprecatedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ai.NewHandler(ai.NewService(&mockLLM{})).RegisterRoutes(r.Group(""))
+
+	for _, path := range []string{"/ai/history?user_id=user1", "/ai/quota?user_id=user1"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 404, w.Code, path)
+	}
+}
+
 func TestHistoryEndpoint(t *testing.T) {
 	_, svc, r := setupHistoryTest()
 	userID := "user1"
@@ -83,3 +83,35 @@ func TestGenerationParams(t *testing.T) {
 	assert.Len(t, history, 1)
 	assert.Equal(t, "test prompt", history[0].Prompt)
 }
+
+type mockParamsLLM struct {
+	mockLLM
+	params ai.GenerationParams
+}
+
+func (m *mockParamsLLM) GenerateWithParams(prompt string, params ai.GenerationParams) (string, error) {
+	m.params = params
+	return "<div>" + prompt + "</div>", nil
+}
+
+func (m *mockParamsLLM) StreamGenerateWithParams(prompt string, params ai.GenerationParams, ch chan string) error {
+	m.params = params
+	return nil
+}
+
+func TestGenerationParams_ForwardedToClient(t *testing.T) {
+	client := &mockParamsLLM{}
+	svc := ai.NewService(client)
+
+	topP := 0.9
+	params := ai.GenerationParams{
+		Model:          "gpt-4o",
+		TopP:           &topP,
+		Stop:           []string{"</div>"},
+		ResponseFormat: "text",
+	}
+
+	_, err := svc.GenerateCodeWithParams("test prompt", "user1", params)
+	assert.NoError(t, err)
+	assert.Equal(t, params, client.params)
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/EliasRanz/ai-code-gen/internal/ai"
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
 )
 
 type mockValidateService struct{}
@@ -43,7 +44,7 @@ func TestValidateCodeHandler_Success(t *testing.T) {
 	h := newValidateTestHandler()
 	r := gin.Default()
 	r.POST("/ai/validate", h.ValidateCode)
-	body, _ := json.Marshal(ai.ValidateRequest{Code: "good"})
+	body, _ := json.Marshal(ai.ValidateRequest{Code: "export const Button = () => <button className=\"btn\">Go</button>;"})
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/ai/validate", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
@@ -53,6 +54,7 @@ func TestValidateCodeHandler_Success(t *testing.T) {
 	_ = json.Unmarshal(w.Body.Bytes(), &resp)
 	assert.True(t, resp.Valid)
 	assert.Empty(t, resp.Errors)
+	assert.Equal(t, "javascript", resp.Language, "the language is detected when the request has none")
 }
 
 func TestValidateCodeHandler_InvalidRequest(t *testing.T) {
@@ -92,3 +94,27 @@ func TestValidateCodeHandler_Failure(t *testing.T) {
 	}
 	assert.True(t, foundScript)
 }
+
+func TestValidateCodeHandler_ReturnsFindings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := newValidateTestHandler()
+	r := gin.Default()
+	r.POST("/ai/validate", h.ValidateCode)
+	body, _ := json.Marshal(ai.ValidateRequest{Code: "<a href=\"/docs\" target=\"_blank\">Docs</a>"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/ai/validate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var resp ai.ValidateResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	// Warnings are reported without failing validation
+	assert.True(t, resp.Valid)
+	assert.Equal(t, []aiapp.SecurityFindingResponse{{
+		Line:     1,
+		Column:   1,
+		Severity: "warning",
+		Rule:     "target-blank",
+		Message:  `target="_blank" without rel="noopener" lets the opened page control this one`,
+	}}, resp.Findings)
+}
@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	infrallm "github.com/EliasRanz/ai-code-gen/internal/infrastructure/llm"
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+func TestGenerateCodeUseCase_MultiFileArtifact(t *testing.T) {
+	var sent ai.GenerationRequest
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(1).(ai.GenerationRequest)
+	}).Return(ai.GenerationResult{
+		Code: `{"files": [{"path": "Button.tsx", "content": "<button/>"}, {"path": "Button.test.tsx", "content": "test()"}], "dependencies": {"react": "^18.3.0"}}`,
+	}, nil)
+
+	req := jobRequest("user-1", "A button", "")
+	req.Framework = "react"
+	req.MultiFile = true
+
+	resp, err := newJobTestUseCase(mockLLM).Execute(context.Background(), req)
+	require.NoError(t, err)
+
+	require.Len(t, sent.Messages, 2)
+	assert.Equal(t, ai.RoleSystem, sent.Messages[0].Role)
+	assert.Contains(t, sent.Messages[0].Content, `"files"`)
+	assert.Equal(t, ai.ChatMessage{Role: ai.RoleUser, Content: "A button"}, sent.Messages[1])
+	assert.Equal(t, "json_object", sent.ResponseFormat)
+
+	require.NotNil(t, resp.Artifact)
+	assert.Equal(t, []aiapp.ArtifactFileResponse{
+		{Path: "Button.tsx", Language: "tsx", Content: "<button/>"},
+		{Path: "Button.test.tsx", Language: "tsx", Content: "test()"},
+	}, resp.Artifact.Files)
+	assert.Equal(t, map[string]string{"react": "^18.3.0"}, resp.Artifact.Dependencies)
+}
+
+func TestGenerateCodeUseCase_SingleFileArtifact(t *testing.T) {
+	var sent ai.GenerationRequest
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(1).(ai.GenerationRequest)
+	}).Return(ai.GenerationResult{Code: "```tsx\nexport const Button = () => <button/>;\n```"}, nil)
+
+	req := jobRequest("user-1", "A button", "")
+	req.Framework = "react"
+
+	resp, err := newJobTestUseCase(mockLLM).Execute(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Empty(t, sent.Messages, "plain requests are sent unchanged")
+	require.NotNil(t, resp.Artifact)
+	assert.Equal(t, []aiapp.ArtifactFileResponse{
+		{Path: "Component.tsx", Language: "tsx", Content: "export const Button = () => <button/>;"},
+	}, resp.Artifact.Files)
+}
+
+func TestGenerateCodeUseCase_MultiFileArtifactWithAnthropic(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet-20241022",
+			"content":[{"type":"text","text":"{\"files\": [{\"path\": \"Button.tsx\", \"content\": \"<button/>\"}]}"}],
+			"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":8}}`)
+	}))
+	defer server.Close()
+
+	service := infrallm.NewAnthropicService(&infrallm.AnthropicConfig{
+		AnthropicConfig: llm.AnthropicConfig{BaseURL: server.URL, APIKey: "test-key"},
+	})
+
+	req := jobRequest("user-1", "A button", "")
+	req.Framework = "react"
+	req.MultiFile = true
+
+	resp, err := newJobTestUseCase(service).Execute(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Contains(t, gotBody["system"], `"files"`, "the artifact format is requested in the system prompt")
+	assert.NotContains(t, gotBody, "response_format")
+	require.NotNil(t, resp.Artifact)
+	assert.Equal(t, []aiapp.ArtifactFileResponse{
+		{Path: "Button.tsx", Language: "tsx", Content: "<button/>"},
+	}, resp.Artifact.Files)
+}
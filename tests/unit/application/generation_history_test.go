@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/user"
+)
+
+// memoryHistoryRepository is an ai.HistoryRepository that records the last query
+type memoryHistoryRepository struct {
+	entries   map[string]ai.GenerationHistory
+	lastQuery ai.HistoryQuery
+}
+
+func (r *memoryHistoryRepository) ListHistory(ctx context.Context, query ai.HistoryQuery) (ai.HistoryPage, error) {
+	r.lastQuery = query
+	var page ai.HistoryPage
+	for _, h := range r.entries {
+		if query.UserID == "" || h.UserID == query.UserID {
+			page.Items = append(page.Items, h)
+		}
+	}
+	return page, nil
+}
+
+func (r *memoryHistoryRepository) GetHistoryEntry(ctx context.Context, id string) (ai.GenerationHistory, error) {
+	h, ok := r.entries[id]
+	if !ok {
+		return ai.GenerationHistory{}, common.NewNotFoundError("generation not found")
+	}
+	return h, nil
+}
+
+func (r *memoryHistoryRepository) ListRevisions(ctx context.Context, id string) ([]ai.GenerationHistory, error) {
+	root := r.entries[id]
+	for root.ParentID != "" {
+		root = r.entries[root.ParentID]
+	}
+	tree := []ai.GenerationHistory{root}
+	for i := 0; i < len(tree); i++ {
+		for _, h := range r.entries {
+			if h.ParentID == tree[i].ID {
+				tree = append(tree, h)
+			}
+		}
+	}
+	return tree, nil
+}
+
+func (r *memoryHistoryRepository) DeleteHistoryEntry(ctx context.Context, id string) error {
+	delete(r.entries, id)
+	return nil
+}
+
+func newHistoryTestService() (*aiapp.GenerationHistoryService, *memoryHistoryRepository) {
+	repo := &memoryHistoryRepository{entries: map[string]ai.GenerationHistory{
+		"gen-1":   {ID: "gen-1", UserID: "user-1", Prompt: "A button", Code: "<button/>", Status: ai.GenerationCompleted},
+		"gen-2":   {ID: "gen-2", UserID: "user-2", Prompt: "A card", Code: "<div/>", Status: ai.GenerationCompleted},
+		"running": {ID: "running", UserID: "user-1", Prompt: "A form", Status: ai.GenerationInProgress},
+	}}
+	users := roleUsers{roles: map[common.UserID]user.Role{
+		"user-1":  user.RoleUser,
+		"user-2":  user.RoleUser,
+		"admin-1": user.RoleAdmin,
+	}}
+	return aiapp.NewGenerationHistoryService(repo, users), repo
+}
+
+func TestGenerationHistoryService_ListIsScopedToCaller(t *testing.T) {
+	ctx := context.Background()
+	history, repo := newHistoryTestService()
+
+	page, err := history.List(ctx, "user-1", aiapp.ListGenerationsRequest{UserID: "user-2"})
+	require.NoError(t, err)
+	assert.Equal(t, common.UserID("user-1"), repo.lastQuery.UserID, "user_id is ignored for non-admins")
+	assert.Len(t, page.Items, 2)
+	for _, item := range page.Items {
+		assert.Empty(t, item.Code, "lists leave out the code")
+	}
+
+	page, err = history.List(ctx, "admin-1", aiapp.ListGenerationsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 3, "admins see every user's generations")
+
+	_, err = history.List(ctx, "admin-1", aiapp.ListGenerationsRequest{UserID: "user-2"})
+	require.NoError(t, err)
+	assert.Equal(t, common.UserID("user-2"), repo.lastQuery.UserID)
+}
+
+func TestGenerationHistoryService_ListFilters(t *testing.T) {
+	history, repo := newHistoryTestService()
+	cursor := ai.HistoryCursor{CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), ID: "0b6a1d2e-8d7b-4a43-9c3e-6f1c2a4e3f40"}
+
+	_, err := history.List(context.Background(), "user-1", aiapp.ListGenerationsRequest{
+		ProjectID: "project-1",
+		Framework: "react",
+		Model:     "gpt-4o-mini",
+		Status:    "failed",
+		From:      "2024-04-01",
+		To:        "2024-05-01T00:00:00Z",
+		Query:     "login form",
+		Cursor:    cursor.Encode(),
+		Limit:     10,
+	})
+	require.NoError(t, err)
+
+	query := repo.lastQuery
+	require.NotNil(t, query.ProjectID)
+	assert.Equal(t, common.ProjectID("project-1"), *query.ProjectID)
+	assert.Equal(t, "react", query.Framework)
+	assert.Equal(t, "gpt-4o-mini", query.Model)
+	assert.Equal(t, ai.GenerationFailed, query.Status)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), query.CreatedAfter)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), query.CreatedBefore)
+	assert.Equal(t, "login form", query.Search)
+	require.NotNil(t, query.Cursor)
+	assert.Equal(t, cursor.ID, query.Cursor.ID)
+	assert.Equal(t, 10, query.PageSize())
+}
+
+func TestGenerationHistoryService_ListRejectsInvalidFilters(t *testing.T) {
+	history, _ := newHistoryTestService()
+	for _, req := range []aiapp.ListGenerationsRequest{
+		{Status: "done"},
+		{From: "yesterday"},
+		{To: "2024-13-01"},
+		{Cursor: "%%%"},
+	} {
+		_, err := history.List(context.Background(), "user-1", req)
+		assert.True(t, common.IsValidationError(err), "request %+v", req)
+	}
+}
+
+func TestGenerationHistoryService_GetAndDelete(t *testing.T) {
+	ctx := context.Background()
+	history, repo := newHistoryTestService()
+
+	entry, err := history.Get(ctx, "user-1", "gen-1")
+	require.NoError(t, err)
+	assert.Equal(t, "<button/>", entry.Code)
+
+	_, err = history.Get(ctx, "user-1", "gen-2")
+	assert.True(t, common.IsNotFoundError(err), "other users' generations are hidden")
+	assert.True(t, common.IsNotFoundError(history.Delete(ctx, "user-1", "gen-2")))
+
+	_, err = history.Get(ctx, "admin-1", "gen-2")
+	assert.NoError(t, err)
+
+	assert.True(t, common.IsConflictError(history.Delete(ctx, "user-1", "running")), "running generations are cancelled, not deleted")
+
+	require.NoError(t, history.Delete(ctx, "user-1", "gen-1"))
+	assert.NotContains(t, repo.entries, "gen-1")
+}
+
+func TestGenerationHistoryService_RevisionsAreScopedToCaller(t *testing.T) {
+	ctx := context.Background()
+	history, repo := newHistoryTestService()
+	repo.entries["gen-1-admin"] = ai.GenerationHistory{ID: "gen-1-admin", ParentID: "gen-1", UserID: "admin-1", Prompt: "Make it red"}
+	repo.entries["gen-1-user"] = ai.GenerationHistory{ID: "gen-1-user", ParentID: "gen-1-admin", UserID: "user-1", Prompt: "Make it blue"}
+
+	revisions, err := history.Revisions(ctx, "user-1", "gen-1")
+	require.NoError(t, err)
+	var ids []string
+	for _, item := range revisions.Items {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []string{"gen-1", "gen-1-user"}, ids, "other users' revisions are hidden")
+
+	_, err = history.Revisions(ctx, "user-2", "gen-1")
+	assert.True(t, common.IsNotFoundError(err))
+
+	revisions, err = history.Revisions(ctx, "admin-1", "gen-1")
+	require.NoError(t, err)
+	assert.Len(t, revisions.Items, 3)
+}
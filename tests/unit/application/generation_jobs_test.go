@@ -0,0 +1,282 @@
+package ai
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/user"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/queue"
+)
+
+// roleUsers is a user.Repository that only knows user roles
+type roleUsers struct {
+	user.Repository
+	roles map[common.UserID]user.Role
+}
+
+func (r roleUsers) GetByID(ctx context.Context, id common.UserID) (user.User, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return user.User{}, common.NewNotFoundError("user not found")
+	}
+	return user.User{ID: id, Role: role}, nil
+}
+
+// recordingJobPublisher records the statuses published for each job
+type recordingJobPublisher struct {
+	mu       sync.Mutex
+	statuses map[string][]ai.GenerationStatus
+}
+
+func (p *recordingJobPublisher) PublishJobEvent(ctx context.Context, job ai.GenerationJob) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.statuses == nil {
+		p.statuses = make(map[string][]ai.GenerationStatus)
+	}
+	p.statuses[job.ID] = append(p.statuses[job.ID], job.Status)
+	return nil
+}
+
+func (p *recordingJobPublisher) get(id string) []ai.GenerationStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.statuses[id]
+}
+
+// newJobTestUseCase returns a GenerateCodeUseCase whose collaborators accept any call
+func newJobTestUseCase(llmService ai.LLMService) *aiapp.GenerateCodeUseCase {
+	mockRepo := new(MockRepository)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+	mockRepo.On("GetQuotaUsage", mock.Anything, mock.Anything).Return(ai.QuotaStatus{Remaining: 1000}, nil)
+	mockRepo.On("SaveGeneration", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return aiapp.NewGenerateCodeUseCase(aiapp.GenerateCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  llmService,
+		RateLimiter: mockRateLimiter,
+	})
+}
+
+func jobRequest(userID common.UserID, prompt, model string) aiapp.GenerateCodeRequest {
+	return aiapp.GenerateCodeRequest{
+		Prompt:           prompt,
+		Language:         "typescript",
+		Complexity:       "simple",
+		UserID:           userID,
+		GenerationParams: aiapp.GenerationParams{Model: model},
+	}
+}
+
+// waitForJob polls a job until it has finished
+func waitForJob(t *testing.T, jobs *aiapp.GenerationJobService, userID common.UserID, id string) *aiapp.GenerationJobResponse {
+	t.Helper()
+	var job *aiapp.GenerationJobResponse
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = jobs.Get(context.Background(), userID, id)
+		require.NoError(t, err)
+		return ai.GenerationStatus(job.Status).IsTerminal()
+	}, 2*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestGenerationJobService_SubmitAndProcess(t *testing.T) {
+	ctx := context.Background()
+	jobQueue := queue.NewMemoryJobQueue(0)
+	publisher := &recordingJobPublisher{}
+	jobs := aiapp.NewGenerationJobService(jobQueue, roleUsers{roles: map[common.UserID]user.Role{"admin-1": user.RoleAdmin}}, publisher)
+
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.AnythingOfType("ai.GenerationRequest")).Return(ai.GenerationResult{
+		ID: "gen-1", Code: "<button/>", Model: "gpt-4o-mini", UsedTokens: 12,
+	}, nil)
+
+	submitted, err := jobs.Submit(ctx, jobRequest("user-1", "A button", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "pending", submitted.Status)
+	assert.Equal(t, ai.DefaultJobModel, submitted.Model)
+	assert.Equal(t, int(ai.JobPriorityNormal), submitted.Priority)
+
+	admin, err := jobs.Submit(ctx, jobRequest("admin-1", "A button", ""))
+	require.NoError(t, err)
+	assert.Equal(t, int(ai.JobPriorityHigh), admin.Priority)
+
+	_, err = jobs.Get(ctx, "user-2", submitted.ID)
+	assert.True(t, common.IsNotFoundError(err), "jobs are only visible to their owner")
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	pool := aiapp.NewGenerationWorkerPool(&aiapp.WorkerPoolConfig{Workers: 1, PollInterval: time.Millisecond}, jobQueue, newJobTestUseCase(mockLLM), publisher)
+	go pool.Run(runCtx)
+
+	job := waitForJob(t, jobs, "user-1", submitted.ID)
+	assert.Equal(t, "completed", job.Status)
+	require.NotNil(t, job.Result)
+	assert.Equal(t, "<button/>", job.Result.Code)
+	assert.Equal(t, 12, job.Result.UsedTokens)
+	assert.Equal(t, []ai.GenerationStatus{ai.GenerationPending, ai.GenerationInProgress, ai.GenerationCompleted}, publisher.get(submitted.ID))
+}
+
+func TestGenerationJobService_SubmitRejectsInvalidRequests(t *testing.T) {
+	jobQueue := queue.NewMemoryJobQueue(0)
+	jobs := aiapp.NewGenerationJobService(jobQueue, nil, nil)
+
+	_, err := jobs.Submit(context.Background(), jobRequest("user-1", "", ""))
+	assert.True(t, common.IsValidationError(err))
+
+	_, ok, err := jobQueue.Dequeue(context.Background(), nil)
+	require.NoError(t, err)
+	assert.False(t, ok, "nothing is queued")
+}
+
+func TestGenerationWorkerPool_FailedJob(t *testing.T) {
+	jobQueue := queue.NewMemoryJobQueue(0)
+	jobs := aiapp.NewGenerationJobService(jobQueue, nil, nil)
+
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(ai.GenerationResult{}, assert.AnError)
+
+	submitted, err := jobs.Submit(context.Background(), jobRequest("user-1", "A button", ""))
+	require.NoError(t, err)
+
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go aiapp.NewGenerationWorkerPool(&aiapp.WorkerPoolConfig{PollInterval: time.Millisecond}, jobQueue, newJobTestUseCase(mockLLM), nil).Run(runCtx)
+
+	job := waitForJob(t, jobs, "user-1", submitted.ID)
+	assert.Equal(t, "failed", job.Status)
+	assert.Equal(t, assert.AnError.Error(), job.Error)
+	assert.Nil(t, job.Result)
+}
+
+func TestGenerationWorkerPool_LimitsConcurrencyPerModel(t *testing.T) {
+	jobQueue := queue.NewMemoryJobQueue(0)
+	jobs := aiapp.NewGenerationJobService(jobQueue, nil, nil)
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	peak := map[string]int{}
+	release := make(chan struct{})
+
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		model := args.Get(1).(ai.GenerationRequest).Model
+		mu.Lock()
+		running[model]++
+		peak[model] = max(peak[model], running[model])
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		running[model]--
+		mu.Unlock()
+	}).Return(ai.GenerationResult{Code: "ok"}, nil)
+
+	var ids []string
+	for _, model := range []string{"slow", "slow", "slow", "fast", "fast", "fast"} {
+		job, err := jobs.Submit(context.Background(), jobRequest("user-1", "A button", model))
+		require.NoError(t, err)
+		ids = append(ids, job.ID)
+	}
+
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	pool := aiapp.NewGenerationWorkerPool(&aiapp.WorkerPoolConfig{
+		Workers:                 6,
+		ModelConcurrency:        map[string]int{"slow": 1},
+		DefaultModelConcurrency: 2,
+		PollInterval:            time.Millisecond,
+	}, jobQueue, newJobTestUseCase(mockLLM), nil)
+	go pool.Run(runCtx)
+
+	// Both models reach their limit while every worker is free
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return running["slow"] == 1 && running["fast"] == 2
+	}, 2*time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	for _, id := range ids {
+		assert.Equal(t, "completed", waitForJob(t, jobs, "user-1", id).Status)
+	}
+	assert.Equal(t, map[string]int{"slow": 1, "fast": 2}, peak)
+}
+
+func TestGenerationWorkerPool_RunsHigherPriorityFirst(t *testing.T) {
+	jobQueue := queue.NewMemoryJobQueue(0)
+	users := roleUsers{roles: map[common.UserID]user.Role{"admin-1": user.RoleAdmin, "user-1": user.RoleUser}}
+	jobs := aiapp.NewGenerationJobService(jobQueue, users, nil)
+
+	var mu sync.Mutex
+	var order []common.UserID
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		mu.Lock()
+		order = append(order, args.Get(1).(ai.GenerationRequest).UserID)
+		mu.Unlock()
+	}).Return(ai.GenerationResult{Code: "ok"}, nil)
+
+	first, err := jobs.Submit(context.Background(), jobRequest("user-1", "A button", ""))
+	require.NoError(t, err)
+	second, err := jobs.Submit(context.Background(), jobRequest("admin-1", "A button", ""))
+	require.NoError(t, err)
+
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go aiapp.NewGenerationWorkerPool(&aiapp.WorkerPoolConfig{Workers: 1, PollInterval: time.Millisecond}, jobQueue, newJobTestUseCase(mockLLM), nil).Run(runCtx)
+
+	waitForJob(t, jobs, "user-1", first.ID)
+	waitForJob(t, jobs, "admin-1", second.ID)
+	assert.Equal(t, []common.UserID{"admin-1", "user-1"}, order)
+}
+
+func TestGenerationWorkerPool_RequeuesJobsOnShutdown(t *testing.T) {
+	jobQueue := queue.NewMemoryJobQueue(0)
+	publisher := &recordingJobPublisher{}
+	jobs := aiapp.NewGenerationJobService(jobQueue, nil, publisher)
+
+	started := make(chan struct{})
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		close(started)
+		<-args.Get(0).(context.Context).Done()
+	}).Return(ai.GenerationResult{}, context.Canceled)
+
+	submitted, err := jobs.Submit(context.Background(), jobRequest("user-1", "A button", ""))
+	require.NoError(t, err)
+
+	runCtx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		aiapp.NewGenerationWorkerPool(&aiapp.WorkerPoolConfig{Workers: 1, PollInterval: time.Millisecond}, jobQueue, newJobTestUseCase(mockLLM), publisher).Run(runCtx)
+		close(done)
+	}()
+
+	<-started
+	stop()
+	<-done
+
+	job, err := jobs.Get(context.Background(), "user-1", submitted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", job.Status)
+	assert.Equal(t, []ai.GenerationStatus{ai.GenerationPending, ai.GenerationInProgress, ai.GenerationPending}, publisher.get(submitted.ID))
+
+	requeued, ok, err := jobQueue.Dequeue(context.Background(), nil)
+	require.NoError(t, err)
+	require.True(t, ok, "the interrupted job is queued for another replica")
+	assert.Equal(t, submitted.ID, requeued.ID)
+}
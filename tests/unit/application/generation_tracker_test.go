@@ -0,0 +1,349 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+// memoryGenerationRepository is an in-memory ai.GenerationRepository
+type memoryGenerationRepository struct {
+	mu          sync.Mutex
+	generations map[string]ai.Generation
+}
+
+func newMemoryGenerationRepository() *memoryGenerationRepository {
+	return &memoryGenerationRepository{generations: make(map[string]ai.Generation)}
+}
+
+func (r *memoryGenerationRepository) CreateGeneration(ctx context.Context, generation ai.Generation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generations[generation.ID] = generation
+	return nil
+}
+
+func (r *memoryGenerationRepository) GetGeneration(ctx context.Context, id string) (ai.Generation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	generation, ok := r.generations[id]
+	if !ok {
+		return ai.Generation{}, common.NewNotFoundError("generation not found")
+	}
+	return generation, nil
+}
+
+func (r *memoryGenerationRepository) UpdateGenerationStatus(ctx context.Context, id string, status ai.GenerationStatus, errorMessage string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	generation, ok := r.generations[id]
+	if !ok {
+		return common.NewNotFoundError("generation not found")
+	}
+	generation.Status = status
+	generation.ErrorMessage = errorMessage
+	r.generations[id] = generation
+	return nil
+}
+
+// memoryCancellationBus fans cancellations out to every subscriber, like Redis pub/sub
+type memoryCancellationBus struct {
+	mu          sync.Mutex
+	subscribers []chan string
+}
+
+func (b *memoryCancellationBus) PublishCancel(ctx context.Context, generationID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subscribers {
+		ch <- generationID
+	}
+	return nil
+}
+
+func (b *memoryCancellationBus) SubscribeCancels(ctx context.Context) (<-chan string, error) {
+	ch := make(chan string, 10)
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, ch)
+	b.mu.Unlock()
+	return ch, nil
+}
+
+func TestGenerationTracker_FinishRecordsStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		cancel  bool
+		status  ai.GenerationStatus
+		message string
+	}{
+		{name: "completed", status: ai.GenerationCompleted},
+		{name: "failed", err: errors.New("upstream unavailable"), status: ai.GenerationFailed, message: "upstream unavailable"},
+		{name: "client disconnected", err: context.Canceled, cancel: true, status: ai.GenerationCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryGenerationRepository()
+			tracker := aiapp.NewGenerationTracker(repo, nil)
+
+			requestCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			generation, ctx, err := tracker.Start(requestCtx, ai.Generation{UserID: "user-1", Prompt: "A button"})
+			require.NoError(t, err)
+			assert.NotEmpty(t, generation.ID)
+			assert.Equal(t, ai.GenerationPending, generation.Status)
+
+			require.NoError(t, tracker.MarkInProgress(ctx, generation.ID))
+			if tt.cancel {
+				cancel()
+			}
+			require.NoError(t, tracker.Finish(ctx, generation.ID, tt.err))
+
+			stored, err := repo.GetGeneration(context.Background(), generation.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, stored.Status)
+			assert.Equal(t, tt.message, stored.ErrorMessage)
+			assert.Error(t, ctx.Err(), "the generation context is released")
+		})
+	}
+}
+
+func TestGenerationTracker_CancelReachesOtherReplicas(t *testing.T) {
+	repo := newMemoryGenerationRepository()
+	bus := &memoryCancellationBus{}
+	api := aiapp.NewGenerationTracker(repo, bus)
+	worker := aiapp.NewGenerationTracker(repo, bus)
+
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	for _, tracker := range []*aiapp.GenerationTracker{api, worker} {
+		go tracker.Run(runCtx)
+	}
+	require.Eventually(t, func() bool {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		return len(bus.subscribers) == 2
+	}, time.Second, time.Millisecond)
+
+	generation, ctx, err := worker.Start(context.Background(), ai.Generation{UserID: "user-1", Prompt: "A button"})
+	require.NoError(t, err)
+
+	require.NoError(t, api.Cancel(context.Background(), "user-1", generation.ID))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("generation was not cancelled")
+	}
+	assert.ErrorIs(t, context.Cause(ctx), aiapp.ErrGenerationCancelled)
+
+	require.NoError(t, worker.Finish(ctx, generation.ID, ctx.Err()))
+	stored, err := repo.GetGeneration(context.Background(), generation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ai.GenerationCancelled, stored.Status)
+}
+
+func TestGenerationTracker_CancelChecksOwnerAndStatus(t *testing.T) {
+	repo := newMemoryGenerationRepository()
+	tracker := aiapp.NewGenerationTracker(repo, nil)
+	ctx := context.Background()
+
+	generation, runCtx, err := tracker.Start(ctx, ai.Generation{UserID: "user-1", Prompt: "A button"})
+	require.NoError(t, err)
+
+	err = tracker.Cancel(ctx, "user-2", generation.ID)
+	assert.True(t, common.IsNotFoundError(err), "another user's generation is not visible")
+	assert.NoError(t, runCtx.Err())
+
+	err = tracker.Cancel(ctx, "user-1", "missing")
+	assert.True(t, common.IsNotFoundError(err))
+
+	require.NoError(t, tracker.Finish(runCtx, generation.ID, nil))
+	err = tracker.Cancel(ctx, "user-1", generation.ID)
+	assert.True(t, common.IsConflictError(err), "finished generations cannot be cancelled")
+}
+
+func TestStreamCodeUseCase_Execute_CancelStopsUpstream(t *testing.T) {
+	ctx := context.Background()
+	userID := common.UserID("test-user")
+
+	mockRepo := new(MockRepository)
+	mockLLM := new(MockLLMService)
+	mockRateLimiter := new(MockRateLimiter)
+	generations := newMemoryGenerationRepository()
+	tracker := aiapp.NewGenerationTracker(generations, nil)
+
+	useCase := aiapp.NewStreamCodeUseCase(aiapp.StreamCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+		Tracker:     tracker,
+	})
+
+	mockRateLimiter.On("Allow", userID).Return(true)
+	mockRepo.On("GetQuotaUsage", ctx, userID).Return(ai.QuotaStatus{UserID: userID, Remaining: 1000}, nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, userID, 1).Return(nil)
+
+	// The LLM streams one chunk, then blocks until its context is cancelled
+	mockLLM.On("GenerateStream", mock.Anything, mock.AnythingOfType("ai.GenerationRequest"), mock.AnythingOfType("chan<- ai.StreamChunk")).Run(func(args mock.Arguments) {
+		upstreamCtx := args.Get(0).(context.Context)
+		ch := args.Get(2).(chan<- ai.StreamChunk)
+		ch <- ai.StreamChunk{Content: "export ", TokenCount: 1}
+		<-upstreamCtx.Done()
+	}).Return(context.Canceled)
+
+	responseChan := make(chan aiapp.StreamCodeResponse, 10)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(responseChan)
+		errChan <- useCase.Execute(ctx, aiapp.StreamCodeRequest{
+			Prompt:     "A button",
+			Language:   "typescript",
+			Complexity: "simple",
+			UserID:     userID,
+		}, responseChan)
+	}()
+
+	started := <-responseChan
+	assert.Equal(t, "started", started.Type)
+	require.NotEmpty(t, started.GenerationID)
+	assert.Equal(t, "chunk", (<-responseChan).Type)
+
+	stored, err := generations.GetGeneration(ctx, started.GenerationID)
+	require.NoError(t, err)
+	assert.Equal(t, ai.GenerationInProgress, stored.Status)
+
+	require.NoError(t, tracker.Cancel(ctx, userID, started.GenerationID))
+
+	cancelled := <-responseChan
+	assert.Equal(t, "cancelled", cancelled.Type)
+	assert.Equal(t, started.GenerationID, cancelled.GenerationID)
+	assert.ErrorIs(t, <-errChan, aiapp.ErrGenerationCancelled)
+
+	stored, err = generations.GetGeneration(ctx, started.GenerationID)
+	require.NoError(t, err)
+	assert.Equal(t, ai.GenerationCancelled, stored.Status)
+
+	// Cancelled generations are not saved to history but their tokens are
+	// still charged
+	mockRepo.AssertNotCalled(t, "SaveGeneration", mock.Anything, mock.Anything)
+	mockRepo.AssertCalled(t, "UpdateQuotaUsage", mock.Anything, userID, 1)
+}
+
+func TestGenerateCodeUseCase_Execute_TracksGeneration(t *testing.T) {
+	ctx := context.Background()
+	generations := newMemoryGenerationRepository()
+	tracker := aiapp.NewGenerationTracker(generations, nil)
+
+	mockRepo := new(MockRepository)
+	mockLLM := new(MockLLMService)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+	mockRepo.On("GetQuotaUsage", mock.Anything, mock.Anything).Return(ai.QuotaStatus{Remaining: 1000}, nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	var saved ai.GenerationHistory
+	mockRepo.On("SaveGeneration", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(ai.GenerationHistory)
+	}).Return(nil)
+
+	// The first generation completes; the second blocks until it is cancelled
+	running := make(chan struct{})
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(ai.GenerationResult{Code: "<button/>", UsedTokens: 3}, nil).Once()
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		close(running)
+		<-args.Get(0).(context.Context).Done()
+	}).Return(ai.GenerationResult{}, context.Canceled).Once()
+
+	useCase := aiapp.NewGenerateCodeUseCase(aiapp.GenerateCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+		Tracker:     tracker,
+	})
+
+	resp, err := useCase.Execute(ctx, jobRequest("user-1", "A button", ""))
+	require.NoError(t, err)
+	assert.Equal(t, saved.ID, resp.GenerationID, "history completes the tracked generation")
+	stored, err := generations.GetGeneration(ctx, resp.GenerationID)
+	require.NoError(t, err)
+	assert.Equal(t, ai.GenerationCompleted, stored.Status)
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := useCase.Execute(ctx, jobRequest("user-1", "A form", ""))
+		errChan <- err
+	}()
+	<-running
+
+	var generationID string
+	generations.mu.Lock()
+	for id, generation := range generations.generations {
+		if generation.Status == ai.GenerationInProgress {
+			generationID = id
+		}
+	}
+	generations.mu.Unlock()
+	require.NotEmpty(t, generationID)
+
+	require.NoError(t, tracker.Cancel(ctx, "user-1", generationID))
+	assert.Error(t, <-errChan)
+
+	stored, err = generations.GetGeneration(ctx, generationID)
+	require.NoError(t, err)
+	assert.Equal(t, ai.GenerationCancelled, stored.Status)
+}
+
+func TestStreamCodeUseCase_Execute_DrainsChunksAfterCompletion(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockLLM := new(MockLLMService)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+	mockRepo.On("GetQuotaUsage", mock.Anything, mock.Anything).Return(ai.QuotaStatus{Remaining: 1000}, nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("SaveGeneration", mock.Anything, mock.Anything).Return(nil)
+
+	// A misbehaving provider keeps sending after the terminal chunk, more than
+	// the stream buffer holds
+	returned := make(chan struct{})
+	mockLLM.On("GenerateStream", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		ch := args.Get(2).(chan<- ai.StreamChunk)
+		ch <- ai.StreamChunk{Content: "<button/>", TokenCount: 1, IsComplete: true}
+		for i := 0; i < 20; i++ {
+			ch <- ai.StreamChunk{Content: "late"}
+		}
+		close(returned)
+	}).Return(nil)
+
+	useCase := aiapp.NewStreamCodeUseCase(aiapp.StreamCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+	})
+
+	responseChan := make(chan aiapp.StreamCodeResponse, 10)
+	err := useCase.Execute(context.Background(), aiapp.StreamCodeRequest{
+		Prompt:     "A button",
+		Language:   "typescript",
+		Complexity: "simple",
+		UserID:     "user-1",
+	}, responseChan)
+	require.NoError(t, err)
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("the provider was left blocked on the stream channel")
+	}
+}
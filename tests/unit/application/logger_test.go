@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+// recordingLogger records the errors logged through it
+type recordingLogger struct {
+	errors []error
+}
+
+func (l *recordingLogger) Error(msg string, err error, fields ...map[string]interface{}) {
+	l.errors = append(l.errors, err)
+}
+
+func TestGenerateCodeUseCase_LogsFailedSaves(t *testing.T) {
+	saveErr := errors.New("database unavailable")
+	mockRepo := new(MockRepository)
+	mockLLM := new(MockLLMService)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+	mockRepo.On("GetQuotaUsage", mock.Anything, mock.Anything).Return(ai.QuotaStatus{Remaining: 1000}, nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("SaveGeneration", mock.Anything, mock.Anything).Return(saveErr)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(ai.GenerationResult{Code: "<button/>", UsedTokens: 3}, nil)
+
+	logger := &recordingLogger{}
+	useCase := aiapp.NewGenerateCodeUseCase(aiapp.GenerateCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+		Logger:      logger,
+	})
+
+	resp, err := useCase.Execute(context.Background(), jobRequest("user-1", "A button", ""))
+	require.NoError(t, err, "a failed save does not fail the request")
+	assert.Empty(t, resp.GenerationID)
+	assert.Equal(t, []error{saveErr}, logger.errors)
+}
+
+func TestStreamCodeUseCase_LogsFailedSaves(t *testing.T) {
+	saveErr := errors.New("database unavailable")
+	mockRepo := new(MockRepository)
+	mockLLM := new(MockLLMService)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+	mockRepo.On("GetQuotaUsage", mock.Anything, mock.Anything).Return(ai.QuotaStatus{Remaining: 1000}, nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("SaveGeneration", mock.Anything, mock.Anything).Return(saveErr)
+	mockLLM.On("GenerateStream", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(2).(chan<- ai.StreamChunk) <- ai.StreamChunk{Content: "<button/>", TokenCount: 3, IsComplete: true}
+	}).Return(nil)
+
+	logger := &recordingLogger{}
+	useCase := aiapp.NewStreamCodeUseCase(aiapp.StreamCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+		Logger:      logger,
+	})
+
+	responseChan := make(chan aiapp.StreamCodeResponse, 10)
+	err := useCase.Execute(context.Background(), aiapp.StreamCodeRequest{
+		Prompt:     "A button",
+		Language:   "typescript",
+		Complexity: "simple",
+		UserID:     "user-1",
+	}, responseChan)
+	require.NoError(t, err)
+	assert.Equal(t, []error{saveErr}, logger.errors)
+}
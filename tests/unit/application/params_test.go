@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	infrallm "github.com/EliasRanz/ai-code-gen/internal/infrastructure/llm"
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+func TestGenerateCodeUseCase_RejectsUnsupportedParamsBeforeQuota(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRateLimiter := new(MockRateLimiter)
+	useCase := aiapp.NewGenerateCodeUseCase(aiapp.GenerateCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  infrallm.NewAnthropicService(&infrallm.AnthropicConfig{}),
+		RateLimiter: mockRateLimiter,
+	})
+
+	seed := 42
+	req := jobRequest("user-1", "A button", "")
+	req.Seed = &seed
+
+	_, err := useCase.Execute(context.Background(), req)
+	require.Error(t, err)
+	assert.True(t, llm.IsInvalidRequest(err))
+	mockRateLimiter.AssertNotCalled(t, "Allow", mock.Anything)
+	mockRepo.AssertNotCalled(t, "GetQuotaUsage", mock.Anything, mock.Anything)
+}
+
+func TestStreamCodeUseCase_RejectsUnsupportedParamsBeforeQuota(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRateLimiter := new(MockRateLimiter)
+	useCase := aiapp.NewStreamCodeUseCase(aiapp.StreamCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  infrallm.NewAnthropicService(&infrallm.AnthropicConfig{}),
+		RateLimiter: mockRateLimiter,
+	})
+
+	req := aiapp.StreamCodeRequest{
+		Prompt:           "A button",
+		Language:         "typescript",
+		Complexity:       "simple",
+		UserID:           "user-1",
+		GenerationParams: aiapp.GenerationParams{ResponseFormat: "json_object"},
+	}
+	responses := make(chan aiapp.StreamCodeResponse, 10)
+
+	err := useCase.Execute(context.Background(), req, responses)
+	require.Error(t, err)
+	assert.True(t, llm.IsInvalidRequest(err))
+
+	response := <-responses
+	assert.Equal(t, "error", response.Type)
+	assert.Contains(t, response.Error, "response_format")
+	mockRepo.AssertNotCalled(t, "GetQuotaUsage", mock.Anything, mock.Anything)
+}
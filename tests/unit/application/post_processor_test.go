@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/postprocess"
+)
+
+// failingProcessor is a CodeProcessor that always fails
+type failingProcessor struct{}
+
+func (failingProcessor) Name() string { return "failing" }
+
+func (failingProcessor) Process(ctx context.Context, code string, target ai.CodeTarget) (string, error) {
+	return "broken", assert.AnError
+}
+
+func TestCodePostProcessor_RecordsAppliedProcessors(t *testing.T) {
+	post := aiapp.NewCodePostProcessor(append([]ai.CodeProcessor{failingProcessor{}}, postprocess.DefaultProcessors()...)...)
+
+	code, applied := post.Process(context.Background(), "Here it is:\n\n```go\npackage main\nfunc main(){}\n```", ai.CodeTarget{Language: "go", RawOutput: true})
+	assert.Equal(t, "package main\n\nfunc main() {}", code)
+	assert.Equal(t, []string{"strip_fences", "gofmt"}, applied)
+}
+
+func TestGenerateCodeUseCase_PostProcessesCode(t *testing.T) {
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(ai.GenerationResult{
+		Code: "Here is your button:\r\n\r\n```tsx\r\nexport const Button = () => <button/>;\r\n```\r\n",
+	}, nil)
+
+	var saved ai.GenerationHistory
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetQuotaUsage", mock.Anything, mock.Anything).Return(ai.QuotaStatus{Remaining: 1000}, nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("SaveGeneration", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(ai.GenerationHistory)
+	}).Return(nil)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+
+	uc := aiapp.NewGenerateCodeUseCase(aiapp.GenerateCodeDeps{
+		Repo:          mockRepo,
+		LLMService:    mockLLM,
+		RateLimiter:   mockRateLimiter,
+		PostProcessor: aiapp.NewCodePostProcessor(postprocess.DefaultProcessors()...),
+	})
+
+	req := jobRequest("user-1", "A button", "")
+	req.Framework = "react"
+	resp, err := uc.Execute(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "export const Button = () => <button/>;", resp.Code)
+	assert.Equal(t, resp.Code, saved.Code)
+	assert.Equal(t, "normalize_line_endings,strip_fences", resp.Metadata[ai.MetadataPostProcessors])
+	assert.Equal(t, resp.Metadata, saved.Metadata)
+	require.NotNil(t, resp.Artifact)
+	assert.Equal(t, "export const Button = () => <button/>;", resp.Artifact.Files[0].Content)
+}
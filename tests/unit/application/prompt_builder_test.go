@@ -0,0 +1,154 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/prompt"
+)
+
+// staticTemplates implements ai.PromptTemplateRepository over a fixed list
+type staticTemplates []ai.PromptTemplate
+
+func (s staticTemplates) ListTemplates(ctx context.Context) ([]ai.PromptTemplate, error) {
+	return s, nil
+}
+
+func TestTemplatePromptBuilder_BuiltinTemplates(t *testing.T) {
+	builder := aiapp.NewTemplatePromptBuilder(prompt.NewBuiltinTemplateRepository())
+
+	tests := []struct {
+		framework string
+		language  string
+		template  string
+		contains  string
+	}{
+		{framework: "react", language: "typescript", template: "react-tailwind", contains: "Tailwind"},
+		{framework: "React", language: "javascript", template: "react-tailwind", contains: "Write javascript"},
+		{framework: "vue", language: "typescript", template: "vue", contains: `lang="ts"`},
+		{framework: "svelte", language: "javascript", template: "svelte", contains: "Svelte"},
+		{framework: "html", template: "html", contains: "<script>"},
+		{framework: "angular", language: "typescript", template: "html", contains: "HTML"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.framework+"/"+tt.language, func(t *testing.T) {
+			result, err := builder.Build(context.Background(), ai.GenerationRequest{
+				Prompt:    "A pricing table",
+				Framework: tt.framework,
+				Language:  tt.language,
+				Style:     "minimal",
+			})
+			require.NoError(t, err)
+
+			require.Len(t, result.Messages, 2)
+			assert.Equal(t, ai.RoleSystem, result.Messages[0].Role)
+			assert.Contains(t, result.Messages[0].Content, tt.contains)
+			assert.Contains(t, result.Messages[0].Content, "The design should be minimal")
+			assert.Equal(t, ai.ChatMessage{Role: ai.RoleUser, Content: "A pricing table"}, result.Messages[1])
+			assert.Equal(t, tt.template, result.Template.Name)
+		})
+	}
+}
+
+func TestTemplatePromptBuilder_SelectsTemplate(t *testing.T) {
+	templates := staticTemplates{
+		{Name: "react", Framework: "react", Version: 1, User: "v1 {{.prompt}}"},
+		{Name: "react", Framework: "react", Version: 3, User: "v3 {{.prompt}}"},
+		{Name: "react-ts", Framework: "react", Language: "typescript", Version: 1, User: "ts {{.prompt}}"},
+	}
+	builder := aiapp.NewTemplatePromptBuilder(templates)
+
+	result, err := builder.Build(context.Background(), ai.GenerationRequest{Prompt: "x", Framework: "react", Language: "javascript"})
+	require.NoError(t, err)
+	assert.Equal(t, "v3 x", result.Messages[0].Content, "latest version wins")
+	assert.Len(t, result.Messages, 1, "an empty system template produces no system message")
+	assert.Equal(t, map[string]string{"template": "react", "template_version": "3"}, result.Template.Metadata())
+
+	result, err = builder.Build(context.Background(), ai.GenerationRequest{Prompt: "x", Framework: "react", Language: "typescript"})
+	require.NoError(t, err)
+	assert.Equal(t, "ts x", result.Messages[0].Content, "language-specific template wins")
+}
+
+func TestTemplatePromptBuilder_Errors(t *testing.T) {
+	t.Run("no template", func(t *testing.T) {
+		builder := aiapp.NewTemplatePromptBuilder(staticTemplates{})
+		_, err := builder.Build(context.Background(), ai.GenerationRequest{Prompt: "x", Framework: "react"})
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown variable", func(t *testing.T) {
+		builder := aiapp.NewTemplatePromptBuilder(staticTemplates{
+			{Name: "html", Framework: "html", Version: 1, User: "{{.promt}}"},
+		})
+		_, err := builder.Build(context.Background(), ai.GenerationRequest{Prompt: "x", Framework: "html"})
+		assert.Error(t, err)
+	})
+}
+
+func TestFileTemplateRepository_LoadsAndValidates(t *testing.T) {
+	repo, err := prompt.NewFileTemplateRepository(fstest.MapFS{
+		"card.v2.json": {Data: []byte(`{"name":"card","framework":"react","version":2,"user":"{{.prompt}}","variables":{"style":"flat"}}`)},
+		"README.md":    {Data: []byte("not a template")},
+	})
+	require.NoError(t, err)
+
+	templates, err := repo.ListTemplates(context.Background())
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	assert.Equal(t, 2, templates[0].Version)
+	assert.Equal(t, "flat", templates[0].Variables["style"])
+
+	_, err = prompt.NewFileTemplateRepository(fstest.MapFS{
+		"broken.json": {Data: []byte(`{"name":"broken","framework":"react","version":1,"user":"{{.prompt"}`)},
+	})
+	assert.Error(t, err)
+}
+
+func TestGenerateCodeUseCase_RecordsTemplateVersion(t *testing.T) {
+	ctx := context.Background()
+	userID := common.UserID("test-user")
+
+	mockRepo := new(MockRepository)
+	mockLLM := new(MockLLMService)
+	mockRateLimiter := new(MockRateLimiter)
+
+	builder := aiapp.NewTemplatePromptBuilder(prompt.NewBuiltinTemplateRepository())
+	useCase := aiapp.NewGenerateCodeUseCase(aiapp.GenerateCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+		Prompts:     builder,
+	})
+
+	mockRateLimiter.On("Allow", userID).Return(true)
+	mockRepo.On("GetQuotaUsage", ctx, userID).Return(ai.QuotaStatus{UserID: userID, Remaining: 100}, nil)
+	mockLLM.On("Generate", ctx, mock.MatchedBy(func(req ai.GenerationRequest) bool {
+		return len(req.Messages) == 2 && req.Messages[0].Role == ai.RoleSystem
+	})).Return(ai.GenerationResult{Code: "<button/>", Model: "gpt-4o", UsedTokens: 10}, nil)
+	mockRepo.On("SaveGeneration", ctx, mock.MatchedBy(func(h ai.GenerationHistory) bool {
+		return h.Metadata[ai.MetadataTemplate] == "react-tailwind" && h.Metadata[ai.MetadataTemplateVersion] == "1"
+	})).Return(nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, userID, 10).Return(nil)
+
+	resp, err := useCase.Execute(ctx, aiapp.GenerateCodeRequest{
+		Prompt:     "A button",
+		Language:   "typescript",
+		Framework:  "react",
+		Complexity: "simple",
+		UserID:     userID,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "1", resp.Metadata[ai.MetadataTemplateVersion])
+	mockLLM.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,248 @@
+package ai
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/quota"
+)
+
+// memoryQuotaPlans is a QuotaPlanRepository backed by a map
+type memoryQuotaPlans map[common.UserID]ai.QuotaAccount
+
+func (m memoryQuotaPlans) GetQuotaAccount(ctx context.Context, userID common.UserID) (ai.QuotaAccount, error) {
+	account, ok := m[userID]
+	if !ok {
+		return ai.QuotaAccount{}, common.NewNotFoundError("user not found")
+	}
+	return account, nil
+}
+
+// consume reserves a request for a user and charges it tokens of model
+func consume(t *testing.T, service *aiapp.QuotaService, userID common.UserID, model string, tokens int) ai.QuotaStatus {
+	t.Helper()
+	_, ok, err := service.Reserve(context.Background(), userID)
+	require.NoError(t, err)
+	require.True(t, ok, "the request is within the quota")
+	status, err := service.Charge(context.Background(), userID, model, tokens)
+	require.NoError(t, err)
+	return status
+}
+
+func TestQuotaService_ChargeAppliesModelMultiplier(t *testing.T) {
+	plans := memoryQuotaPlans{"user-1": {
+		UserID: "user-1",
+		Plan: ai.QuotaPlan{
+			Tier:             ai.PlanPro,
+			DailyTokens:      1000,
+			MonthlyTokens:    5000,
+			ModelMultipliers: map[string]float64{"gpt-4o": 2, "gpt-4o-mini": 0.5},
+		},
+	}}
+	service := aiapp.NewQuotaService(plans, quota.NewMemoryQuotaCounter())
+
+	consume(t, service, "user-1", "gpt-4o-2024-08-06", 100)
+	status := consume(t, service, "user-1", "gpt-4o-mini", 101)
+
+	assert.Equal(t, ai.PlanPro, status.Tier)
+	assert.Equal(t, 251, status.UsedToday, "gpt-4o counts double and gpt-4o-mini half, rounded up")
+	assert.Equal(t, 251, status.UsedThisMonth)
+	assert.Equal(t, 749, status.Remaining)
+	assert.Equal(t, 2, status.RequestsToday)
+	assert.True(t, status.CanGenerate())
+}
+
+func TestQuotaService_Limits(t *testing.T) {
+	tests := []struct {
+		name        string
+		plan        ai.QuotaPlan
+		canGenerate bool
+		remaining   int
+	}{
+		{"within limits", ai.QuotaPlan{DailyTokens: 1000, MonthlyTokens: 5000, DailyRequests: 5}, true, 700},
+		{"daily tokens used up", ai.QuotaPlan{DailyTokens: 300}, false, 0},
+		{"monthly tokens cap the day", ai.QuotaPlan{DailyTokens: 1000, MonthlyTokens: 400}, true, 100},
+		{"daily requests used up", ai.QuotaPlan{DailyTokens: 1000, DailyRequests: 3}, false, 700},
+		{"monthly requests used up", ai.QuotaPlan{DailyTokens: 1000, MonthlyRequests: 3}, false, 700},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := aiapp.NewQuotaService(memoryQuotaPlans{"user-1": {UserID: "user-1", Plan: tt.plan}}, quota.NewMemoryQuotaCounter())
+			for i := 0; i < 3; i++ {
+				consume(t, service, "user-1", "gpt-4o-mini", 100)
+			}
+
+			status, err := service.Status(context.Background(), "user-1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.canGenerate, status.CanGenerate())
+			assert.Equal(t, tt.remaining, status.Remaining)
+		})
+	}
+}
+
+func TestQuotaService_ReserveIsAtomic(t *testing.T) {
+	plans := memoryQuotaPlans{"user-1": {UserID: "user-1", Plan: ai.QuotaPlan{DailyTokens: 1000, DailyRequests: 5}}}
+	service := aiapp.NewQuotaService(plans, quota.NewMemoryQuotaCounter())
+
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := service.Reserve(context.Background(), "user-1")
+			assert.NoError(t, err)
+			if ok {
+				reserved.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 5, reserved.Load(), "concurrent requests cannot overshoot the limit")
+	status, err := service.Status(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, 5, status.RequestsToday, "rejected requests are not counted")
+}
+
+func TestQuotaService_ReserveStopsAtTokenLimit(t *testing.T) {
+	plans := memoryQuotaPlans{"user-1": {UserID: "user-1", Plan: ai.QuotaPlan{DailyTokens: 1000, MonthlyTokens: 300}}}
+	service := aiapp.NewQuotaService(plans, quota.NewMemoryQuotaCounter())
+	consume(t, service, "user-1", "gpt-4o-mini", 300)
+
+	status, ok, err := service.Reserve(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 1, status.RequestsThisMonth)
+	assert.False(t, status.CanGenerate())
+}
+
+func TestQuotaService_ResetsInUserTimezone(t *testing.T) {
+	plans := memoryQuotaPlans{
+		"tokyo":   {UserID: "tokyo", Plan: ai.QuotaPlan{DailyTokens: 1000}, Timezone: "Asia/Tokyo"},
+		"unknown": {UserID: "unknown", Plan: ai.QuotaPlan{DailyTokens: 1000}, Timezone: "Mars/Olympus_Mons"},
+	}
+	service := aiapp.NewQuotaService(plans, quota.NewMemoryQuotaCounter())
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	now := time.Now().In(tokyo)
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, tokyo)
+	firstOfMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, tokyo)
+
+	status, err := service.Status(context.Background(), "tokyo")
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", status.Timezone)
+	assert.Equal(t, midnight.Format(time.RFC3339), status.ResetTime)
+	assert.Equal(t, firstOfMonth.Format(time.RFC3339), status.MonthlyResetTime)
+	assert.Contains(t, status.ResetTime, "+09:00")
+
+	status, err = service.Status(context.Background(), "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", status.Timezone, "unknown timezones fall back to UTC")
+}
+
+func TestQuotaService_GetQuota(t *testing.T) {
+	plans := memoryQuotaPlans{"user-1": {UserID: "user-1", Plan: ai.QuotaPlan{Tier: ai.PlanFree, DailyTokens: 1000, DailyRequests: 10}}}
+	service := aiapp.NewQuotaService(plans, quota.NewMemoryQuotaCounter())
+	consume(t, service, "user-1", "gpt-4o-mini", 250)
+
+	resp, err := service.GetQuota(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "free", resp.Tier)
+	assert.True(t, resp.CanGenerate)
+	assert.Equal(t, 250, resp.Daily.Tokens.Used)
+	require.NotNil(t, resp.Daily.Tokens.Remaining)
+	assert.Equal(t, 750, *resp.Daily.Tokens.Remaining)
+	require.NotNil(t, resp.Daily.Requests.Remaining)
+	assert.Equal(t, 9, *resp.Daily.Requests.Remaining)
+	assert.Nil(t, resp.Monthly.Tokens.Remaining, "the plan has no monthly token limit")
+
+	_, err = service.GetQuota(context.Background(), "missing")
+	assert.True(t, common.IsNotFoundError(err))
+}
+
+func TestGenerateCodeUseCase_CountsUsageWithQuotaService(t *testing.T) {
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(ai.GenerationResult{
+		Code: "export const a = 1;", Model: "gpt-4o", UsedTokens: 300,
+	}, nil)
+	mockRepo := new(MockRepository)
+	mockRepo.On("SaveGeneration", mock.Anything, mock.Anything).Return(nil)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+
+	plans := memoryQuotaPlans{"user-1": {UserID: "user-1", Plan: ai.QuotaPlan{
+		DailyTokens:      1000,
+		ModelMultipliers: map[string]float64{"gpt-4o": 2},
+	}}}
+	service := aiapp.NewQuotaService(plans, quota.NewMemoryQuotaCounter())
+	uc := aiapp.NewGenerateCodeUseCase(aiapp.GenerateCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+		Quota:       service,
+	})
+
+	_, err := uc.Execute(context.Background(), jobRequest("user-1", "A counter", ""))
+	require.NoError(t, err)
+
+	status, err := service.Status(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, 600, status.UsedToday)
+
+	// The second generation exhausts the daily tokens, blocking the third
+	_, err = uc.Execute(context.Background(), jobRequest("user-1", "A counter", ""))
+	require.NoError(t, err)
+	_, err = uc.Execute(context.Background(), jobRequest("user-1", "A counter", ""))
+	assert.True(t, common.IsValidationError(err))
+	mockRepo.AssertNotCalled(t, "GetQuotaUsage", mock.Anything, mock.Anything)
+	mockLLM.AssertNumberOfCalls(t, "Generate", 2)
+}
+
+func TestStreamCodeUseCase_ChargesCancelledStreams(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockLLM := new(MockLLMService)
+	mockLLM.On("GenerateStream", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		ch := args.Get(2).(chan<- ai.StreamChunk)
+		ch <- ai.StreamChunk{Content: "export const a", TokenCount: 40, Model: "gpt-4o"}
+		cancel()
+		ch <- ai.StreamChunk{Error: context.Canceled}
+	}).Return(nil)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+
+	plans := memoryQuotaPlans{"user-1": {UserID: "user-1", Plan: ai.QuotaPlan{
+		DailyTokens:      1000,
+		ModelMultipliers: map[string]float64{"gpt-4o": 2},
+	}}}
+	service := aiapp.NewQuotaService(plans, quota.NewMemoryQuotaCounter())
+	uc := aiapp.NewStreamCodeUseCase(aiapp.StreamCodeDeps{
+		Repo:        new(MockRepository),
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+		Quota:       service,
+	})
+
+	responses := make(chan aiapp.StreamCodeResponse, 10)
+	err := uc.Execute(ctx, aiapp.StreamCodeRequest{Prompt: "A counter", Framework: "react", UserID: "user-1"}, responses)
+	require.ErrorIs(t, err, context.Canceled)
+
+	status, err := service.Status(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, 80, status.UsedToday, "tokens streamed before the cancellation are charged")
+	assert.Equal(t, 1, status.RequestsToday)
+}
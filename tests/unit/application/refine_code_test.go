@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+func TestRefineCodeUseCase_BuildsOnParent(t *testing.T) {
+	ctx := context.Background()
+	projectID := common.ProjectID("project-1")
+	history := &memoryHistoryRepository{entries: map[string]ai.GenerationHistory{
+		"root": {ID: "root", UserID: "user-1", ProjectID: &projectID, Prompt: "A button", Code: "<button>Go</button>", Framework: "react", Status: ai.GenerationCompleted},
+	}}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetQuotaUsage", mock.Anything, mock.Anything).Return(ai.QuotaStatus{Remaining: 1000}, nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("SaveGeneration", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		h := args.Get(1).(ai.GenerationHistory)
+		history.entries[h.ID] = h
+	}).Return(nil)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+
+	var sent ai.GenerationRequest
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(1).(ai.GenerationRequest)
+	}).Return(ai.GenerationResult{Code: `<button className="blue">Go</button>`, Model: "gpt-4o-mini"}, nil)
+
+	generate := aiapp.NewGenerateCodeUseCase(aiapp.GenerateCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+	})
+	refine := aiapp.NewRefineCodeUseCase(history, generate)
+
+	resp, err := refine.Execute(ctx, aiapp.RefineCodeRequest{ParentID: "root", Instruction: "Make the button blue", UserID: "user-1"})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.GenerationID)
+	assert.Equal(t, "react", resp.Framework)
+
+	require.Len(t, sent.Messages, 3)
+	assert.Equal(t, ai.ChatMessage{Role: ai.RoleUser, Content: "A button"}, sent.Messages[0])
+	assert.Equal(t, ai.ChatMessage{Role: ai.RoleAssistant, Content: "<button>Go</button>"}, sent.Messages[1])
+	assert.Contains(t, sent.Messages[2].Content, "Make the button blue")
+	assert.Equal(t, "react", sent.Framework)
+	assert.Equal(t, &projectID, sent.ProjectID)
+
+	child := history.entries[resp.GenerationID]
+	assert.Equal(t, "root", child.ParentID)
+	assert.Equal(t, "Make the button blue", child.Prompt)
+
+	// Branch from the root a second time; both children share the parent
+	second, err := refine.Execute(ctx, aiapp.RefineCodeRequest{ParentID: "root", Instruction: "Make the button red", UserID: "user-1"})
+	require.NoError(t, err)
+
+	revisions, err := aiapp.NewGenerationHistoryService(history, nil).Revisions(ctx, "user-1", second.GenerationID)
+	require.NoError(t, err)
+	require.Len(t, revisions.Items, 3)
+	assert.Equal(t, "root", revisions.Items[0].ID)
+	for _, item := range revisions.Items[1:] {
+		assert.Equal(t, "root", item.ParentID)
+	}
+}
+
+func TestRefineCodeUseCase_RejectsInvalidParents(t *testing.T) {
+	history := &memoryHistoryRepository{entries: map[string]ai.GenerationHistory{
+		"mine":   {ID: "mine", UserID: "user-1", Prompt: "A button", Code: "<button/>", Status: ai.GenerationCompleted},
+		"failed": {ID: "failed", UserID: "user-1", Prompt: "A card", Status: ai.GenerationFailed},
+	}}
+	refine := aiapp.NewRefineCodeUseCase(history, nil)
+	ctx := context.Background()
+
+	_, err := refine.Execute(ctx, aiapp.RefineCodeRequest{ParentID: "mine", UserID: "user-1"})
+	assert.True(t, common.IsValidationError(err), "an instruction is required")
+
+	_, err = refine.Execute(ctx, aiapp.RefineCodeRequest{ParentID: "mine", Instruction: "Blue", UserID: "user-2"})
+	assert.True(t, common.IsNotFoundError(err), "only the owner can refine a generation")
+
+	_, err = refine.Execute(ctx, aiapp.RefineCodeRequest{ParentID: "missing", Instruction: "Blue", UserID: "user-1"})
+	assert.True(t, common.IsNotFoundError(err))
+
+	_, err = refine.Execute(ctx, aiapp.RefineCodeRequest{ParentID: "failed", Instruction: "Blue", UserID: "user-1"})
+	assert.True(t, common.IsConflictError(err))
+}
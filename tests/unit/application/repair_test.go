@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/validation"
+)
+
+// newRepairTestUseCase creates a use case that repairs code generated by
+// llm, capturing the history entry it saves
+func newRepairTestUseCase(llm *MockLLMService, config *aiapp.RepairConfig, saved *ai.GenerationHistory) *aiapp.GenerateCodeUseCase {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetQuotaUsage", mock.Anything, mock.Anything).Return(ai.QuotaStatus{Remaining: 100000}, nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("SaveGeneration", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*saved = args.Get(1).(ai.GenerationHistory)
+	}).Return(nil)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+
+	return aiapp.NewGenerateCodeUseCase(aiapp.GenerateCodeDeps{
+		Repo:         mockRepo,
+		LLMService:   llm,
+		RateLimiter:  mockRateLimiter,
+		Validator:    validation.NewService(),
+		RepairConfig: config,
+	})
+}
+
+// generated returns an LLM result for code
+func generated(code string, tokens int) ai.GenerationResult {
+	return ai.GenerationResult{Code: code, Model: "gpt-4o-mini", UsedTokens: tokens, EstimatedCost: float64(tokens) / 1000}
+}
+
+func TestGenerateCodeUseCase_RepairsInvalidCode(t *testing.T) {
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(generated("export const total = (a: number) => a +;", 100), nil).Once()
+	mockLLM.On("Generate", mock.Anything, mock.MatchedBy(func(req ai.GenerationRequest) bool {
+		last := req.Messages[len(req.Messages)-1]
+		return len(req.Messages) == 3 &&
+			req.Messages[1].Content == "export const total = (a: number) => a +;" &&
+			strings.Contains(last.Content, "component.ts:1:") &&
+			strings.Contains(last.Content, "Fix these problems")
+	})).Return(generated("export const total = (a: number) => a + 1;", 50), nil).Once()
+
+	var saved ai.GenerationHistory
+	uc := newRepairTestUseCase(mockLLM, nil, &saved)
+	resp, err := uc.Execute(context.Background(), jobRequest("user-1", "A total function", ""))
+	require.NoError(t, err)
+	mockLLM.AssertExpectations(t)
+
+	assert.Equal(t, "export const total = (a: number) => a + 1;", resp.Code)
+	assert.Equal(t, 150, resp.UsedTokens)
+	assert.InDelta(t, 0.15, resp.EstimatedCost, 1e-9)
+	require.Len(t, resp.RepairAttempts, 2)
+	assert.False(t, resp.RepairAttempts[0].Valid)
+	assert.Equal(t, -10, resp.RepairAttempts[0].Score)
+	assert.Equal(t, "component.ts", resp.RepairAttempts[0].Diagnostics[0].Path)
+	assert.True(t, resp.RepairAttempts[1].Valid)
+
+	assert.Equal(t, resp.Code, saved.Code)
+	assert.Equal(t, 150, saved.Tokens)
+	require.Len(t, saved.RepairAttempts, 2)
+	assert.Equal(t, "export const total = (a: number) => a +;", saved.RepairAttempts[0].Code)
+}
+
+func TestGenerateCodeUseCase_RepairReturnsBestAttempt(t *testing.T) {
+	mockLLM := new(MockLLMService)
+	// Each broken file holds one error
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(generated(`{"files": [{"path": "a.ts", "content": "const a = ;"}, {"path": "b.ts", "content": "const b = ;"}]}`, 10), nil).Once()
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(generated(`{"files": [{"path": "a.ts", "content": "const a = 1;"}, {"path": "b.ts", "content": "const b = ;"}]}`, 10), nil).Once()
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(generated(`{"files": [{"path": "a.ts", "content": "const a = ;"}, {"path": "b.ts", "content": "const b = ;"}, {"path": "c.ts", "content": "const c = ;"}]}`, 10), nil).Once()
+
+	var saved ai.GenerationHistory
+	uc := newRepairTestUseCase(mockLLM, &aiapp.RepairConfig{MaxAttempts: 3}, &saved)
+	resp, err := uc.Execute(context.Background(), jobRequest("user-1", "Constants", ""))
+	require.NoError(t, err)
+
+	require.Len(t, resp.RepairAttempts, 3)
+	assert.Equal(t, []int{-20, -10, -30}, []int{resp.RepairAttempts[0].Score, resp.RepairAttempts[1].Score, resp.RepairAttempts[2].Score})
+	require.NotNil(t, resp.Artifact)
+	assert.Equal(t, "const a = 1;", resp.Artifact.Files[0].Content, "the best-scoring attempt wins")
+	assert.Equal(t, 30, resp.UsedTokens)
+}
+
+func TestGenerateCodeUseCase_RepairStopsAtTokenBudget(t *testing.T) {
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(generated("const a = ;", 500), nil).Once()
+
+	var saved ai.GenerationHistory
+	uc := newRepairTestUseCase(mockLLM, &aiapp.RepairConfig{MaxAttempts: 5, TokenBudget: 400}, &saved)
+	resp, err := uc.Execute(context.Background(), jobRequest("user-1", "A constant", ""))
+	require.NoError(t, err)
+	mockLLM.AssertNumberOfCalls(t, "Generate", 1)
+
+	require.Len(t, resp.RepairAttempts, 1)
+	assert.False(t, resp.RepairAttempts[0].Valid)
+	assert.Equal(t, "const a = ;", resp.Code)
+}
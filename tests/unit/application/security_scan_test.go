@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/security"
+)
+
+// memorySecurityPolicies is a SecurityPolicyRepository backed by a map of
+// projects owned by user-1
+type memorySecurityPolicies map[common.ProjectID]ai.SecurityPolicy
+
+func (m memorySecurityPolicies) GetSecurityPolicy(ctx context.Context, userID common.UserID, projectID common.ProjectID) (ai.SecurityPolicy, error) {
+	policy, ok := m[projectID]
+	if !ok || userID != "user-1" {
+		return ai.SecurityPolicy{}, common.NewNotFoundError("project not found")
+	}
+	return policy, nil
+}
+
+func TestGenerateCodeUseCase_StoresSecurityFindings(t *testing.T) {
+	mockLLM := new(MockLLMService)
+	mockLLM.On("Generate", mock.Anything, mock.Anything).Return(ai.GenerationResult{
+		Code: "export const Link = () => <a href=\"/docs\" target=\"_blank\">Docs</a>;\nexport const run = (s) => eval(s);",
+	}, nil)
+
+	var saved ai.GenerationHistory
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetQuotaUsage", mock.Anything, mock.Anything).Return(ai.QuotaStatus{Remaining: 1000}, nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("SaveGeneration", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(ai.GenerationHistory)
+	}).Return(nil)
+	mockRateLimiter := new(MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything).Return(true)
+
+	projectID := common.ProjectID("project-1")
+	scanner := aiapp.NewCodeScanner(security.NewScanner(), memorySecurityPolicies{
+		projectID: {DisabledRules: []string{"target-blank"}},
+	})
+	uc := aiapp.NewGenerateCodeUseCase(aiapp.GenerateCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+		Scanner:     scanner,
+	})
+
+	// The project disables target-blank
+	req := jobRequest("user-1", "A docs link", "")
+	req.Framework = "react"
+	req.ProjectID = &projectID
+	resp, err := uc.Execute(context.Background(), req)
+	require.NoError(t, err)
+
+	require.Len(t, resp.SecurityFindings, 1)
+	assert.Equal(t, aiapp.SecurityFindingResponse{
+		Rule:     "eval",
+		Severity: "error",
+		Message:  "eval executes arbitrary strings as code",
+		Path:     "Component.tsx",
+		Line:     2,
+		Column:   27,
+	}, resp.SecurityFindings[0])
+	require.Len(t, saved.SecurityFindings, 1)
+	assert.Equal(t, "eval", saved.SecurityFindings[0].Rule)
+
+	// Projects without a stored policy use the default one
+	unknown := common.ProjectID("project-2")
+	req.ProjectID = &unknown
+	resp, err = uc.Execute(context.Background(), req)
+	require.NoError(t, err)
+	assert.Len(t, resp.SecurityFindings, 2)
+
+	// Other users cannot apply the project's policy
+	req = jobRequest("user-2", "A docs link", "")
+	req.Framework = "react"
+	req.ProjectID = &projectID
+	resp, err = uc.Execute(context.Background(), req)
+	require.NoError(t, err)
+	assert.Len(t, resp.SecurityFindings, 2)
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	aiapp "github.com/EliasRanz/ai-code-gen/internal/application/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	infrallm "github.com/EliasRanz/ai-code-gen/internal/infrastructure/llm"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/prompt"
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+// newCassetteOpenAIService returns an OpenAIService replaying testdata/name.
+// Run with RECORD_CASSETTES=1 and OPENAI_API_KEY set to re-record it; set
+// OPENAI_BASE_URL to record against an OpenAI-compatible server instead.
+func newCassetteOpenAIService(t *testing.T, name string) *infrallm.OpenAIService {
+	t.Helper()
+
+	config := &llm.CassetteConfig{Path: "testdata/" + name}
+	baseURL := "https://api.openai.com/v1"
+	if os.Getenv("RECORD_CASSETTES") != "" {
+		config.Mode = llm.CassetteRecord
+		if url := os.Getenv("OPENAI_BASE_URL"); url != "" {
+			baseURL = url
+		}
+	}
+
+	cassette, err := llm.NewCassette(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, cassette.Save()) })
+
+	return infrallm.NewOpenAIServiceWithConfig(&infrallm.OpenAIConfig{
+		APIKey:     os.Getenv("OPENAI_API_KEY"),
+		BaseURL:    baseURL,
+		Model:      "gpt-4o-mini",
+		HTTPClient: cassette,
+	})
+}
+
+func TestStreamCodeUseCase_Execute_ReplaysRecordedStream(t *testing.T) {
+	ctx := context.Background()
+	userID := common.UserID("test-user")
+
+	mockRepo := new(MockRepository)
+	mockRateLimiter := new(MockRateLimiter)
+	mockPublisher := new(MockEventPublisher)
+
+	builder := aiapp.NewTemplatePromptBuilder(prompt.NewBuiltinTemplateRepository())
+	useCase := aiapp.NewStreamCodeUseCase(aiapp.StreamCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  newCassetteOpenAIService(t, "openai_stream_code.json"),
+		RateLimiter: mockRateLimiter,
+		Publisher:   mockPublisher,
+		Prompts:     builder,
+	})
+
+	mockRateLimiter.On("Allow", userID).Return(true)
+	mockRepo.On("GetQuotaUsage", ctx, userID).Return(ai.QuotaStatus{UserID: userID, Remaining: 1000}, nil)
+
+	var history ai.GenerationHistory
+	mockRepo.On("SaveGeneration", ctx, mock.AnythingOfType("ai.GenerationHistory")).Run(func(args mock.Arguments) {
+		history = args.Get(1).(ai.GenerationHistory)
+	}).Return(nil)
+	mockRepo.On("UpdateQuotaUsage", mock.Anything, userID, 59).Return(nil)
+	mockPublisher.On("PublishGenerationEvent", ctx, userID, 59).Return(nil)
+
+	temperature := 0.0
+	responseChan := make(chan aiapp.StreamCodeResponse, 20)
+	err := useCase.Execute(ctx, aiapp.StreamCodeRequest{
+		Prompt:           "A counter button",
+		Language:         "typescript",
+		Framework:        "react",
+		Complexity:       "simple",
+		UserID:           userID,
+		GenerationParams: aiapp.GenerationParams{Temperature: &temperature},
+	}, responseChan)
+	require.NoError(t, err)
+	close(responseChan)
+
+	var content string
+	var chunks []aiapp.StreamCodeResponse
+	for response := range responseChan {
+		content += response.Content
+		chunks = append(chunks, response)
+	}
+
+	assert.Equal(t, "export function Counter() {\n  return <button>0</button>;\n}", content)
+	require.Len(t, chunks, 5, "3 deltas, the terminal chunk and the completion")
+	assert.Equal(t, "stop", chunks[3].FinishReason)
+	assert.Equal(t, 59, chunks[4].TokenCount)
+
+	assert.Equal(t, "gpt-4o-mini-2024-07-18", history.Model)
+	assert.Equal(t, content, history.Code)
+	assert.Equal(t, "react-tailwind", history.Metadata[ai.MetadataTemplate])
+	mockRepo.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+}
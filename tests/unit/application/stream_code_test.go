@@ -94,7 +94,12 @@ func TestStreamCodeUseCase_Execute_ModelNameCapture(t *testing.T) {
 	mockRateLimiter := new(MockRateLimiter)
 	mockPublisher := new(MockEventPublisher)
 
-	useCase := aiapp.NewStreamCodeUseCase(mockRepo, mockLLM, mockRateLimiter, mockPublisher)
+	useCase := aiapp.NewStreamCodeUseCase(aiapp.StreamCodeDeps{
+		Repo:        mockRepo,
+		LLMService:  mockLLM,
+		RateLimiter: mockRateLimiter,
+		Publisher:   mockPublisher,
+	})
 
 	request := aiapp.StreamCodeRequest{
 		Prompt:     "Generate a React component",
@@ -157,7 +162,7 @@ func TestStreamCodeUseCase_Execute_ModelNameCapture(t *testing.T) {
 			capturedHistory = args.Get(1).(ai.GenerationHistory)
 		}).Return(nil)
 
-		mockRepo.On("UpdateQuotaUsage", ctx, userID, 12).Return(nil)
+		mockRepo.On("UpdateQuotaUsage", mock.Anything, userID, 12).Return(nil)
 		mockPublisher.On("PublishGenerationEvent", ctx, userID, 12).Return(nil)
 
 		// Execute
@@ -203,7 +208,12 @@ func TestStreamCodeUseCase_Execute_ModelNameCapture(t *testing.T) {
 		mockRateLimiter = new(MockRateLimiter)
 		mockPublisher = new(MockEventPublisher)
 
-		useCase = aiapp.NewStreamCodeUseCase(mockRepo, mockLLM, mockRateLimiter, mockPublisher)
+		useCase = aiapp.NewStreamCodeUseCase(aiapp.StreamCodeDeps{
+			Repo:        mockRepo,
+			LLMService:  mockLLM,
+			RateLimiter: mockRateLimiter,
+			Publisher:   mockPublisher,
+		})
 
 		// Setup quota check
 		quota := ai.QuotaStatus{
@@ -243,7 +253,7 @@ func TestStreamCodeUseCase_Execute_ModelNameCapture(t *testing.T) {
 			capturedHistory = args.Get(1).(ai.GenerationHistory)
 		}).Return(nil)
 
-		mockRepo.On("UpdateQuotaUsage", ctx, userID, 5).Return(nil)
+		mockRepo.On("UpdateQuotaUsage", mock.Anything, userID, 5).Return(nil)
 		mockPublisher.On("PublishGenerationEvent", ctx, userID, 5).Return(nil)
 
 		// Execute
@@ -279,7 +289,12 @@ func TestStreamCodeUseCase_Execute_ModelNameCapture(t *testing.T) {
 		mockRateLimiter = new(MockRateLimiter)
 		mockPublisher = new(MockEventPublisher)
 
-		useCase = aiapp.NewStreamCodeUseCase(mockRepo, mockLLM, mockRateLimiter, mockPublisher)
+		useCase = aiapp.NewStreamCodeUseCase(aiapp.StreamCodeDeps{
+			Repo:        mockRepo,
+			LLMService:  mockLLM,
+			RateLimiter: mockRateLimiter,
+			Publisher:   mockPublisher,
+		})
 
 		// Setup quota check
 		quota := ai.QuotaStatus{
@@ -325,7 +340,7 @@ func TestStreamCodeUseCase_Execute_ModelNameCapture(t *testing.T) {
 			capturedHistory = args.Get(1).(ai.GenerationHistory)
 		}).Return(nil)
 
-		mockRepo.On("UpdateQuotaUsage", ctx, userID, 7).Return(nil)
+		mockRepo.On("UpdateQuotaUsage", mock.Anything, userID, 7).Return(nil)
 		mockPublisher.On("PublishGenerationEvent", ctx, userID, 7).Return(nil)
 
 		// Execute
@@ -363,7 +378,12 @@ func TestStreamCodeUseCase_Execute_ErrorHandling(t *testing.T) {
 		mockRateLimiter := new(MockRateLimiter)
 		mockPublisher := new(MockEventPublisher)
 
-		useCase := aiapp.NewStreamCodeUseCase(mockRepo, mockLLM, mockRateLimiter, mockPublisher)
+		useCase := aiapp.NewStreamCodeUseCase(aiapp.StreamCodeDeps{
+			Repo:        mockRepo,
+			LLMService:  mockLLM,
+			RateLimiter: mockRateLimiter,
+			Publisher:   mockPublisher,
+		})
 
 		request := aiapp.StreamCodeRequest{
 			Prompt:     "Generate code",
@@ -383,6 +403,8 @@ func TestStreamCodeUseCase_Execute_ErrorHandling(t *testing.T) {
 			ResetTime:  "2024-01-01T00:00:00Z",
 		}
 		mockRepo.On("GetQuotaUsage", ctx, userID).Return(quota, nil)
+		// The tokens streamed before the error are still billed
+		mockRepo.On("UpdateQuotaUsage", mock.Anything, userID, 3).Return(nil)
 
 		// Setup rate limiter
 		mockRateLimiter.On("Allow", userID).Return(true)
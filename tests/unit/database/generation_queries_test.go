@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	infradb "github.com/EliasRanz/ai-code-gen/internal/infrastructure/database"
+)
+
+// newMockGenerationRepository returns a generation repository backed by sqlmock
+func newMockGenerationRepository(t *testing.T) (*infradb.PostgreSQLGenerationRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		sqlDB.Close()
+	})
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	return infradb.NewPostgreSQLGenerationRepositoryWithConfig(db, &infradb.GenerationRepositoryConfig{DailyTokenLimit: 1000}), mock
+}
+
+func generationRows(ids ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "parent_id", "user_id", "name", "prompt", "status", "created_at"})
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, id := range ids {
+		var parent any
+		if i > 0 {
+			parent = ids[0]
+		}
+		rows.AddRow(id, parent, "user-1", "Button", "A button", "completed", created.Add(-time.Duration(i)*time.Minute))
+	}
+	return rows
+}
+
+func TestGenerationRepository_ListHistoryPages(t *testing.T) {
+	repo, mock := newMockGenerationRepository(t)
+	cursor := ai.HistoryCursor{CreatedAt: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), ID: "6f1c2a4e-8d7b-4a43-9c3e-0b6a1d2e3f40"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "ui_generations" WHERE user_id = $1 AND framework = $2 AND (created_at, id) < ($3, $4) AND "ui_generations"."deleted_at" IS NULL ORDER BY created_at DESC, id DESC LIMIT $5`)).
+		WithArgs("user-1", "react", cursor.CreatedAt, cursor.ID, 3).
+		WillReturnRows(generationRows(
+			"00000000-0000-4000-8000-000000000001",
+			"00000000-0000-4000-8000-000000000002",
+			"00000000-0000-4000-8000-000000000003",
+		))
+
+	page, err := repo.ListHistory(context.Background(), ai.HistoryQuery{UserID: "user-1", Framework: "react", Cursor: &cursor, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2, "the extra row only signals another page")
+
+	next, err := ai.ParseHistoryCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, page.Items[1].ID, next.ID, "the next page starts after the last item")
+	assert.True(t, page.Items[1].CreatedAt.Equal(next.CreatedAt))
+}
+
+func TestGenerationRepository_ListHistoryLastPage(t *testing.T) {
+	repo, mock := newMockGenerationRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "ui_generations" WHERE "ui_generations"."deleted_at" IS NULL ORDER BY created_at DESC, id DESC LIMIT $1`)).
+		WithArgs(ai.DefaultHistoryPageSize + 1).
+		WillReturnRows(generationRows("gen-1"))
+
+	page, err := repo.ListHistory(context.Background(), ai.HistoryQuery{})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestGenerationRepository_ListRevisions(t *testing.T) {
+	repo, mock := newMockGenerationRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WITH RECURSIVE ancestors AS`) + `(?s).*` + regexp.QuoteMeta(`SELECT * FROM tree WHERE deleted_at IS NULL ORDER BY created_at, id`)).
+		WithArgs("gen-2").
+		WillReturnRows(generationRows("gen-1", "gen-2"))
+
+	revisions, err := repo.ListRevisions(context.Background(), "gen-2")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "gen-1", revisions[0].ID)
+	assert.Equal(t, "gen-1", revisions[1].ParentID)
+
+	mock.ExpectQuery(`WITH RECURSIVE`).WithArgs("missing").WillReturnRows(generationRows())
+	_, err = repo.ListRevisions(context.Background(), "missing")
+	assert.True(t, common.IsNotFoundError(err))
+}
+
+func TestGenerationRepository_GetQuotaUsage(t *testing.T) {
+	repo, mock := newMockGenerationRepository(t)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(tokens), 0) FROM "daily_token_usage" WHERE user_id = $1 AND day = $2`)).
+		WithArgs("user-1", today).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(1200))
+
+	status, err := repo.GetQuotaUsage(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1000, status.DailyLimit)
+	assert.Equal(t, 1200, status.UsedToday)
+	assert.Equal(t, 0, status.Remaining, "usage past the limit leaves nothing")
+	assert.Equal(t, today.Add(24*time.Hour).Format(time.RFC3339), status.ResetTime)
+}
+
+func TestGenerationRepository_UpdateQuotaUsage(t *testing.T) {
+	repo, mock := newMockGenerationRepository(t)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	mock.ExpectExec(regexp.QuoteMeta(`ON CONFLICT (user_id, day) DO UPDATE SET tokens = daily_token_usage.tokens + EXCLUDED.tokens`)).
+		WithArgs("user-1", today, 250).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.UpdateQuotaUsage(context.Background(), "user-1", 250))
+	require.NoError(t, repo.UpdateQuotaUsage(context.Background(), "user-1", 0), "nothing to count")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenerationRepository_SaveGenerationKeepsOwner(t *testing.T) {
+	repo, mock := newMockGenerationRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`ON CONFLICT ("id") DO UPDATE SET`) + `.*` + regexp.QuoteMeta(`WHERE ui_generations.user_id = EXCLUDED.user_id`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.SaveGeneration(context.Background(), ai.GenerationHistory{ID: "gen-1", UserID: "user-2", Prompt: "A button"})
+	assert.True(t, common.IsConflictError(err), "another user's generation is not overwritten")
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	infradb "github.com/EliasRanz/ai-code-gen/internal/infrastructure/database"
+)
+
+func TestGenerationModel_HistoryRoundTrip(t *testing.T) {
+	projectID := common.ProjectID("project-1")
+	history := ai.GenerationHistory{
+		ID:        "gen-1",
+		UserID:    "user-1",
+		ProjectID: &projectID,
+		Prompt:    "A login form\nwith validation",
+		Code:      "<form/>",
+		Artifact: &ai.Artifact{
+			Files:        []ai.ArtifactFile{{Path: "LoginForm.tsx", Language: "tsx", Content: "<form/>"}},
+			Dependencies: map[string]string{"zod": "^3.23.0"},
+		},
+		SecurityFindings: []ai.SecurityFinding{
+			{Rule: "eval", Severity: ai.SeverityError, Message: "eval executes arbitrary strings as code", Path: "LoginForm.tsx", Line: 3, Column: 5},
+		},
+		RepairAttempts: []ai.RepairAttempt{
+			{Attempt: 1, Code: "<form>", Tokens: 30, Diagnostics: []ai.Diagnostic{
+				{Path: "LoginForm.tsx", Line: 1, Column: 7, Severity: ai.SeverityError, Rule: "syntax", Message: "Unexpected end of file"},
+			}},
+			{Attempt: 2, Code: "<form/>", Valid: true, Tokens: 12},
+		},
+		Framework:      "react",
+		Model:          "gpt-4o-mini",
+		Tokens:         42,
+		Status:         ai.GenerationCompleted,
+		ProcessingTime: 1500 * time.Millisecond,
+		Metadata:       map[string]string{"prompt_template": "component", "prompt_version": "2"},
+	}
+
+	var model infradb.GenerationModel
+	require.NoError(t, model.FromGenerationHistory(history))
+	assert.Equal(t, "A login form", model.Name)
+	require.NotNil(t, model.ProcessingTimeMS)
+	assert.Equal(t, 1500, *model.ProcessingTimeMS)
+	assert.JSONEq(t, `{"prompt_template":"component","prompt_version":"2"}`, model.Metadata)
+
+	assert.Equal(t, history, model.ToGenerationHistory())
+}
+
+func TestGenerationModel_HistoryDefaults(t *testing.T) {
+	var model infradb.GenerationModel
+	require.NoError(t, model.FromGenerationHistory(ai.GenerationHistory{UserID: "user-1"}))
+
+	assert.Equal(t, "Untitled generation", model.Name)
+	assert.Equal(t, string(ai.GenerationCompleted), model.Status)
+	assert.Equal(t, "{}", model.Metadata, "empty metadata is stored as a JSON object")
+	assert.Equal(t, "{}", model.Assets)
+	assert.Equal(t, "[]", model.SecurityFindings)
+	assert.Equal(t, "[]", model.RepairAttempts)
+	assert.Nil(t, model.ProjectID)
+	assert.Nil(t, model.GeneratedCode)
+	assert.Nil(t, model.ProcessingTimeMS)
+}
@@ -0,0 +1,91 @@
+package ai_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+func TestParseArtifact_JSONDocument(t *testing.T) {
+	output := `{
+  "files": [
+    {"path": "src/Button.tsx", "language": "tsx", "content": "export const Button = () => <button/>;"},
+    {"path": "src/Button.module.css", "content": ".button { color: blue; }"},
+    {"path": "", "content": "dropped"}
+  ],
+  "dependencies": {"clsx": "^2.0.0"}
+}`
+
+	artifact := ai.ParseArtifact(output, "Component.tsx")
+	assert.Equal(t, []ai.ArtifactFile{
+		{Path: "src/Button.tsx", Language: "tsx", Content: "export const Button = () => <button/>;"},
+		{Path: "src/Button.module.css", Language: "css", Content: ".button { color: blue; }"},
+	}, artifact.Files)
+	assert.Equal(t, map[string]string{"clsx": "^2.0.0"}, artifact.Dependencies)
+}
+
+func TestParseArtifact_FencedJSONWithDependencyList(t *testing.T) {
+	output := "Here you go:\n\n```json\n" +
+		`{"files": [{"path": "Card.vue", "content": "<template><div/></template>"}], "dependencies": ["pinia"]}` +
+		"\n```\n"
+
+	artifact := ai.ParseArtifact(output, "Component.vue")
+	assert.Equal(t, []ai.ArtifactFile{{Path: "Card.vue", Language: "vue", Content: "<template><div/></template>"}}, artifact.Files)
+	assert.Equal(t, map[string]string{"pinia": "latest"}, artifact.Dependencies)
+}
+
+func TestParseArtifact_MarkdownFences(t *testing.T) {
+	output := "Sure! Here is the component.\n\n" +
+		"**src/Button.tsx**\n```tsx\nexport function Button() {}\n```\n\n" +
+		"```css title=\"src/Button.css\"\n.button {}\n```\n\n" +
+		"```ts:src/types.ts\nexport type Props = {};\n```\n\n" +
+		"And a usage example:\n```tsx\n<Button />\n```\n"
+
+	artifact := ai.ParseArtifact(output, "Component.tsx")
+	assert.Equal(t, []ai.ArtifactFile{
+		{Path: "src/Button.tsx", Language: "tsx", Content: "export function Button() {}"},
+		{Path: "src/Button.css", Language: "css", Content: ".button {}"},
+		{Path: "src/types.ts", Language: "typescript", Content: "export type Props = {};"},
+		{Path: "Component.tsx", Language: "tsx", Content: "<Button />"},
+	}, artifact.Files)
+	assert.Nil(t, artifact.Dependencies)
+}
+
+func TestParseArtifact_FallsBackToSingleFile(t *testing.T) {
+	for _, output := range []string{
+		"<div class=\"card\"></div>",
+		`{"files": []}`,
+		"{not json",
+	} {
+		artifact := ai.ParseArtifact("  "+output+"\n", "index.html")
+		assert.Equal(t, []ai.ArtifactFile{{Path: "index.html", Language: "html", Content: output}}, artifact.Files, output)
+	}
+}
+
+func TestParseArtifact_SanitizesPaths(t *testing.T) {
+	output := `{
+  "files": [
+    {"path": "./src/../src/Button.tsx", "content": "a"},
+    {"path": "/etc/passwd", "content": "b"},
+    {"path": "../../outside.ts", "content": "c"},
+    {"path": "src\\Button.tsx", "content": "d"},
+    {"path": "src/Button.tsx", "content": "e"}
+  ]
+}`
+
+	artifact := ai.ParseArtifact(output, "Component.tsx")
+	assert.Equal(t, []ai.ArtifactFile{
+		{Path: "src/Button.tsx", Language: "tsx", Content: "a"},
+		{Path: "src/Button-4.tsx", Language: "tsx", Content: "d"},
+		{Path: "src/Button-5.tsx", Language: "tsx", Content: "e"},
+	}, artifact.Files)
+}
+
+func TestParseArtifact_FenceWithEscapingPathUsesFallback(t *testing.T) {
+	output := "```ts:../../secrets.ts\nexport {};\n```\n"
+
+	artifact := ai.ParseArtifact(output, "Component.ts")
+	assert.Equal(t, []ai.ArtifactFile{{Path: "Component.ts", Language: "typescript", Content: "export {};"}}, artifact.Files)
+}
@@ -0,0 +1,37 @@
+package ai_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+)
+
+func TestHistoryCursor_RoundTrip(t *testing.T) {
+	cursor := ai.HistoryCursor{
+		CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC),
+		ID:        "6f1c2a4e-8d7b-4a43-9c3e-0b6a1d2e3f40",
+	}
+
+	parsed, err := ai.ParseHistoryCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(parsed.CreatedAt))
+	assert.Equal(t, cursor.ID, parsed.ID)
+}
+
+func TestParseHistoryCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"", "not base64!", "bm8tc2VwYXJhdG9y", "YWJjOmlk", "MTIzOmdlbi05"} {
+		_, err := ai.ParseHistoryCursor(s)
+		assert.True(t, common.IsValidationError(err), "cursor %q", s)
+	}
+}
+
+func TestHistoryQuery_PageSize(t *testing.T) {
+	assert.Equal(t, ai.DefaultHistoryPageSize, ai.HistoryQuery{}.PageSize())
+	assert.Equal(t, 5, ai.HistoryQuery{Limit: 5}.PageSize())
+	assert.Equal(t, ai.MaxHistoryPageSize, ai.HistoryQuery{Limit: 1000}.PageSize())
+}
@@ -0,0 +1,30 @@
+package ai_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+func TestGenerationRequest_ValidateMaxTokens(t *testing.T) {
+	tokens := func(n int) *int { return &n }
+	tests := []struct {
+		name      string
+		maxTokens *int
+		valid     bool
+	}{
+		{"unset", nil, true},
+		{"above the old 4096 cap", tokens(16384), true},
+		{"zero", tokens(0), false},
+		{"negative", tokens(-1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := ai.GenerationRequest{Prompt: "A button", UserID: "user-1", MaxTokens: tt.maxTokens}
+			assert.Equal(t, tt.valid, req.Validate() == nil)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package ai_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+)
+
+func TestPosition(t *testing.T) {
+	code := "ab\ncd\n"
+	for _, tt := range []struct {
+		offset, line, column int
+	}{
+		{0, 1, 1},
+		{1, 1, 2},
+		{3, 2, 1},
+		{4, 2, 2},
+		{-1, 1, 1},
+		{100, 3, 1},
+	} {
+		line, column := ai.Position(code, tt.offset)
+		assert.Equal(t, [2]int{tt.line, tt.column}, [2]int{line, column}, "offset %d", tt.offset)
+	}
+}
@@ -0,0 +1,65 @@
+package generation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/generation"
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+	"github.com/EliasRanz/ai-code-gen/internal/user"
+)
+
+// newCassetteVLLMClient returns a VLLM client replaying testdata/name.
+// Run with RECORD_CASSETTES=1 and VLLM_BASE_URL set to re-record it.
+func newCassetteVLLMClient(t *testing.T, name string) *llm.VLLMClient {
+	t.Helper()
+
+	config := &llm.CassetteConfig{Path: "testdata/" + name}
+	baseURL := "http://vllm.test"
+	if os.Getenv("RECORD_CASSETTES") != "" {
+		config.Mode = llm.CassetteRecord
+		baseURL = os.Getenv("VLLM_BASE_URL")
+	}
+
+	cassette, err := llm.NewCassette(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, cassette.Save()) })
+
+	return llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: baseURL, HTTPClient: cassette})
+}
+
+func TestStreamGenerationHandler_ReplaysRecordedStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := generation.NewService(newCassetteVLLMClient(t, "vllm_stream.json"), nil, mockAuthService())
+
+	r := gin.New()
+	r.POST("/generate/stream", func(c *gin.Context) {
+		c.Set("user", &user.User{ID: "test-user", IsActive: true})
+		service.StreamGenerationHandler(c)
+	})
+
+	body := `{"model":"Qwen/Qwen2.5-Coder-7B-Instruct","prompt":"A save button","max_tokens":64}`
+	req := httptest.NewRequest("POST", "/generate/stream", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+	require.Len(t, events, 4, "3 data events and done")
+	assert.Contains(t, events[0], `"text":"\u003cbutton"`)
+	assert.Contains(t, events[2], `"finish_reason":"stop"`)
+	assert.Contains(t, events[2], `"total_tokens":17`)
+	assert.Contains(t, events[3], "event: done")
+}
@@ -0,0 +1,238 @@
+package generation
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/generation"
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+	"github.com/EliasRanz/ai-code-gen/internal/user"
+)
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// readSSEEvent reads the next event from r
+func readSSEEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// newResumableServer serves the streaming handler for the user in the X-User header
+func newResumableServer(t *testing.T, service *generation.Service) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/generate/stream", func(c *gin.Context) {
+		c.Set("user", &user.User{ID: c.GetHeader("X-User"), IsActive: true})
+		service.StreamGenerationHandler(c)
+	})
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// openStream posts to the streaming endpoint, resuming from lastEventID when set
+func openStream(t *testing.T, ctx context.Context, server *httptest.Server, userID, lastEventID string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, "POST", server.URL+"/generate/stream",
+		strings.NewReader(`{"model":"test-model","prompt":"A save button"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User", userID)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func textResponse(text string) *llm.GenerationResponse {
+	return &llm.GenerationResponse{ID: "cmpl-1", Choices: []llm.Choice{{Text: text}}}
+}
+
+func TestStreamGenerationHandler_NumbersEvents(t *testing.T) {
+	respChan := make(chan *llm.GenerationResponse, 2)
+	respChan <- textResponse("<button>")
+	respChan <- textResponse("Save</button>")
+	close(respChan)
+
+	mockLLM := new(MockLLMClient)
+	mockLLM.On("GenerateStream", mock.Anything, mock.Anything).Return((<-chan *llm.GenerationResponse)(respChan), nil)
+	server := newResumableServer(t, generation.NewService(mockLLM, nil, mockAuthService()))
+
+	body := bufio.NewReader(openStream(t, context.Background(), server, "user-1", "").Body)
+	first := readSSEEvent(t, body)
+	streamID := strings.TrimSuffix(first.ID, ":1")
+	require.NotEqual(t, first.ID, streamID, "event IDs end with the sequence number")
+	assert.NotEqual(t, "cmpl-1", streamID, "the upstream ID is not reused")
+
+	assert.Equal(t, streamID+":2", readSSEEvent(t, body).ID)
+	done := readSSEEvent(t, body)
+	assert.Equal(t, "done", done.Event)
+	assert.Equal(t, streamID+":3", done.ID)
+}
+
+func TestStreamGenerationHandler_ResumesFromLastEventID(t *testing.T) {
+	respChan := make(chan *llm.GenerationResponse, 1)
+	respChan <- textResponse("<button>")
+	mockLLM := new(MockLLMClient)
+	mockLLM.On("GenerateStream", mock.Anything, mock.Anything).Return((<-chan *llm.GenerationResponse)(respChan), nil)
+	service := generation.NewServiceWithStreamBuffer(mockLLM, nil, mockAuthService(), generation.NewMemoryStreamBuffer(time.Minute))
+	server := newResumableServer(t, service)
+
+	// The first connection drops after one chunk
+	ctx, disconnect := context.WithCancel(context.Background())
+	body := bufio.NewReader(openStream(t, ctx, server, "user-1", "").Body)
+	first := readSSEEvent(t, body)
+	assert.Contains(t, first.Data, `\u003cbutton\u003e`)
+	disconnect()
+
+	// The generation keeps going while the client is away
+	upstreamCtx := mockLLM.Calls[0].Arguments.Get(0).(context.Context)
+	assert.NoError(t, upstreamCtx.Err(), "the upstream request outlives the connection")
+	respChan <- textResponse("Save")
+
+	// Another user cannot resume the stream
+	other := openStream(t, context.Background(), server, "user-2", first.ID)
+	assert.Equal(t, http.StatusNotFound, other.StatusCode)
+
+	// The owner gets the missed chunk, then the rest live
+	resumed := openStream(t, context.Background(), server, "user-1", first.ID)
+	require.Equal(t, http.StatusOK, resumed.StatusCode)
+	body = bufio.NewReader(resumed.Body)
+
+	streamID := strings.TrimSuffix(first.ID, ":1")
+	missed := readSSEEvent(t, body)
+	assert.Equal(t, streamID+":2", missed.ID)
+	assert.Contains(t, missed.Data, "Save")
+
+	respChan <- textResponse("</button>")
+	close(respChan)
+	live := readSSEEvent(t, body)
+	assert.Equal(t, streamID+":3", live.ID)
+	assert.Contains(t, live.Data, `\u003c/button\u003e`)
+	done := readSSEEvent(t, body)
+	assert.Equal(t, "done", done.Event)
+	assert.Equal(t, streamID+":4", done.ID)
+
+	mockLLM.AssertNumberOfCalls(t, "GenerateStream", 1)
+}
+
+// flakyStreamBuffer fails every append after the first limit
+type flakyStreamBuffer struct {
+	*generation.MemoryStreamBuffer
+	limit int
+}
+
+func (b *flakyStreamBuffer) Append(ctx context.Context, streamKey string, event generation.StreamEvent) (generation.StreamEvent, error) {
+	if b.limit == 0 {
+		return generation.StreamEvent{}, errors.New("connection refused")
+	}
+	b.limit--
+	return b.MemoryStreamBuffer.Append(ctx, streamKey, event)
+}
+
+func TestStreamGenerationHandler_EndsStreamWhenBufferFails(t *testing.T) {
+	respChan := make(chan *llm.GenerationResponse)
+	go func() {
+		defer close(respChan)
+		for _, text := range []string{"<button>", "Save", "</button>"} {
+			respChan <- textResponse(text)
+		}
+	}()
+	mockLLM := new(MockLLMClient)
+	mockLLM.On("GenerateStream", mock.Anything, mock.Anything).Return((<-chan *llm.GenerationResponse)(respChan), nil)
+	buffer := &flakyStreamBuffer{MemoryStreamBuffer: generation.NewMemoryStreamBuffer(time.Minute), limit: 1}
+	server := newResumableServer(t, generation.NewServiceWithStreamBuffer(mockLLM, nil, mockAuthService(), buffer))
+
+	body := bufio.NewReader(openStream(t, context.Background(), server, "user-1", "").Body)
+	first := readSSEEvent(t, body)
+	streamID := strings.TrimSuffix(first.ID, ":1")
+
+	failed := readSSEEvent(t, body)
+	assert.Equal(t, "error", failed.Event)
+	assert.Equal(t, streamID+":2", failed.ID, "the error follows the last buffered event")
+	assert.Contains(t, failed.Data, "stream_error")
+	_, err := body.ReadString('\n')
+	assert.ErrorIs(t, err, io.EOF, "the stream ends")
+}
+
+func TestStreamGenerationHandler_ResumeErrors(t *testing.T) {
+	mockLLM := new(MockLLMClient)
+
+	tests := []struct {
+		name        string
+		service     *generation.Service
+		lastEventID string
+		status      int
+	}{
+		{"resumption disabled", generation.NewService(mockLLM, nil, mockAuthService()), "stream:1", http.StatusNotFound},
+		{"malformed event ID", generation.NewServiceWithStreamBuffer(mockLLM, nil, mockAuthService(), generation.NewMemoryStreamBuffer(0)), "stream", http.StatusBadRequest},
+		{"unknown stream", generation.NewServiceWithStreamBuffer(mockLLM, nil, mockAuthService(), generation.NewMemoryStreamBuffer(0)), "stream:1", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := openStream(t, context.Background(), newResumableServer(t, tt.service), "user-1", tt.lastEventID)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+	mockLLM.AssertNotCalled(t, "GenerateStream", mock.Anything, mock.Anything)
+}
+
+func TestMemoryStreamBuffer_FollowFinishedStream(t *testing.T) {
+	ctx := context.Background()
+	buffer := generation.NewMemoryStreamBuffer(time.Minute)
+
+	for _, name := range []string{"data", "data", "done"} {
+		_, err := buffer.Append(ctx, "user-1:stream", generation.StreamEvent{Event: name, Data: []byte(`{}`)})
+		require.NoError(t, err)
+	}
+
+	events, err := buffer.Follow(ctx, "user-1:stream", 1)
+	require.NoError(t, err)
+	var seqs []int64
+	for event := range events {
+		seqs = append(seqs, event.Seq)
+	}
+	assert.Equal(t, []int64{2, 3}, seqs)
+
+	// A client that already has the terminal event is done straight away
+	events, err = buffer.Follow(ctx, "user-1:stream", 3)
+	require.NoError(t, err)
+	_, open := <-events
+	assert.False(t, open)
+}
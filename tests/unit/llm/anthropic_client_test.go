@@ -0,0 +1,214 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	infrallm "github.com/EliasRanz/ai-code-gen/internal/infrastructure/llm"
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+// newReplayServer serves a recorded Messages API event stream from testdata
+func newReplayServer(t *testing.T, fixture string, gotBody *map[string]interface{}) *httptest.Server {
+	t.Helper()
+	recorded, err := os.ReadFile("testdata/" + fixture)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		assert.Equal(t, llm.AnthropicVersion, r.Header.Get("anthropic-version"))
+		if gotBody != nil {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(gotBody))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write(recorded)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestAnthropicClient(baseURL string) *llm.AnthropicClient {
+	return llm.NewAnthropicClient(&llm.AnthropicConfig{BaseURL: baseURL, APIKey: "test-key", RetryBaseDelay: time.Millisecond})
+}
+
+func TestAnthropicClient_Generate(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet-20241022",
+			"content":[{"type":"text","text":"<button>"},{"type":"text","text":"OK</button>"}],
+			"stop_reason":"stop_sequence","stop_sequence":"</html>","usage":{"input_tokens":12,"output_tokens":8}}`)
+	}))
+	defer server.Close()
+
+	temperature := 0.3
+	resp, err := newTestAnthropicClient(server.URL).Generate(context.Background(), &llm.GenerationRequest{
+		Model: "claude-3-5-sonnet-20241022",
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "You write HTML."},
+			{Role: llm.RoleUser, Content: "A button"},
+		},
+		Temperature: &temperature,
+		Stop:        []string{"</html>"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "You write HTML.", gotBody["system"], "system messages move to the system prompt")
+	assert.Equal(t, []interface{}{map[string]interface{}{"role": "user", "content": "A button"}}, gotBody["messages"])
+	assert.Equal(t, float64(4096), gotBody["max_tokens"], "max_tokens is required by the API")
+	assert.Equal(t, []interface{}{"</html>"}, gotBody["stop_sequences"])
+
+	assert.Equal(t, "<button>OK</button>", resp.Choices[0].Content())
+	assert.Equal(t, "stop", *resp.Choices[0].FinishReason)
+	assert.Equal(t, &llm.Usage{PromptTokens: 12, CompletionTokens: 8, TotalTokens: 20}, resp.Usage)
+}
+
+func TestAnthropicClient_GenerateStream_ReplaysRecordedEvents(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := newReplayServer(t, "anthropic_stream.sse", &gotBody)
+
+	ch, err := newTestAnthropicClient(server.URL).GenerateStream(context.Background(), &llm.GenerationRequest{
+		Model:  "claude-3-5-sonnet-20241022",
+		Prompt: "A button",
+	})
+	require.NoError(t, err)
+	responses := collectStream(t, ch)
+
+	assert.Equal(t, true, gotBody["stream"])
+	require.Len(t, responses, 3)
+	assert.Equal(t, "export function ", responses[0].Choices[0].Content())
+	assert.Equal(t, "Button() {}", responses[1].Choices[0].Content())
+
+	terminal := responses[2]
+	assert.Nil(t, terminal.Error)
+	assert.Equal(t, "msg_01XFDUDYJgAACzvnptvVoYEL", terminal.ID)
+	assert.Equal(t, "length", *terminal.Choices[0].FinishReason)
+	assert.Equal(t, &llm.Usage{PromptTokens: 25, CompletionTokens: 15, TotalTokens: 40}, terminal.Usage)
+}
+
+func TestAnthropicClient_GenerateStream_ErrorEvent(t *testing.T) {
+	server := newReplayServer(t, "anthropic_stream_error.sse", nil)
+
+	ch, err := newTestAnthropicClient(server.URL).GenerateStream(context.Background(), &llm.GenerationRequest{
+		Model:  "claude-3-5-sonnet-20241022",
+		Prompt: "A button",
+	})
+	require.NoError(t, err)
+	responses := collectStream(t, ch)
+
+	require.Len(t, responses, 2)
+	assert.Equal(t, "partial", responses[0].Choices[0].Content())
+	require.NotNil(t, responses[1].Error)
+	assert.Equal(t, "overloaded_error", responses[1].Error.Code)
+}
+
+func TestAnthropicClient_GenerateStream_Truncated(t *testing.T) {
+	server := newSSEServer(t,
+		"event: message_start\n",
+		`data: {"type":"message_start","message":{"id":"msg_3","model":"claude-3-5-haiku-latest","usage":{"input_tokens":3,"output_tokens":1}}}`+"\n\n",
+	)
+
+	ch, err := newTestAnthropicClient(server.URL).GenerateStream(context.Background(), &llm.GenerationRequest{Model: "claude-3-5-haiku-latest", Prompt: "x"})
+	require.NoError(t, err)
+	responses := collectStream(t, ch)
+
+	require.Len(t, responses, 1)
+	require.NotNil(t, responses[0].Error)
+	assert.Equal(t, "incomplete_stream", responses[0].Error.Code)
+}
+
+func TestAnthropicClient_MapsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: field required"}}`)
+	}))
+	defer server.Close()
+
+	_, err := newTestAnthropicClient(server.URL).Generate(context.Background(), &llm.GenerationRequest{Model: "claude-3-5-haiku-latest", Prompt: "x"})
+
+	var llmErr *llm.LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "invalid_request_error", llmErr.Code)
+	assert.Equal(t, http.StatusBadRequest, llmErr.StatusCode)
+	assert.Contains(t, llmErr.Details, "max_tokens")
+	assert.False(t, llm.IsRetryableError(err))
+}
+
+func TestAnthropicClient_RejectsUnsupportedParams(t *testing.T) {
+	seed := 1
+	_, err := newTestAnthropicClient("http://127.0.0.1:0").Generate(context.Background(), &llm.GenerationRequest{
+		Model:  "claude-3-5-sonnet-20241022",
+		Prompt: "x",
+		Seed:   &seed,
+	})
+	assert.True(t, llm.IsInvalidRequest(err))
+}
+
+func TestAnthropicService_GenerateStream(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := newReplayServer(t, "anthropic_stream.sse", &gotBody)
+
+	service := infrallm.NewAnthropicService(&infrallm.AnthropicConfig{
+		AnthropicConfig: llm.AnthropicConfig{BaseURL: server.URL, APIKey: "test-key"},
+		Model:           "claude-3-5-sonnet-20241022",
+	})
+
+	ch := make(chan ai.StreamChunk, 10)
+	err := service.GenerateStream(context.Background(), ai.GenerationRequest{
+		Prompt: "A button",
+		UserID: "user-1",
+		Messages: []ai.ChatMessage{
+			{Role: ai.RoleSystem, Content: "You write React."},
+			{Role: ai.RoleUser, Content: "A button"},
+		},
+	}, ch)
+	require.NoError(t, err)
+	close(ch)
+
+	var content string
+	var terminal []ai.StreamChunk
+	for chunk := range ch {
+		content += chunk.Content
+		if chunk.IsComplete {
+			terminal = append(terminal, chunk)
+		}
+	}
+
+	assert.Equal(t, "You write React.", gotBody["system"])
+	assert.Equal(t, map[string]interface{}{"user_id": "user-1"}, gotBody["metadata"])
+	assert.Equal(t, "export function Button() {}", content)
+	require.Len(t, terminal, 1)
+	assert.Equal(t, 40, terminal[0].TokenCount)
+	assert.Equal(t, "length", terminal[0].FinishReason)
+	assert.Equal(t, "claude-3-5-sonnet-20241022", terminal[0].Model)
+}
+
+func TestAnthropicService_Generate_ReportsCost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"msg_1","model":"claude-3-5-sonnet-20241022","content":[{"type":"text","text":"ok"}],
+			"stop_reason":"end_turn","usage":{"input_tokens":1000,"output_tokens":500}}`)
+	}))
+	defer server.Close()
+
+	service := infrallm.NewAnthropicService(&infrallm.AnthropicConfig{
+		AnthropicConfig: llm.AnthropicConfig{BaseURL: server.URL, APIKey: "test-key"},
+	})
+	result, err := service.Generate(context.Background(), ai.GenerationRequest{Prompt: "x", UserID: "u"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "msg_1", result.ID)
+	assert.Equal(t, 1500, result.UsedTokens)
+	// $3 per 1M prompt tokens, $15 per 1M completion tokens
+	assert.InDelta(t, 0.0105, result.EstimatedCost, 1e-9)
+}
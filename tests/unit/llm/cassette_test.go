@@ -0,0 +1,147 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+// recordStream records one VLLM streaming exchange against server into path
+func recordStream(t *testing.T, path, baseURL string, req *llm.GenerationRequest) []*llm.GenerationResponse {
+	t.Helper()
+	cassette, err := llm.NewCassette(&llm.CassetteConfig{Path: path, Mode: llm.CassetteRecord})
+	require.NoError(t, err)
+
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: baseURL, HTTPClient: cassette})
+	ch, err := client.GenerateStream(context.Background(), req)
+	require.NoError(t, err)
+	responses := collectStream(t, ch)
+
+	require.NoError(t, cassette.Save())
+	return responses
+}
+
+func TestCassette_RecordThenReplay(t *testing.T) {
+	server := newSSEServer(t,
+		`data: {"id":"cmpl-1","object":"text_completion","model":"m","choices":[{"index":0,"text":"Hel"}]}`+"\n\n",
+		// Partial JSON split across writes must replay with the same boundaries
+		`data: {"id":"cmpl-1","object":"text_completion","model":"m",`,
+		`"choices":[{"index":0,"text":"lo","finish_reason":"stop"}]}`+"\n\n",
+		"data: [DONE]\n\n",
+	)
+	path := filepath.Join(t.TempDir(), "stream.json")
+	req := &llm.GenerationRequest{Model: "m", Prompt: "hi", UserID: "recorder"}
+
+	recorded := recordStream(t, path, server.URL, req)
+	server.Close()
+
+	cassette, err := llm.NewCassette(&llm.CassetteConfig{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, llm.CassetteReplay, cassette.Mode())
+
+	// The host is not part of the match, and neither is the caller
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: "http://vllm.invalid", HTTPClient: cassette})
+	ch, err := client.GenerateStream(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi", UserID: "someone-else"})
+	require.NoError(t, err)
+	replayed := collectStream(t, ch)
+
+	assert.Equal(t, recorded, replayed)
+	require.Len(t, replayed, 2)
+	assert.Equal(t, "Hel", replayed[0].Choices[0].Content())
+	assert.Equal(t, "lo", replayed[1].Choices[0].Content())
+}
+
+func TestCassette_ReplayMissIsNotRetried(t *testing.T) {
+	server := newSSEServer(t, `data: {"id":"cmpl-1","choices":[{"index":0,"text":"ok"}]}`+"\n\n", "data: [DONE]\n\n")
+	path := filepath.Join(t.TempDir(), "stream.json")
+	recordStream(t, path, server.URL, &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+
+	cassette, err := llm.NewCassette(&llm.CassetteConfig{Path: path})
+	require.NoError(t, err)
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: "http://vllm.invalid", HTTPClient: cassette, RetryBaseDelay: time.Hour})
+
+	_, err = client.GenerateStream(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "a different prompt"})
+
+	var llmErr *llm.LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "cassette_miss", llmErr.Code)
+}
+
+func TestCassette_MatchesNormalizedJSON(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"n":%d}`, atomic.AddInt32(&calls, 1))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "json.json")
+	recorder, err := llm.NewCassette(&llm.CassetteConfig{Path: path, Mode: llm.CassetteRecord})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		doRequest(t, recorder, server.URL+"/v1/completions?b=2&a=1", `{"model":"m","prompt":"x","user":"u1"}`)
+	}
+	require.NoError(t, recorder.Save())
+
+	replayer, err := llm.NewCassette(&llm.CassetteConfig{Path: path})
+	require.NoError(t, err)
+
+	// Key order, query order and the user field do not affect matching;
+	// identical requests replay in order and the last recording repeats
+	assert.Equal(t, `{"n":1}`, doRequest(t, replayer, "http://other/v1/completions?a=1&b=2", `{"prompt":"x","model":"m","user":"u2"}`))
+	assert.Equal(t, `{"n":2}`, doRequest(t, replayer, "http://other/v1/completions?a=1&b=2", `{"model":"m","prompt":"x"}`))
+	assert.Equal(t, `{"n":2}`, doRequest(t, replayer, "http://other/v1/completions?a=1&b=2", `{"model":"m","prompt":"x"}`))
+}
+
+func TestCassette_RealtimeReplayKeepsTiming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("second"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "timing.json")
+	recorder, err := llm.NewCassette(&llm.CassetteConfig{Path: path, Mode: llm.CassetteRecord})
+	require.NoError(t, err)
+	doRequest(t, recorder, server.URL, "")
+	require.NoError(t, recorder.Save())
+
+	fast, err := llm.NewCassette(&llm.CassetteConfig{Path: path})
+	require.NoError(t, err)
+	start := time.Now()
+	assert.Equal(t, "firstsecond", doRequest(t, fast, "http://other/", ""))
+	assert.Less(t, time.Since(start), 40*time.Millisecond)
+
+	realtime, err := llm.NewCassette(&llm.CassetteConfig{Path: path, Realtime: true})
+	require.NoError(t, err)
+	start = time.Now()
+	assert.Equal(t, "firstsecond", doRequest(t, realtime, "http://other/", ""))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+// doRequest performs a POST through client and returns the response body
+func doRequest(t *testing.T, client llm.HTTPClientInterface, url, body string) string {
+	t.Helper()
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(data)
+}
@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+func newTestLlamaCppClient(baseURL string) *llm.LlamaCppClient {
+	return llm.NewLlamaCppClient(&llm.LlamaCppConfig{BaseURL: baseURL, RetryBaseDelay: time.Millisecond})
+}
+
+func TestLlamaCppClient_Generate_AppliesChatTemplate(t *testing.T) {
+	var templateBody, completionBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apply-template":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&templateBody))
+			fmt.Fprint(w, `{"prompt":"<|system|>You write HTML.<|user|>A button<|assistant|>"}`)
+		case "/completion":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&completionBody))
+			fmt.Fprint(w, `{"content":"<button>OK</button>","stop":true,"stopped_limit":false,"tokens_predicted":6,"tokens_evaluated":14}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := newTestLlamaCppClient(server.URL).Generate(context.Background(), &llm.GenerationRequest{
+		Model: "local",
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "You write HTML."},
+			{Role: llm.RoleUser, Content: "A button"},
+		},
+		MaxTokens:      128,
+		Seed:           intPtr(7),
+		ResponseFormat: &llm.ResponseFormat{Type: llm.ResponseFormatJSONObject},
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, templateBody["messages"], 2)
+	assert.Equal(t, "<|system|>You write HTML.<|user|>A button<|assistant|>", completionBody["prompt"])
+	assert.Equal(t, float64(128), completionBody["n_predict"])
+	assert.Equal(t, float64(7), completionBody["seed"])
+	assert.Equal(t, map[string]interface{}{}, completionBody["json_schema"])
+
+	assert.Equal(t, "local", resp.Model, "the request model labels servers that report none")
+	assert.Equal(t, "<button>OK</button>", resp.Choices[0].Content())
+	assert.Equal(t, "stop", *resp.Choices[0].FinishReason)
+	assert.Equal(t, &llm.Usage{PromptTokens: 14, CompletionTokens: 6, TotalTokens: 20}, resp.Usage)
+}
+
+func TestLlamaCppClient_GenerateStream(t *testing.T) {
+	server := newSSEServer(t,
+		`data: {"content":"export ","stop":false}`+"\n\n",
+		`data: {"content":"default App","stop":false}`+"\n\n",
+		`data: {"content":"","model":"qwen2.5-coder-7b-q4_k_m.gguf","stop":true,"stopped_limit":true,"tokens_predicted":4,"tokens_evaluated":9}`+"\n\n",
+	)
+
+	ch, err := newTestLlamaCppClient(server.URL).GenerateStream(context.Background(), &llm.GenerationRequest{Model: "local", Prompt: "An app"})
+	require.NoError(t, err)
+	responses := collectStream(t, ch)
+
+	require.Len(t, responses, 3)
+	assert.Equal(t, "export ", responses[0].Choices[0].Content())
+	assert.Equal(t, "default App", responses[1].Choices[0].Content())
+
+	terminal := responses[2]
+	assert.Nil(t, terminal.Error)
+	assert.Equal(t, "qwen2.5-coder-7b-q4_k_m.gguf", terminal.Model)
+	assert.Equal(t, "length", *terminal.Choices[0].FinishReason)
+	assert.Equal(t, &llm.Usage{PromptTokens: 9, CompletionTokens: 4, TotalTokens: 13}, terminal.Usage)
+}
+
+func TestLlamaCppClient_GenerateStream_ErrorEvent(t *testing.T) {
+	server := newSSEServer(t,
+		`data: {"content":"partial","stop":false}`+"\n\n",
+		`data: {"error":{"code":500,"message":"context shift is disabled","type":"server_error"}}`+"\n\n",
+	)
+
+	ch, err := newTestLlamaCppClient(server.URL).GenerateStream(context.Background(), &llm.GenerationRequest{Model: "local", Prompt: "x"})
+	require.NoError(t, err)
+	responses := collectStream(t, ch)
+
+	require.Len(t, responses, 2)
+	require.NotNil(t, responses[1].Error)
+	assert.Contains(t, responses[1].Error.Error(), "context shift is disabled")
+}
+
+func TestLlamaCppClient_HealthWhileLoading(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/health", r.URL.Path)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"code":503,"message":"Loading model","type":"unavailable_error"}}`)
+	}))
+	defer server.Close()
+
+	err := newTestLlamaCppClient(server.URL).Health(context.Background())
+
+	var llmErr *llm.LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, http.StatusServiceUnavailable, llmErr.StatusCode)
+	assert.Equal(t, "Loading model", llmErr.Details)
+}
+
+func TestLlamaCppClient_GetModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"qwen2.5-coder-7b-q4_k_m.gguf","object":"model","created":1731400000,"meta":{"n_ctx_train":32768}}]}`)
+	}))
+	defer server.Close()
+
+	models, err := newTestLlamaCppClient(server.URL).GetModels(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, models, 1)
+	assert.Equal(t, "qwen2.5-coder-7b-q4_k_m.gguf", models[0].ID)
+	assert.Equal(t, "llama.cpp", models[0].Provider)
+	assert.Equal(t, 32768, models[0].MaxTokens)
+}
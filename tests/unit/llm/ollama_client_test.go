@@ -0,0 +1,198 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	infrallm "github.com/EliasRanz/ai-code-gen/internal/infrastructure/llm"
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+// newNDJSONServer streams lines as Ollama does, one JSON object per line
+func newNDJSONServer(t *testing.T, path string, gotBody *map[string]interface{}, lines ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, path, r.URL.Path)
+		if gotBody != nil {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(gotBody))
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestOllamaClient(baseURL string) *llm.OllamaClient {
+	return llm.NewOllamaClient(&llm.OllamaConfig{BaseURL: baseURL, RetryBaseDelay: time.Millisecond})
+}
+
+func TestOllamaClient_Generate(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := newNDJSONServer(t, "/api/generate", &gotBody,
+		`{"model":"qwen2.5-coder:7b","response":"<button>OK</button>","done":true,"done_reason":"stop","prompt_eval_count":11,"eval_count":6}`,
+	)
+
+	resp, err := newTestOllamaClient(server.URL).Generate(context.Background(), &llm.GenerationRequest{
+		Model:       "qwen2.5-coder:7b",
+		Prompt:      "A button",
+		MaxTokens:   256,
+		Temperature: floatPtr(0),
+		Stop:        []string{"</html>"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, false, gotBody["stream"], "Ollama streams unless told otherwise")
+	assert.Equal(t, "A button", gotBody["prompt"])
+	assert.Equal(t, map[string]interface{}{
+		"num_predict": float64(256),
+		"temperature": float64(0),
+		"stop":        []interface{}{"</html>"},
+	}, gotBody["options"])
+
+	assert.Equal(t, "qwen2.5-coder:7b", resp.Model)
+	assert.Equal(t, "<button>OK</button>", resp.Choices[0].Content())
+	assert.Equal(t, "stop", *resp.Choices[0].FinishReason)
+	assert.Equal(t, &llm.Usage{PromptTokens: 11, CompletionTokens: 6, TotalTokens: 17}, resp.Usage)
+}
+
+func TestOllamaClient_GenerateStream_Chat(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := newNDJSONServer(t, "/api/chat", &gotBody,
+		`{"model":"llama3.1","message":{"role":"assistant","content":"export "},"done":false}`,
+		`{"model":"llama3.1","message":{"role":"assistant","content":"default App"},"done":false}`,
+		`{"model":"llama3.1","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":20,"eval_count":4}`,
+	)
+
+	ch, err := newTestOllamaClient(server.URL).GenerateStream(context.Background(), &llm.GenerationRequest{
+		Model: "llama3.1",
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "You write React."},
+			{Role: llm.RoleUser, Content: "An app"},
+		},
+		ResponseFormat: &llm.ResponseFormat{Type: llm.ResponseFormatJSONObject},
+	})
+	require.NoError(t, err)
+	responses := collectStream(t, ch)
+
+	assert.Equal(t, true, gotBody["stream"])
+	assert.Equal(t, "json", gotBody["format"])
+	assert.Len(t, gotBody["messages"], 2)
+
+	require.Len(t, responses, 3)
+	assert.Equal(t, "export ", responses[0].Choices[0].Content())
+	assert.Equal(t, "default App", responses[1].Choices[0].Content())
+
+	terminal := responses[2]
+	assert.Nil(t, terminal.Error)
+	assert.Equal(t, responses[0].ID, terminal.ID, "all responses of a stream share an ID")
+	assert.Equal(t, "length", *terminal.Choices[0].FinishReason)
+	assert.Equal(t, &llm.Usage{PromptTokens: 20, CompletionTokens: 4, TotalTokens: 24}, terminal.Usage)
+}
+
+func TestOllamaClient_GenerateStream_ErrorLine(t *testing.T) {
+	server := newNDJSONServer(t, "/api/generate", nil,
+		`{"model":"llama3.1","response":"partial","done":false}`,
+		`{"error":"an unknown error was encountered while running the model"}`,
+	)
+
+	ch, err := newTestOllamaClient(server.URL).GenerateStream(context.Background(), &llm.GenerationRequest{Model: "llama3.1", Prompt: "x"})
+	require.NoError(t, err)
+	responses := collectStream(t, ch)
+
+	require.Len(t, responses, 2)
+	assert.Equal(t, "partial", responses[0].Choices[0].Content())
+	require.NotNil(t, responses[1].Error)
+	assert.Equal(t, "stream_error", responses[1].Error.Code)
+}
+
+func TestOllamaClient_GenerateStream_Truncated(t *testing.T) {
+	server := newNDJSONServer(t, "/api/generate", nil, `{"model":"llama3.1","response":"partial","done":false}`)
+
+	ch, err := newTestOllamaClient(server.URL).GenerateStream(context.Background(), &llm.GenerationRequest{Model: "llama3.1", Prompt: "x"})
+	require.NoError(t, err)
+	responses := collectStream(t, ch)
+
+	require.Len(t, responses, 2)
+	require.NotNil(t, responses[1].Error)
+	assert.Equal(t, "incomplete_stream", responses[1].Error.Code)
+}
+
+func TestOllamaClient_ModelNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"missing\" not found, try pulling it first"}`)
+	}))
+	defer server.Close()
+
+	_, err := newTestOllamaClient(server.URL).Generate(context.Background(), &llm.GenerationRequest{Model: "missing", Prompt: "x"})
+
+	var llmErr *llm.LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "model_not_found", llmErr.Code)
+	assert.Contains(t, llmErr.Details, "try pulling it first")
+}
+
+func TestOllamaClient_GetModels(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/api/tags", r.URL.Path)
+		fmt.Fprint(w, `{"models":[{"name":"qwen2.5-coder:7b","model":"qwen2.5-coder:7b","modified_at":"2024-11-12T10:00:00Z",
+			"size":4683087332,"details":{"family":"qwen2","parameter_size":"7.6B","quantization_level":"Q4_K_M"}}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestOllamaClient(server.URL)
+	models, err := client.GetModels(context.Background())
+	require.NoError(t, err)
+	_, err = client.GetModels(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, calls, "model list is cached")
+	require.Len(t, models, 1)
+	assert.Equal(t, "qwen2.5-coder:7b", models[0].ID)
+	assert.Equal(t, "Ollama", models[0].Provider)
+	assert.Equal(t, "qwen2 7.6B Q4_K_M", models[0].Description)
+}
+
+func TestOllamaClient_Health(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/version", r.URL.Path)
+		fmt.Fprint(w, `{"version":"0.4.2"}`)
+	}))
+	defer server.Close()
+
+	assert.NoError(t, newTestOllamaClient(server.URL).Health(context.Background()))
+	assert.Error(t, newTestOllamaClient("http://127.0.0.1:1").Health(context.Background()))
+}
+
+func TestOllamaService_Generate_IsFree(t *testing.T) {
+	server := newNDJSONServer(t, "/api/chat", nil,
+		`{"model":"qwen2.5-coder","message":{"role":"assistant","content":"ok"},"done":true,"done_reason":"stop","prompt_eval_count":1000,"eval_count":500}`,
+	)
+
+	service := infrallm.NewOllamaService(&infrallm.OllamaConfig{OllamaConfig: llm.OllamaConfig{BaseURL: server.URL}})
+	result, err := service.Generate(context.Background(), ai.GenerationRequest{
+		Prompt:   "x",
+		UserID:   "u",
+		Messages: []ai.ChatMessage{{Role: ai.RoleUser, Content: "x"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "ok", result.Code)
+	assert.Equal(t, 1500, result.UsedTokens)
+	assert.Zero(t, result.EstimatedCost, "local models have no price")
+}
@@ -2,8 +2,15 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
 	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
 	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/llm"
@@ -125,3 +132,193 @@ func TestOpenAIService_GenerationRequest(t *testing.T) {
 		t.Error("Expected error due to cancelled context or API issues")
 	}
 }
+
+func TestOpenAIService_Generate_ReportsUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		fmt.Fprint(w, `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o-2024-08-06","choices":[{"index":0,"message":{"role":"assistant","content":"<div/>"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}`)
+	}))
+	defer server.Close()
+
+	service := llm.NewOpenAIServiceWithConfig(&llm.OpenAIConfig{APIKey: "test", BaseURL: server.URL})
+	result, err := service.Generate(context.Background(), ai.GenerationRequest{Prompt: "A div", UserID: "u"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "chatcmpl-1", result.ID)
+	assert.Equal(t, 1500, result.UsedTokens)
+	assert.Equal(t, 1000, result.PromptTokens)
+	assert.Equal(t, 500, result.CompletionTokens)
+	// gpt-4o: $2.50 per 1M prompt tokens, $10 per 1M completion tokens
+	assert.InDelta(t, 0.0075, result.EstimatedCost, 1e-9)
+}
+
+func TestOpenAIService_Generate_EstimatesMissingUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"local-1","model":"llama-3-8b","choices":[{"index":0,"message":{"role":"assistant","content":"export function Button() { return <button>Click me</button>; }"}}]}`)
+	}))
+	defer server.Close()
+
+	service := llm.NewOpenAIServiceWithConfig(&llm.OpenAIConfig{APIKey: "test", BaseURL: server.URL})
+	result, err := service.Generate(context.Background(), ai.GenerationRequest{Prompt: "A button", UserID: "u"})
+	require.NoError(t, err)
+
+	assert.Greater(t, result.PromptTokens, 0)
+	assert.Greater(t, result.CompletionTokens, 0)
+	assert.Equal(t, result.PromptTokens+result.CompletionTokens, result.UsedTokens)
+	assert.Zero(t, result.EstimatedCost, "self-hosted models have no price")
+}
+
+func TestOpenAIService_GenerateStream_RequestsUsage(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+	}))
+	defer server.Close()
+
+	service := llm.NewOpenAIServiceWithConfig(&llm.OpenAIConfig{APIKey: "test", BaseURL: server.URL})
+	ch := make(chan ai.StreamChunk, 10)
+	_ = service.GenerateStream(context.Background(), ai.GenerationRequest{Prompt: "A div", UserID: "u"}, ch)
+
+	assert.Equal(t, true, gotBody["stream"])
+	assert.Equal(t, map[string]interface{}{"include_usage": true}, gotBody["stream_options"])
+}
+
+func TestOpenAIService_GenerateStream_SingleTerminalChunk(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keepalive\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"func \"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"main()\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"length\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"choices\":[],\"usage\":{\"prompt_tokens\":7,\"completion_tokens\":3,\"total_tokens\":10}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	service := llm.NewOpenAIServiceWithConfig(&llm.OpenAIConfig{APIKey: "test", BaseURL: server.URL})
+	ch := make(chan ai.StreamChunk, 10)
+	require.NoError(t, service.GenerateStream(context.Background(), ai.GenerationRequest{Prompt: "main", UserID: "u"}, ch))
+	close(ch)
+
+	var content string
+	var terminal []ai.StreamChunk
+	for chunk := range ch {
+		content += chunk.Content
+		if chunk.IsComplete {
+			terminal = append(terminal, chunk)
+		}
+	}
+
+	assert.Equal(t, "func main()", content)
+	require.Len(t, terminal, 1)
+	assert.Equal(t, "length", terminal[0].FinishReason)
+	assert.Equal(t, 10, terminal[0].TokenCount)
+	assert.Equal(t, "gpt-4o", terminal[0].Model)
+}
+
+func TestOpenAIService_GenerateStream_ErrorEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"partial\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"error\":{\"message\":\"model overloaded\",\"type\":\"server_error\"}}\n\n")
+	}))
+	defer server.Close()
+
+	service := llm.NewOpenAIServiceWithConfig(&llm.OpenAIConfig{APIKey: "test", BaseURL: server.URL})
+	ch := make(chan ai.StreamChunk, 10)
+	err := service.GenerateStream(context.Background(), ai.GenerationRequest{Prompt: "main", UserID: "u"}, ch)
+	close(ch)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model overloaded")
+	for chunk := range ch {
+		assert.False(t, chunk.IsComplete, "a failed stream must not report completion")
+	}
+}
+
+func TestOpenAIService_Generate_SendsRequestParams(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		fmt.Fprint(w, `{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"{}"}}]}`)
+	}))
+	defer server.Close()
+
+	temperature := 0.2
+	maxTokens := 256
+	seed := 7
+	service := llm.NewOpenAIServiceWithConfig(&llm.OpenAIConfig{APIKey: "test", BaseURL: server.URL})
+	_, err := service.Generate(context.Background(), ai.GenerationRequest{
+		Prompt:         "A form",
+		UserID:         "u",
+		Model:          "gpt-4o",
+		Temperature:    &temperature,
+		MaxTokens:      &maxTokens,
+		Seed:           &seed,
+		Stop:           []string{"</html>"},
+		ResponseFormat: "json_object",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "gpt-4o", gotBody["model"])
+	assert.Equal(t, 0.2, gotBody["temperature"])
+	assert.Equal(t, float64(256), gotBody["max_tokens"])
+	assert.Equal(t, float64(7), gotBody["seed"])
+	assert.Equal(t, []interface{}{"</html>"}, gotBody["stop"])
+	assert.Equal(t, map[string]interface{}{"type": "json_object"}, gotBody["response_format"])
+}
+
+func TestOpenAIService_Generate_RejectsParamsOutsideAllowList(t *testing.T) {
+	service := llm.NewOpenAIServiceWithConfig(&llm.OpenAIConfig{APIKey: "test", BaseURL: "http://127.0.0.1:0"})
+	_, err := service.Generate(context.Background(), ai.GenerationRequest{
+		Prompt:         "A form",
+		UserID:         "u",
+		Model:          "gpt-4",
+		ResponseFormat: "json_object",
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "response_format")
+}
+
+func TestOpenAIService_CheckParamsUsesResolvedModel(t *testing.T) {
+	service := llm.NewOpenAIServiceWithConfig(&llm.OpenAIConfig{APIKey: "test", Model: "gpt-4"})
+
+	req := ai.GenerationRequest{ResponseFormat: "json_object"}
+	assert.Error(t, service.CheckParams(req))
+	assert.False(t, service.AcceptsParam(req, "response_format"))
+
+	req.Model = "gpt-4o-2024-08-06"
+	assert.NoError(t, service.CheckParams(req))
+	assert.True(t, service.AcceptsParam(req, "response_format"))
+}
+
+func TestOpenAIService_Generate_SendsRenderedMessages(t *testing.T) {
+	var gotBody OpenAIRequestBody
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		fmt.Fprint(w, `{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"<div/>"}}]}`)
+	}))
+	defer server.Close()
+
+	service := llm.NewOpenAIServiceWithConfig(&llm.OpenAIConfig{APIKey: "test", BaseURL: server.URL})
+	_, err := service.Generate(context.Background(), ai.GenerationRequest{
+		Prompt: "A card",
+		UserID: "u",
+		Messages: []ai.ChatMessage{
+			{Role: ai.RoleSystem, Content: "You write React."},
+			{Role: ai.RoleUser, Content: "A card"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []llm.Message{
+		{Role: "system", Content: "You write React."},
+		{Role: "user", Content: "A card"},
+	}, gotBody.Messages)
+}
+
+// OpenAIRequestBody captures the messages sent to the API
+type OpenAIRequestBody struct {
+	Messages []llm.Message `json:"messages"`
+}
@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+func intPtr(v int) *int           { return &v }
+
+func TestParamAllowList_Validate(t *testing.T) {
+	allowList := llm.DefaultParamAllowList()
+
+	tests := []struct {
+		name    string
+		model   string
+		params  llm.SamplingParams
+		wantErr bool
+	}{
+		{name: "defaults", model: "gpt-4o", params: llm.SamplingParams{}},
+		{name: "zero temperature", model: "gpt-4o", params: llm.SamplingParams{Temperature: floatPtr(0)}},
+		{name: "temperature out of range", model: "gpt-4o", params: llm.SamplingParams{Temperature: floatPtr(2.5)}, wantErr: true},
+		{name: "top_p out of range", model: "gpt-4o", params: llm.SamplingParams{TopP: floatPtr(0)}, wantErr: true},
+		{name: "too many stop sequences", model: "gpt-4o", params: llm.SamplingParams{Stop: []string{"a", "b", "c", "d", "e"}}, wantErr: true},
+		{name: "penalty out of range", model: "gpt-4o", params: llm.SamplingParams{FrequencyPenalty: floatPtr(-3)}, wantErr: true},
+		{name: "unknown response format", model: "gpt-4o", params: llm.SamplingParams{ResponseFormat: &llm.ResponseFormat{Type: "xml"}}, wantErr: true},
+		{name: "json mode", model: "gpt-4o-2024-08-06", params: llm.SamplingParams{ResponseFormat: &llm.ResponseFormat{Type: "json_object"}}},
+		{name: "json mode not allowed", model: "gpt-4", params: llm.SamplingParams{ResponseFormat: &llm.ResponseFormat{Type: "json_object"}}, wantErr: true},
+		{name: "max_tokens above model cap", model: "gpt-3.5-turbo", params: llm.SamplingParams{MaxTokens: 5000}, wantErr: true},
+		{name: "unlisted model accepts everything", model: "llama-3-8b", params: llm.SamplingParams{MaxTokens: 100000, Seed: intPtr(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := allowList.Validate(tt.model, tt.params)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.True(t, llm.IsInvalidRequest(err))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVLLMClient_Generate_SendsSamplingParams(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		fmt.Fprint(w, `{"id":"cmpl-1","model":"m","choices":[{"index":0,"text":"ok"}]}`)
+	}))
+	defer server.Close()
+
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL})
+	_, err := client.Generate(context.Background(), &llm.GenerationRequest{
+		Model:          "m",
+		Prompt:         "hi",
+		MaxTokens:      64,
+		Temperature:    floatPtr(0),
+		TopP:           floatPtr(0.9),
+		Stop:           []string{"\n\n"},
+		Seed:           intPtr(42),
+		ResponseFormat: &llm.ResponseFormat{Type: llm.ResponseFormatJSONObject},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(64), gotBody["max_tokens"])
+	assert.Equal(t, float64(0), gotBody["temperature"], "an explicit zero temperature must be sent")
+	assert.Equal(t, 0.9, gotBody["top_p"])
+	assert.Equal(t, []interface{}{"\n\n"}, gotBody["stop"])
+	assert.Equal(t, float64(42), gotBody["seed"])
+	assert.Equal(t, map[string]interface{}{"type": "json_object"}, gotBody["response_format"])
+	assert.NotContains(t, gotBody, "presence_penalty")
+}
+
+func TestVLLMClient_Generate_RejectsDisallowedParams(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	client := llm.NewVLLMClient(&llm.VLLMConfig{
+		BaseURL: server.URL,
+		Params:  llm.ParamAllowList{"m": {Allowed: []string{llm.ParamMaxTokens}}},
+	})
+	_, err := client.Generate(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi", Seed: intPtr(1)})
+
+	require.Error(t, err)
+	assert.True(t, llm.IsInvalidRequest(err))
+	assert.False(t, called, "invalid requests must not reach the provider")
+}
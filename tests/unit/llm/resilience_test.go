@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+// fastPolicy keeps retry waits short in tests
+var fastPolicy = llm.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second}
+
+// postFactory builds a POST request carrying body
+func postFactory(url, body string) llm.RequestFactory {
+	return func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(body))
+	}
+}
+
+func TestResilientClient_RetriesServerErrorsWithFreshBody(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"prompt":"hi"}`, string(body), "body must be re-sent on every attempt")
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			http.Error(w, "overloaded", http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	client := llm.NewResilientClient(llm.NewDefaultHTTPClient(time.Second), fastPolicy, nil)
+	resp, err := client.Do(context.Background(), postFactory(server.URL, `{"prompt":"hi"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestResilientClient_DoesNotRetryClientErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := llm.NewResilientClient(llm.NewDefaultHTTPClient(time.Second), fastPolicy, nil)
+	resp, err := client.Do(context.Background(), postFactory(server.URL, "{}"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestResilientClient_HonorsRetryAfter(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	client := llm.NewResilientClient(llm.NewDefaultHTTPClient(5*time.Second), fastPolicy, nil)
+	start := time.Now()
+	resp, err := client.Do(context.Background(), postFactory(server.URL, "{}"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestResilientClient_GivesUpWhenRetryAfterTooLong(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := llm.NewResilientClient(llm.NewDefaultHTTPClient(time.Second), fastPolicy, nil)
+	resp, err := client.Do(context.Background(), postFactory(server.URL, "{}"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestCircuitBreaker_OpensAndRecovers(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	breaker := llm.NewCircuitBreaker("test", llm.CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: 50 * time.Millisecond})
+	policy := llm.RetryPolicy{MaxRetries: 0, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	client := llm.NewResilientClient(llm.NewDefaultHTTPClient(time.Second), policy, breaker)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Do(context.Background(), postFactory(server.URL, "{}"))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, llm.BreakerOpen, breaker.State())
+	assert.Error(t, client.Health())
+
+	// Requests are rejected without reaching the server while open
+	_, err := client.Do(context.Background(), postFactory(server.URL, "{}"))
+	var llmErr *llm.LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "circuit_open", llmErr.Code)
+	assert.True(t, llm.IsRetryableError(err), "router should fall back when a breaker is open")
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, llm.BreakerHalfOpen, breaker.State())
+
+	healthy.Store(true)
+	resp, err := client.Do(context.Background(), postFactory(server.URL, "{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, llm.BreakerClosed, breaker.State())
+	assert.NoError(t, client.Health())
+}
+
+func TestCircuitBreaker_HalfOpenAllowsSingleProbe(t *testing.T) {
+	breaker := llm.NewCircuitBreaker("test", llm.CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond})
+	breaker.RecordFailure()
+	require.Error(t, breaker.Allow())
+
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, breaker.Allow())
+	assert.Error(t, breaker.Allow(), "only one probe may be in flight")
+
+	breaker.RecordFailure()
+	assert.Equal(t, llm.BreakerOpen, breaker.State())
+}
+
+func TestRetryPolicy_BackoffIsBounded(t *testing.T) {
+	policy := llm.RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 0; attempt < 10; attempt++ {
+		delay := policy.Backoff(attempt)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, time.Second)
+		if attempt == 0 {
+			assert.LessOrEqual(t, delay, 100*time.Millisecond)
+		}
+	}
+}
+
+func TestVLLMClient_Generate_RetriesTransientFailures(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			http.Error(w, "warming up", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"id":"cmpl-1","model":"m","choices":[{"index":0,"text":"done"}]}`)
+	}))
+	defer server.Close()
+
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL, RetryBaseDelay: time.Millisecond})
+	resp, err := client.Generate(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp.Choices[0].Text)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+// fakeLLMClient is a scripted LLMClient for router tests
+type fakeLLMClient struct {
+	err       error
+	text      string
+	models    []llm.Model
+	healthErr error
+	calls     int
+}
+
+func (f *fakeLLMClient) Generate(ctx context.Context, req *llm.GenerationRequest) (*llm.GenerationResponse, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &llm.GenerationResponse{Model: req.Model, Choices: []llm.Choice{{Text: f.text}}}, nil
+}
+
+func (f *fakeLLMClient) GenerateStream(ctx context.Context, req *llm.GenerationRequest) (<-chan *llm.GenerationResponse, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	ch := make(chan *llm.GenerationResponse, 2)
+	ch <- &llm.GenerationResponse{Model: req.Model, Choices: []llm.Choice{{Text: f.text}}}
+	close(ch)
+	return ch, nil
+}
+
+func (f *fakeLLMClient) GetModels(ctx context.Context) ([]llm.Model, error) {
+	return f.models, f.err
+}
+
+func (f *fakeLLMClient) Health(ctx context.Context) error {
+	return f.healthErr
+}
+
+func (f *fakeLLMClient) Close() error {
+	return nil
+}
+
+func TestRouter_Generate_FallsBackOnServerError(t *testing.T) {
+	primary := &fakeLLMClient{err: &llm.LLMError{Code: "upstream_error", Message: "boom", StatusCode: http.StatusBadGateway}}
+	secondary := &fakeLLMClient{text: "ok"}
+
+	router := llm.NewRouter([]llm.Provider{
+		{Name: "vllm", Client: primary},
+		{Name: "openai", Client: secondary},
+	})
+
+	resp, err := router.Generate(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Choices[0].Text)
+	assert.Equal(t, "openai", resp.Metadata[llm.MetadataProvider])
+	assert.Equal(t, []string{"vllm"}, resp.Metadata[llm.MetadataFailedProviders])
+	assert.Equal(t, 1, primary.calls)
+}
+
+func TestRouter_Generate_FallsBackOnConnectionError(t *testing.T) {
+	// Dialing a closed server yields a connection refused error
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	_, dialErr := http.Get(server.URL)
+	require.Error(t, dialErr)
+
+	router := llm.NewRouter([]llm.Provider{
+		{Name: "vllm", Client: &fakeLLMClient{err: fmt.Errorf("request failed: %w", dialErr)}},
+		{Name: "backup", Client: &fakeLLMClient{text: "ok"}},
+	})
+
+	resp, err := router.Generate(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "backup", resp.Metadata[llm.MetadataProvider])
+}
+
+func TestRouter_Generate_DoesNotFallBackOnClientError(t *testing.T) {
+	clientErr := &llm.LLMError{Code: "upstream_error", Message: "bad request", StatusCode: http.StatusBadRequest}
+	secondary := &fakeLLMClient{text: "ok"}
+
+	router := llm.NewRouter([]llm.Provider{
+		{Name: "vllm", Client: &fakeLLMClient{err: clientErr}},
+		{Name: "openai", Client: secondary},
+	})
+
+	_, err := router.Generate(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+	assert.ErrorIs(t, err, clientErr)
+	assert.Equal(t, 0, secondary.calls)
+}
+
+func TestRouter_RoutesByModel(t *testing.T) {
+	vllm := &fakeLLMClient{text: "from vllm"}
+	openai := &fakeLLMClient{text: "from openai"}
+
+	router := llm.NewRouter([]llm.Provider{
+		{Name: "vllm", Client: vllm, Models: []string{"llama-3-70b"}},
+		{Name: "openai", Client: openai, Models: []string{"gpt-4o"}},
+	})
+
+	resp, err := router.Generate(context.Background(), &llm.GenerationRequest{Model: "gpt-4o", Prompt: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "from openai", resp.Choices[0].Text)
+	assert.Equal(t, 0, vllm.calls)
+
+	_, err = router.Generate(context.Background(), &llm.GenerationRequest{Model: "unknown", Prompt: "hi"})
+	var llmErr *llm.LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "model_not_found", llmErr.Code)
+}
+
+func TestRouter_GenerateStream_AnnotatesProvider(t *testing.T) {
+	router := llm.NewRouter([]llm.Provider{
+		{Name: "vllm", Client: &fakeLLMClient{err: context.DeadlineExceeded}},
+		{Name: "openai", Client: &fakeLLMClient{text: "streamed"}},
+	})
+
+	ch, err := router.GenerateStream(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+	require.NoError(t, err)
+
+	responses := collectStream(t, ch)
+	require.Len(t, responses, 1)
+	assert.Equal(t, "openai", responses[0].Metadata[llm.MetadataProvider])
+}
+
+func TestRouter_AllProvidersFail(t *testing.T) {
+	router := llm.NewRouter([]llm.Provider{
+		{Name: "a", Client: &fakeLLMClient{err: &llm.LLMError{StatusCode: 500}}},
+		{Name: "b", Client: &fakeLLMClient{err: &llm.LLMError{StatusCode: 503}}},
+	})
+
+	_, err := router.Generate(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all providers failed")
+}
+
+func TestRouter_HealthAndModels(t *testing.T) {
+	router := llm.NewRouter([]llm.Provider{
+		{Name: "a", Client: &fakeLLMClient{healthErr: errors.New("down"), models: []llm.Model{{ID: "shared"}}}},
+		{Name: "b", Client: &fakeLLMClient{models: []llm.Model{{ID: "shared"}, {ID: "extra"}}}},
+	})
+
+	assert.NoError(t, router.Health(context.Background()))
+
+	models, err := router.GetModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+	assert.Equal(t, "a", models[0].Provider)
+	assert.Equal(t, "b", models[1].Provider)
+}
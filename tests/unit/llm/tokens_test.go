@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		min, max int
+	}{
+		{name: "empty", text: "", min: 0, max: 0},
+		{name: "single word", text: "hello", min: 1, max: 2},
+		{name: "sentence", text: "Create a blue button with rounded corners", min: 7, max: 12},
+		{name: "code", text: "export const Button = () => <button className=\"btn\">Click</button>;", min: 15, max: 35},
+		{name: "digits", text: "1234567", min: 3, max: 3},
+		{name: "cjk", text: "按钮", min: 2, max: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := llm.EstimateTokens(tt.text)
+			assert.GreaterOrEqual(t, got, tt.min)
+			assert.LessOrEqual(t, got, tt.max)
+		})
+	}
+}
+
+func TestEstimateMessagesTokens(t *testing.T) {
+	assert.Zero(t, llm.EstimateMessagesTokens(nil))
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You write React components."},
+		{Role: llm.RoleUser, Content: "A button"},
+	}
+	content := llm.EstimateTokens(messages[0].Content) + llm.EstimateTokens(messages[1].Content)
+	assert.Greater(t, llm.EstimateMessagesTokens(messages), content)
+}
+
+func TestPriceTable_Cost(t *testing.T) {
+	prices := llm.DefaultPriceTable()
+
+	assert.InDelta(t, 0.002, prices.Cost("gpt-3.5-turbo", 1000, 1000), 1e-9)
+	// Dated snapshots use the longest matching prefix, not "gpt-4"
+	assert.InDelta(t, 0.00075, prices.Cost("gpt-4o-mini-2024-07-18", 1000, 1000), 1e-9)
+	assert.Zero(t, prices.Cost("llama-3-70b", 1000, 1000))
+}
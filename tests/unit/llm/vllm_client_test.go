@@ -0,0 +1,351 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/llm"
+)
+
+// newSSEServer returns a server that writes each part as a separate flushed write
+func newSSEServer(t *testing.T, parts ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, part := range parts {
+			fmt.Fprint(w, part)
+			flusher.Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// collectStream drains a stream channel with a timeout
+func collectStream(t *testing.T, ch <-chan *llm.GenerationResponse) []*llm.GenerationResponse {
+	t.Helper()
+	var responses []*llm.GenerationResponse
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case resp, ok := <-ch:
+			if !ok {
+				return responses
+			}
+			responses = append(responses, resp)
+		case <-timeout:
+			t.Fatal("timed out waiting for stream to close")
+		}
+	}
+}
+
+func TestVLLMClient_GenerateStream_ParsesSSE(t *testing.T) {
+	server := newSSEServer(t,
+		": keep-alive\n\n",
+		`data: {"id":"cmpl-1","object":"text_completion","model":"m","choices":[{"index":0,"text":"Hel"}]}`+"\n\n",
+		// Partial JSON split across writes
+		`data: {"id":"cmpl-1","object":"text_completion","model":"m",`,
+		`"choices":[{"index":0,"text":"lo"}]}`+"\n\n",
+		// Multi-line event
+		"data: {\"id\":\"cmpl-1\",\"object\":\"text_completion\",\"model\":\"m\",\n",
+		"data: \"choices\":[{\"index\":0,\"text\":\"!\",\"finish_reason\":\"stop\"}]}\n\n",
+		`data: {"id":"cmpl-1","object":"text_completion","model":"m","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":3,"total_tokens":6}}`+"\n\n",
+		"data: [DONE]\n\n",
+		`data: {"id":"after-done","choices":[{"index":0,"text":"ignored"}]}`+"\n\n",
+	)
+
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL})
+	ch, err := client.GenerateStream(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+	require.NoError(t, err)
+
+	responses := collectStream(t, ch)
+	require.Len(t, responses, 4)
+
+	text := ""
+	for _, resp := range responses {
+		assert.Nil(t, resp.Error)
+		for _, choice := range resp.Choices {
+			text += choice.Text
+		}
+	}
+	assert.Equal(t, "Hello!", text)
+
+	require.NotNil(t, responses[2].Choices[0].FinishReason)
+	assert.Equal(t, "stop", *responses[2].Choices[0].FinishReason)
+
+	require.NotNil(t, responses[3].Usage)
+	assert.Equal(t, 6, responses[3].Usage.TotalTokens)
+}
+
+func TestVLLMClient_GenerateStream_MidStreamError(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    string
+		wantCode string
+		wantMsg  string
+	}{
+		{
+			name:     "vLLM error object",
+			event:    `data: {"object":"error","message":"out of memory","type":"InternalServerError"}` + "\n\n",
+			wantCode: "InternalServerError",
+			wantMsg:  "out of memory",
+		},
+		{
+			name:     "nested error object",
+			event:    `data: {"error":{"message":"context length exceeded","type":"invalid_request_error"}}` + "\n\n",
+			wantCode: "invalid_request_error",
+			wantMsg:  "context length exceeded",
+		},
+		{
+			name:     "error event with plain text",
+			event:    "event: error\ndata: upstream went away\n\n",
+			wantCode: "stream_error",
+			wantMsg:  "upstream went away",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSSEServer(t,
+				`data: {"id":"cmpl-1","model":"m","choices":[{"index":0,"text":"partial"}]}`+"\n\n",
+				tt.event,
+				`data: {"id":"cmpl-1","model":"m","choices":[{"index":0,"text":"ignored"}]}`+"\n\n",
+			)
+
+			client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL})
+			ch, err := client.GenerateStream(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+			require.NoError(t, err)
+
+			responses := collectStream(t, ch)
+			require.Len(t, responses, 2)
+			assert.Nil(t, responses[0].Error)
+			require.NotNil(t, responses[1].Error)
+			assert.Equal(t, tt.wantCode, responses[1].Error.Code)
+			assert.Equal(t, tt.wantMsg, responses[1].Error.Message)
+		})
+	}
+}
+
+func TestVLLMClient_GenerateStream_UnterminatedFinalEvent(t *testing.T) {
+	server := newSSEServer(t,
+		`data: {"id":"cmpl-1","model":"m","choices":[{"index":0,"text":"tail","finish_reason":"length"}]}`,
+	)
+
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL})
+	ch, err := client.GenerateStream(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+	require.NoError(t, err)
+
+	responses := collectStream(t, ch)
+	require.Len(t, responses, 1)
+	assert.Equal(t, "tail", responses[0].Choices[0].Text)
+	require.NotNil(t, responses[0].Choices[0].FinishReason)
+	assert.Equal(t, "length", *responses[0].Choices[0].FinishReason)
+}
+
+func TestVLLMClient_GenerateStream_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL})
+	_, err := client.GenerateStream(context.Background(), &llm.GenerationRequest{Model: "m", Prompt: "hi"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model not found")
+}
+
+func TestVLLMClient_Generate_ChatCompletions(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"chat-1","object":"chat.completion","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"<button/>"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL})
+	resp, err := client.Generate(context.Background(), &llm.GenerationRequest{
+		Model: "m",
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "You write React components."},
+			{Role: llm.RoleUser, Content: "A button"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/v1/chat/completions", gotPath)
+	assert.NotContains(t, gotBody, "prompt")
+	messages, ok := gotBody["messages"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "system", messages[0].(map[string]interface{})["role"])
+
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "<button/>", resp.Choices[0].Content())
+}
+
+func TestVLLMClient_GenerateStream_ChatCompletions(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"chat-1","model":"m","choices":[{"index":0,"delta":{"role":"assistant"}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"id":"chat-1","model":"m","choices":[{"index":0,"delta":{"content":"<div>"}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"id":"chat-1","model":"m","choices":[{"index":0,"delta":{"content":"</div>"},"finish_reason":"stop"}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL})
+	ch, err := client.GenerateStream(context.Background(), &llm.GenerationRequest{
+		Model:    "m",
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "A div"}},
+	})
+	require.NoError(t, err)
+
+	text := ""
+	for _, resp := range collectStream(t, ch) {
+		for _, choice := range resp.Choices {
+			text += choice.Content()
+		}
+	}
+	assert.Equal(t, "/v1/chat/completions", gotPath)
+	assert.Equal(t, "<div></div>", text)
+}
+
+func TestVLLMClient_Generate_InvalidMessageRole(t *testing.T) {
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: "http://localhost:0"})
+	_, err := client.Generate(context.Background(), &llm.GenerationRequest{
+		Model:    "m",
+		Messages: []llm.Message{{Role: "tool", Content: "x"}},
+	})
+
+	var llmErr *llm.LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "invalid_request", llmErr.Code)
+}
+
+func TestVLLMClient_GetModels(t *testing.T) {
+	var calls int32
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		atomic.AddInt32(&calls, 1)
+		if fail.Load() {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"llama-3-8b","object":"model","created":1700000000,"owned_by":"vllm","root":"meta-llama/Meta-Llama-3-8B","max_model_len":8192}]}`)
+	}))
+	defer server.Close()
+
+	client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL, ModelsCacheTTL: 50 * time.Millisecond})
+
+	models, err := client.GetModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.Equal(t, "llama-3-8b", models[0].ID)
+	assert.Equal(t, "VLLM", models[0].Provider)
+	assert.Equal(t, 8192, models[0].MaxTokens)
+	assert.Equal(t, int64(1700000000), models[0].CreatedAt.Unix())
+
+	// Served from cache within the TTL
+	_, err = client.GetModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// A failed refresh falls back to the stale list
+	time.Sleep(60 * time.Millisecond)
+	fail.Store(true)
+	models, err = client.GetModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestVLLMClient_Health(t *testing.T) {
+	t.Run("healthy server", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/health", r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL})
+		assert.NoError(t, client.Health(context.Background()))
+	})
+
+	t.Run("unhealthy server", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "engine dead", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL})
+		err := client.Health(context.Background())
+		var llmErr *llm.LLMError
+		require.ErrorAs(t, err, &llmErr)
+		assert.Equal(t, http.StatusServiceUnavailable, llmErr.StatusCode)
+	})
+
+	t.Run("slow server times out", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		defer server.Close()
+		defer close(release)
+
+		client := llm.NewVLLMClient(&llm.VLLMConfig{BaseURL: server.URL, HealthTimeout: 50 * time.Millisecond})
+		start := time.Now()
+		assert.Error(t, client.Health(context.Background()))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestDefaultHTTPClient_TimeoutBoundsHeadersNotStreamedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-headers" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(40 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	client := llm.NewDefaultHTTPClient(100 * time.Millisecond)
+
+	req, err := http.NewRequest("GET", server.URL+"/stream", nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err, "a stream longer than the timeout is read to the end")
+	assert.Contains(t, string(body), "data: 3")
+
+	req, err = http.NewRequest("GET", server.URL+"/slow-headers", nil)
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	assert.Error(t, err, "waiting for headers is still bounded")
+}
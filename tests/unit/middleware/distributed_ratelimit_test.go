@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/ratelimit"
+	"github.com/EliasRanz/ai-code-gen/internal/middleware"
+)
+
+// stubPlans is a QuotaPlanRepository returning each user's tier
+type stubPlans map[common.UserID]ai.PlanTier
+
+func (s stubPlans) GetQuotaAccount(ctx context.Context, userID common.UserID) (ai.QuotaAccount, error) {
+	return ai.QuotaAccount{UserID: userID, Plan: ai.QuotaPlan{Tier: s[userID]}}, nil
+}
+
+// countingPlans is a QuotaPlanRepository putting every user on the pro plan
+// that counts its lookups
+type countingPlans struct {
+	lookups int
+}
+
+func (p *countingPlans) GetQuotaAccount(ctx context.Context, userID common.UserID) (ai.QuotaAccount, error) {
+	p.lookups++
+	return ai.QuotaAccount{UserID: userID, Plan: ai.QuotaPlan{Tier: ai.PlanPro}}, nil
+}
+
+// newRateLimitedRouter serves /generate and /other, authenticating requests
+// by their X-User header
+func newRateLimitedRouter(config middleware.RateLimitConfig, plans ai.QuotaPlanRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	limiter := ratelimit.NewLimiterWithStore(nil, ratelimit.Policy{})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("user_id", common.UserID(user))
+		}
+	})
+	router.Use(middleware.DistributedRateLimit(limiter, config, plans))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	router.POST("/generate", ok)
+	router.GET("/other", ok)
+	return router
+}
+
+func doRequest(router *gin.Engine, method, path, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDistributedRateLimit_Headers(t *testing.T) {
+	router := newRateLimitedRouter(middleware.RateLimitConfig{
+		RateLimitPolicies: middleware.RateLimitPolicies{Default: ratelimit.Policy{Limit: 2, Period: time.Minute}},
+	}, nil)
+
+	w := doRequest(router, "GET", "/other", "user-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	doRequest(router, "GET", "/other", "user-1")
+	w = doRequest(router, "GET", "/other", "user-1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "rate limit exceeded")
+}
+
+func TestDistributedRateLimit_KeysByUserThenIP(t *testing.T) {
+	router := newRateLimitedRouter(middleware.RateLimitConfig{
+		RateLimitPolicies: middleware.RateLimitPolicies{Default: ratelimit.Policy{Limit: 1, Period: time.Minute}},
+	}, nil)
+
+	assert.Equal(t, http.StatusOK, doRequest(router, "GET", "/other", "user-1").Code)
+	assert.Equal(t, http.StatusOK, doRequest(router, "GET", "/other", "user-2").Code, "users on one IP are limited separately")
+	assert.Equal(t, http.StatusOK, doRequest(router, "GET", "/other", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(router, "GET", "/other", "").Code, "anonymous requests are limited by IP")
+}
+
+func TestDistributedRateLimit_RouteAndPlanPolicies(t *testing.T) {
+	router := newRateLimitedRouter(middleware.RateLimitConfig{
+		RateLimitPolicies: middleware.RateLimitPolicies{Default: ratelimit.Policy{Limit: 100, Period: time.Minute}},
+		Routes: map[string]middleware.RateLimitPolicies{
+			"POST /generate": {
+				Default: ratelimit.Policy{Limit: 1, Period: time.Minute},
+				Plans:   map[ai.PlanTier]ratelimit.Policy{ai.PlanPro: {Limit: 3, Period: time.Minute}},
+			},
+		},
+	}, stubPlans{"pro-user": ai.PlanPro, "free-user": ai.PlanFree})
+
+	assert.Equal(t, http.StatusOK, doRequest(router, "POST", "/generate", "free-user").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(router, "POST", "/generate", "free-user").Code)
+	assert.Equal(t, http.StatusOK, doRequest(router, "GET", "/other", "free-user").Code, "other routes have their own limit")
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(router, "POST", "/generate", "pro-user").Code)
+	}
+	w := doRequest(router, "POST", "/generate", "pro-user")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+}
+
+func TestDistributedRateLimit_CachesPlans(t *testing.T) {
+	plans := &countingPlans{}
+	router := newRateLimitedRouter(middleware.RateLimitConfig{
+		RateLimitPolicies: middleware.RateLimitPolicies{
+			Default: ratelimit.Policy{Limit: 1, Period: time.Minute},
+			Plans:   map[ai.PlanTier]ratelimit.Policy{ai.PlanPro: {Limit: 10, Period: time.Minute}},
+		},
+	}, plans)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(router, "GET", "/other", "pro-user").Code)
+	}
+	assert.Equal(t, 1, plans.lookups, "the plan is looked up once per cache TTL")
+
+	doRequest(router, "GET", "/other", "other-user")
+	assert.Equal(t, 2, plans.lookups)
+}
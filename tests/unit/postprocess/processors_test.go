@@ -0,0 +1,84 @@
+package postprocess
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/postprocess"
+)
+
+func process(t *testing.T, p ai.CodeProcessor, code, language string) string {
+	t.Helper()
+	out, err := p.Process(context.Background(), code, ai.CodeTarget{Language: language, RawOutput: true})
+	require.NoError(t, err)
+	return out
+}
+
+func processFile(t *testing.T, p ai.CodeProcessor, code, language string) string {
+	t.Helper()
+	out, err := p.Process(context.Background(), code, ai.CodeTarget{Language: language})
+	require.NoError(t, err)
+	return out
+}
+
+func TestFenceStripper(t *testing.T) {
+	p := postprocess.FenceStripper{}
+
+	assert.Equal(t, "<button>Go</button>", process(t, p, "Here you go:\n\n```html\n<button>Go</button>\n```\n\nEnjoy!", ""))
+	assert.Equal(t, "const a = 1;\n\n.a {}", process(t, p, "```js\nconst a = 1;\n```\nand\n```css\n.a {}\n```", ""))
+	assert.Equal(t, "<div/>", process(t, p, "<div/>", ""), "code without fences is unchanged")
+
+	readme := "# Card\n\n```tsx\n<Card />\n```"
+	assert.Equal(t, readme, process(t, p, readme, "markdown"), "markdown keeps its fences")
+	assert.Equal(t, readme, processFile(t, p, readme, "tsx"), "artifact files are left alone")
+}
+
+func TestProseStripper(t *testing.T) {
+	p := postprocess.ProseStripper{}
+
+	code := "export const Card = () => (\n  <div />\n);\n\n\nexport default Card;"
+	input := "Sure! Here is the card component you asked for:\n\n" + code +
+		"\n\nThis component renders an empty card.\nYou can style it with Tailwind classes."
+	assert.Equal(t, code, process(t, p, input, ""), "blank lines inside the code are kept")
+
+	assert.Equal(t, code, process(t, p, code, ""))
+	assert.Equal(t, "Just a sentence with words.", process(t, p, "Just a sentence with words.", ""), "the last paragraph is never dropped")
+	python := "\"\"\"Card helpers.\"\"\"\n\nThe Card class below is rendered server side.\n\nclass Card:\n    pass"
+	assert.Equal(t, python, process(t, p, python, ""), "prose between code paragraphs is kept")
+
+	shell := "# Install the dependencies first\nnpm install clsx\n\n# Then start the dev server\nnpm run dev"
+	assert.Equal(t, shell, process(t, p, shell, "bash"), "comments are not prose")
+	heading := "## Usage Of The Card\n\nexport const Card = 1;"
+	assert.Equal(t, heading, process(t, p, heading, ""), "headings are not prose")
+
+	notes := "Sure! Here are the release notes for you.\n\n- Added a card"
+	assert.Equal(t, notes, process(t, p, notes, "markdown"), "markdown keeps its prose")
+	assert.Equal(t, input, processFile(t, p, input, "tsx"), "artifact files are left alone")
+}
+
+func TestLineEndingNormalizer(t *testing.T) {
+	p := postprocess.LineEndingNormalizer{}
+
+	assert.Equal(t, "\na  \nb\nc\t\n\nd\n\n", process(t, p, "\r\na  \r\nb\rc\t\r\n\r\nd\n\n", ""), "only line endings change")
+}
+
+func TestGoFormatter(t *testing.T) {
+	p := postprocess.GoFormatter{}
+
+	assert.Equal(t, "package main\n\nfunc main() { println(1) }", process(t, p, "package main\nfunc main(){println(1)}", "go"))
+	assert.Equal(t, "func(){}", process(t, p, "func(){}", "typescript"), "only Go is formatted")
+
+	_, err := p.Process(context.Background(), "package main\nfunc {", ai.CodeTarget{Language: "go"})
+	assert.Error(t, err)
+}
+
+func TestJSONFormatter(t *testing.T) {
+	p := postprocess.JSONFormatter{}
+
+	assert.Equal(t, "{\n  \"a\": [\n    1\n  ]\n}", process(t, p, `{"a":[1]}`, "json"))
+	assert.Equal(t, `{"a":1}`, process(t, p, `{"a":1}`, "javascript"))
+}
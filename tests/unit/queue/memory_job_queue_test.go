@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/domain/common"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/queue"
+)
+
+func newJob(id, model string, priority ai.JobPriority, age time.Duration) ai.GenerationJob {
+	job := ai.GenerationJob{ID: id, UserID: "user-1", Model: model, Priority: priority, Status: ai.GenerationPending}
+	job.CreatedAt = time.Now().Add(-age)
+	return job
+}
+
+func TestMemoryJobQueue_DequeueOrder(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemoryJobQueue(0)
+
+	require.NoError(t, q.Enqueue(ctx, newJob("old", "a", ai.JobPriorityNormal, 3*time.Second)))
+	require.NoError(t, q.Enqueue(ctx, newJob("older-other-model", "b", ai.JobPriorityNormal, 4*time.Second)))
+	require.NoError(t, q.Enqueue(ctx, newJob("new", "a", ai.JobPriorityNormal, time.Second)))
+	require.NoError(t, q.Enqueue(ctx, newJob("admin", "a", ai.JobPriorityHigh, 0)))
+
+	var order []string
+	for {
+		job, ok, err := q.Dequeue(ctx, nil)
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+		order = append(order, job.ID)
+	}
+	assert.Equal(t, []string{"admin", "older-other-model", "old", "new"}, order)
+}
+
+func TestMemoryJobQueue_DequeueSkipsExcludedModels(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemoryJobQueue(0)
+	require.NoError(t, q.Enqueue(ctx, newJob("busy-model", "a", ai.JobPriorityHigh, time.Second)))
+	require.NoError(t, q.Enqueue(ctx, newJob("free-model", "b", ai.JobPriorityNormal, 0)))
+
+	job, ok, err := q.Dequeue(ctx, []string{"a"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "free-model", job.ID)
+
+	_, ok, err = q.Dequeue(ctx, []string{"a"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMemoryJobQueue_ExpiresFinishedJobs(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemoryJobQueue(time.Millisecond)
+
+	job := newJob("done", "a", ai.JobPriorityNormal, 0)
+	require.NoError(t, q.Enqueue(ctx, job))
+	job.Status = ai.GenerationCompleted
+	require.NoError(t, q.UpdateJob(ctx, job))
+
+	stored, err := q.GetJob(ctx, "done")
+	require.NoError(t, err)
+	assert.Equal(t, ai.GenerationCompleted, stored.Status)
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, q.Enqueue(ctx, newJob("next", "a", ai.JobPriorityNormal, 0)))
+	_, err = q.GetJob(ctx, "done")
+	assert.True(t, common.IsNotFoundError(err))
+}
+
+func TestMemoryJobQueue_RequeueKeepsPriorityOrder(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemoryJobQueue(0)
+	require.NoError(t, q.Enqueue(ctx, newJob("old", "a", ai.JobPriorityNormal, 2*time.Second)))
+	require.NoError(t, q.Enqueue(ctx, newJob("new", "a", ai.JobPriorityNormal, 0)))
+
+	job, ok, err := q.Dequeue(ctx, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "old", job.ID)
+	require.NoError(t, q.RenewLease(ctx, job.ID))
+
+	job.Status = ai.GenerationPending
+	require.NoError(t, q.Requeue(ctx, job))
+
+	job, ok, err = q.Dequeue(ctx, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "old", job.ID, "a requeued job keeps its place ahead of newer jobs")
+
+	assert.True(t, common.IsNotFoundError(q.Requeue(ctx, newJob("unknown", "a", ai.JobPriorityNormal, 0))))
+}
+
+func TestMemoryJobQueue_FinishedJobsLeaveThePendingQueue(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemoryJobQueue(0)
+	job := newJob("requeued", "a", ai.JobPriorityNormal, 0)
+	require.NoError(t, q.Enqueue(ctx, job))
+
+	// The job finishes while queued, e.g. after its lease was requeued
+	job.Status = ai.GenerationCompleted
+	require.NoError(t, q.UpdateJob(ctx, job))
+
+	_, ok, err := q.Dequeue(ctx, nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	stored, err := q.GetJob(ctx, "requeued")
+	require.NoError(t, err)
+	assert.Equal(t, ai.GenerationCompleted, stored.Status)
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/ratelimit"
+)
+
+var _ ai.RateLimiter = (*ratelimit.Limiter)(nil)
+
+// countingStore is a Store allowing every request that counts its calls
+type countingStore struct {
+	takes int
+}
+
+func (s *countingStore) Take(ctx context.Context, key string, policy ratelimit.Policy) (ratelimit.Result, error) {
+	s.takes++
+	if err := ctx.Err(); err != nil {
+		return ratelimit.Result{}, err
+	}
+	return ratelimit.Result{Allowed: true, Limit: policy.Limit, Remaining: policy.Limit}, nil
+}
+
+func (s *countingStore) Reset(ctx context.Context, key string) error {
+	return nil
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	store := ratelimit.NewMemoryStore(0)
+	policy := ratelimit.Policy{Limit: 3, Period: time.Minute}
+	ctx := context.Background()
+
+	for want := 2; want >= 0; want-- {
+		result, err := store.Take(ctx, "client", policy)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, want, result.Remaining)
+	}
+
+	result, err := store.Take(ctx, "client", policy)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.InDelta(t, 20*time.Second, result.RetryAfter, float64(time.Second), "one request frees up every 20 seconds")
+	assert.InDelta(t, time.Minute, result.ResetAfter, float64(time.Second))
+
+	result, err = store.Take(ctx, "other", policy)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed, "keys are limited separately")
+}
+
+func TestMemoryStore_SpreadsRequestsOverPeriod(t *testing.T) {
+	store := ratelimit.NewMemoryStore(0)
+	policy := ratelimit.Policy{Limit: 20, Period: time.Second, Burst: 1}
+
+	result, _ := store.Take(context.Background(), "client", policy)
+	assert.True(t, result.Allowed)
+	result, _ = store.Take(context.Background(), "client", policy)
+	assert.False(t, result.Allowed, "a burst of 1 allows one request per 50ms")
+
+	time.Sleep(60 * time.Millisecond)
+	result, _ = store.Take(context.Background(), "client", policy)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	store := ratelimit.NewMemoryStore(2)
+	policy := ratelimit.Policy{Limit: 1, Period: time.Minute}
+	ctx := context.Background()
+
+	_, _ = store.Take(ctx, "a", policy)
+	_, _ = store.Take(ctx, "b", policy)
+	_, _ = store.Take(ctx, "a", policy) // Denied, but marks a as used
+	_, _ = store.Take(ctx, "c", policy)
+	assert.Equal(t, 2, store.Len())
+
+	result, _ := store.Take(ctx, "a", policy)
+	assert.False(t, result.Allowed, "a was used recently and kept")
+	result, _ = store.Take(ctx, "b", policy)
+	assert.True(t, result.Allowed, "b was evicted and starts over")
+}
+
+func TestLimiter_AllowAndReset(t *testing.T) {
+	limiter := ratelimit.NewLimiterWithStore(nil, ratelimit.Policy{Name: "ai", Limit: 2, Period: time.Minute})
+
+	assert.True(t, limiter.Allow("user-1"))
+	assert.True(t, limiter.Allow("user-1"))
+	assert.False(t, limiter.Allow("user-1"))
+	assert.True(t, limiter.Allow("user-2"))
+
+	limiter.Reset("user-1")
+	assert.True(t, limiter.Allow("user-1"))
+}
+
+func TestLimiter_FallsBackToMemoryWhenRedisIsDown(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: 50 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+	defer client.Close()
+	limiter := ratelimit.NewLimiter(client, ratelimit.Policy{Limit: 1, Period: time.Minute})
+
+	assert.True(t, limiter.Allow("user-1"))
+	assert.False(t, limiter.Allow("user-1"), "the fallback still enforces the limit")
+}
+
+func TestLimiter_CancelledRequestsKeepTheStore(t *testing.T) {
+	store := &countingStore{}
+	limiter := ratelimit.NewLimiterWithStore(store, ratelimit.Policy{})
+	policy := ratelimit.Policy{Limit: 1, Period: time.Minute}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter.Take(ctx, "user:1", policy)
+	assert.Zero(t, store.takes, "cancelled requests do not reach the store")
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.Take(context.Background(), "user:1", policy).Allowed)
+	}
+	assert.Equal(t, 3, store.takes, "the store stays in use after a cancelled request")
+}
@@ -0,0 +1,129 @@
+package validation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EliasRanz/ai-code-gen/internal/domain/ai"
+	"github.com/EliasRanz/ai-code-gen/internal/infrastructure/validation"
+)
+
+func validate(t *testing.T, code, language string) ai.ValidationResult {
+	t.Helper()
+	result, err := validation.NewService().Validate(context.Background(), code, language)
+	require.NoError(t, err)
+	return result
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"package main\n\nfunc main() {}", "go"},
+		{"func add(a, b int) int {\n\treturn a + b\n}", "go"},
+		{`{"name": "button", "private": true}`, "json"},
+		{"def hello():\n    print({'a': 1})", "python"},
+		{"<!DOCTYPE html>\n<html><body></body></html>", "html"},
+		{`<div class="card"><p>Hi</p></div>`, "html"},
+		{".card {\n  padding: 1rem;\n}", "css"},
+		{"export const Button = () => <button className=\"btn\">Go</button>;", "javascript"},
+		{"interface Props { label: string }\nexport function Button(props: Props) { return null }", "typescript"},
+		{"const items = useState<string[]>([]);\nexport const n: number = 1;", "typescript"},
+		{"export function Button({ label }: { label: string }) {\n  return <button>{label}</button>;\n}", "tsx"},
+		{"This is just random text without any code structure", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, validation.Detect(tt.code), tt.code)
+	}
+}
+
+func TestService_Validate_Go(t *testing.T) {
+	result := validate(t, "package main\n\nfunc main() {\n\tx := \n}\n", "go")
+	assert.False(t, result.Valid)
+	require.NotEmpty(t, result.Diagnostics)
+	assert.Equal(t, 5, result.Diagnostics[0].Line)
+	assert.Equal(t, "syntax", result.Diagnostics[0].Rule)
+	assert.Equal(t, ai.SeverityError, result.Diagnostics[0].Severity)
+
+	// Lines of snippets without a package clause match the snippet
+	result = validate(t, "func main() {\n\tfmt.Println(\"hi\"\n}", "golang")
+	assert.False(t, result.Valid)
+	assert.Equal(t, "go", result.Language)
+	assert.Equal(t, 2, result.Diagnostics[0].Line)
+
+	assert.True(t, validate(t, "func main() {\n\tfmt.Println(\"hi\")\n}", "go").Valid)
+}
+
+func TestService_Validate_JSON(t *testing.T) {
+	result := validate(t, "{\n  \"a\": 1,\n  \"b\": \n}", "json")
+	assert.False(t, result.Valid)
+	require.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, 4, result.Diagnostics[0].Line)
+	assert.Equal(t, 1, result.Diagnostics[0].Column)
+
+	assert.True(t, validate(t, `{"a": [1, 2]}`, "json").Valid)
+}
+
+func TestService_Validate_HTML(t *testing.T) {
+	result := validate(t, "<div>\n  <span>Hi</div>\n</section>", "html")
+	assert.False(t, result.Valid)
+	require.Len(t, result.Diagnostics, 2)
+	assert.Equal(t, ai.Diagnostic{Line: 2, Column: 3, Severity: ai.SeverityError, Rule: "unclosed-tag", Message: "<span> is not closed before </div>"}, result.Diagnostics[0])
+	assert.Equal(t, "unexpected-end-tag", result.Diagnostics[1].Rule)
+	assert.Equal(t, 3, result.Diagnostics[1].Line)
+
+	// Void elements and optional end tags need no closing tag
+	result = validate(t, `<ul><li>One<li>Two</ul><img src="a.png" alt="" alt=""><br>`, "html")
+	assert.True(t, result.Valid)
+	require.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, ai.SeverityWarning, result.Diagnostics[0].Severity)
+	assert.Equal(t, "duplicate-attribute", result.Diagnostics[0].Rule)
+}
+
+func TestService_Validate_CSS(t *testing.T) {
+	result := validate(t, ".a {\n  color red;\n}\n.b { margin: ; }\n}", "css")
+	assert.False(t, result.Valid)
+	rules := make([]string, 0, len(result.Diagnostics))
+	for _, d := range result.Diagnostics {
+		rules = append(rules, d.Rule)
+	}
+	assert.Equal(t, []string{"invalid-declaration", "invalid-declaration", "unexpected-brace"}, rules)
+	assert.Equal(t, 2, result.Diagnostics[0].Line)
+	assert.Equal(t, 3, result.Diagnostics[0].Column)
+
+	assert.True(t, validate(t, "@media (min-width: 640px) {\n  .a { content: \"{;}\"; @apply px-4; }\n}", "css").Valid)
+	assert.Equal(t, "unclosed-block", validate(t, ".a { color: red;", "css").Diagnostics[0].Rule)
+	assert.True(t, validate(t, ".a {\n  // don't\n  &:hover { color: red; }\n}", "scss").Valid)
+}
+
+func TestService_Validate_Script(t *testing.T) {
+	tsx := "export function Button({ label }: { label: string }) {\n  return <button onClick={() => alert(label)}>{label}</button>;\n}"
+	assert.True(t, validate(t, tsx, "tsx").Valid)
+	assert.False(t, validate(t, tsx, "typescript").Valid, "TypeScript files cannot contain JSX")
+	assert.True(t, validate(t, "const n = <number>value;\nexport default n;", "ts").Valid, "type assertions are not JSX in TypeScript files")
+
+	result := validate(t, "export const Card = () => (\n  <div>\n    <p>Hi</div>\n);", "jsx")
+	assert.False(t, result.Valid)
+	require.NotEmpty(t, result.Diagnostics)
+	assert.Equal(t, 3, result.Diagnostics[0].Line)
+	assert.Positive(t, result.Diagnostics[0].Column)
+}
+
+func TestService_Validate_UnsupportedAndUnrecognized(t *testing.T) {
+	result := validate(t, "def hello():\n    print('hi')", "")
+	assert.True(t, result.Valid)
+	assert.Equal(t, "python", result.Language)
+	assert.Equal(t, ai.SeverityInfo, result.Diagnostics[0].Severity)
+
+	result = validate(t, "This is just random text without any code structure", "")
+	assert.False(t, result.Valid)
+	assert.Equal(t, []string{"Code does not look like any supported language"}, result.Errors)
+
+	result = validate(t, "  \n", "go")
+	assert.False(t, result.Valid)
+	assert.Equal(t, "empty", result.Diagnostics[0].Rule)
+}